@@ -14,8 +14,10 @@ import (
 	"github.com/ChainSafe/gossamer/chain/gssmr"
 	"github.com/ChainSafe/gossamer/dot"
 	ctoml "github.com/ChainSafe/gossamer/dot/config/toml"
+	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/state"
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/sync"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
@@ -131,6 +133,10 @@ func createDotConfig(ctx *cli.Context) (*dot.Config, error) {
 		return nil, err
 	}
 
+	if !cfg.Global.SyncMode.IsValid() {
+		return nil, fmt.Errorf("--%s must be either %s or %s", SyncModeFlag.Name, sync.FullMode, sync.FastMode)
+	}
+
 	// set remaining cli configuration values
 	setDotInitConfig(ctx, tomlCfg.Init, &cfg.Init)
 	setDotAccountConfig(ctx, tomlCfg.Account, &cfg.Account)
@@ -143,6 +149,26 @@ func createDotConfig(ctx *cli.Context) (*dot.Config, error) {
 		cfg.State.Rewind = rewind
 	}
 
+	for _, badBlock := range ctx.GlobalStringSlice(BadBlockFlag.Name) {
+		hash, err := common.HexToBytes(badBlock)
+		if err != nil || len(hash) != len(common.Hash{}) {
+			return nil, fmt.Errorf("--%s must be a 0x prefixed block hash: %s", BadBlockFlag.Name, badBlock)
+		}
+
+		cfg.State.BadBlocks = append(cfg.State.BadBlocks, common.NewHash(hash))
+	}
+
+	cfg.State.TrieCacheSize = ctx.GlobalInt(TrieCacheSizeFlag.Name)
+	if cfg.State.TrieCacheSize < 0 {
+		return nil, fmt.Errorf("--%s cannot be negative", TrieCacheSizeFlag.Name)
+	}
+
+	storageCacheSize := ctx.GlobalInt(StorageCacheSizeFlag.Name)
+	if storageCacheSize < 0 {
+		return nil, fmt.Errorf("--%s cannot be negative", StorageCacheSizeFlag.Name)
+	}
+	cfg.State.StorageCacheSize = storageCacheSize * 1024 * 1024
+
 	// set system info
 	setSystemInfoConfig(ctx, cfg)
 
@@ -456,6 +482,10 @@ func setDotGlobalConfigFromToml(tomlCfg *ctoml.Config, cfg *dot.GlobalConfig) {
 
 		cfg.RetainBlocks = tomlCfg.Global.RetainBlocks
 		cfg.Pruning = pruner.Mode(tomlCfg.Global.Pruning)
+
+		if tomlCfg.Global.SyncMode != "" {
+			cfg.SyncMode = sync.Mode(tomlCfg.Global.SyncMode)
+		}
 	}
 }
 
@@ -486,6 +516,12 @@ func setDotGlobalConfigFromFlags(ctx *cli.Context, cfg *dot.GlobalConfig) error
 
 	cfg.RetainBlocks = ctx.Int64(RetainBlockNumberFlag.Name)
 	cfg.Pruning = pruner.Mode(ctx.String(PruningFlag.Name))
+
+	// check --sync flag and update node configuration
+	if syncMode := ctx.String(SyncModeFlag.Name); syncMode != "" {
+		cfg.SyncMode = sync.Mode(syncMode)
+	}
+
 	cfg.NoTelemetry = ctx.Bool("no-telemetry")
 
 	var telemetryEndpoints []genesis.TelemetryEndpoint
@@ -636,6 +672,7 @@ func setDotCoreConfig(ctx *cli.Context, tomlCfg ctoml.CoreConfig, cfg *dot.CoreC
 // setDotNetworkConfig sets dot.NetworkConfig using flag values from the cli context
 func setDotNetworkConfig(ctx *cli.Context, tomlCfg ctoml.NetworkConfig, cfg *dot.NetworkConfig) {
 	cfg.Port = tomlCfg.Port
+	cfg.ListenAddrs = tomlCfg.ListenAddrs
 	cfg.Bootnodes = tomlCfg.Bootnodes
 	cfg.ProtocolID = tomlCfg.ProtocolID
 	cfg.NoBootstrap = tomlCfg.NoBootstrap
@@ -644,12 +681,42 @@ func setDotNetworkConfig(ctx *cli.Context, tomlCfg ctoml.NetworkConfig, cfg *dot
 	cfg.MaxPeers = tomlCfg.MaxPeers
 	cfg.PersistentPeers = tomlCfg.PersistentPeers
 	cfg.DiscoveryInterval = time.Second * time.Duration(tomlCfg.DiscoveryInterval)
+	cfg.SwarmKey = tomlCfg.SwarmKey
+	cfg.BandwidthLimits.Global = network.RateLimit{
+		In:  tomlCfg.MaxInboundRate,
+		Out: tomlCfg.MaxOutboundRate,
+	}
+
+	if len(tomlCfg.ProtocolRateLimits) > 0 {
+		cfg.BandwidthLimits.Protocols = make(map[string]network.RateLimit)
+		for name, limit := range tomlCfg.ProtocolRateLimits {
+			cfg.BandwidthLimits.Protocols[name] = network.RateLimit{
+				In:  limit.In,
+				Out: limit.Out,
+			}
+		}
+	}
 
 	// check --port flag and update node configuration
 	if port := ctx.GlobalUint(PortFlag.Name); port != 0 {
 		cfg.Port = uint16(port)
 	}
 
+	// check --listen-addr flag and update node configuration
+	if listenAddrs := ctx.GlobalString(ListenAddrFlag.Name); listenAddrs != "" {
+		cfg.ListenAddrs = strings.Split(listenAddrs, ",")
+	}
+
+	// check --max-inbound-rate flag and update node configuration
+	if rate := ctx.GlobalUint64(MaxInboundRateFlag.Name); rate != 0 {
+		cfg.BandwidthLimits.Global.In = rate
+	}
+
+	// check --max-outbound-rate flag and update node configuration
+	if rate := ctx.GlobalUint64(MaxOutboundRateFlag.Name); rate != 0 {
+		cfg.BandwidthLimits.Global.Out = rate
+	}
+
 	// check --bootnodes flag and update node configuration
 	if bootnodes := ctx.GlobalString(BootnodesFlag.Name); bootnodes != "" {
 		cfg.Bootnodes = strings.Split(ctx.GlobalString(BootnodesFlag.Name), ",")
@@ -680,17 +747,22 @@ func setDotNetworkConfig(ctx *cli.Context, tomlCfg ctoml.NetworkConfig, cfg *dot
 		cfg.PublicIP = pubip
 	}
 
+	// check --swarm-key flag and update node configuration
+	if swarmKey := ctx.GlobalString(SwarmKeyFlag.Name); swarmKey != "" {
+		cfg.SwarmKey = swarmKey
+	}
+
 	if len(cfg.PersistentPeers) == 0 {
 		cfg.PersistentPeers = []string(nil)
 	}
 
 	logger.Debugf(
-		"network configuration: port=%d bootnodes=%s protocol=%s nobootstrap=%t "+
+		"network configuration: port=%d listen-addrs=%s bootnodes=%s protocol=%s nobootstrap=%t "+
 			"nomdns=%t minpeers=%d maxpeers=%d persistent-peers=%s "+
-			"discovery-interval=%s",
-		cfg.Port, strings.Join(cfg.Bootnodes, ","), cfg.ProtocolID, cfg.NoBootstrap,
-		cfg.NoMDNS, cfg.MinPeers, cfg.MaxPeers, strings.Join(cfg.PersistentPeers, ","),
-		cfg.DiscoveryInterval,
+			"discovery-interval=%s max-inbound-rate=%d max-outbound-rate=%d",
+		cfg.Port, strings.Join(cfg.ListenAddrs, ","), strings.Join(cfg.Bootnodes, ","),
+		cfg.ProtocolID, cfg.NoBootstrap, cfg.NoMDNS, cfg.MinPeers, cfg.MaxPeers, strings.Join(cfg.PersistentPeers, ","),
+		cfg.DiscoveryInterval, cfg.BandwidthLimits.Global.In, cfg.BandwidthLimits.Global.Out,
 	)
 }
 
@@ -799,6 +871,7 @@ func updateDotConfigFromGenesisJSONRaw(tomlCfg ctoml.Config, cfg *dot.Config) {
 	cfg.Global.ID = gen.ID
 	cfg.Network.Bootnodes = gen.Bootnodes
 	cfg.Network.ProtocolID = gen.ProtocolID
+	cfg.Network.ForkID = gen.ForkID
 
 	if gen.ProtocolID == "" {
 		logger.Critical("empty protocol ID in genesis file, please set it!")
@@ -842,6 +915,9 @@ func updateDotConfigFromGenesisData(ctx *cli.Context, cfg *dot.Config) error {
 		cfg.Network.ProtocolID = gen.ProtocolID
 	}
 
+	// the fork ID can't be overridden, since it identifies the chain along with its genesis hash
+	cfg.Network.ForkID = gen.ForkID
+
 	// close database
 	err = db.Close()
 	if err != nil {
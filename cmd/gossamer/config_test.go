@@ -13,9 +13,12 @@ import (
 	"github.com/ChainSafe/gossamer/chain/gssmr"
 	"github.com/ChainSafe/gossamer/dot"
 	ctoml "github.com/ChainSafe/gossamer/dot/config/toml"
+	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/dot/sync"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/genesis"
 	"github.com/ChainSafe/gossamer/lib/utils"
 
@@ -80,8 +83,6 @@ func TestInitConfigFromFlags(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -119,8 +120,6 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -141,6 +140,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -154,6 +154,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       dot.KusamaConfig().Global.SyncMode,
 			},
 		},
 		{
@@ -167,6 +168,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -180,6 +182,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -193,6 +196,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -206,6 +210,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: true,
 				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -219,6 +224,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				LogLvl:         log.Info,
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    uint32(9871),
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -233,6 +239,7 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 				PublishMetrics: testCfg.Global.PublishMetrics,
 				MetricsPort:    testCfg.Global.MetricsPort,
 				NoTelemetry:    true,
+				SyncMode:       testCfg.Global.SyncMode,
 			},
 		},
 		{
@@ -255,6 +262,21 @@ func TestGlobalConfigFromFlags(t *testing.T) {
 					{Endpoint: "ws://localhost:8001/submit", Verbosity: 0},
 					{Endpoint: "ws://foo/bar", Verbosity: 0},
 				},
+				SyncMode: testCfg.Global.SyncMode,
+			},
+		},
+		{
+			"Test gossamer --sync",
+			[]string{"config", "sync", "name"},
+			[]interface{}{testCfgFile.Name(), "fast", testCfg.Global.Name},
+			dot.GlobalConfig{
+				Name:           testCfg.Global.Name,
+				ID:             testCfg.Global.ID,
+				BasePath:       testCfg.Global.BasePath,
+				LogLvl:         log.Info,
+				PublishMetrics: testCfg.Global.PublishMetrics,
+				MetricsPort:    testCfg.Global.MetricsPort,
+				SyncMode:       sync.FastMode,
 			},
 		},
 	}
@@ -277,8 +299,6 @@ func TestGlobalConfigFromFlagsFails(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -309,6 +329,30 @@ func TestGlobalConfigFromFlagsFails(t *testing.T) {
 			"could not set global config from flags: could not parse verbosity from telemetry-url: " +
 				`strconv.Atoi: parsing "k": invalid syntax`,
 		},
+		{
+			"Test gossamer invalid --sync",
+			[]string{"config", "sync", "name"},
+			[]interface{}{testCfgFile.Name(), "warp", testCfg.Global.Name},
+			"--sync must be either full or fast",
+		},
+		{
+			"Test gossamer invalid --bad-block",
+			[]string{"config", "bad-block", "name"},
+			[]interface{}{testCfgFile.Name(), []string{"0x0a0b"}, testCfg.Global.Name},
+			"--bad-block must be a 0x prefixed block hash: 0x0a0b",
+		},
+		{
+			"Test gossamer invalid --trie-cache-size",
+			[]string{"config", "trie-cache-size", "name"},
+			[]interface{}{testCfgFile.Name(), int64(-1), testCfg.Global.Name},
+			"--trie-cache-size cannot be negative",
+		},
+		{
+			"Test gossamer invalid --storage-cache-size",
+			[]string{"config", "storage-cache-size", "name"},
+			[]interface{}{testCfgFile.Name(), int64(-1), testCfg.Global.Name},
+			"--storage-cache-size cannot be negative",
+		},
 	}
 
 	for _, c := range testcases {
@@ -325,14 +369,55 @@ func TestGlobalConfigFromFlagsFails(t *testing.T) {
 	}
 }
 
+// TestStateConfigFromFlags tests createDotConfig using relevant state flags
+func TestStateConfigFromFlags(t *testing.T) {
+	testCfg, testCfgFile := newTestConfigWithFile(t)
+	require.NotNil(t, testCfg)
+	require.NotNil(t, testCfgFile)
+
+	badBlocks := []common.Hash{{0xa}, {0xb}}
+	ctx, err := newTestContext(
+		"Test gossamer --bad-block",
+		[]string{"config", "bad-block", "name"},
+		[]interface{}{testCfgFile.Name(), []string{badBlocks[0].String(), badBlocks[1].String()}, testCfg.Global.Name},
+	)
+	require.Nil(t, err)
+
+	cfg, err := createDotConfig(ctx)
+	require.Nil(t, err)
+	require.Equal(t, badBlocks, cfg.State.BadBlocks)
+	require.Zero(t, cfg.State.TrieCacheSize)
+	require.Zero(t, cfg.State.StorageCacheSize)
+
+	ctx, err = newTestContext(
+		"Test gossamer --trie-cache-size",
+		[]string{"config", "trie-cache-size", "name"},
+		[]interface{}{testCfgFile.Name(), int64(100000), testCfg.Global.Name},
+	)
+	require.Nil(t, err)
+
+	cfg, err = createDotConfig(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 100000, cfg.State.TrieCacheSize)
+
+	ctx, err = newTestContext(
+		"Test gossamer --storage-cache-size",
+		[]string{"config", "storage-cache-size", "name"},
+		[]interface{}{testCfgFile.Name(), int64(64), testCfg.Global.Name},
+	)
+	require.Nil(t, err)
+
+	cfg, err = createDotConfig(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 64*1024*1024, cfg.State.StorageCacheSize)
+}
+
 // TestAccountConfigFromFlags tests createDotAccountConfig using relevant account flags
 func TestAccountConfigFromFlags(t *testing.T) {
 	testCfg, testCfgFile := newTestConfigWithFile(t)
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -380,8 +465,6 @@ func TestCoreConfigFromFlags(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -435,8 +518,6 @@ func TestNetworkConfigFromFlags(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -537,6 +618,40 @@ func TestNetworkConfigFromFlags(t *testing.T) {
 				PublicIP:          "10.0.5.2",
 			},
 		},
+		{
+			"Test gossamer --max-inbound-rate --max-outbound-rate",
+			[]string{"config", "max-inbound-rate", "max-outbound-rate"},
+			[]interface{}{testCfgFile.Name(), "1024", "2048"},
+			dot.NetworkConfig{
+				Port:              testCfg.Network.Port,
+				Bootnodes:         testCfg.Network.Bootnodes,
+				ProtocolID:        testCfg.Network.ProtocolID,
+				NoBootstrap:       testCfg.Network.NoBootstrap,
+				NoMDNS:            testCfg.Network.NoMDNS,
+				DiscoveryInterval: time.Second * 10,
+				MinPeers:          testCfg.Network.MinPeers,
+				MaxPeers:          testCfg.Network.MaxPeers,
+				BandwidthLimits: network.BandwidthLimits{
+					Global: network.RateLimit{In: 1024, Out: 2048},
+				},
+			},
+		},
+		{
+			"Test gossamer --swarm-key",
+			[]string{"config", "swarm-key"},
+			[]interface{}{testCfgFile.Name(), "/tmp/swarm.key"},
+			dot.NetworkConfig{
+				Port:              testCfg.Network.Port,
+				Bootnodes:         testCfg.Network.Bootnodes,
+				ProtocolID:        testCfg.Network.ProtocolID,
+				NoBootstrap:       testCfg.Network.NoBootstrap,
+				NoMDNS:            testCfg.Network.NoMDNS,
+				DiscoveryInterval: time.Second * 10,
+				MinPeers:          testCfg.Network.MinPeers,
+				MaxPeers:          testCfg.Network.MaxPeers,
+				SwarmKey:          "/tmp/swarm.key",
+			},
+		},
 	}
 
 	for _, c := range testcases {
@@ -557,8 +672,6 @@ func TestRPCConfigFromFlags(t *testing.T) {
 	require.NotNil(t, testCfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
 
@@ -767,8 +880,6 @@ func TestUpdateConfigFromGenesisJSON(t *testing.T) {
 	testCfg, testCfgFile := newTestConfigWithFile(t)
 	genFile := dot.NewTestGenesisRawFile(t, testCfg)
 
-	defer utils.RemoveTestDir(t)
-
 	ctx, err := newTestContext(
 		t.Name(),
 		[]string{"config", "genesis", "name"},
@@ -822,8 +933,6 @@ func TestUpdateConfigFromGenesisJSON(t *testing.T) {
 func TestUpdateConfigFromGenesisJSON_Default(t *testing.T) {
 	testCfg, testCfgFile := newTestConfigWithFile(t)
 
-	defer utils.RemoveTestDir(t)
-
 	ctx, err := newTestContext(
 		t.Name(),
 		[]string{"config", "genesis", "name"},
@@ -873,8 +982,6 @@ func TestUpdateConfigFromGenesisData(t *testing.T) {
 	testCfg, testCfgFile := newTestConfigWithFile(t)
 	genFile := dot.NewTestGenesisRawFile(t, testCfg)
 
-	defer utils.RemoveTestDir(t)
-
 	ctx, err := newTestContext(
 		t.Name(),
 		[]string{"config", "genesis", "name"},
@@ -956,8 +1063,6 @@ func TestGlobalNodeName_WhenNodeAlreadyHasStoredName(t *testing.T) {
 	genPath := dot.NewTestGenesisAndRuntime(t)
 	require.NotNil(t, genPath)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -1007,8 +1112,6 @@ func TestGlobalNodeNamePriorityOrder(t *testing.T) {
 	require.NotNil(t, cfg)
 	require.NotNil(t, testCfgFile)
 
-	defer utils.RemoveTestDir(t)
-
 	// call another command and test the name
 	testApp := cli.NewApp()
 	testApp.Writer = io.Discard
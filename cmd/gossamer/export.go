@@ -74,6 +74,7 @@ func dotConfigToToml(dcfg *dot.Config) *ctoml.Config {
 		MetricsPort:  dcfg.Global.MetricsPort,
 		RetainBlocks: dcfg.Global.RetainBlocks,
 		Pruning:      string(dcfg.Global.Pruning),
+		SyncMode:     string(dcfg.Global.SyncMode),
 	}
 
 	cfg.Log = ctoml.LogConfig{
@@ -105,6 +106,7 @@ func dotConfigToToml(dcfg *dot.Config) *ctoml.Config {
 
 	cfg.Network = ctoml.NetworkConfig{
 		Port:              dcfg.Network.Port,
+		ListenAddrs:       dcfg.Network.ListenAddrs,
 		Bootnodes:         dcfg.Network.Bootnodes,
 		ProtocolID:        dcfg.Network.ProtocolID,
 		NoBootstrap:       dcfg.Network.NoBootstrap,
@@ -112,6 +114,19 @@ func dotConfigToToml(dcfg *dot.Config) *ctoml.Config {
 		DiscoveryInterval: int(dcfg.Network.DiscoveryInterval / time.Second),
 		MinPeers:          dcfg.Network.MinPeers,
 		MaxPeers:          dcfg.Network.MaxPeers,
+		MaxInboundRate:    dcfg.Network.BandwidthLimits.Global.In,
+		MaxOutboundRate:   dcfg.Network.BandwidthLimits.Global.Out,
+		SwarmKey:          dcfg.Network.SwarmKey,
+	}
+
+	if len(dcfg.Network.BandwidthLimits.Protocols) > 0 {
+		cfg.Network.ProtocolRateLimits = make(map[string]ctoml.RateLimitConfig)
+		for name, limit := range dcfg.Network.BandwidthLimits.Protocols {
+			cfg.Network.ProtocolRateLimits[name] = ctoml.RateLimitConfig{
+				In:  limit.In,
+				Out: limit.Out,
+			}
+		}
 	}
 
 	cfg.RPC = ctoml.RPCConfig{
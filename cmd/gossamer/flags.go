@@ -5,6 +5,7 @@ package main
 
 import (
 	"github.com/ChainSafe/gossamer/chain/dev"
+	"github.com/ChainSafe/gossamer/dot"
 	"github.com/urfave/cli"
 )
 
@@ -173,6 +174,51 @@ var (
 		Name:  "first-slot",
 		Usage: "The first BABE slot of the network",
 	}
+	// SnapshotFlag is the path to a state snapshot written by export-state, imported instead of a JSON state
+	SnapshotFlag = cli.StringFlag{
+		Name:  "snapshot",
+		Usage: "Path to a state snapshot file written by export-state, instead of --state, --header and --first-slot",
+	}
+)
+
+// ExportState flags
+var (
+	// SnapshotBlockFlag is the hash of the block whose state is exported
+	SnapshotBlockFlag = cli.StringFlag{
+		Name:  "block",
+		Usage: "Hash of the block whose state to export, defaults to the highest finalised block",
+	}
+	// SnapshotOutFlag is the path to the state snapshot file to write
+	SnapshotOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Path to the state snapshot file to write",
+	}
+)
+
+// ExportBlocks and ImportBlocks flags
+var (
+	// FromBlockFlag is the number of the first block to export
+	FromBlockFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Number of the first block to export",
+		Value: 1,
+	}
+	// ToBlockFlag is the number of the last block to export
+	ToBlockFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Number of the last block to export, defaults to the best block",
+	}
+	// BlockFormatFlag is the format of the block file
+	BlockFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the block file, either binary or json",
+		Value: string(dot.BinaryBlockFile),
+	}
+	// BlockFileFlag is the path to the block file
+	BlockFileFlag = cli.StringFlag{
+		Name:  "file",
+		Usage: "Path to the block file to export to or import from",
+	}
 )
 
 // BuildSpec-only flags
@@ -198,6 +244,21 @@ var (
 		Name:  "port",
 		Usage: "Set network listening port",
 	}
+	// ListenAddrFlag Set network listening multiaddrs
+	ListenAddrFlag = cli.StringFlag{
+		Name:  "listen-addr",
+		Usage: "Comma separated multiaddrs to listen on, using TCP, WebSocket (/ws) or QUIC (/quic) transports",
+	}
+	// MaxInboundRateFlag Set the global inbound bandwidth limit
+	MaxInboundRateFlag = cli.Uint64Flag{
+		Name:  "max-inbound-rate",
+		Usage: "Maximum rate in bytes per second at which messages are received from peers (0 = unlimited)",
+	}
+	// MaxOutboundRateFlag Set the global outbound bandwidth limit
+	MaxOutboundRateFlag = cli.Uint64Flag{
+		Name:  "max-outbound-rate",
+		Usage: "Maximum rate in bytes per second at which messages are sent to peers (0 = unlimited)",
+	}
 	// BootnodesFlag Network service settings
 	BootnodesFlag = cli.StringFlag{
 		Name:  "bootnodes",
@@ -223,6 +284,11 @@ var (
 		Name:  "pubip",
 		Usage: "Overrides public IP address used for peer to peer networking",
 	}
+	// SwarmKeyFlag Set the pre-shared key file of a private network
+	SwarmKeyFlag = cli.StringFlag{
+		Name:  "swarm-key",
+		Usage: "Path to the pre-shared key file of a private network, only nodes with the same key can connect",
+	}
 )
 
 // RPC service configuration flags
@@ -366,6 +432,39 @@ var (
 	}
 )
 
+// sync flags
+var (
+	// SyncModeFlag sets how the node syncs the chain, either by executing every block,
+	// or by importing the headers and downloading the state of the latest finalised block
+	SyncModeFlag = cli.StringFlag{
+		Name:  "sync",
+		Usage: `Sync mode ("full", "fast")`,
+	}
+
+	// BadBlockFlag is the hash of a block which the node refuses to import, along with its descendants,
+	// in addition to the bad blocks of the chain spec. This flag can be passed multiple times.
+	BadBlockFlag = cli.StringSliceFlag{
+		Name:  "bad-block",
+		Usage: "Hash of a block to refuse along with its descendants, this flag can be passed multiple times",
+	}
+)
+
+// state flags
+var (
+	// TrieCacheSizeFlag makes the node load the state tries lazily from the database, keeping the given
+	// number of trie nodes in memory, rather than loading the state tries fully into memory
+	TrieCacheSizeFlag = cli.IntFlag{
+		Name:  "trie-cache-size",
+		Usage: "Number of state trie nodes to keep in memory, loading the state tries lazily from the database " +
+			"(0 = load the state tries into memory)",
+	}
+	// StorageCacheSizeFlag sets the size of the cache of the storage values of the best block
+	StorageCacheSizeFlag = cli.IntFlag{
+		Name:  "storage-cache-size",
+		Usage: "Size in MiB of the cache of the storage values of the best block (0 = disabled)",
+	}
+)
+
 // BABE flags
 var (
 	BABELeadFlag = cli.BoolFlag{
@@ -408,12 +507,16 @@ var (
 
 		// network flags
 		PortFlag,
+		ListenAddrFlag,
+		MaxInboundRateFlag,
+		MaxOutboundRateFlag,
 		BootnodesFlag,
 		ProtocolFlag,
 		RolesFlag,
 		NoBootstrapFlag,
 		NoMDNSFlag,
 		PublicIPFlag,
+		SwarmKeyFlag,
 
 		// rpc flags
 		RPCEnabledFlag,
@@ -437,6 +540,14 @@ var (
 		NoTelemetryFlag,
 		TelemetryURLFlag,
 
+		// sync flags
+		SyncModeFlag,
+		BadBlockFlag,
+
+		// state flags
+		TrieCacheSizeFlag,
+		StorageCacheSizeFlag,
+
 		// BABE flags
 		BABELeadFlag,
 	}
@@ -486,8 +597,29 @@ var (
 		StateFlag,
 		HeaderFlag,
 		FirstSlotFlag,
+		SnapshotFlag,
 	}
 
+	// ExportStateFlags are the flags that are valid for use with the export-state subcommand
+	ExportStateFlags = append([]cli.Flag{
+		SnapshotBlockFlag,
+		SnapshotOutFlag,
+	}, GlobalFlags...)
+
+	// ExportBlocksFlags are the flags that are valid for use with the export-blocks subcommand
+	ExportBlocksFlags = append([]cli.Flag{
+		FromBlockFlag,
+		ToBlockFlag,
+		BlockFormatFlag,
+		BlockFileFlag,
+	}, GlobalFlags...)
+
+	// ImportBlocksFlags are the flags that are valid for use with the import-blocks subcommand
+	ImportBlocksFlags = append([]cli.Flag{
+		BlockFormatFlag,
+		BlockFileFlag,
+	}, GlobalFlags...)
+
 	PruningFlags = []cli.Flag{
 		ChainFlag,
 		ConfigFlag,
@@ -4,13 +4,16 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/ChainSafe/gossamer/dot"
 	"github.com/ChainSafe/gossamer/dot/state"
 	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/utils"
 	"github.com/urfave/cli"
@@ -23,7 +26,10 @@ const (
 	buildSpecCommandName     = "build-spec"
 	importRuntimeCommandName = "import-runtime"
 	importStateCommandName   = "import-state"
+	exportStateCommandName   = "export-state"
 	pruningStateCommandName  = "prune-state"
+	exportBlocksCommandName  = "export-blocks"
+	importBlocksCommandName  = "import-blocks"
 )
 
 // app is the cli application
@@ -100,14 +106,55 @@ var (
 	importStateCommand = cli.Command{
 		Action:    FixFlagOrder(importStateAction),
 		Name:      importStateCommandName,
-		Usage:     "Import state from a JSON file and set it as the chain head state",
+		Usage:     "Import state from a JSON file or a state snapshot and set it as the chain head state",
 		ArgsUsage: "",
 		Flags:     ImportStateFlags,
 		Category:  "IMPORT-STATE",
 		Description: "The import-state command allows a JSON file containing a given state " +
 			"in the form of key-value pairs to be imported.\n" +
 			"Input can be generated by using the RPC function state_getPairs.\n" +
-			"\tUsage: gossamer import-state --state state.json --header header.json --first-slot <first slot of network>\n",
+			"\tUsage: gossamer import-state --state state.json --header header.json --first-slot <first slot of network>\n" +
+			"It also imports the state snapshots written by export-state, verifying their checksum and state root.\n" +
+			"\tUsage: gossamer import-state --snapshot snap.bin\n",
+	}
+
+	// exportStateCommand defines the "export-state" subcommand (ie, `gossamer export-state`)
+	exportStateCommand = cli.Command{
+		Action:    FixFlagOrder(exportStateAction),
+		Name:      exportStateCommandName,
+		Usage:     "Export the state of a block to a state snapshot file",
+		ArgsUsage: "",
+		Flags:     ExportStateFlags,
+		Category:  "EXPORT-STATE",
+		Description: "The export-state command writes the state trie entries of a block, along with its header, " +
+			"epoch data and GRANDPA set, to a binary state snapshot file which can be imported by import-state.\n" +
+			"\tUsage: gossamer export-state --block <block hash> --out snap.bin\n",
+	}
+
+	// exportBlocksCommand defines the "export-blocks" subcommand (ie, `gossamer export-blocks`)
+	exportBlocksCommand = cli.Command{
+		Action:    FixFlagOrder(exportBlocksAction),
+		Name:      exportBlocksCommandName,
+		Usage:     "Export a range of blocks and their justifications to a file",
+		ArgsUsage: "",
+		Flags:     ExportBlocksFlags,
+		Category:  "EXPORT-BLOCKS",
+		Description: "The export-blocks command writes the SCALE encoded blocks from --from to --to included, " +
+			"along with their justifications, to a binary or JSON file.\n" +
+			"\tUsage: gossamer export-blocks --from 1 --to 1000 --format binary --file blocks.bin\n",
+	}
+
+	// importBlocksCommand defines the "import-blocks" subcommand (ie, `gossamer import-blocks`)
+	importBlocksCommand = cli.Command{
+		Action:    FixFlagOrder(importBlocksAction),
+		Name:      importBlocksCommandName,
+		Usage:     "Import blocks and their justifications from a file written by export-blocks",
+		ArgsUsage: "",
+		Flags:     ImportBlocksFlags,
+		Category:  "IMPORT-BLOCKS",
+		Description: "The import-blocks command verifies and executes the blocks of a file written by " +
+			"export-blocks, the same way as blocks received while syncing.\n" +
+			"\tUsage: gossamer import-blocks --format binary --file blocks.bin\n",
 	}
 
 	pruningCommand = cli.Command{
@@ -138,7 +185,10 @@ func init() {
 		buildSpecCommand,
 		importRuntimeCommand,
 		importStateCommand,
+		exportStateCommand,
 		pruningCommand,
+		exportBlocksCommand,
+		importBlocksCommand,
 	}
 	app.Flags = RootFlags
 }
@@ -152,6 +202,10 @@ func main() {
 }
 
 func importStateAction(ctx *cli.Context) error {
+	if snapshotFP := ctx.String(SnapshotFlag.Name); snapshotFP != "" {
+		return importStateSnapshot(ctx, snapshotFP)
+	}
+
 	var (
 		stateFP, headerFP string
 		firstSlot         int
@@ -179,6 +233,81 @@ func importStateAction(ctx *cli.Context) error {
 	return dot.ImportState(cfg.Global.BasePath, stateFP, headerFP, uint64(firstSlot))
 }
 
+// importStateSnapshot imports the state snapshot file written by export-state at the given path
+func importStateSnapshot(ctx *cli.Context, fp string) error {
+	cfg, err := createImportStateConfig(ctx)
+	if err != nil {
+		logger.Errorf("failed to create node configuration: %s", err)
+		return err
+	}
+	cfg.Global.BasePath = utils.ExpandDir(cfg.Global.BasePath)
+
+	if !dot.NodeInitialized(cfg.Global.BasePath) {
+		return fmt.Errorf("node at base path %s is not initialised, run init first", cfg.Global.BasePath)
+	}
+
+	file, err := os.Open(filepath.Clean(fp))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Errorf("failed to close state snapshot file: %s", err)
+		}
+	}()
+
+	return dot.ImportStateSnapshot(cfg.Global.BasePath, bufio.NewReader(file))
+}
+
+// exportStateAction is the action for the "export-state" subcommand, writes the state of a block from the
+// node database to a state snapshot file
+func exportStateAction(ctx *cli.Context) error {
+	fp := ctx.String(SnapshotOutFlag.Name)
+	if fp == "" {
+		return fmt.Errorf("must provide argument to --%s", SnapshotOutFlag.Name)
+	}
+
+	var hash *common.Hash
+	if block := ctx.String(SnapshotBlockFlag.Name); block != "" {
+		b, err := common.HexToBytes(block)
+		if err != nil || len(b) != len(common.Hash{}) {
+			return fmt.Errorf("--%s must be a 0x prefixed block hash: %s", SnapshotBlockFlag.Name, block)
+		}
+
+		h := common.NewHash(b)
+		hash = &h
+	}
+
+	if _, err := setupLogger(ctx); err != nil {
+		logger.Errorf("failed to setup logger: %s", err)
+		return err
+	}
+
+	cfg, err := createDotConfig(ctx)
+	if err != nil {
+		logger.Errorf("failed to create node configuration: %s", err)
+		return err
+	}
+	cfg.Global.BasePath = utils.ExpandDir(cfg.Global.BasePath)
+
+	file, err := os.Create(filepath.Clean(fp))
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = dot.ExportStateSnapshot(cfg, hash, w)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
 // importRuntimeAction generates a genesis file given a .wasm runtime binary.
 func importRuntimeAction(ctx *cli.Context) error {
 	arguments := ctx.Args()
@@ -196,6 +325,116 @@ func importRuntimeAction(ctx *cli.Context) error {
 	return nil
 }
 
+// blockFileFormat returns the block file format given to --format
+func blockFileFormat(ctx *cli.Context) (dot.BlockFileFormat, error) {
+	format := dot.BlockFileFormat(ctx.String(BlockFormatFlag.Name))
+	if !format.IsValid() {
+		return "", fmt.Errorf("--%s must be either %s or %s",
+			BlockFormatFlag.Name, dot.BinaryBlockFile, dot.JSONBlockFile)
+	}
+
+	return format, nil
+}
+
+// exportBlocksAction is the action for the "export-blocks" subcommand, writes a range of blocks
+// and their justifications from the node database to a block file
+func exportBlocksAction(ctx *cli.Context) error {
+	format, err := blockFileFormat(ctx)
+	if err != nil {
+		return err
+	}
+
+	fp := ctx.String(BlockFileFlag.Name)
+	if fp == "" {
+		return fmt.Errorf("must provide argument to --%s", BlockFileFlag.Name)
+	}
+
+	if _, err = setupLogger(ctx); err != nil {
+		logger.Errorf("failed to setup logger: %s", err)
+		return err
+	}
+
+	cfg, err := createDotConfig(ctx)
+	if err != nil {
+		logger.Errorf("failed to create node configuration: %s", err)
+		return err
+	}
+	cfg.Global.BasePath = utils.ExpandDir(cfg.Global.BasePath)
+
+	file, err := os.Create(filepath.Clean(fp))
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = dot.ExportBlocks(cfg.Global.BasePath, ctx.Uint64(FromBlockFlag.Name), ctx.Uint64(ToBlockFlag.Name),
+		format, w)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// importBlocksAction is the action for the "import-blocks" subcommand, verifies and executes the blocks
+// of a block file written by export-blocks, then stores them in the node database
+func importBlocksAction(ctx *cli.Context) error {
+	format, err := blockFileFormat(ctx)
+	if err != nil {
+		return err
+	}
+
+	fp := ctx.String(BlockFileFlag.Name)
+	if fp == "" {
+		return fmt.Errorf("must provide argument to --%s", BlockFileFlag.Name)
+	}
+
+	lvl, err := setupLogger(ctx)
+	if err != nil {
+		logger.Errorf("failed to setup logger: %s", err)
+		return err
+	}
+
+	cfg, err := createDotConfig(ctx)
+	if err != nil {
+		logger.Errorf("failed to create node configuration: %s", err)
+		return err
+	}
+
+	cfg.Global.LogLvl = lvl
+	cfg.Global.BasePath = utils.ExpandDir(cfg.Global.BasePath)
+
+	if !dot.NodeInitialized(cfg.Global.BasePath) {
+		return fmt.Errorf("node at base path %s is not initialised, run init first", cfg.Global.BasePath)
+	}
+
+	err = updateDotConfigFromGenesisData(ctx, cfg)
+	if err != nil {
+		logger.Errorf("failed to update config from genesis data: %s", err)
+		return err
+	}
+
+	// the node neither produces blocks nor votes while importing, so it doesn't need any keys
+	cfg.Core.BabeAuthority = false
+	cfg.Core.GrandpaAuthority = false
+
+	file, err := os.Open(filepath.Clean(fp))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Errorf("failed to close block file: %s", err)
+		}
+	}()
+
+	return dot.ImportBlocks(cfg, keystore.NewGlobalKeystore(), format, bufio.NewReader(file))
+}
+
 // gossamerAction is the root action for the gossamer command, creates a node
 // configuration, loads the keystore, initialises the node if not initialised,
 // then creates and starts the node and node services
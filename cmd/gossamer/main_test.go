@@ -272,3 +272,32 @@ func TestBuildSpecCommandWithOutput(t *testing.T) {
 	require.Empty(t, outb)
 	require.Empty(t, errb)
 }
+
+func TestBlockFileFormat(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected dot.BlockFileFormat
+		err      bool
+	}{
+		{format: "binary", expected: dot.BinaryBlockFile},
+		{format: "json", expected: dot.JSONBlockFile},
+		{format: "csv", err: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			ctx, err := newTestContext(tc.format, []string{BlockFormatFlag.Name}, []interface{}{tc.format})
+			require.NoError(t, err)
+
+			format, err := blockFileFormat(ctx)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, format)
+		})
+	}
+}
@@ -26,8 +26,6 @@ func TestLoadConfig(t *testing.T) {
 	genFile := dot.NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := dot.InitNode(cfg)
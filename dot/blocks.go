@@ -0,0 +1,388 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package dot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/dot/sync"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+	"github.com/ChainSafe/gossamer/lib/services"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+
+	"github.com/ChainSafe/gossamer/internal/log"
+)
+
+// BlockFileFormat is the format of the block files written by ExportBlocks and read by ImportBlocks
+type BlockFileFormat string
+
+const (
+	// BinaryBlockFile is the number of blocks as a SCALE encoded uint64, followed by the SCALE encoded
+	// blocks, each followed by its optional justification
+	BinaryBlockFile BlockFileFormat = "binary"
+	// JSONBlockFile is a stream of JSON objects, one per block, holding the hex encoded SCALE encoding
+	// of the block and its justification
+	JSONBlockFile BlockFileFormat = "json"
+)
+
+// IsValid returns true if the format is a known block file format
+func (f BlockFileFormat) IsValid() bool {
+	return f == BinaryBlockFile || f == JSONBlockFile
+}
+
+// importProgressInterval is the number of imported blocks between two progress logs
+const importProgressInterval = 1000
+
+// ExportBlocks writes the blocks numbered from `from` to `to` included, along with their justifications,
+// from the database with the given path to w in the given format. If to is 0, the blocks are exported up
+// to the best block.
+func ExportBlocks(basepath string, from, to uint64, format BlockFileFormat, w io.Writer) error {
+	if to != 0 && from > to {
+		return fmt.Errorf("%w: from %d is greater than to %d", errInvalidBlockRange, from, to)
+	}
+
+	srv := state.NewService(state.Config{
+		Path:     basepath,
+		LogLevel: log.Info,
+	})
+
+	if err := srv.Start(); err != nil {
+		return fmt.Errorf("failed to start state service: %w", err)
+	}
+	defer stopService(srv, "state")
+
+	best, err := srv.Block.BestBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	if to == 0 {
+		to = best.Uint64()
+	}
+
+	if from > to || best.Cmp(new(big.Int).SetUint64(to)) < 0 {
+		return fmt.Errorf("%w: cannot export blocks %d to %d with best block number %s",
+			errInvalidBlockRange, from, to, best)
+	}
+
+	bw, err := newBlockWriter(w, format, to-from+1)
+	if err != nil {
+		return err
+	}
+
+	for num := from; num <= to; num++ {
+		block, err := getExportedBlock(srv.Block, num)
+		if err != nil {
+			return fmt.Errorf("failed to get block %d: %w", num, err)
+		}
+
+		if err = bw.write(block); err != nil {
+			return fmt.Errorf("failed to write block %d: %w", num, err)
+		}
+	}
+
+	logger.Infof("exported blocks %d to %d", from, to)
+	return nil
+}
+
+func getExportedBlock(bs *state.BlockState, num uint64) (*exportedBlock, error) {
+	hash, err := bs.GetHashByNumber(new(big.Int).SetUint64(num))
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := bs.GetBlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	eb := &exportedBlock{
+		Block: *block,
+	}
+
+	has, err := bs.HasJustification(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		return eb, nil
+	}
+
+	justification, err := bs.GetJustification(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	eb.Justification = &justification
+	return eb, nil
+}
+
+// ImportBlocks imports the blocks read from r in the given format into the node with the given config.
+// The blocks are verified and executed the same way as the blocks received while syncing, and the
+// parent of the first block must already be imported.
+func ImportBlocks(cfg *Config, ks *keystore.GlobalKeystore, format BlockFileFormat, r io.Reader) error {
+	br, err := newBlockReader(r, format)
+	if err != nil {
+		return err
+	}
+
+	stateSrvc, err := createStateService(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopService(stateSrvc, "state")
+
+	ns, err := createRuntimeStorage(stateSrvc)
+	if err != nil {
+		return err
+	}
+
+	// the blocks are imported without the network service
+	err = loadRuntime(cfg, ns, stateSrvc, ks, nil)
+	if err != nil {
+		return err
+	}
+
+	ver, err := createBlockVerifier(stateSrvc)
+	if err != nil {
+		return err
+	}
+
+	dh, err := createDigestHandler(stateSrvc)
+	if err != nil {
+		return err
+	}
+
+	if err = dh.Start(); err != nil {
+		return err
+	}
+	defer stopService(dh, "digest handler")
+
+	coreSrvc, err := createCoreService(cfg, ks, stateSrvc, nil, dh)
+	if err != nil {
+		return fmt.Errorf("failed to create core service: %s", err)
+	}
+
+	// the core service needs to be started to handle the imported blocks asynchronously
+	if err = coreSrvc.Start(); err != nil {
+		return err
+	}
+	defer stopService(coreSrvc, "core")
+
+	fg, err := createGRANDPAService(cfg, stateSrvc, dh, ks.Gran, nil)
+	if err != nil {
+		return err
+	}
+
+	importer, err := sync.NewBlockImporter(&sync.Config{
+		LogLvl:             cfg.Log.SyncLvl,
+		BlockState:         stateSrvc.Block,
+		StorageState:       stateSrvc.Storage,
+		TransactionState:   stateSrvc.Transaction,
+		FinalityGadget:     fg,
+		BabeVerifier:       ver,
+		BlockImportHandler: coreSrvc,
+	})
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+	for {
+		block, err := br.read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read block: %w", err)
+		}
+
+		if err = importer.ImportBlock(&block.Block, block.Justification); err != nil {
+			return fmt.Errorf("failed to import block %s with hash %s: %w",
+				block.Block.Header.Number, block.Block.Header.Hash(), err)
+		}
+
+		imported++
+		if imported%importProgressInterval == 0 {
+			logger.Infof("imported %d blocks, last block is number %s", imported, block.Block.Header.Number)
+		}
+	}
+
+	logger.Infof("imported %d blocks, best block is %s", imported, stateSrvc.Block.BestBlockHash())
+	return nil
+}
+
+func stopService(s services.Service, name string) {
+	if err := s.Stop(); err != nil {
+		logger.Errorf("failed to stop %s service: %s", name, err)
+	}
+}
+
+// exportedBlock is a block along with its justification, as stored in a block file
+type exportedBlock struct {
+	Block         types.Block
+	Justification *[]byte
+}
+
+// jsonExportedBlock is the JSON representation of an exportedBlock. The number and hash of the block
+// are only there to make the file readable, and to check the decoded block.
+type jsonExportedBlock struct {
+	Number        uint64      `json:"number"`
+	Hash          common.Hash `json:"hash"`
+	Block         string      `json:"block"`
+	Justification *string     `json:"justification,omitempty"`
+}
+
+type blockWriter interface {
+	write(*exportedBlock) error
+}
+
+type blockReader interface {
+	// read returns the next block, or io.EOF if there are no more blocks
+	read() (*exportedBlock, error)
+}
+
+// newBlockWriter returns a blockWriter writing the given number of blocks to w in the given format
+func newBlockWriter(w io.Writer, format BlockFileFormat, count uint64) (blockWriter, error) {
+	switch format {
+	case BinaryBlockFile:
+		enc, err := scale.Marshal(count)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = w.Write(enc); err != nil {
+			return nil, err
+		}
+
+		return &binaryBlockWriter{w: w}, nil
+	case JSONBlockFile:
+		return &jsonBlockWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errInvalidBlockFileFormat, format)
+	}
+}
+
+// newBlockReader returns a blockReader reading blocks from r in the given format
+func newBlockReader(r io.Reader, format BlockFileFormat) (blockReader, error) {
+	switch format {
+	case BinaryBlockFile:
+		br := &binaryBlockReader{dec: scale.NewDecoder(r)}
+		if err := br.dec.Decode(&br.remaining); err != nil {
+			return nil, fmt.Errorf("failed to decode number of blocks: %w", err)
+		}
+
+		return br, nil
+	case JSONBlockFile:
+		return &jsonBlockReader{dec: json.NewDecoder(r)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errInvalidBlockFileFormat, format)
+	}
+}
+
+type binaryBlockWriter struct {
+	w io.Writer
+}
+
+func (bw *binaryBlockWriter) write(block *exportedBlock) error {
+	enc, err := scale.Marshal(*block)
+	if err != nil {
+		return err
+	}
+
+	_, err = bw.w.Write(enc)
+	return err
+}
+
+type binaryBlockReader struct {
+	dec       *scale.Decoder
+	remaining uint64
+}
+
+func (br *binaryBlockReader) read() (*exportedBlock, error) {
+	if br.remaining == 0 {
+		return nil, io.EOF
+	}
+
+	block := &exportedBlock{
+		Block: types.NewEmptyBlock(),
+	}
+
+	if err := br.dec.Decode(block); err != nil {
+		return nil, err
+	}
+
+	br.remaining--
+	return block, nil
+}
+
+type jsonBlockWriter struct {
+	enc *json.Encoder
+}
+
+func (bw *jsonBlockWriter) write(block *exportedBlock) error {
+	enc, err := block.Block.Encode()
+	if err != nil {
+		return err
+	}
+
+	jb := &jsonExportedBlock{
+		Number: block.Block.Header.Number.Uint64(),
+		Hash:   block.Block.Header.Hash(),
+		Block:  common.BytesToHex(enc),
+	}
+
+	if block.Justification != nil {
+		justification := common.BytesToHex(*block.Justification)
+		jb.Justification = &justification
+	}
+
+	return bw.enc.Encode(jb)
+}
+
+type jsonBlockReader struct {
+	dec *json.Decoder
+}
+
+func (br *jsonBlockReader) read() (*exportedBlock, error) {
+	jb := new(jsonExportedBlock)
+	if err := br.dec.Decode(jb); err != nil {
+		return nil, err
+	}
+
+	enc, err := common.HexToBytes(jb.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &exportedBlock{
+		Block: types.NewEmptyBlock(),
+	}
+
+	if err = scale.Unmarshal(enc, &block.Block); err != nil {
+		return nil, err
+	}
+
+	if hash := block.Block.Header.Hash(); hash != jb.Hash {
+		return nil, fmt.Errorf("%w: expected %s, got %s", errBlockHashMismatch, jb.Hash, hash)
+	}
+
+	if jb.Justification != nil {
+		justification, err := common.HexToBytes(*jb.Justification)
+		if err != nil {
+			return nil, err
+		}
+
+		block.Justification = &justification
+	}
+
+	return block, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package dot
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/dot/sync"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExportedBlocks(t *testing.T) []*exportedBlock {
+	var blocks []*exportedBlock
+	parentHash := common.Hash{1}
+	for i := 1; i <= 3; i++ {
+		digest := types.NewDigest()
+		err := digest.Add(types.PreRuntimeDigest{
+			ConsensusEngineID: types.BabeEngineID,
+			Data:              []byte{byte(i)},
+		})
+		require.NoError(t, err)
+
+		header, err := types.NewHeader(parentHash, common.Hash{byte(i)}, common.Hash{},
+			big.NewInt(int64(i)), digest)
+		require.NoError(t, err)
+
+		block := &exportedBlock{
+			Block: types.NewBlock(*header, types.Body{{1, 2, byte(i)}}),
+		}
+		blocks = append(blocks, block)
+		parentHash = header.Hash()
+	}
+
+	justification := []byte("justification")
+	blocks[1].Justification = &justification
+	return blocks
+}
+
+func TestBlockFile_RoundTrip(t *testing.T) {
+	for _, format := range []BlockFileFormat{BinaryBlockFile, JSONBlockFile} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			blocks := newTestExportedBlocks(t)
+
+			buf := new(bytes.Buffer)
+			bw, err := newBlockWriter(buf, format, uint64(len(blocks)))
+			require.NoError(t, err)
+
+			for _, block := range blocks {
+				err = bw.write(block)
+				require.NoError(t, err)
+			}
+
+			br, err := newBlockReader(buf, format)
+			require.NoError(t, err)
+
+			for _, expected := range blocks {
+				block, err := br.read()
+				require.NoError(t, err)
+				require.Equal(t, expected.Block.Header.Hash(), block.Block.Header.Hash())
+				require.Equal(t, expected.Block.Body, block.Block.Body)
+				require.Equal(t, expected.Justification, block.Justification)
+			}
+
+			_, err = br.read()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestBlockFile_InvalidFormat(t *testing.T) {
+	_, err := newBlockWriter(new(bytes.Buffer), "csv", 1)
+	require.ErrorIs(t, err, errInvalidBlockFileFormat)
+
+	_, err = newBlockReader(new(bytes.Buffer), "csv")
+	require.ErrorIs(t, err, errInvalidBlockFileFormat)
+
+	// the number of blocks is missing
+	_, err = newBlockReader(new(bytes.Buffer), BinaryBlockFile)
+	require.Error(t, err)
+}
+
+func TestJSONBlockReader_HashMismatch(t *testing.T) {
+	buf := new(bytes.Buffer)
+	bw, err := newBlockWriter(buf, JSONBlockFile, 1)
+	require.NoError(t, err)
+
+	err = bw.write(newTestExportedBlocks(t)[0])
+	require.NoError(t, err)
+
+	// replace the parent hash of the encoded block, which then doesn't match the hash in the file
+	enc := strings.Replace(buf.String(), `"block":"0x01`, `"block":"0x02`, 1)
+	br, err := newBlockReader(strings.NewReader(enc), JSONBlockFile)
+	require.NoError(t, err)
+
+	_, err = br.read()
+	require.ErrorIs(t, err, errBlockHashMismatch)
+}
+
+func TestExportBlocks_InvalidRange(t *testing.T) {
+	err := ExportBlocks(t.TempDir(), 2, 1, BinaryBlockFile, new(bytes.Buffer))
+	require.ErrorIs(t, err, errInvalidBlockRange)
+}
+
+func TestExportImportBlocks(t *testing.T) {
+	newInitialisedConfig := func(genesis string) *Config {
+		cfg := NewTestConfig(t)
+		cfg.Init.Genesis = genesis
+		cfg.Core.GrandpaAuthority = false
+
+		err := InitNode(cfg)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	genFile := NewTestGenesisRawFile(t, NewTestConfig(t))
+	src := newInitialisedConfig(genFile.Name())
+	dst := newInitialisedConfig(genFile.Name())
+
+	buf := new(bytes.Buffer)
+	err := ExportBlocks(src.Global.BasePath, 0, 0, BinaryBlockFile, buf)
+	require.NoError(t, err)
+
+	err = ImportBlocks(dst, keystore.NewGlobalKeystore(), BinaryBlockFile, buf)
+	require.NoError(t, err)
+
+	// store a justification that isn't signed by the GRANDPA voters in the source database
+	srcState := state.NewService(state.Config{
+		Path:     src.Global.BasePath,
+		LogLevel: log.Info,
+	})
+	err = srcState.Start()
+	require.NoError(t, err)
+
+	genesisHash := srcState.Block.GenesisHash()
+	err = srcState.Block.SetJustification(genesisHash, []byte("justification"))
+	require.NoError(t, err)
+	err = srcState.Stop()
+	require.NoError(t, err)
+
+	buf.Reset()
+	err = ExportBlocks(src.Global.BasePath, 0, 0, BinaryBlockFile, buf)
+	require.NoError(t, err)
+
+	err = ImportBlocks(dst, keystore.NewGlobalKeystore(), BinaryBlockFile, buf)
+	require.ErrorIs(t, err, sync.ErrInvalidJustification)
+}
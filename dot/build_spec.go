@@ -29,6 +29,7 @@ func (b *BuildSpec) ToJSON() ([]byte, error) {
 		ChainType:  b.genesis.ChainType,
 		Bootnodes:  b.genesis.Bootnodes,
 		ProtocolID: b.genesis.ProtocolID,
+		ForkID:     b.genesis.ForkID,
 		Properties: b.genesis.Properties,
 		Genesis: genesis.Fields{
 			Runtime: b.genesis.GenesisFields().Runtime,
@@ -45,6 +46,7 @@ func (b *BuildSpec) ToJSONRaw() ([]byte, error) {
 		ChainType:  b.genesis.ChainType,
 		Bootnodes:  b.genesis.Bootnodes,
 		ProtocolID: b.genesis.ProtocolID,
+		ForkID:     b.genesis.ForkID,
 		Properties: b.genesis.Properties,
 		Genesis: genesis.Fields{
 			Raw: b.genesis.GenesisFields().Raw,
@@ -131,6 +133,7 @@ func BuildFromDB(path string) (*BuildSpec, error) {
 	tmpGen.ID = gData.ID
 	tmpGen.Bootnodes = common.BytesToStringArray(gData.Bootnodes)
 	tmpGen.ProtocolID = gData.ProtocolID
+	tmpGen.ForkID = gData.ForkID
 
 	bs := &BuildSpec{
 		genesis: tmpGen,
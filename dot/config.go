@@ -12,10 +12,13 @@ import (
 	"github.com/ChainSafe/gossamer/chain/gssmr"
 	"github.com/ChainSafe/gossamer/chain/kusama"
 	"github.com/ChainSafe/gossamer/chain/polkadot"
+	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/sync"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/internal/pprof"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/genesis"
 )
 
@@ -48,6 +51,7 @@ type GlobalConfig struct {
 	TelemetryURLs  []genesis.TelemetryEndpoint
 	RetainBlocks   int64
 	Pruning        pruner.Mode
+	SyncMode       sync.Mode
 }
 
 // LogConfig represents the log levels for individual packages
@@ -76,8 +80,10 @@ type AccountConfig struct {
 // NetworkConfig is to marshal/unmarshal toml network config vars
 type NetworkConfig struct {
 	Port              uint16
+	ListenAddrs       []string
 	Bootnodes         []string
 	ProtocolID        string
+	ForkID            string
 	NoBootstrap       bool
 	NoMDNS            bool
 	MinPeers          int
@@ -85,6 +91,11 @@ type NetworkConfig struct {
 	PersistentPeers   []string
 	DiscoveryInterval time.Duration
 	PublicIP          string
+	BandwidthLimits   network.BandwidthLimits
+	SwarmKey          string
+
+	// NewHost is used to create the libp2p host of the network service if set, see network.Config
+	NewHost network.HostConstructor `toml:"-"`
 }
 
 // CoreConfig is to marshal/unmarshal toml core config vars
@@ -142,6 +153,14 @@ func (r *RPCConfig) String() string {
 // StateConfig is the config for the State service
 type StateConfig struct {
 	Rewind int
+	// BadBlocks are refused along with their descendants, in addition to the bad blocks of the genesis
+	BadBlocks []common.Hash
+	// TrieCacheSize is the number of state trie nodes kept in memory when the state tries are loaded
+	// lazily from the database, zero loads the state tries fully into memory
+	TrieCacheSize int
+	// StorageCacheSize is the size in bytes of the cache of the storage values of the best block,
+	// zero disables the cache
+	StorageCacheSize int
 }
 
 // networkServiceEnabled returns true if the network service is enabled
@@ -174,6 +193,7 @@ func GssmrConfig() *Config {
 			MetricsPort:   gssmr.DefaultMetricsPort,
 			RetainBlocks:  gssmr.DefaultRetainBlocks,
 			Pruning:       pruner.Mode(gssmr.DefaultPruningMode),
+			SyncMode:      sync.FullMode,
 			TelemetryURLs: gssmr.DefaultTelemetryURLs,
 		},
 		Log: LogConfig{
@@ -237,6 +257,7 @@ func KusamaConfig() *Config {
 			MetricsPort:   kusama.DefaultMetricsPort,
 			RetainBlocks:  gssmr.DefaultRetainBlocks,
 			Pruning:       pruner.Mode(gssmr.DefaultPruningMode),
+			SyncMode:      sync.FullMode,
 			TelemetryURLs: kusama.DefaultTelemetryURLs,
 		},
 		Log: LogConfig{
@@ -293,6 +314,7 @@ func PolkadotConfig() *Config {
 			LogLvl:        polkadot.DefaultLvl,
 			RetainBlocks:  gssmr.DefaultRetainBlocks,
 			Pruning:       pruner.Mode(gssmr.DefaultPruningMode),
+			SyncMode:      sync.FullMode,
 			MetricsPort:   gssmr.DefaultMetricsPort,
 			TelemetryURLs: polkadot.DefaultTelemetryURLs,
 		},
@@ -351,6 +373,7 @@ func DevConfig() *Config {
 			MetricsPort:   dev.DefaultMetricsPort,
 			RetainBlocks:  dev.DefaultRetainBlocks,
 			Pruning:       pruner.Mode(dev.DefaultPruningMode),
+			SyncMode:      sync.FullMode,
 			TelemetryURLs: dev.DefaultTelemetryURLs,
 		},
 		Log: LogConfig{
@@ -24,6 +24,7 @@ type GlobalConfig struct {
 	MetricsPort  uint32 `toml:"metrics-port,omitempty"`
 	RetainBlocks int64  `toml:"retain-blocks,omitempty"`
 	Pruning      string `toml:"pruning,omitempty"`
+	SyncMode     string `toml:"sync,omitempty"`
 }
 
 // LogConfig represents the log levels for individual packages
@@ -52,6 +53,7 @@ type AccountConfig struct {
 // NetworkConfig is to marshal/unmarshal toml network config vars
 type NetworkConfig struct {
 	Port              uint16   `toml:"port,omitempty"`
+	ListenAddrs       []string `toml:"listen-addrs,omitempty"`
 	Bootnodes         []string `toml:"bootnodes,omitempty"`
 	ProtocolID        string   `toml:"protocol,omitempty"`
 	NoBootstrap       bool     `toml:"nobootstrap,omitempty"`
@@ -61,6 +63,17 @@ type NetworkConfig struct {
 	PersistentPeers   []string `toml:"persistent-peers,omitempty"`
 	DiscoveryInterval int      `toml:"discovery-interval,omitempty"`
 	PublicIP          string   `toml:"public-ip,omitempty"`
+	SwarmKey          string   `toml:"swarm-key,omitempty"`
+
+	MaxInboundRate     uint64                     `toml:"max-inbound-rate,omitempty"`
+	MaxOutboundRate    uint64                     `toml:"max-outbound-rate,omitempty"`
+	ProtocolRateLimits map[string]RateLimitConfig `toml:"protocol-rate-limits,omitempty"`
+}
+
+// RateLimitConfig is to marshal/unmarshal toml per-protocol rate limits, in bytes per second
+type RateLimitConfig struct {
+	In  uint64 `toml:"in,omitempty"`
+	Out uint64 `toml:"out,omitempty"`
 }
 
 // CoreConfig is to marshal/unmarshal toml core config vars
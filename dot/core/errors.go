@@ -45,6 +45,9 @@ var (
 	// ErrNilDigestHandler is returned when the DigestHandler interface is nil
 	ErrNilDigestHandler = errors.New("cannot have nil DigestHandler")
 
+	// ErrBadBlock is returned when importing a block on the list of bad blocks, or descending from one
+	ErrBadBlock = errors.New("block is a bad block or descends from one")
+
 	errNilCodeSubstitutedState = errors.New("cannot have nil CodeSubstitutedStat")
 )
 
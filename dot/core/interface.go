@@ -45,6 +45,7 @@ type BlockState interface {
 	HandleRuntimeChanges(newState *rtstorage.TrieState, in runtime.Instance, bHash common.Hash) error
 	GetRuntime(*common.Hash) (runtime.Instance, error)
 	StoreRuntime(common.Hash, runtime.Instance)
+	IsBadBlock(header *types.Header) bool
 }
 
 //go:generate mockery --name StorageState --structname StorageState --case underscore --keeptree
@@ -55,6 +56,7 @@ type StorageState interface {
 	LoadCodeHash(root *common.Hash) (common.Hash, error)
 	TrieState(root *common.Hash) (*rtstorage.TrieState, error)
 	StoreTrie(*rtstorage.TrieState, *types.Header) error
+	StoreBlock(*rtstorage.TrieState, *types.Block) error
 	GetStateRootFromBlock(bhash *common.Hash) (*common.Hash, error)
 	GetStorage(root *common.Hash, key []byte) ([]byte, error)
 	GenerateTrieProof(stateRoot common.Hash, keys [][]byte) ([][]byte, error)
@@ -67,6 +67,12 @@ func (s *Service) HandleTransactionMessage(peerID peer.ID, msg *network.Transact
 				return nil
 			}
 
+			// the peer sent us a valid transaction it didn't send us before
+			s.net.ReportPeer(peerset.ReputationChange{
+				Value:  peerset.GoodTransactionValue,
+				Reason: peerset.GoodTransactionReason,
+			}, peerID)
+
 			// create new valid transaction
 			vtx := transaction.NewValidTransaction(tx, val)
 
@@ -87,11 +93,6 @@ func (s *Service) HandleTransactionMessage(peerID peer.ID, msg *network.Transact
 		}
 	}
 
-	s.net.ReportPeer(peerset.ReputationChange{
-		Value:  peerset.GoodTransactionValue,
-		Reason: peerset.GoodTransactionReason,
-	}, peerID)
-
 	msg.Extrinsics = toPropagate
 	return len(msg.Extrinsics) > 0, nil
 }
@@ -4,16 +4,20 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/centrifuge/go-substrate-rpc-client/v3/signature"
 	ctypes "github.com/centrifuge/go-substrate-rpc-client/v3/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ChainSafe/gossamer/dot/core/mocks"
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/state"
 	"github.com/ChainSafe/gossamer/dot/sync"
 	"github.com/ChainSafe/gossamer/dot/types"
@@ -21,6 +25,9 @@ import (
 	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	runtimemocks "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
@@ -162,3 +169,93 @@ func TestService_HandleTransactionMessage(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, b)
 }
+
+func TestService_HandleTransactionMessage_ReportPeer(t *testing.T) {
+	const peer1 = "testPeer1"
+
+	testCases := []struct {
+		name      string
+		validity  *transaction.Validity
+		err       error
+		change    *peerset.ReputationChange
+		propagate bool
+	}{
+		{
+			name:     "valid transaction",
+			validity: &transaction.Validity{Propagate: true},
+			change: &peerset.ReputationChange{
+				Value:  peerset.GoodTransactionValue,
+				Reason: peerset.GoodTransactionReason,
+			},
+			propagate: true,
+		},
+		{
+			name: "invalid transaction",
+			err:  runtime.ErrInvalidTransaction,
+			change: &peerset.ReputationChange{
+				Value:  peerset.BadTransactionValue,
+				Reason: peerset.BadTransactionReason,
+			},
+		},
+		{
+			// the transaction may be valid once we imported more blocks, so the peer isn't reported
+			name: "unknown transaction",
+			err:  runtime.ErrUnknownTransaction,
+		},
+		{
+			name: "validation error",
+			err:  errors.New("failed to call runtime"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			header := &types.Header{
+				Number: big.NewInt(1),
+				Digest: types.NewDigest(),
+			}
+			hash := header.Hash()
+
+			ts, err := rtstorage.NewTrieState(nil)
+			require.NoError(t, err)
+
+			rt := new(runtimemocks.Instance)
+			rt.On("SetContextStorage", ts)
+			rt.On("ValidateTransaction", mock.AnythingOfType("types.Extrinsic")).Return(tc.validity, tc.err)
+
+			blockState := new(mocks.BlockState)
+			blockState.On("BestBlockHeader").Return(header, nil)
+			blockState.On("GetRuntime", &hash).Return(rt, nil)
+
+			storageState := new(mocks.StorageState)
+			storageState.On("Lock")
+			storageState.On("Unlock")
+			storageState.On("TrieState", &header.StateRoot).Return(ts, nil)
+
+			net := new(mocks.Network)
+			net.On("IsSynced").Return(true)
+			net.On("ReportPeer", mock.AnythingOfType("peerset.ReputationChange"), peer.ID(peer1))
+
+			s := &Service{
+				blockState:       blockState,
+				storageState:     storageState,
+				transactionState: state.NewTransactionState(),
+				net:              net,
+			}
+
+			msg := &network.TransactionMessage{Extrinsics: []types.Extrinsic{{1, 2, 3}}}
+			propagate, err := s.HandleTransactionMessage(peer1, msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.propagate, propagate)
+
+			if tc.change == nil {
+				net.AssertNotCalled(t, "ReportPeer", mock.Anything, mock.Anything)
+				return
+			}
+
+			net.AssertCalled(t, "ReportPeer", *tc.change, peer.ID(peer1))
+			net.AssertNumberOfCalls(t, "ReportPeer", 1)
+		})
+	}
+}
@@ -413,6 +413,20 @@ func (_m *BlockState) HighestCommonAncestor(a common.Hash, b common.Hash) (commo
 	return r0, r1
 }
 
+// IsBadBlock provides a mock function with given fields: header
+func (_m *BlockState) IsBadBlock(header *types.Header) bool {
+	ret := _m.Called(header)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*types.Header) bool); ok {
+		r0 = rf(header)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // StoreRuntime provides a mock function with given fields: _a0, _a1
 func (_m *BlockState) StoreRuntime(_a0 common.Hash, _a1 runtime.Instance) {
 	_m.Called(_a0, _a1)
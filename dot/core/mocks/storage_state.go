@@ -137,6 +137,20 @@ func (_m *StorageState) Lock() {
 	_m.Called()
 }
 
+// StoreBlock provides a mock function with given fields: _a0, _a1
+func (_m *StorageState) StoreBlock(_a0 *storage.TrieState, _a1 *types.Block) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*storage.TrieState, *types.Block) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // StoreTrie provides a mock function with given fields: _a0, _a1
 func (_m *StorageState) StoreTrie(_a0 *storage.TrieState, _a1 *types.Header) error {
 	ret := _m.Called(_a0, _a1)
@@ -193,21 +193,20 @@ func (s *Service) handleBlock(block *types.Block, state *rtstorage.TrieState) er
 		return fmt.Errorf("unable to handle block due to nil parameter")
 	}
 
-	// store updates state trie nodes in database
-	err := s.storageState.StoreTrie(state, &block.Header)
-	if err != nil {
-		logger.Warnf("failed to store state trie for imported block %s: %s",
-			block.Header.Hash(), err)
-		return err
+	if s.blockState.IsBadBlock(&block.Header) {
+		return fmt.Errorf("%w: hash %s", ErrBadBlock, block.Header.Hash())
 	}
 
-	// store block in database
-	if err = s.blockState.AddBlock(block); err != nil {
+	// store the block along with its updated state trie nodes in database, at once
+	err := s.storageState.StoreBlock(state, block)
+	if err != nil {
 		if err == blocktree.ErrParentNotFound && block.Header.Number.Cmp(big.NewInt(0)) != 0 {
 			return err
 		} else if err == blocktree.ErrBlockExists || block.Header.Number.Cmp(big.NewInt(0)) == 0 {
 			// this is fine
 		} else {
+			logger.Warnf("failed to store imported block %s with its state trie: %s",
+				block.Header.Hash(), err)
 			return err
 		}
 	}
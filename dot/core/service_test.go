@@ -857,6 +857,32 @@ func TestDecodeSessionKeys_WhenGetRuntimeReturnError(t *testing.T) {
 	require.Nil(t, b)
 }
 
+func TestService_HandleBlockImport_BadBlock(t *testing.T) {
+	block := &types.Block{
+		Header: types.Header{
+			Number: big.NewInt(1),
+			Digest: types.NewDigest(),
+		},
+		Body: types.Body{},
+	}
+
+	mockBlockState := new(mocks.BlockState)
+	mockBlockState.On("IsBadBlock", &block.Header).Return(true)
+	mockStorageState := new(mocks.StorageState)
+
+	s := &Service{
+		blockState:   mockBlockState,
+		storageState: mockStorageState,
+	}
+
+	ts, err := rtstorage.NewTrieState(nil)
+	require.NoError(t, err)
+
+	err = s.HandleBlockImport(block, ts)
+	require.True(t, errors.Is(err, ErrBadBlock))
+	mockStorageState.AssertNotCalled(t, "StoreBlock", ts, block)
+}
+
 func TestGetReadProofAt(t *testing.T) {
 	keysToProof := [][]byte{[]byte("first_key"), []byte("another_key")}
 	mockedProofs := [][]byte{[]byte("proof01"), []byte("proof02")}
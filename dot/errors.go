@@ -12,3 +12,9 @@ var ErrNoKeysProvided = errors.New("no keys provided for authority node")
 
 // ErrInvalidKeystoreType when trying to create a service with the wrong keystore type
 var ErrInvalidKeystoreType = errors.New("invalid keystore type")
+
+var (
+	errInvalidBlockRange      = errors.New("invalid block range")
+	errInvalidBlockFileFormat = errors.New("invalid block file format")
+	errBlockHashMismatch      = errors.New("block hash does not match decoded block")
+)
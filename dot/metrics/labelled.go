@@ -0,0 +1,76 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Registry holds the metrics partitioned by labels, which the go-ethereum metrics can't express.
+// They are served on the /metrics endpoint along with the go-ethereum metrics.
+var Registry = prometheus.NewRegistry()
+
+// NewCounterVec creates a counter partitioned by the given labels and registers it
+func NewCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name,
+		Help: help,
+	}, labels)
+	Registry.MustRegister(c)
+	return c
+}
+
+// NewHistogramVec creates a histogram partitioned by the given labels and registers it.
+// If buckets is nil, the default prometheus buckets are used.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name,
+		Help:    help,
+		Buckets: buckets,
+	}, labels)
+	Registry.MustRegister(h)
+	return h
+}
+
+// bufferedResponse is a http.ResponseWriter writing into a buffer
+type bufferedResponse struct {
+	bytes.Buffer
+	header http.Header
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (*bufferedResponse) WriteHeader(int) {}
+
+// handler returns a handler serving the output of the given go-ethereum metrics handler followed by
+// the labelled metrics of Registry, in the prometheus text format
+func handler(ethHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the go-ethereum handler sets the content length, so its output is buffered
+		// before the labelled metrics are appended to it
+		resp := &bufferedResponse{header: make(http.Header)}
+		ethHandler.ServeHTTP(resp, r)
+
+		families, err := Registry.Gather()
+		if err != nil {
+			logger.Errorf("failed to gather labelled metrics: %s", err)
+		}
+
+		enc := expfmt.NewEncoder(&resp.Buffer, expfmt.FmtText)
+		for _, mf := range families {
+			if err = enc.Encode(mf); err != nil {
+				logger.Errorf("failed to encode metric %s: %s", mf.GetName(), err)
+			}
+		}
+
+		w.Header().Set("Content-Type", string(expfmt.FmtText))
+		_, _ = w.Write(resp.Bytes())
+	})
+}
@@ -29,7 +29,7 @@ func PublishMetrics(address string) {
 // setupMetricsServer starts a dedicated metrics server at the given address.
 func setupMetricsServer(address string) {
 	m := http.NewServeMux()
-	m.Handle("/metrics", prometheus.Handler(ethmetrics.DefaultRegistry))
+	m.Handle("/metrics", handler(prometheus.Handler(ethmetrics.DefaultRegistry)))
 	logger.Info("Starting metrics server at http://" + address + "/metrics")
 	go func() {
 		if err := http.ListenAndServe(address, m); err != nil {
@@ -0,0 +1,420 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	libp2phost "github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/routing"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ChainSafe/gossamer/dot/network/proto"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+)
+
+// authorityDiscoveryKeyLength is the length of the DHT keys of the authority discovery records,
+// which are sha2-256 multihashes
+const authorityDiscoveryKeyLength = 34
+
+var (
+	authorityPublishInterval  = time.Hour
+	authorityResolveInterval  = time.Minute * 10
+	authorityDiscoveryTimeout = time.Minute
+)
+
+// authorityDiscoveryKey returns the DHT key of the records of the given authority,
+// which is the sha2-256 multihash of its authority discovery key
+func authorityDiscoveryKey(pub *sr25519.PublicKey) (string, error) {
+	h, err := multihash.Sum(pub.Encode(), multihash.SHA2_256, -1)
+	if err != nil {
+		return "", err
+	}
+
+	return string(h), nil
+}
+
+// newSignedAuthorityRecord creates a record of the given addresses signed with the given authority keypair,
+// and with the libp2p key of the peer the addresses are of
+func newSignedAuthorityRecord(kp *sr25519.Keypair, peerKey crypto.PrivKey, addrs []ma.Multiaddr) ([]byte, error) {
+	record := &pb.AuthorityRecord{
+		Addresses: make([][]byte, len(addrs)),
+	}
+
+	for i, addr := range addrs {
+		record.Addresses[i] = addr.Bytes()
+	}
+
+	enc, err := proto.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	authSig, err := kp.Sign(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	peerSig, err := peerKey.Sign(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	peerPub, err := crypto.MarshalPublicKey(peerKey.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&pb.SignedAuthorityRecord{
+		Record:        enc,
+		AuthSignature: authSig,
+		PeerSignature: &pb.PeerSignature{
+			Signature: peerSig,
+			PublicKey: peerPub,
+		},
+	})
+}
+
+// decodeAuthorityRecord decodes and verifies a record published by an authority, and returns the addresses
+// of the authority. The authority signature is only verified if the authority key is given, since the key
+// of a record is the hash of the authority key. The peer signature is verified if the record has one.
+func decodeAuthorityRecord(value []byte, authority *sr25519.PublicKey) (peer.AddrInfo, error) {
+	signed := new(pb.SignedAuthorityRecord)
+	if err := proto.Unmarshal(value, signed); err != nil {
+		return peer.AddrInfo{}, fmt.Errorf("%w: %s", errInvalidAuthorityRecord, err)
+	}
+
+	if authority != nil {
+		ok, err := authority.Verify(signed.Record, signed.AuthSignature)
+		if err != nil || !ok {
+			return peer.AddrInfo{}, errInvalidAuthorityRecordSignature
+		}
+	}
+
+	record := new(pb.AuthorityRecord)
+	if err := proto.Unmarshal(signed.Record, record); err != nil {
+		return peer.AddrInfo{}, fmt.Errorf("%w: %s", errInvalidAuthorityRecord, err)
+	}
+
+	addrs := make([]ma.Multiaddr, len(record.Addresses))
+	for i, b := range record.Addresses {
+		addr, err := ma.NewMultiaddrBytes(b)
+		if err != nil {
+			return peer.AddrInfo{}, fmt.Errorf("%w: %s", errInvalidAuthorityRecord, err)
+		}
+		addrs[i] = addr
+	}
+
+	infos, err := peer.AddrInfosFromP2pAddrs(addrs...)
+	if err != nil {
+		return peer.AddrInfo{}, fmt.Errorf("%w: %s", errInvalidAuthorityRecord, err)
+	}
+
+	// all the addresses of a record must be of the same peer
+	if len(infos) != 1 {
+		return peer.AddrInfo{}, fmt.Errorf("%w: expected addresses of 1 peer, got %d",
+			errInvalidAuthorityRecord, len(infos))
+	}
+
+	if signed.PeerSignature != nil {
+		if err = verifyPeerSignature(signed.Record, signed.PeerSignature, infos[0].ID); err != nil {
+			return peer.AddrInfo{}, err
+		}
+	}
+
+	return infos[0], nil
+}
+
+// verifyPeerSignature checks that the record is signed with the libp2p key of the given peer
+func verifyPeerSignature(record []byte, sig *pb.PeerSignature, id peer.ID) error {
+	pub, err := crypto.UnmarshalPublicKey(sig.PublicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidAuthorityRecord, err)
+	}
+
+	if !id.MatchesPublicKey(pub) {
+		return fmt.Errorf("%w: peer signature public key is not of peer %s", errInvalidAuthorityRecord, id)
+	}
+
+	ok, err := pub.Verify(record, sig.Signature)
+	if err != nil || !ok {
+		return errInvalidAuthorityRecordSignature
+	}
+
+	return nil
+}
+
+// authorityRecordValidator validates the authority discovery records stored in the DHT. Since the key of
+// a record is the hash of the authority key, the authority signature is only verified for the records of
+// the authorities of the current authority set.
+type authorityRecordValidator struct {
+	sync.RWMutex
+	authorities map[string]*sr25519.PublicKey // the authority keys by the keys of their records
+}
+
+func newAuthorityRecordValidator() *authorityRecordValidator {
+	return &authorityRecordValidator{
+		authorities: make(map[string]*sr25519.PublicKey),
+	}
+}
+
+// setAuthorities sets the authority keys whose records are verified
+func (v *authorityRecordValidator) setAuthorities(authorities []*sr25519.PublicKey) error {
+	keys := make(map[string]*sr25519.PublicKey, len(authorities))
+	for _, pub := range authorities {
+		key, err := authorityDiscoveryKey(pub)
+		if err != nil {
+			return err
+		}
+		keys[key] = pub
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	v.authorities = keys
+	return nil
+}
+
+// Validate checks that the record is stored under an authority discovery key, and that it's signed
+// by the authority if the authority is known
+func (v *authorityRecordValidator) Validate(key string, value []byte) error {
+	if len(key) != authorityDiscoveryKeyLength {
+		return errAuthorityRecordKeyMismatch
+	}
+
+	v.RLock()
+	authority := v.authorities[key]
+	v.RUnlock()
+
+	_, err := decodeAuthorityRecord(value, authority)
+	return err
+}
+
+// Select returns the index of the first valid record, since the records don't say when they were created
+func (v *authorityRecordValidator) Select(key string, values [][]byte) (int, error) {
+	for i, value := range values {
+		if v.Validate(key, value) == nil {
+			return i, nil
+		}
+	}
+
+	return 0, errInvalidAuthorityRecord
+}
+
+// authorityDiscovery publishes the addresses of our authority keys that are part of the current
+// authority set on the DHT, and resolves the addresses of the other authorities so that we can
+// connect to them directly.
+type authorityDiscovery struct {
+	ctx        context.Context
+	h          libp2phost.Host
+	dht        routing.ValueStore
+	blockState BlockState
+	keystore   keystore.Keystore
+	handler    PeerAdd
+	validator  *authorityRecordValidator
+
+	sync.RWMutex
+	authorities map[[sr25519.PublicKeyLength]byte]peer.AddrInfo
+}
+
+func newAuthorityDiscovery(ctx context.Context, h libp2phost.Host, blockState BlockState,
+	ks keystore.Keystore, handler PeerAdd, validator *authorityRecordValidator) *authorityDiscovery {
+	return &authorityDiscovery{
+		ctx:         ctx,
+		h:           h,
+		blockState:  blockState,
+		keystore:    ks,
+		handler:     handler,
+		validator:   validator,
+		authorities: make(map[[sr25519.PublicKeyLength]byte]peer.AddrInfo),
+	}
+}
+
+// start begins publishing and resolving authority addresses using the given DHT
+func (ad *authorityDiscovery) start(dht routing.ValueStore) {
+	ad.dht = dht
+	go ad.run()
+}
+
+func (ad *authorityDiscovery) run() {
+	publish := time.NewTimer(0)
+	defer publish.Stop()
+	resolve := time.NewTimer(0)
+	defer resolve.Stop()
+
+	for {
+		select {
+		case <-ad.ctx.Done():
+			return
+		case <-publish.C:
+			if err := ad.publish(); err != nil {
+				logger.Warnf("failed to publish authority addresses: %s", err)
+			}
+			publish.Reset(authorityPublishInterval)
+		case <-resolve.C:
+			if err := ad.resolve(); err != nil {
+				logger.Warnf("failed to resolve authority addresses: %s", err)
+			}
+			resolve.Reset(authorityResolveInterval)
+		}
+	}
+}
+
+// currentAuthorities returns the authority discovery keys of the current authority set
+func (ad *authorityDiscovery) currentAuthorities() ([]*sr25519.PublicKey, error) {
+	rt, err := ad.blockState.GetRuntime(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.AuthorityDiscoveryAuthorities()
+}
+
+// addrs returns our addresses, including our peer ID
+func (ad *authorityDiscovery) addrs() []ma.Multiaddr {
+	p2p, err := ma.NewComponent("p2p", ad.h.ID().Pretty())
+	if err != nil {
+		return nil
+	}
+
+	addrs := make([]ma.Multiaddr, 0, len(ad.h.Addrs()))
+	for _, addr := range ad.h.Addrs() {
+		addrs = append(addrs, addr.Encapsulate(p2p))
+	}
+
+	return addrs
+}
+
+// publish puts a record of our addresses on the DHT for each of our keys in the current authority set
+func (ad *authorityDiscovery) publish() error {
+	if ad.keystore == nil || ad.keystore.Size() == 0 {
+		return nil
+	}
+
+	authorities, err := ad.currentAuthorities()
+	if err != nil {
+		return err
+	}
+
+	addrs := ad.addrs()
+	if len(addrs) == 0 {
+		return errors.New("no addresses to publish")
+	}
+
+	peerKey := ad.h.Peerstore().PrivKey(ad.h.ID())
+	if peerKey == nil {
+		return errors.New("no private key of the host")
+	}
+
+	for _, pub := range authorities {
+		kp, ok := ad.keystore.GetKeypair(pub).(*sr25519.Keypair)
+		if !ok {
+			continue
+		}
+
+		key, err := authorityDiscoveryKey(pub)
+		if err != nil {
+			return err
+		}
+
+		value, err := newSignedAuthorityRecord(kp, peerKey, addrs)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ad.ctx, authorityDiscoveryTimeout)
+		err = ad.dht.PutValue(ctx, key, value)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		logger.Debugf("published addresses of authority %s", pub.Hex())
+	}
+
+	return nil
+}
+
+// resolve looks up the addresses of the authorities of the current authority set on the DHT
+func (ad *authorityDiscovery) resolve() error {
+	authorities, err := ad.currentAuthorities()
+	if err != nil {
+		return err
+	}
+
+	// the records of the current authorities are checked to be signed by them when they are retrieved
+	if err = ad.validator.setAuthorities(authorities); err != nil {
+		return err
+	}
+
+	resolved := make(map[[sr25519.PublicKeyLength]byte]peer.AddrInfo, len(authorities))
+	for _, pub := range authorities {
+		if ad.keystore != nil && ad.keystore.GetKeypair(pub) != nil {
+			// that's us
+			continue
+		}
+
+		info, err := ad.lookup(pub)
+		if err != nil {
+			logger.Debugf("failed to resolve addresses of authority %s: %s", pub.Hex(), err)
+			continue
+		}
+
+		var id [sr25519.PublicKeyLength]byte
+		copy(id[:], pub.Encode())
+		resolved[id] = info
+
+		if info.ID == ad.h.ID() {
+			continue
+		}
+
+		ad.h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.AddressTTL)
+		ad.handler.AddPeer(0, info.ID)
+	}
+
+	ad.Lock()
+	ad.authorities = resolved
+	ad.Unlock()
+
+	logger.Debugf("resolved addresses of %d authorities out of %d", len(resolved), len(authorities))
+	return nil
+}
+
+func (ad *authorityDiscovery) lookup(pub *sr25519.PublicKey) (peer.AddrInfo, error) {
+	key, err := authorityDiscoveryKey(pub)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ad.ctx, authorityDiscoveryTimeout)
+	defer cancel()
+
+	value, err := ad.dht.GetValue(ctx, key)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+
+	return decodeAuthorityRecord(value, pub)
+}
+
+// authorityAddrs returns the last resolved addresses of the given authority
+func (ad *authorityDiscovery) authorityAddrs(pub *sr25519.PublicKey) (peer.AddrInfo, bool) {
+	var id [sr25519.PublicKeyLength]byte
+	copy(id[:], pub.Encode())
+
+	ad.RLock()
+	defer ad.RUnlock()
+	info, has := ad.authorities[id]
+	return info, has
+}
@@ -0,0 +1,265 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	kaddht "github.com/libp2p/go-libp2p-kad-dht"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ChainSafe/gossamer/dot/network/proto"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+	"github.com/ChainSafe/gossamer/lib/runtime/mocks"
+)
+
+type mockPeerAdd struct {
+	sync.Mutex
+	added []peer.ID
+}
+
+func (m *mockPeerAdd) AddPeer(_ int, ids ...peer.ID) {
+	m.Lock()
+	defer m.Unlock()
+	m.added = append(m.added, ids...)
+}
+
+func (*mockPeerAdd) Incoming(int, ...peer.ID)        {}
+func (*mockPeerAdd) AddReservedPeer(int, ...peer.ID) {}
+func (*mockPeerAdd) SetReservedPeer(int, ...peer.ID) {}
+
+func (m *mockPeerAdd) peers() []peer.ID {
+	m.Lock()
+	defer m.Unlock()
+	return append([]peer.ID{}, m.added...)
+}
+
+func newTestAuthorityRecord(t *testing.T, kp *sr25519.Keypair) (string, []byte, peer.ID) {
+	peerKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	id, err := peer.IDFromPrivateKey(peerKey)
+	require.NoError(t, err)
+
+	addr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/7001/p2p/" + id.Pretty())
+	require.NoError(t, err)
+
+	key, err := authorityDiscoveryKey(kp.Public().(*sr25519.PublicKey))
+	require.NoError(t, err)
+
+	value, err := newSignedAuthorityRecord(kp, peerKey, []ma.Multiaddr{addr})
+	require.NoError(t, err)
+
+	return key, value, id
+}
+
+func TestAuthorityDiscoveryKey(t *testing.T) {
+	kr, err := keystore.NewSr25519Keyring()
+	require.NoError(t, err)
+
+	pub := kr.Alice().Public().(*sr25519.PublicKey)
+	key, err := authorityDiscoveryKey(pub)
+	require.NoError(t, err)
+
+	// the key is the sha2-256 multihash of the authority key
+	digest := sha256.Sum256(pub.Encode())
+	require.Equal(t, string(append([]byte{0x12, 0x20}, digest[:]...)), key)
+}
+
+func TestAuthorityRecordValidator_Validate(t *testing.T) {
+	kr, err := keystore.NewSr25519Keyring()
+	require.NoError(t, err)
+
+	alice := kr.Alice().(*sr25519.Keypair)
+	bob := kr.Bob().(*sr25519.Keypair)
+
+	key, value, id := newTestAuthorityRecord(t, alice)
+
+	// the authority signature can't be verified without knowing the authority
+	v := newAuthorityRecordValidator()
+	err = v.Validate(key, value)
+	require.NoError(t, err)
+
+	info, err := decodeAuthorityRecord(value, alice.Public().(*sr25519.PublicKey))
+	require.NoError(t, err)
+	require.Equal(t, id, info.ID)
+	require.Len(t, info.Addrs, 1)
+	require.Equal(t, "/ip4/127.0.0.1/tcp/7001", info.Addrs[0].String())
+
+	err = v.setAuthorities([]*sr25519.PublicKey{alice.Public().(*sr25519.PublicKey)})
+	require.NoError(t, err)
+	err = v.Validate(key, value)
+	require.NoError(t, err)
+
+	// a record signed by another authority is rejected
+	signed := new(pb.SignedAuthorityRecord)
+	err = proto.Unmarshal(value, signed)
+	require.NoError(t, err)
+
+	signed.AuthSignature, err = bob.Sign(signed.Record)
+	require.NoError(t, err)
+	forged, err := proto.Marshal(signed)
+	require.NoError(t, err)
+
+	err = v.Validate(key, forged)
+	require.ErrorIs(t, err, errInvalidAuthorityRecordSignature)
+
+	// a record signed with the key of another peer is rejected
+	_, other, _ := newTestAuthorityRecord(t, alice)
+	otherSigned := new(pb.SignedAuthorityRecord)
+	err = proto.Unmarshal(other, otherSigned)
+	require.NoError(t, err)
+
+	signed = new(pb.SignedAuthorityRecord)
+	err = proto.Unmarshal(value, signed)
+	require.NoError(t, err)
+	signed.PeerSignature = otherSigned.PeerSignature
+	forged, err = proto.Marshal(signed)
+	require.NoError(t, err)
+
+	err = v.Validate(key, forged)
+	require.ErrorIs(t, err, errInvalidAuthorityRecord)
+
+	// the peer signature is optional for the records of older versions
+	signed.PeerSignature = nil
+	unsigned, err := proto.Marshal(signed)
+	require.NoError(t, err)
+
+	err = v.Validate(key, unsigned)
+	require.NoError(t, err)
+
+	err = v.Validate(key, []byte{1, 2, 3})
+	require.ErrorIs(t, err, errInvalidAuthorityRecord)
+
+	err = v.Validate("/authority/"+key, value)
+	require.ErrorIs(t, err, errAuthorityRecordKeyMismatch)
+}
+
+func TestAuthorityRecordValidator_Select(t *testing.T) {
+	kr, err := keystore.NewSr25519Keyring()
+	require.NoError(t, err)
+
+	alice := kr.Alice().(*sr25519.Keypair)
+
+	key, first, _ := newTestAuthorityRecord(t, alice)
+	_, second, _ := newTestAuthorityRecord(t, alice)
+
+	v := newAuthorityRecordValidator()
+	idx, err := v.Select(key, [][]byte{first, second})
+	require.NoError(t, err)
+	require.Equal(t, 0, idx)
+
+	idx, err = v.Select(key, [][]byte{[]byte{1}, second})
+	require.NoError(t, err)
+	require.Equal(t, 1, idx)
+
+	_, err = v.Select(key, [][]byte{[]byte{1}})
+	require.ErrorIs(t, err, errInvalidAuthorityRecord)
+}
+
+func TestAuthorityDiscovery_PublishResolve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the default mocknet peers have test keys that can't sign the records
+	mn := mocknet.New(ctx)
+	for i := 0; i < 3; i++ {
+		key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 7001+i))
+		require.NoError(t, err)
+		_, err = mn.AddPeer(key, addr)
+		require.NoError(t, err)
+	}
+	err := mn.LinkAll()
+	require.NoError(t, err)
+	err = mn.ConnectAllButSelf()
+	require.NoError(t, err)
+
+	hosts := mn.Hosts()
+	dhts := make([]*kaddht.IpfsDHT, len(hosts))
+	validators := make([]*authorityRecordValidator, len(hosts))
+	for i, h := range hosts {
+		validators[i] = newAuthorityRecordValidator()
+		dhts[i], err = kaddht.New(ctx, h,
+			kaddht.Mode(kaddht.ModeServer),
+			kaddht.ProtocolPrefix("/gossamer/test"),
+			kaddht.Validator(validators[i]),
+		)
+		require.NoError(t, err)
+		defer dhts[i].Close() //nolint
+	}
+
+	for _, d := range dhts {
+		d := d
+		require.Eventually(t, func() bool {
+			return d.RoutingTable().Size() == len(hosts)-1
+		}, 10*time.Second, 50*time.Millisecond)
+	}
+
+	kr, err := keystore.NewSr25519Keyring()
+	require.NoError(t, err)
+
+	authorities := []*sr25519.PublicKey{
+		kr.Alice().Public().(*sr25519.PublicKey),
+		kr.Bob().Public().(*sr25519.PublicKey),
+	}
+
+	rt := new(mocks.Instance)
+	rt.On("AuthorityDiscoveryAuthorities").Return(authorities, nil)
+	blockState := new(MockBlockState)
+	blockState.On("GetRuntime", mock.Anything).Return(rt, nil)
+
+	newNode := func(i int, kp *sr25519.Keypair) (*authorityDiscovery, *mockPeerAdd) {
+		ks := keystore.NewBasicKeystore(keystore.AudiName, kp.Type())
+		ks.Insert(kp)
+		handler := new(mockPeerAdd)
+		ad := newAuthorityDiscovery(ctx, hosts[i], blockState, ks, handler, validators[i])
+		ad.dht = dhts[i]
+		return ad, handler
+	}
+
+	nodeA, handlerA := newNode(0, kr.Alice().(*sr25519.Keypair))
+	nodeB, handlerB := newNode(1, kr.Bob().(*sr25519.Keypair))
+
+	err = nodeA.publish()
+	require.NoError(t, err)
+
+	// bob has not published yet, so only alice is resolved
+	err = nodeB.resolve()
+	require.NoError(t, err)
+	require.Equal(t, []peer.ID{hosts[0].ID()}, handlerB.peers())
+
+	info, has := nodeB.authorityAddrs(authorities[0])
+	require.True(t, has)
+	require.Equal(t, hosts[0].ID(), info.ID)
+	require.ElementsMatch(t, hosts[0].Addrs(), info.Addrs)
+
+	_, has = nodeB.authorityAddrs(authorities[1])
+	require.False(t, has)
+
+	err = nodeB.publish()
+	require.NoError(t, err)
+
+	err = nodeA.resolve()
+	require.NoError(t, err)
+	require.Equal(t, []peer.ID{hosts[1].ID()}, handlerA.peers())
+
+	info, has = nodeA.authorityAddrs(authorities[1])
+	require.True(t, has)
+	require.Equal(t, hosts[1].ID(), info.ID)
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+const (
+	// DefaultBanDuration is how long a peer is banned for when its reputation
+	// drops below the peerset banned threshold, or when no duration is given.
+	DefaultBanDuration = time.Hour * 12
+
+	bannedPeersPrefix = "/gossamer/banned-peers"
+)
+
+// banEntry is the reason and expiry of a ban, as stored in the datastore
+type banEntry struct {
+	Reason  string
+	Expires int64 // unix timestamp in seconds
+}
+
+func (e banEntry) expired(now time.Time) bool {
+	return now.Unix() >= e.Expires
+}
+
+// banList keeps track of banned peers. The bans are persisted in the libp2p datastore so that
+// they survive restarts. It implements connmgr.ConnectionGater to refuse connections with banned peers.
+type banList struct {
+	sync.RWMutex
+	ds      datastore.Datastore
+	entries map[peer.ID]banEntry
+}
+
+// newBanList creates a banList and loads the bans that haven't expired yet from the datastore.
+func newBanList(ds datastore.Datastore) (*banList, error) {
+	bl := &banList{
+		ds:      ds,
+		entries: make(map[peer.ID]banEntry),
+	}
+
+	results, err := ds.Query(query.Query{Prefix: bannedPeersPrefix})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		id, err := peer.Decode(key.BaseNamespace())
+		if err != nil {
+			logger.Warnf("failed to decode banned peer id %s: %s", entry.Key, err)
+			continue
+		}
+
+		var be banEntry
+		if err = scale.Unmarshal(entry.Value, &be); err != nil {
+			logger.Warnf("failed to decode ban of peer %s: %s", id, err)
+			continue
+		}
+
+		if be.expired(now) {
+			if err = ds.Delete(key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		bl.entries[id] = be
+	}
+
+	return bl, nil
+}
+
+func banKey(id peer.ID) datastore.Key {
+	return datastore.NewKey(bannedPeersPrefix).ChildString(id.Pretty())
+}
+
+// ban bans the given peer for the given duration, overwriting any previous ban.
+func (bl *banList) ban(id peer.ID, reason string, duration time.Duration) error {
+	entry := banEntry{
+		Reason:  reason,
+		Expires: time.Now().Add(duration).Unix(),
+	}
+
+	enc, err := scale.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	bl.Lock()
+	defer bl.Unlock()
+
+	if err = bl.ds.Put(banKey(id), enc); err != nil {
+		return err
+	}
+
+	bl.entries[id] = entry
+	return nil
+}
+
+// unban removes the ban of the given peer, if any.
+func (bl *banList) unban(id peer.ID) error {
+	bl.Lock()
+	defer bl.Unlock()
+
+	if err := bl.ds.Delete(banKey(id)); err != nil {
+		return err
+	}
+
+	delete(bl.entries, id)
+	return nil
+}
+
+// isBanned returns whether the given peer is currently banned.
+func (bl *banList) isBanned(id peer.ID) bool {
+	bl.RLock()
+	entry, has := bl.entries[id]
+	bl.RUnlock()
+
+	if !has {
+		return false
+	}
+
+	if !entry.expired(time.Now()) {
+		return true
+	}
+
+	if err := bl.unban(id); err != nil {
+		logger.Warnf("failed to remove expired ban of peer %s: %s", id, err)
+	}
+
+	return false
+}
+
+// bannedPeers returns the peers that are currently banned, sorted by expiry.
+func (bl *banList) bannedPeers() []common.BannedPeer {
+	bl.RLock()
+	defer bl.RUnlock()
+
+	now := time.Now()
+	peers := make([]common.BannedPeer, 0, len(bl.entries))
+	for id, entry := range bl.entries {
+		if entry.expired(now) {
+			continue
+		}
+
+		peers = append(peers, common.BannedPeer{
+			PeerID:  id.String(),
+			Reason:  entry.Reason,
+			Expires: time.Unix(entry.Expires, 0),
+		})
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Expires.Equal(peers[j].Expires) {
+			return strings.Compare(peers[i].PeerID, peers[j].PeerID) < 0
+		}
+		return peers[i].Expires.Before(peers[j].Expires)
+	})
+
+	return peers
+}
+
+// InterceptPeerDial refuses to dial banned peers.
+func (bl *banList) InterceptPeerDial(id peer.ID) (allow bool) {
+	return !bl.isBanned(id)
+}
+
+// InterceptAddrDial refuses to dial banned peers.
+func (bl *banList) InterceptAddrDial(id peer.ID, _ ma.Multiaddr) (allow bool) {
+	return !bl.isBanned(id)
+}
+
+// InterceptAccept allows all inbound connections, since the remote peer is not known yet.
+func (*banList) InterceptAccept(network.ConnMultiaddrs) (allow bool) {
+	return true
+}
+
+// InterceptSecured refuses connections with banned peers once their identity is known.
+func (bl *banList) InterceptSecured(_ network.Direction, id peer.ID, _ network.ConnMultiaddrs) (allow bool) {
+	return !bl.isBanned(id)
+}
+
+// InterceptUpgraded allows all upgraded connections, banned peers are refused in InterceptSecured.
+func (*banList) InterceptUpgraded(network.Conn) (allow bool, reason control.DisconnectReason) {
+	return true, 0
+}
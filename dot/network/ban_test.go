@@ -0,0 +1,123 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+func newTestBanList(t *testing.T) (*banList, datastore.Datastore) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	bl, err := newBanList(ds)
+	require.NoError(t, err)
+	return bl, ds
+}
+
+func TestBanList_BanUnban(t *testing.T) {
+	bl, _ := newTestBanList(t)
+
+	id, err := peer.Decode("QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ")
+	require.NoError(t, err)
+
+	require.False(t, bl.isBanned(id))
+	require.Empty(t, bl.bannedPeers())
+
+	err = bl.ban(id, "Bad message", time.Hour)
+	require.NoError(t, err)
+	require.True(t, bl.isBanned(id))
+
+	banned := bl.bannedPeers()
+	require.Len(t, banned, 1)
+	require.Equal(t, id.String(), banned[0].PeerID)
+	require.Equal(t, "Bad message", banned[0].Reason)
+
+	err = bl.unban(id)
+	require.NoError(t, err)
+	require.False(t, bl.isBanned(id))
+	require.Empty(t, bl.bannedPeers())
+}
+
+func TestBanList_Persisted(t *testing.T) {
+	bl, ds := newTestBanList(t)
+
+	id, err := peer.Decode("QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ")
+	require.NoError(t, err)
+
+	err = bl.ban(id, "Bad message", time.Hour)
+	require.NoError(t, err)
+
+	reloaded, err := newBanList(ds)
+	require.NoError(t, err)
+	require.True(t, reloaded.isBanned(id))
+	require.Equal(t, bl.bannedPeers(), reloaded.bannedPeers())
+
+	err = reloaded.unban(id)
+	require.NoError(t, err)
+
+	reloaded, err = newBanList(ds)
+	require.NoError(t, err)
+	require.False(t, reloaded.isBanned(id))
+}
+
+func TestBanList_Expired(t *testing.T) {
+	bl, ds := newTestBanList(t)
+
+	id, err := peer.Decode("QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ")
+	require.NoError(t, err)
+
+	// a ban that has already expired is dropped when loading the list
+	enc, err := scale.Marshal(banEntry{
+		Reason:  "Bad message",
+		Expires: time.Now().Add(-time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	err = ds.Put(banKey(id), enc)
+	require.NoError(t, err)
+
+	reloaded, err := newBanList(ds)
+	require.NoError(t, err)
+	require.False(t, reloaded.isBanned(id))
+
+	has, err := ds.Has(banKey(id))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// a ban that expires while the node is running is removed lazily
+	err = bl.ban(id, "Bad message", 0)
+	require.NoError(t, err)
+	require.False(t, bl.isBanned(id))
+	require.Empty(t, bl.bannedPeers())
+
+	has, err = ds.Has(banKey(id))
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestBanList_ConnectionGater(t *testing.T) {
+	bl, _ := newTestBanList(t)
+
+	id, err := peer.Decode("QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ")
+	require.NoError(t, err)
+
+	require.True(t, bl.InterceptPeerDial(id))
+	require.True(t, bl.InterceptAddrDial(id, nil))
+	require.True(t, bl.InterceptSecured(network.DirInbound, id, nil))
+
+	err = bl.ban(id, "Bad message", time.Hour)
+	require.NoError(t, err)
+
+	require.False(t, bl.InterceptPeerDial(id))
+	require.False(t, bl.InterceptAddrDial(id, nil))
+	require.False(t, bl.InterceptSecured(network.DirInbound, id, nil))
+	require.True(t, bl.InterceptAccept(nil))
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"golang.org/x/time/rate"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// names of the protocols that can be rate limited, as used in BandwidthLimits.Protocols
+const (
+	syncProtocolName          = "sync"
+	lightProtocolName         = "light"
+	blockAnnounceProtocolName = "block-announces"
+	transactionsProtocolName  = "transactions"
+	grandpaProtocolName       = "grandpa"
+	unknownProtocolName       = "unknown"
+)
+
+var rateLimitedProtocols = map[string]struct{}{
+	syncProtocolName:          {},
+	lightProtocolName:         {},
+	blockAnnounceProtocolName: {},
+	transactionsProtocolName:  {},
+	grandpaProtocolName:       {},
+}
+
+// RateLimit is an inbound and outbound rate limit in bytes per second, a zero value means no limit.
+type RateLimit struct {
+	In  uint64
+	Out uint64
+}
+
+// BandwidthLimits are the rate limits applied to the messages we send and receive.
+// Global applies to the sum of all protocols, Protocols is keyed by protocol name
+// (sync, light, block-announces, transactions or grandpa).
+type BandwidthLimits struct {
+	Global    RateLimit
+	Protocols map[string]RateLimit
+}
+
+// limiter is a pair of inbound and outbound rate limiters, a nil limiter means no limit.
+type limiter struct {
+	in  *rate.Limiter
+	out *rate.Limiter
+}
+
+func newLimiter(limit RateLimit) limiter {
+	return limiter{
+		in:  newRateLimiter(limit.In),
+		out: newRateLimiter(limit.Out),
+	}
+}
+
+// newRateLimiter creates a limiter allowing bursts of up to one second worth of traffic
+func newRateLimiter(bytesPerSecond uint64) *rate.Limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))
+}
+
+// waitN blocks until n bytes are allowed by the limiter. Messages bigger than the burst size
+// are let through in chunks, so that they are rate limited rather than rejected.
+func waitN(ctx context.Context, l *rate.Limiter, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	for n > 0 {
+		chunk := n
+		if chunk > l.Burst() {
+			chunk = l.Burst()
+		}
+
+		if err := l.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+
+		n -= chunk
+	}
+
+	return nil
+}
+
+// bandwidthManager enforces the bandwidth limits and keeps track of the bandwidth used per protocol.
+type bandwidthManager struct {
+	ctx       context.Context
+	bwc       *metrics.BandwidthCounter
+	global    limiter
+	protocols map[string]limiter
+}
+
+func newBandwidthManager(ctx context.Context, limits BandwidthLimits) (*bandwidthManager, error) {
+	bm := &bandwidthManager{
+		ctx:       ctx,
+		bwc:       metrics.NewBandwidthCounter(),
+		global:    newLimiter(limits.Global),
+		protocols: make(map[string]limiter, len(limits.Protocols)),
+	}
+
+	for name, limit := range limits.Protocols {
+		if _, ok := rateLimitedProtocols[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownRateLimitProtocol, name)
+		}
+
+		bm.protocols[name] = newLimiter(limit)
+	}
+
+	return bm, nil
+}
+
+// protocolName returns the name of the protocol without its prefix and version,
+// eg. /gossamer/gssmr/0/sync/2 becomes sync.
+func protocolName(pid protocol.ID) string {
+	parts := strings.Split(strings.TrimSuffix(string(pid), "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" {
+		return unknownProtocolName
+	}
+
+	return parts[len(parts)-2]
+}
+
+// waitOutbound blocks until the outbound limits allow sending n bytes over the given protocol.
+func (bm *bandwidthManager) waitOutbound(pid protocol.ID, n int) error {
+	if err := waitN(bm.ctx, bm.protocols[protocolName(pid)].out, n); err != nil {
+		return err
+	}
+
+	return waitN(bm.ctx, bm.global.out, n)
+}
+
+// logSent records n bytes sent to the given peer over the given protocol.
+func (bm *bandwidthManager) logSent(pid protocol.ID, p peer.ID, n int) {
+	bm.bwc.LogSentMessage(int64(n))
+	bm.bwc.LogSentMessageStream(int64(n), pid, p)
+}
+
+// logRecv records n bytes received from the given peer over the given protocol, then blocks until
+// the inbound limits allow them. Since we don't read from the stream again until then, this applies
+// back-pressure to the sender.
+func (bm *bandwidthManager) logRecv(pid protocol.ID, p peer.ID, n int) error {
+	bm.bwc.LogRecvMessage(int64(n))
+	bm.bwc.LogRecvMessageStream(int64(n), pid, p)
+
+	if err := waitN(bm.ctx, bm.protocols[protocolName(pid)].in, n); err != nil {
+		return err
+	}
+
+	return waitN(bm.ctx, bm.global.in, n)
+}
+
+// bandwidth returns the bandwidth used in total and per protocol name
+func (bm *bandwidthManager) bandwidth() common.Bandwidth {
+	bw := common.Bandwidth{
+		Total:     toBandwidthStats(bm.bwc.GetBandwidthTotals()),
+		Protocols: make(map[string]common.BandwidthStats),
+	}
+
+	for pid, stats := range bm.bwc.GetBandwidthByProtocol() {
+		name := protocolName(pid)
+		s := bw.Protocols[name]
+		s.TotalIn += stats.TotalIn
+		s.TotalOut += stats.TotalOut
+		s.RateIn += stats.RateIn
+		s.RateOut += stats.RateOut
+		bw.Protocols[name] = s
+	}
+
+	return bw
+}
+
+func toBandwidthStats(stats metrics.Stats) common.BandwidthStats {
+	return common.BandwidthStats{
+		TotalIn:  stats.TotalIn,
+		TotalOut: stats.TotalOut,
+		RateIn:   stats.RateIn,
+		RateOut:  stats.RateOut,
+	}
+}
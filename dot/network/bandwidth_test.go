@@ -0,0 +1,121 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolName(t *testing.T) {
+	testCases := []struct {
+		pid  protocol.ID
+		name string
+	}{
+		{pid: "/gossamer/gssmr/0/sync/2", name: syncProtocolName},
+		{pid: "/gossamer/gssmr/0/light/2", name: lightProtocolName},
+		{pid: "/gossamer/gssmr/0/block-announces/1", name: blockAnnounceProtocolName},
+		{pid: "/gossamer/gssmr/0/transactions/1", name: transactionsProtocolName},
+		{pid: "/paritytech/grandpa/1", name: grandpaProtocolName},
+		{pid: "/sync/2/", name: syncProtocolName},
+		{pid: "", name: unknownProtocolName},
+		{pid: "sync", name: unknownProtocolName},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.name, protocolName(tc.pid), tc.pid)
+	}
+}
+
+func TestNewBandwidthManager_UnknownProtocol(t *testing.T) {
+	_, err := newBandwidthManager(context.Background(), BandwidthLimits{
+		Protocols: map[string]RateLimit{
+			"kademlia": {In: 1024},
+		},
+	})
+	require.True(t, errors.Is(err, errUnknownRateLimitProtocol))
+
+	bm, err := newBandwidthManager(context.Background(), BandwidthLimits{
+		Protocols: map[string]RateLimit{
+			syncProtocolName: {In: 1024},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, bm.protocols[syncProtocolName].in)
+	require.Nil(t, bm.protocols[syncProtocolName].out)
+	require.Nil(t, bm.global.in)
+	require.Nil(t, bm.global.out)
+}
+
+func TestBandwidthManager_Limits(t *testing.T) {
+	const limit = 1000
+
+	pid := protocol.ID("/gossamer/gssmr/0/sync/2")
+	other := protocol.ID("/gossamer/gssmr/0/transactions/1")
+
+	bm, err := newBandwidthManager(context.Background(), BandwidthLimits{
+		Protocols: map[string]RateLimit{
+			syncProtocolName: {In: limit, Out: limit},
+		},
+	})
+	require.NoError(t, err)
+
+	// the first second worth of traffic is let through immediately, the rest is delayed
+	start := time.Now()
+	err = bm.waitOutbound(pid, limit+limit/2)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Millisecond*400))
+
+	start = time.Now()
+	err = bm.logRecv(pid, peer.ID("noot"), limit)
+	require.NoError(t, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Millisecond*200))
+
+	// protocols without limits aren't delayed
+	start = time.Now()
+	err = bm.waitOutbound(other, limit*10)
+	require.NoError(t, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Millisecond*200))
+}
+
+func TestBandwidthManager_GlobalLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bm, err := newBandwidthManager(ctx, BandwidthLimits{
+		Global: RateLimit{In: 100, Out: 100},
+	})
+	require.NoError(t, err)
+
+	err = bm.waitOutbound("/gossamer/gssmr/0/transactions/1", 100)
+	require.NoError(t, err)
+
+	// waiting is aborted when the context is cancelled
+	cancel()
+	err = bm.waitOutbound("/gossamer/gssmr/0/transactions/1", 100)
+	require.Error(t, err)
+}
+
+func TestBandwidthManager_Bandwidth(t *testing.T) {
+	bm, err := newBandwidthManager(context.Background(), BandwidthLimits{})
+	require.NoError(t, err)
+
+	bm.logSent("/gossamer/gssmr/0/sync/2", peer.ID("noot"), 100)
+	bm.logSent("/gossamer/other/0/sync/2", peer.ID("noot"), 50)
+	err = bm.logRecv("/paritytech/grandpa/1", peer.ID("noot"), 10)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		bw := bm.bandwidth()
+		return bw.Total.TotalOut == 150 &&
+			bw.Total.TotalIn == 10 &&
+			bw.Protocols[syncProtocolName].TotalOut == 150 &&
+			bw.Protocols[grandpaProtocolName].TotalIn == 10
+	}, time.Second*5, time.Millisecond*100)
+}
@@ -193,6 +193,10 @@ func (s *Service) validateBlockAnnounceHandshake(from peer.ID, hs Handshake) err
 		np.inboundHandshakeData.Store(from, data)
 	}
 
+	if bhs.Roles&authorityRole != 0 {
+		s.host.cm.TagPeer(from, authorityTag, authorityTagValue)
+	}
+
 	// if peer has higher best block than us, begin syncing
 	latestHeader, err := s.blockState.BestBlockHeader()
 	if err != nil {
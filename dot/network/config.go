@@ -5,12 +5,16 @@ package network
 
 import (
 	"errors"
+	"fmt"
 	"path"
 	"time"
 
+	"github.com/libp2p/go-libp2p-core/connmgr"
 	"github.com/libp2p/go-libp2p-core/crypto"
+	libp2phost "github.com/libp2p/go-libp2p-core/host"
 
 	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/keystore"
 )
 
 const (
@@ -47,6 +51,11 @@ const (
 // DefaultBootnodes the default value for Config.Bootnodes
 var DefaultBootnodes = []string(nil)
 
+// HostConstructor creates a libp2p host with the given identity. The host must notify the connection manager
+// of its connections and refuse the connections the connection gater doesn't allow.
+type HostConstructor func(key crypto.PrivKey, cm connmgr.ConnManager, gater connmgr.ConnectionGater) (
+	libp2phost.Host, error)
+
 // Config is used to configure a network service
 type Config struct {
 	LogLvl  log.Level
@@ -67,12 +76,18 @@ type Config struct {
 	PublicIP string
 	// Port the network port used for listening
 	Port uint16
+	// ListenAddrs the multiaddrs the node listens on (TCP, WebSocket or QUIC),
+	// defaults to listening on Port over TCP
+	ListenAddrs []string
 	// RandSeed the seed used to generate the network p2p identity (0 = non-deterministic random seed)
 	RandSeed int64
 	// Bootnodes the peer addresses used for bootstrapping
 	Bootnodes []string
 	// ProtocolID the protocol ID for network messages
 	ProtocolID string
+	// ForkID the fork ID of the chain from the chain spec, if any. The genesis hash based protocol IDs
+	// are of the form /<genesis hash>/<fork id>/<sub-protocol> when it is set.
+	ForkID string
 	// NoBootstrap disables bootstrapping
 	NoBootstrap bool
 	// NoMDNS disables MDNS discovery
@@ -86,6 +101,21 @@ type Config struct {
 	// PersistentPeers is a list of multiaddrs which the node should remain connected to
 	PersistentPeers []string
 
+	// BandwidthLimits the inbound and outbound rate limits, globally and per protocol
+	BandwidthLimits BandwidthLimits
+
+	// SwarmKey is the path to the pre-shared key file of a private network. If set, only nodes that
+	// have the same key can connect to us, and we only discover those over mDNS.
+	SwarmKey string
+
+	// NewHost is used to create the libp2p host instead of listening on ListenAddrs, if set.
+	// This allows running the service over an in-memory network, see the simnet package.
+	NewHost HostConstructor
+
+	// AuthorityDiscoveryKeystore holds the authority discovery keys used to publish our addresses
+	// on the DHT when running as an authority
+	AuthorityDiscoveryKeystore keystore.Keystore
+
 	// privateKey the private key for the network p2p identity
 	privateKey crypto.PrivKey
 
@@ -124,6 +154,10 @@ func (c *Config) build() error {
 		c.Port = DefaultPort
 	}
 
+	if len(c.ListenAddrs) == 0 {
+		c.ListenAddrs = []string{fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", c.Port)}
+	}
+
 	// build identity configuration
 	err = c.buildIdentity()
 	if err != nil {
@@ -5,7 +5,11 @@ package network
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p-core/connmgr"
 	"github.com/libp2p/go-libp2p-core/network"
@@ -15,6 +19,25 @@ import (
 	"github.com/ChainSafe/gossamer/dot/peerset"
 )
 
+const (
+	// reservedTag is used to tag reserved peers.
+	reservedTag = "reserved"
+	// bootnodeTag is used to tag peers that were configured as bootnodes.
+	bootnodeTag = "bootnode"
+	// syncingTag is used to tag peers that served us block requests.
+	syncingTag = "syncing"
+	// authorityTag is used to tag peers that announced themselves as authorities.
+	authorityTag = "authority"
+
+	// authorityRole is the roles bit set by authority nodes in their handshake (see Table D.2)
+	authorityRole = byte(4)
+
+	reservedTagValue  = 1000
+	authorityTagValue = 100
+	syncingTagValue   = 50
+	bootnodeTagValue  = 10
+)
+
 // ConnManager implements connmgr.ConnManager
 type ConnManager struct {
 	sync.Mutex
@@ -24,12 +47,23 @@ type ConnManager struct {
 	disconnectHandler func(peer.ID)
 
 	// protectedPeers contains a list of peers that are protected from pruning
-	// when we reach the maximum numbers of peers.
-	protectedPeers *sync.Map // map[peer.ID]struct{}
+	// when we reach the maximum numbers of peers, along with the tags they are protected under.
+	protectedPeers map[peer.ID]map[string]struct{}
+	// requests counts the requests protected by protectRequest, to give each of them its own tag
+	requests uint64
 
 	// persistentPeers contains peers we should remain connected to.
 	persistentPeers *sync.Map // map[peer.ID]struct{}
 
+	// peerTags contains the tags assigned to each peer, used to decide which
+	// peers to prune first when we reach the maximum numbers of peers.
+	peerTags map[peer.ID]*connmgr.TagInfo
+
+	trimMu sync.Mutex
+	closed bool
+	// trimCh requests the trim loop to trim the open connections
+	trimCh chan struct{}
+
 	peerSetHandler PeerSetHandler
 }
 
@@ -42,8 +76,10 @@ func newConnManager(min, max int, peerSetCfg *peerset.ConfigSet) (*ConnManager,
 	return &ConnManager{
 		min:             min,
 		max:             max,
-		protectedPeers:  new(sync.Map),
+		protectedPeers:  make(map[peer.ID]map[string]struct{}),
 		persistentPeers: new(sync.Map),
+		peerTags:        make(map[peer.ID]*connmgr.TagInfo),
+		trimCh:          make(chan struct{}, 1),
 		peerSetHandler:  psh,
 	}, nil
 }
@@ -62,42 +98,248 @@ func (cm *ConnManager) Notifee() network.Notifiee {
 	return nb
 }
 
-// TagPeer is unimplemented
-func (*ConnManager) TagPeer(peer.ID, string, int) {}
+// TagPeer tags a peer with a string, associating a weight with the tag.
+func (cm *ConnManager) TagPeer(id peer.ID, tag string, value int) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	cm.tagInfo(id).Tags[tag] = value
+}
+
+// UntagPeer removes the tagged value from the peer.
+func (cm *ConnManager) UntagPeer(id peer.ID, tag string) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	info, has := cm.peerTags[id]
+	if !has {
+		return
+	}
+
+	delete(info.Tags, tag)
+}
+
+// UpsertTag updates an existing tag or inserts a new one.
+// The upsert function is called with the current value of the tag, or zero if the tag
+// doesn't exist yet, and its result is stored as the new value.
+func (cm *ConnManager) UpsertTag(id peer.ID, tag string, upsert func(int) int) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	info := cm.tagInfo(id)
+	info.Tags[tag] = upsert(info.Tags[tag])
+}
+
+// GetTagInfo returns the metadata associated with the peer,
+// or nil if no metadata has been recorded for the peer.
+func (cm *ConnManager) GetTagInfo(id peer.ID) *connmgr.TagInfo {
+	cm.Lock()
+	defer cm.Unlock()
+
+	info, has := cm.peerTags[id]
+	if !has {
+		return nil
+	}
+
+	tags := make(map[string]int, len(info.Tags))
+	value := 0
+	for tag, v := range info.Tags {
+		tags[tag] = v
+		value += v
+	}
+
+	return &connmgr.TagInfo{
+		FirstSeen: info.FirstSeen,
+		Value:     value,
+		Tags:      tags,
+		Conns:     make(map[string]time.Time),
+	}
+}
+
+// tagInfo returns the tag info of the given peer, creating it if necessary.
+// It must be called with the lock held.
+func (cm *ConnManager) tagInfo(id peer.ID) *connmgr.TagInfo {
+	info, has := cm.peerTags[id]
+	if !has {
+		info = &connmgr.TagInfo{
+			FirstSeen: time.Now(),
+			Tags:      make(map[string]int),
+		}
+		cm.peerTags[id] = info
+	}
+
+	return info
+}
+
+// TrimOpenConns disconnects the lowest-value peers until we are no longer above the
+// maximum number of peers. Protected and persistent peers are never trimmed.
+func (cm *ConnManager) TrimOpenConns(ctx context.Context) {
+	cm.trimMu.Lock()
+	defer cm.trimMu.Unlock()
+
+	if cm.closed || cm.host == nil {
+		return
+	}
+
+	peers := cm.host.peers()
+	excess := len(peers) - cm.max
+	if excess <= 0 {
+		return
+	}
+
+	candidates := cm.trimCandidates(peers)
+	if excess > len(candidates) {
+		excess = len(candidates)
+	}
+
+	for _, id := range candidates[:excess] {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		logger.Debugf("trimming connection with peer %s", id)
+		// TODO: currently we only have one set so setID is 0, change this once we have more set in peerSet.
+		cm.peerSetHandler.DisconnectPeer(0, id)
+
+		// the peer may not be known by the peerset as connected (eg. if we dialled it directly),
+		// so close the connection ourselves as well.
+		if err := cm.host.closePeer(id); err != nil {
+			logger.Debugf("failed to close connection with peer %s: %s", id, err)
+		}
+	}
+}
+
+// trimLoop trims the open connections whenever a trim is requested, until the context is done.
+// Trims requested while one is running are coalesced into a single trim.
+func (cm *ConnManager) trimLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cm.trimCh:
+			cm.TrimOpenConns(ctx)
+		}
+	}
+}
+
+// requestTrim requests the trim loop to trim the open connections, unless a trim is already pending.
+func (cm *ConnManager) requestTrim() {
+	select {
+	case cm.trimCh <- struct{}{}:
+	default:
+	}
+}
 
-// UntagPeer is unimplemented
-func (*ConnManager) UntagPeer(peer.ID, string) {}
+// trimCandidates returns the unprotected peers sorted by ascending value, ie. the peers that
+// should be pruned first come first. The value of a peer is determined by its peerset
+// reputation and the sum of its tags. Ties are broken by keeping the oldest peers.
+func (cm *ConnManager) trimCandidates(peers []peer.ID) []peer.ID {
+	candidates := cm.unprotectedPeers(peers)
+
+	values := make(map[peer.ID]int64, len(candidates))
+	firstSeen := make(map[peer.ID]time.Time, len(candidates))
+	for _, id := range candidates {
+		var value int64
+		if rep, err := cm.peerSetHandler.PeerReputation(id); err == nil {
+			value = int64(rep)
+		}
 
-// UpsertTag is unimplemented
-func (*ConnManager) UpsertTag(peer.ID, string, func(int) int) {}
+		if info := cm.GetTagInfo(id); info != nil {
+			value += int64(info.Value)
+			firstSeen[id] = info.FirstSeen
+		}
+
+		values[id] = value
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if values[a] != values[b] {
+			return values[a] < values[b]
+		}
 
-// GetTagInfo is unimplemented
-func (*ConnManager) GetTagInfo(peer.ID) *connmgr.TagInfo { return &connmgr.TagInfo{} }
+		return firstSeen[a].After(firstSeen[b])
+	})
 
-// TrimOpenConns is unimplemented
-func (*ConnManager) TrimOpenConns(context.Context) {}
+	return candidates
+}
 
 // Protect peer will add the given peer to the protectedPeerMap which will
-// protect the peer from pruning.
-func (cm *ConnManager) Protect(id peer.ID, _ string) {
-	cm.protectedPeers.Store(id, struct{}{})
+// protect the peer from pruning. A peer may be protected under several tags.
+func (cm *ConnManager) Protect(id peer.ID, tag string) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	tags, has := cm.protectedPeers[id]
+	if !has {
+		tags = make(map[string]struct{})
+		cm.protectedPeers[id] = tags
+	}
+
+	tags[tag] = struct{}{}
 }
 
-// Unprotect peer will remove the given peer from prune protection.
-// returns true if we have successfully removed the peer from the
-// protectedPeerMap. False otherwise.
-func (cm *ConnManager) Unprotect(id peer.ID, _ string) bool {
-	_, wasDeleted := cm.protectedPeers.LoadAndDelete(id)
-	return wasDeleted
+// Unprotect removes the protection placed on the given peer under the given tag.
+// It returns true if the peer is still protected under a different tag,
+// false otherwise.
+func (cm *ConnManager) Unprotect(id peer.ID, tag string) bool {
+	cm.Lock()
+	defer cm.Unlock()
+
+	tags, has := cm.protectedPeers[id]
+	if !has {
+		return false
+	}
+
+	delete(tags, tag)
+	if len(tags) == 0 {
+		delete(cm.protectedPeers, id)
+		return false
+	}
+
+	return true
 }
 
-// Close is unimplemented
-func (*ConnManager) Close() error { return nil }
+// Close stops the connection manager from trimming any further connections.
+func (cm *ConnManager) Close() error {
+	cm.trimMu.Lock()
+	defer cm.trimMu.Unlock()
+
+	cm.closed = true
+	return nil
+}
+
+// protectRequest protects the peer from pruning while a request is in flight. Each request is protected under
+// its own tag derived from the given one, so that the first of several concurrent requests to the peer to
+// complete doesn't unprotect it while the others are still in flight. It returns the function removing the
+// protection once the request is done.
+func (cm *ConnManager) protectRequest(id peer.ID, tag string) (unprotect func()) {
+	reqTag := fmt.Sprintf("%s/%d", tag, atomic.AddUint64(&cm.requests, 1))
+	cm.Protect(id, reqTag)
+	return func() {
+		cm.Unprotect(id, reqTag)
+	}
+}
 
 // IsProtected returns whether the given peer is protected from pruning or not.
-func (cm *ConnManager) IsProtected(id peer.ID, _ string) (protected bool) {
-	_, ok := cm.protectedPeers.Load(id)
-	return ok
+// If tag is empty, it returns whether the peer is protected under any tag.
+func (cm *ConnManager) IsProtected(id peer.ID, tag string) (protected bool) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	tags, has := cm.protectedPeers[id]
+	if !has {
+		return false
+	}
+
+	if tag == "" {
+		return len(tags) > 0
+	}
+
+	_, protected = tags[tag]
+	return protected
 }
 
 // Listen is called when network starts listening on an address
@@ -132,13 +374,21 @@ func (cm *ConnManager) Connected(n network.Network, c network.Conn) {
 	if cm.connectHandler != nil {
 		cm.connectHandler(c.RemotePeer())
 	}
+
+	if len(n.Peers()) > cm.max {
+		cm.requestTrim()
+	}
 }
 
 // Disconnected is called when a connection closed
-func (cm *ConnManager) Disconnected(_ network.Network, c network.Conn) {
+func (cm *ConnManager) Disconnected(n network.Network, c network.Conn) {
 	logger.Tracef("Host %s disconnected from peer %s", c.LocalPeer(), c.RemotePeer())
 
-	cm.Unprotect(c.RemotePeer(), "")
+	// the protections are kept while the peer has other open connections
+	if len(n.ConnsToPeer(c.RemotePeer())) == 0 {
+		cm.unprotectAll(c.RemotePeer())
+	}
+
 	if cm.disconnectHandler != nil {
 		cm.disconnectHandler(c.RemotePeer())
 	}
@@ -160,3 +410,28 @@ func (cm *ConnManager) isPersistent(p peer.ID) bool {
 	_, ok := cm.persistentPeers.Load(p)
 	return ok
 }
+
+// unprotectAll removes all the protections placed on the given peer, as well as the tags
+// that only make sense while connected. Reserved and bootnode tags come from the node
+// configuration, so they are kept.
+func (cm *ConnManager) unprotectAll(id peer.ID) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	delete(cm.protectedPeers, id)
+
+	info, has := cm.peerTags[id]
+	if !has {
+		return
+	}
+
+	for tag := range info.Tags {
+		if tag != reservedTag && tag != bootnodeTag {
+			delete(info.Tags, tag)
+		}
+	}
+
+	if len(info.Tags) == 0 {
+		delete(cm.peerTags, id)
+	}
+}
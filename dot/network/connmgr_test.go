@@ -4,10 +4,12 @@
 package network
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
 	"github.com/stretchr/testify/require"
@@ -238,3 +240,190 @@ func TestSetReservedPeer(t *testing.T) {
 	// TODO: once reservedOnly mode is implemented and reservedOnly is set to true, change expected value to 1 (nodeC)
 	require.Equal(t, 3, node3.host.peerCount())
 }
+
+func TestTagUntagPeer(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	p1 := peer.ID("a")
+	require.Nil(t, cm.GetTagInfo(p1))
+
+	cm.TagPeer(p1, bootnodeTag, bootnodeTagValue)
+	cm.TagPeer(p1, syncingTag, syncingTagValue)
+	cm.UpsertTag(p1, syncingTag, func(v int) int { return v + 1 })
+
+	info := cm.GetTagInfo(p1)
+	require.Equal(t, bootnodeTagValue+syncingTagValue+1, info.Value)
+	require.Equal(t, map[string]int{
+		bootnodeTag: bootnodeTagValue,
+		syncingTag:  syncingTagValue + 1,
+	}, info.Tags)
+
+	cm.UntagPeer(p1, syncingTag)
+	info = cm.GetTagInfo(p1)
+	require.Equal(t, bootnodeTagValue, info.Value)
+
+	// only the configuration tags should survive a disconnection
+	cm.TagPeer(p1, authorityTag, authorityTagValue)
+	cm.unprotectAll(p1)
+	info = cm.GetTagInfo(p1)
+	require.Equal(t, map[string]int{bootnodeTag: bootnodeTagValue}, info.Tags)
+}
+
+func TestProtectMultipleTags(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	p1 := peer.ID("a")
+	cm.Protect(p1, syncingTag)
+	cm.Protect(p1, reservedTag)
+	require.True(t, cm.IsProtected(p1, ""))
+	require.True(t, cm.IsProtected(p1, syncingTag))
+
+	require.True(t, cm.Unprotect(p1, syncingTag))
+	require.False(t, cm.IsProtected(p1, syncingTag))
+	require.True(t, cm.IsProtected(p1, ""))
+
+	require.False(t, cm.Unprotect(p1, reservedTag))
+	require.False(t, cm.IsProtected(p1, ""))
+}
+
+func TestProtectRequest(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	// two concurrent requests to the same peer
+	p1 := peer.ID("a")
+	unprotectFirst := cm.protectRequest(p1, syncingTag)
+	unprotectSecond := cm.protectRequest(p1, syncingTag)
+	require.True(t, cm.IsProtected(p1, ""))
+
+	// the peer stays protected while the second request is in flight
+	unprotectFirst()
+	require.True(t, cm.IsProtected(p1, ""))
+
+	unprotectSecond()
+	require.False(t, cm.IsProtected(p1, ""))
+}
+
+func TestTrimCandidates(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	p1 := peer.ID("a")
+	p2 := peer.ID("b")
+	p3 := peer.ID("c")
+	p4 := peer.ID("d")
+
+	cm.TagPeer(p1, authorityTag, authorityTagValue)
+	cm.TagPeer(p2, bootnodeTag, bootnodeTagValue)
+	cm.Protect(p3, syncingTag)
+
+	candidates := cm.trimCandidates([]peer.ID{p1, p2, p3, p4})
+	require.Equal(t, []peer.ID{p4, p2, p1}, candidates)
+}
+
+func TestTrimOpenConns(t *testing.T) {
+	if testing.Short() {
+		t.Skip() // this sometimes fails on CI
+	}
+
+	nodes := make([]*Service, 3)
+	for i := range nodes {
+		config := &Config{
+			BasePath:    utils.NewTestBasePath(t, fmt.Sprintf("node%d", i)),
+			Port:        7000 + uint16(i),
+			NoBootstrap: true,
+			NoMDNS:      true,
+		}
+		nodes[i] = createTestService(t, config)
+	}
+
+	configA := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeA"),
+		Port:        7010,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+	nodeA := createTestService(t, configA)
+
+	for i, node := range nodes {
+		nodeA.host.cm.TagPeer(node.host.id(), syncingTag, (i+1)*syncingTagValue)
+		err := nodeA.host.connect(node.host.addrInfo())
+		require.NoError(t, err)
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	require.Equal(t, len(nodes), nodeA.host.peerCount())
+
+	// lower the maximum number of peers, the peer with the lowest value should be dropped
+	nodeA.host.cm.max = len(nodes) - 1
+	nodeA.host.cm.TrimOpenConns(context.Background())
+	time.Sleep(time.Millisecond * 200)
+
+	require.Equal(t, len(nodes)-1, nodeA.host.peerCount())
+	require.Empty(t, nodeA.host.h.Network().ConnsToPeer(nodes[0].host.id()))
+}
+
+// testConnNetwork is a network.Network that only knows the open connections of its peers
+type testConnNetwork struct {
+	network.Network
+	conns map[peer.ID][]network.Conn
+}
+
+func (n *testConnNetwork) ConnsToPeer(p peer.ID) []network.Conn {
+	return n.conns[p]
+}
+
+type testConn struct {
+	network.Conn
+	remote peer.ID
+}
+
+func (c *testConn) LocalPeer() peer.ID {
+	return ""
+}
+
+func (c *testConn) RemotePeer() peer.ID {
+	return c.remote
+}
+
+func TestDisconnected_KeepsProtectionWithOpenConns(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	p1 := peer.ID("a")
+	cm.Protect(p1, syncingTag)
+	cm.TagPeer(p1, syncingTag, syncingTagValue)
+
+	first, second := &testConn{remote: p1}, &testConn{remote: p1}
+	n := &testConnNetwork{
+		conns: map[peer.ID][]network.Conn{p1: {second}},
+	}
+
+	// the peer is still connected through the second connection
+	cm.Disconnected(n, first)
+	require.True(t, cm.IsProtected(p1, syncingTag))
+	require.Equal(t, syncingTagValue, cm.GetTagInfo(p1).Value)
+
+	n.conns[p1] = nil
+	cm.Disconnected(n, second)
+	require.False(t, cm.IsProtected(p1, ""))
+	require.Nil(t, cm.GetTagInfo(p1))
+}
+
+func TestConnected_RequestsSingleTrim(t *testing.T) {
+	peerCfgSet := peerset.NewConfigSet(uint32(1), uint32(4), false, time.Second*2)
+	cm, err := newConnManager(1, 4, peerCfgSet)
+	require.NoError(t, err)
+
+	// the trims requested before the trim loop handles them are coalesced
+	cm.requestTrim()
+	cm.requestTrim()
+	require.Len(t, cm.trimCh, 1)
+}
@@ -9,7 +9,7 @@ import (
 	"time"
 
 	ethmetrics "github.com/ethereum/go-ethereum/metrics"
-	badger "github.com/ipfs/go-ds-badger2"
+	"github.com/ipfs/go-datastore"
 	libp2phost "github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
@@ -39,14 +39,15 @@ type discovery struct {
 	rd                 *libp2pdiscovery.RoutingDiscovery
 	h                  libp2phost.Host
 	bootnodes          []peer.AddrInfo
-	ds                 *badger.Datastore
+	ds                 datastore.Batching
 	pid                protocol.ID
 	minPeers, maxPeers int
 	handler            PeerSetHandler
+	validator          *authorityRecordValidator
 }
 
 func newDiscovery(ctx context.Context, h libp2phost.Host,
-	bootnodes []peer.AddrInfo, ds *badger.Datastore,
+	bootnodes []peer.AddrInfo, ds datastore.Batching,
 	pid protocol.ID, min, max int, handler PeerSetHandler) *discovery {
 	return &discovery{
 		ctx:       ctx,
@@ -57,6 +58,7 @@ func newDiscovery(ctx context.Context, h libp2phost.Host,
 		minPeers:  min,
 		maxPeers:  max,
 		handler:   handler,
+		validator: newAuthorityRecordValidator(),
 	}
 }
 
@@ -96,7 +98,11 @@ func (d *discovery) start() error {
 		dual.DHTOption(kaddht.Datastore(d.ds)),
 		dual.DHTOption(kaddht.BootstrapPeers(d.bootnodes...)),
 		dual.DHTOption(kaddht.V1ProtocolOverride(d.pid + "/kad")),
+		// the DHT only accepts validators other than the IPFS ones outside of the IPFS protocol prefix
+		dual.DHTOption(kaddht.ProtocolPrefix(d.pid)),
 		dual.DHTOption(kaddht.Mode(kaddht.ModeAutoServer)),
+		// like in substrate, the only records stored in the DHT are the authority discovery records
+		dual.DHTOption(kaddht.Validator(d.validator)),
 	}
 
 	// create DHT service
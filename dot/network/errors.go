@@ -7,11 +7,27 @@ import (
 	"errors"
 )
 
+// ErrRequestTimeout is returned when a peer doesn't answer a request in time
+var ErrRequestTimeout = errors.New("request timed out")
+
 var (
-	errCannotValidateHandshake = errors.New("failed to validate handshake")
-	errMessageTypeNotValid     = errors.New("message type is not valid")
-	errMessageIsNotHandshake   = errors.New("failed to convert message to Handshake")
-	errMissingHandshakeMutex   = errors.New("outboundHandshakeMutex does not exist")
-	errInvalidHandshakeForPeer = errors.New("peer previously sent invalid handshake")
-	errHandshakeTimeout        = errors.New("handshake timeout reached")
+	errCannotValidateHandshake  = errors.New("failed to validate handshake")
+	errMessageTypeNotValid      = errors.New("message type is not valid")
+	errMessageIsNotHandshake    = errors.New("failed to convert message to Handshake")
+	errMissingHandshakeMutex    = errors.New("outboundHandshakeMutex does not exist")
+	errInvalidHandshakeForPeer  = errors.New("peer previously sent invalid handshake")
+	errHandshakeTimeout         = errors.New("handshake timeout reached")
+	errUnsupportedTransport     = errors.New("unsupported transport in listen address")
+	errUnknownRateLimitProtocol = errors.New("unknown protocol in bandwidth limits")
+	errQUICNotSupported         = errors.New("QUIC transport is not enabled, build with Go 1.17 and the quic tag")
+	errQUICPrivateNetwork       = errors.New("QUIC transport cannot be used in a private network")
+
+	errMessageTooLarge   = errors.New("message size greater than allocated message buffer")
+	errTooManyRequests   = errors.New("too many requests in flight to peer")
+	errEmptyResponse     = errors.New("received empty response")
+	errEmptyLightRequest = errors.New("light request without request data")
+
+	errInvalidAuthorityRecord          = errors.New("invalid authority record")
+	errInvalidAuthorityRecordSignature = errors.New("invalid authority record signature")
+	errAuthorityRecordKeyMismatch      = errors.New("authority record does not match its key")
 )
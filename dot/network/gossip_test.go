@@ -81,7 +81,7 @@ func TestGossip(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	_, err = nodeA.host.send(addrInfoB.ID, "", testBlockAnnounceMessage)
+	_, err = nodeA.host.send(addrInfoB.ID, testBlockAnnounceMessage, "")
 	require.NoError(t, err)
 
 	time.Sleep(TestMessageTimeout)
@@ -5,6 +5,7 @@ package network
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"path"
@@ -12,15 +13,18 @@ import (
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/chyeh/pubip"
 	"github.com/dgraph-io/ristretto"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	badger "github.com/ipfs/go-ds-badger2"
 	"github.com/libp2p/go-libp2p"
 	libp2phost "github.com/libp2p/go-libp2p-core/host"
-	"github.com/libp2p/go-libp2p-core/metrics"
 	libp2pnetwork "github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/pnet"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
 	ma "github.com/multiformats/go-multiaddr"
@@ -48,44 +52,17 @@ type host struct {
 	bootnodes       []peer.AddrInfo
 	persistentPeers []peer.AddrInfo
 	protocolID      protocol.ID
+	chainProtocolID protocol.ID
 	cm              *ConnManager
-	ds              *badger.Datastore
+	ds              datastore.Batching
 	messageCache    *messageCache
-	bwc             *metrics.BandwidthCounter
+	bwm             *bandwidthManager
+	bans            *banList
+	pnetID          string // fingerprint of the swarm key, empty unless we are in a private network
 	closeSync       sync.Once
 }
 
 func newHost(ctx context.Context, cfg *Config) (*host, error) {
-	// create multiaddress (without p2p identity)
-	addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", cfg.Port))
-	if err != nil {
-		return nil, err
-	}
-
-	var externalAddr ma.Multiaddr
-	if cfg.PublicIP != "" {
-		ip := net.ParseIP(cfg.PublicIP)
-		if ip == nil {
-			return nil, fmt.Errorf("invalid public ip: %s", cfg.PublicIP)
-		}
-		logger.Debugf("using config PublicIP: %s", ip)
-		externalAddr, err = ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", ip, cfg.Port))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		ip, err := pubip.Get()
-		if err != nil {
-			logger.Errorf("failed to get public IP error: %v", err)
-		} else {
-			logger.Debugf("got public IP", "IP", ip)
-			externalAddr, err = ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", ip, cfg.Port))
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
 	// format bootnodes
 	bns, err := stringsToAddrInfos(cfg.Bootnodes)
 	if err != nil {
@@ -116,56 +93,57 @@ func newHost(ctx context.Context, cfg *Config) (*host, error) {
 
 	for _, pp := range pps {
 		cm.persistentPeers.Store(pp.ID, struct{}{})
+		cm.TagPeer(pp.ID, reservedTag, reservedTagValue)
+	}
+
+	for _, bn := range bns {
+		cm.TagPeer(bn.ID, bootnodeTag, bootnodeTagValue)
 	}
 
 	// format protocol id
 	pid := protocol.ID(cfg.ProtocolID)
+	chainPid := chainProtocolID(cfg.BlockState.GenesisHash(), cfg.ForkID)
 
-	ds, err := badger.NewDatastore(path.Join(cfg.BasePath, "libp2p-datastore"), &badger.DefaultOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	privateIPs := ma.NewFilters()
-	for _, cidr := range privateCIDRs {
-		_, ipnet, err := net.ParseCIDR(cidr)
+	var ds datastore.Batching
+	if cfg.NewHost != nil {
+		// hosts created by the constructor run on an in-memory network, so their data is kept in memory too
+		ds = dssync.MutexWrap(datastore.NewMapDatastore())
+	} else {
+		ds, err = badger.NewDatastore(path.Join(cfg.BasePath, "libp2p-datastore"), &badger.DefaultOptions)
 		if err != nil {
 			return nil, err
 		}
-		privateIPs.AddFilter(*ipnet, ma.ActionDeny)
 	}
 
-	ps, err := pstoreds.NewPeerstore(ctx, ds, pstoreds.DefaultOpts())
+	bans, err := newBanList(ds)
 	if err != nil {
 		return nil, err
 	}
 
-	// set libp2p host options
-	opts := []libp2p.Option{
-		libp2p.ListenAddrs(addr),
-		libp2p.DisableRelay(),
-		libp2p.Identity(cfg.privateKey),
-		libp2p.NATPortMap(),
-		libp2p.Peerstore(ps),
-		libp2p.ConnectionManager(cm),
-		libp2p.AddrsFactory(func(as []ma.Multiaddr) []ma.Multiaddr {
-			addrs := []ma.Multiaddr{}
-			for _, addr := range as {
-				if !privateIPs.AddrBlocked(addr) {
-					addrs = append(addrs, addr)
-				}
-			}
-			if externalAddr == nil {
-				return addrs
-			}
-			return append(addrs, externalAddr)
-		}),
+	var (
+		psk    pnet.PSK
+		pnetID string
+	)
+	if cfg.SwarmKey != "" {
+		psk, err = loadSwarmKey(cfg.SwarmKey)
+		if err != nil {
+			return nil, err
+		}
+		pnetID = pskFingerprint(psk)
+		logger.Infof("running in private network %s", pnetID)
 	}
 
-	// create libp2p host instance
-	h, err := libp2p.New(ctx, opts...)
-	if err != nil {
-		return nil, err
+	var h libp2phost.Host
+	if cfg.NewHost != nil {
+		h, err = cfg.NewHost(cfg.privateKey, cm, bans)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		h, err = newLibp2pHost(ctx, cfg, cm, bans, ds, psk)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	cacheSize := 64 << 20 // 64 MB
@@ -182,7 +160,11 @@ func newHost(ctx context.Context, cfg *Config) (*host, error) {
 		return nil, err
 	}
 
-	bwc := metrics.NewBandwidthCounter()
+	bwm, err := newBandwidthManager(ctx, cfg.BandwidthLimits)
+	if err != nil {
+		return nil, err
+	}
+
 	discovery := newDiscovery(ctx, h, bns, ds, pid, cfg.MinPeers, cfg.MaxPeers, cm.peerSetHandler)
 
 	host := &host{
@@ -191,17 +173,111 @@ func newHost(ctx context.Context, cfg *Config) (*host, error) {
 		discovery:       discovery,
 		bootnodes:       bns,
 		protocolID:      pid,
+		chainProtocolID: chainPid,
 		cm:              cm,
 		ds:              ds,
 		persistentPeers: pps,
 		messageCache:    msgCache,
-		bwc:             bwc,
+		bwm:             bwm,
+		bans:            bans,
+		pnetID:          pnetID,
 	}
 
 	cm.host = host
 	return host, nil
 }
 
+// newLibp2pHost creates the libp2p host listening on the configured addresses.
+// If a pre-shared key is given, the host only accepts connections from nodes that have the same key.
+func newLibp2pHost(ctx context.Context, cfg *Config, cm *ConnManager, bans *banList,
+	ds datastore.Batching, psk pnet.PSK) (libp2phost.Host, error) {
+	// create listen multiaddresses (without p2p identity)
+	listenAddrs, err := parseListenAddrs(cfg.ListenAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if psk != nil {
+		for _, addr := range listenAddrs {
+			// QUIC has its own encryption that libp2p doesn't protect with the key
+			if isQUICAddr(addr) {
+				return nil, errQUICPrivateNetwork
+			}
+		}
+	}
+
+	transportOpts, err := transportOptions(listenAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var externalAddrs []ma.Multiaddr
+	if cfg.PublicIP != "" {
+		ip := net.ParseIP(cfg.PublicIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid public ip: %s", cfg.PublicIP)
+		}
+		logger.Debugf("using config PublicIP: %s", ip)
+		externalAddrs, err = publicAddrs(listenAddrs, ip)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ip, err := pubip.Get()
+		if err != nil {
+			logger.Errorf("failed to get public IP error: %v", err)
+		} else {
+			logger.Debugf("got public IP", "IP", ip)
+			externalAddrs, err = publicAddrs(listenAddrs, ip)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	privateIPs := ma.NewFilters()
+	for _, cidr := range privateCIDRs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		privateIPs.AddFilter(*ipnet, ma.ActionDeny)
+	}
+
+	ps, err := pstoreds.NewPeerstore(ctx, ds, pstoreds.DefaultOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	// set libp2p host options
+	opts := []libp2p.Option{
+		libp2p.ListenAddrs(listenAddrs...),
+		libp2p.DisableRelay(),
+		libp2p.Identity(cfg.privateKey),
+		libp2p.NATPortMap(),
+		libp2p.Peerstore(ps),
+		libp2p.ConnectionManager(cm),
+		libp2p.ConnectionGater(bans),
+		libp2p.AddrsFactory(func(as []ma.Multiaddr) []ma.Multiaddr {
+			addrs := []ma.Multiaddr{}
+			for _, addr := range as {
+				if !privateIPs.AddrBlocked(addr) {
+					addrs = append(addrs, addr)
+				}
+			}
+			return append(addrs, externalAddrs...)
+		}),
+	}
+	opts = append(opts, transportOpts...)
+
+	if psk != nil {
+		opts = append(opts, libp2p.PrivateNetwork(psk))
+	}
+
+	// create libp2p host instance
+	return libp2p.New(ctx, opts...)
+}
+
 // close closes host services and the libp2p host (host services first)
 func (h *host) close() error {
 	// close DHT service
@@ -234,11 +310,38 @@ func (h *host) close() error {
 	return nil
 }
 
+// chainProtocolID returns the prefix of the protocol IDs based on the genesis hash of the chain,
+// ie. /<genesis hash> or /<genesis hash>/<fork id> if the chain has a fork ID.
+func chainProtocolID(genesisHash common.Hash, forkID string) protocol.ID {
+	pid := "/" + hex.EncodeToString(genesisHash[:])
+	if forkID != "" {
+		pid += "/" + forkID
+	}
+
+	return protocol.ID(pid)
+}
+
+// protocolIDs returns the IDs of the given sub-protocol in order of preference, the genesis hash
+// based ID followed by the legacy ID based on the protocol ID of the chain.
+func (h *host) protocolIDs(sub protocol.ID) []protocol.ID {
+	return []protocol.ID{
+		h.chainProtocolID + sub,
+		h.protocolID + sub,
+	}
+}
+
 // registerStreamHandler registers the stream handler for the given protocol id.
 func (h *host) registerStreamHandler(pid protocol.ID, handler func(libp2pnetwork.Stream)) {
 	h.h.SetStreamHandler(pid, handler)
 }
 
+// registerStreamHandlers registers the stream handler for each of the given protocol ids.
+func (h *host) registerStreamHandlers(pids []protocol.ID, handler func(libp2pnetwork.Stream)) {
+	for _, pid := range pids {
+		h.registerStreamHandler(pid, handler)
+	}
+}
+
 // connect connects the host to a specific peer address
 func (h *host) connect(p peer.AddrInfo) (err error) {
 	h.h.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.PermanentAddrTTL)
@@ -263,15 +366,16 @@ func (h *host) bootstrap() {
 }
 
 // send creates a new outbound stream with the given peer and writes the message. It also returns
-// the newly created stream.
-func (h *host) send(p peer.ID, pid protocol.ID, msg Message) (libp2pnetwork.Stream, error) {
+// the newly created stream. The stream uses the first of the given protocol ids supported by the peer.
+func (h *host) send(p peer.ID, msg Message, pids ...protocol.ID) (libp2pnetwork.Stream, error) {
 	// open outbound stream with host protocol id
-	stream, err := h.h.NewStream(h.ctx, p, pid)
+	stream, err := h.h.NewStream(h.ctx, p, pids...)
 	if err != nil {
-		logger.Tracef("failed to open new stream with peer %s using protocol %s: %s", p, pid, err)
+		logger.Tracef("failed to open new stream with peer %s using protocols %v: %s", p, pids, err)
 		return nil, err
 	}
 
+	pid := stream.Protocol()
 	logger.Tracef(
 		"Opened stream with host %s, peer %s and protocol %s",
 		h.id(), p, pid)
@@ -298,16 +402,38 @@ func (h *host) writeToStream(s libp2pnetwork.Stream, msg Message) error {
 	lenBytes := uint64ToLEB128(msgLen)
 	encMsg = append(lenBytes, encMsg...)
 
+	if err = h.bwm.waitOutbound(s.Protocol(), len(encMsg)); err != nil {
+		return err
+	}
+
 	sent, err := s.Write(encMsg)
 	if err != nil {
 		return err
 	}
 
-	h.bwc.LogSentMessage(int64(sent))
+	h.bwm.logSent(s.Protocol(), s.Conn().RemotePeer(), sent)
+	recordMessage(s.Protocol(), false, sent)
 
 	return nil
 }
 
+// readStream reads a message from the stream into the buffer, accounting for it and applying
+// the inbound bandwidth limits
+func (h *host) readStream(s libp2pnetwork.Stream, buf []byte) (int, error) {
+	n, err := readStream(s, buf)
+	if err != nil {
+		return n, err
+	}
+
+	recordMessage(s.Protocol(), true, n)
+
+	if err = h.bwm.logRecv(s.Protocol(), s.Conn().RemotePeer(), n); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
 // id returns the host id
 func (h *host) id() peer.ID {
 	return h.h.ID()
@@ -331,6 +457,7 @@ func (h *host) addReservedPeers(addrs ...string) error {
 			return err
 		}
 		h.h.Peerstore().AddAddrs(addrInfo.ID, addrInfo.Addrs, peerstore.PermanentAddrTTL)
+		h.cm.TagPeer(addrInfo.ID, reservedTag, reservedTagValue)
 		h.cm.peerSetHandler.AddReservedPeer(0, addrInfo.ID)
 	}
 
@@ -345,16 +472,39 @@ func (h *host) removeReservedPeers(ids ...string) error {
 			return err
 		}
 		h.cm.peerSetHandler.RemoveReservedPeer(0, peerID)
-		h.h.ConnManager().Unprotect(peerID, "")
+		h.cm.UntagPeer(peerID, reservedTag)
+		h.h.ConnManager().Unprotect(peerID, reservedTag)
 	}
 
 	return nil
 }
 
-// supportsProtocol checks if the protocol is supported by peerID
+// reportPeer reports the reputation change to the peerset. If the change makes the reputation of the peer
+// drop below the peerset banned threshold, the peerset sends a Ban message and the peer is banned.
+func (h *host) reportPeer(change peerset.ReputationChange, p peer.ID) {
+	h.cm.peerSetHandler.ReportPeer(change, p)
+}
+
+// banPeer bans the given peer for the given duration and closes any connection with it.
+func (h *host) banPeer(p peer.ID, reason string, duration time.Duration) error {
+	if err := h.bans.ban(p, reason, duration); err != nil {
+		return err
+	}
+
+	// TODO: currently we only have one set so setID is 0, change this once we have more set in peerSet.
+	h.cm.peerSetHandler.DisconnectPeer(0, p)
+	return h.closePeer(p)
+}
+
+// supportsProtocol checks if any of the protocols is supported by peerID
 // returns an error if could not get peer protocols
-func (h *host) supportsProtocol(peerID peer.ID, protocol protocol.ID) (bool, error) {
-	peerProtocols, err := h.h.Peerstore().SupportsProtocols(peerID, string(protocol))
+func (h *host) supportsProtocol(peerID peer.ID, protocols ...protocol.ID) (bool, error) {
+	pids := make([]string, len(protocols))
+	for i, pid := range protocols {
+		pids[i] = string(pid)
+	}
+
+	peerProtocols, err := h.h.Peerstore().SupportsProtocols(peerID, pids...)
 	if err != nil {
 		return false, err
 	}
@@ -399,17 +549,27 @@ func (h *host) closePeer(peer peer.ID) error {
 	return h.h.Network().ClosePeer(peer)
 }
 
-func (h *host) closeProtocolStream(pID protocol.ID, p peer.ID) {
+func (h *host) closeProtocolStream(pIDs []protocol.ID, p peer.ID) {
 	connToPeer := h.h.Network().ConnsToPeer(p)
 	for _, c := range connToPeer {
 		for _, st := range c.GetStreams() {
-			if st.Protocol() != pID {
+			if !containsProtocol(pIDs, st.Protocol()) {
 				continue
 			}
 			err := st.Close()
 			if err != nil {
-				logger.Tracef("Failed to close stream for protocol %s: %s", pID, err)
+				logger.Tracef("Failed to close stream for protocol %s: %s", st.Protocol(), err)
 			}
 		}
 	}
 }
+
+func containsProtocol(pids []protocol.ID, pid protocol.ID) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+
+	return false
+}
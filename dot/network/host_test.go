@@ -218,7 +218,7 @@ func TestSend(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	_, err = nodeA.host.send(addrInfoB.ID, nodeB.host.protocolID, testBlockRequestMessage)
+	_, err = nodeA.host.send(addrInfoB.ID, testBlockRequestMessage, nodeB.host.protocolID)
 	require.NoError(t, err)
 
 	time.Sleep(TestMessageTimeout)
@@ -229,6 +229,88 @@ func TestSend(t *testing.T) {
 	require.Equal(t, testBlockRequestMessage, msg[0])
 }
 
+func TestChainProtocolID(t *testing.T) {
+	genesisHash := common.MustHexToHash("0x91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3")
+
+	tests := []struct {
+		forkID   string
+		expected protocol.ID
+	}{
+		{
+			expected: "/91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3",
+		},
+		{
+			forkID:   "fork1",
+			expected: "/91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3/fork1",
+		},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, chainProtocolID(genesisHash, test.forkID))
+	}
+}
+
+// test host send method negotiates the legacy protocol id with peers that don't support the genesis based one
+func TestSend_ProtocolFallback(t *testing.T) {
+	configA := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeA"),
+		Port:        7001,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+
+	nodeA := createTestService(t, configA)
+	nodeA.noGossip = true
+
+	configB := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeB"),
+		Port:        7002,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+
+	nodeB := createTestService(t, configB)
+	nodeB.noGossip = true
+
+	const testSubProtocol protocol.ID = "/test/1"
+	pids := nodeA.host.protocolIDs(testSubProtocol)
+	require.Equal(t, []protocol.ID{
+		nodeA.host.chainProtocolID + testSubProtocol,
+		nodeA.host.protocolID + testSubProtocol,
+	}, pids)
+
+	// nodeB only supports the legacy protocol id
+	handler := newTestStreamHandler(testBlockRequestMessageDecoder)
+	nodeB.host.registerStreamHandler(nodeB.host.protocolID+testSubProtocol, handler.handleStream)
+
+	addrInfoB := nodeB.host.addrInfo()
+	err := nodeA.host.connect(addrInfoB)
+	if failedToDial(err) {
+		time.Sleep(TestBackoffTimeout)
+		err = nodeA.host.connect(addrInfoB)
+	}
+	require.NoError(t, err)
+
+	stream, err := nodeA.host.send(addrInfoB.ID, testBlockRequestMessage, pids...)
+	require.NoError(t, err)
+	require.Equal(t, pids[1], stream.Protocol())
+
+	// once nodeB supports both, the genesis based protocol id is preferred
+	nodeB.host.registerStreamHandlers(pids, handler.handleStream)
+	require.Eventually(t, func() bool {
+		// wait for nodeB to advertise the new protocol to nodeA
+		supported, err := nodeA.host.supportsProtocol(addrInfoB.ID, pids[0])
+		return err == nil && supported
+	}, TestMessageTimeout*10, TestMessageTimeout/10)
+
+	stream, err = nodeA.host.send(addrInfoB.ID, testBlockRequestMessage, pids...)
+	require.NoError(t, err)
+	require.Equal(t, pids[0], stream.Protocol())
+
+	time.Sleep(TestMessageTimeout)
+	require.Len(t, handler.messages[nodeA.host.id()], 2)
+}
+
 // test host send method with existing stream
 func TestExistingStream(t *testing.T) {
 	basePathA := utils.NewTestBasePath(t, "nodeA")
@@ -268,7 +350,7 @@ func TestExistingStream(t *testing.T) {
 	require.NoError(t, err)
 
 	// node A opens the stream to send the first message
-	stream, err := nodeA.host.send(addrInfoB.ID, nodeB.host.protocolID, testBlockRequestMessage)
+	stream, err := nodeA.host.send(addrInfoB.ID, testBlockRequestMessage, nodeB.host.protocolID)
 	require.NoError(t, err)
 
 	time.Sleep(TestMessageTimeout)
@@ -280,7 +362,7 @@ func TestExistingStream(t *testing.T) {
 	require.NotNil(t, handlerB.messages[nodeA.host.id()], "node B timeout waiting for message from node A")
 
 	// node B opens the stream to send the first message
-	stream, err = nodeB.host.send(addrInfoA.ID, nodeB.host.protocolID, testBlockRequestMessage)
+	stream, err = nodeB.host.send(addrInfoA.ID, testBlockRequestMessage, nodeB.host.protocolID)
 	require.NoError(t, err)
 
 	time.Sleep(TestMessageTimeout)
@@ -336,7 +418,7 @@ func TestStreamCloseMetadataCleanup(t *testing.T) {
 	}
 
 	// node A opens the stream to send the first message
-	_, err = nodeA.host.send(nodeB.host.id(), nodeB.host.protocolID+blockAnnounceID, testHandshake)
+	_, err = nodeA.host.send(nodeB.host.id(), testHandshake, nodeB.host.protocolID+blockAnnounceID)
 	require.NoError(t, err)
 
 	info := nodeA.notificationsProtocols[BlockAnnounceMsgType]
@@ -539,7 +621,7 @@ func TestStreamCloseEOF(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	stream, err := nodeA.host.send(addrInfoB.ID, nodeB.host.protocolID, testBlockRequestMessage)
+	stream, err := nodeA.host.send(addrInfoB.ID, testBlockRequestMessage, nodeB.host.protocolID)
 	require.NoError(t, err)
 	require.False(t, handler.exit)
 
@@ -638,11 +720,13 @@ func TestBannedPeer(t *testing.T) {
 
 	require.Equal(t, 0, nodeA.host.peerCount())
 	require.Equal(t, 0, nodeB.host.peerCount())
+	require.True(t, nodeA.host.bans.isBanned(addrInfoB.ID))
 
+	// the ban outlasts the peerset reputation recovering above the banned threshold
 	time.Sleep(3 * time.Second)
 
-	require.Equal(t, 1, nodeA.host.peerCount())
-	require.Equal(t, 1, nodeB.host.peerCount())
+	require.Equal(t, 0, nodeA.host.peerCount())
+	require.Equal(t, 0, nodeB.host.peerCount())
 }
 
 // Test to check reputation updated by peer set manager
@@ -14,12 +14,15 @@ func (s *Service) readStream(stream libp2pnetwork.Stream, decoder messageDecoder
 	defer s.resetInboundStream(stream)
 	s.streamManager.logNewStream(stream)
 
+	recordSubstreamOpened(stream.Protocol(), true)
+	defer recordSubstreamClosed(stream.Protocol(), true)
+
 	peer := stream.Conn().RemotePeer()
 	msgBytes := s.bufPool.get()
 	defer s.bufPool.put(msgBytes)
 
 	for {
-		n, err := readStream(stream, msgBytes[:])
+		n, err := s.host.readStream(stream, msgBytes[:])
 		if err != nil {
 			logger.Tracef(
 				"failed to read from stream id %s of peer %s using protocol %s: %s",
@@ -33,6 +36,7 @@ func (s *Service) readStream(stream libp2pnetwork.Stream, decoder messageDecoder
 		// stream should always be inbound if it passes through service.readStream
 		msg, err := decoder(msgBytes[:n], peer, isInbound(stream))
 		if err != nil {
+			recordDecodeFailure(stream.Protocol(), true)
 			logger.Tracef("failed to decode message from stream id %s using protocol %s: %s",
 				stream.ID(), stream.Protocol(), err)
 			continue
@@ -46,8 +50,6 @@ func (s *Service) readStream(stream libp2pnetwork.Stream, decoder messageDecoder
 			logger.Tracef("failed to handle message %s from stream id %s: %s", msg, stream.ID(), err)
 			return
 		}
-
-		s.host.bwc.LogRecvMessage(int64(n))
 	}
 }
 
@@ -59,7 +61,7 @@ func (s *Service) resetInboundStream(stream libp2pnetwork.Stream) {
 	defer s.notificationsMu.Unlock()
 
 	for _, prtl := range s.notificationsProtocols {
-		if prtl.protocolID != protocolID {
+		if !containsProtocol(prtl.protocolIDs(), protocolID) {
 			continue
 		}
 
@@ -5,42 +5,60 @@ package network
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 
-	libp2pnetwork "github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 )
 
-// handleLightStream handles streams with the <protocol-id>/light/2 protocol ID
-func (s *Service) handleLightStream(stream libp2pnetwork.Stream) {
-	s.readStream(stream, s.decodeLightMessage, s.handleLightMsg)
-}
+var (
+	maxLightRequestSize  uint64 = 1024 * 1024     // 1mb
+	maxLightResponseSize uint64 = 1024 * 1024 * 4 // 4mb
+	lightRequestTimeout         = time.Second * 15
+)
 
-func (s *Service) decodeLightMessage(in []byte, peer peer.ID, _ bool) (Message, error) {
-	s.lightRequestMu.RLock()
-	defer s.lightRequestMu.RUnlock()
+const (
+	// maxLightRequestsPerPeer is the maximum number of light requests awaiting a response from a single peer
+	maxLightRequestsPerPeer = 2
+	// maxInboundLightRequests is the maximum number of light requests we are answering at once
+	maxInboundLightRequests = 16
+)
 
-	// check if we are the requester
-	if _, ok := s.lightRequest[peer]; ok {
-		// if we are, decode the bytes as a LightResponse
-		return newLightResponseFromBytes(in)
+func (s *Service) newLightProtocol() *requestResponseProtocol {
+	return newRequestResponseProtocol(s.host, requestResponseConfig{
+		protocolIDs:        s.host.protocolIDs(lightID),
+		maxRequestSize:     maxLightRequestSize,
+		maxResponseSize:    maxLightResponseSize,
+		requestTimeout:     lightRequestTimeout,
+		maxInFlightPerPeer: maxLightRequestsPerPeer,
+		maxInboundQueue:    maxInboundLightRequests,
+		decodeRequest:      decodeLightRequest,
+		handleRequest:      s.handleLightRequest,
+	})
+}
+
+// DoLightRequest sends a light request to the given peer and returns its response.
+func (s *Service) DoLightRequest(to peer.ID, req *LightRequest) (*LightResponse, error) {
+	resp := NewLightResponse()
+	if err := s.lightProtocol.do(s.ctx, to, req, resp); err != nil {
+		return nil, err
 	}
 
-	// otherwise, decode bytes as LightRequest
-	return newLightRequestFromBytes(in)
+	return resp, nil
 }
 
-func (s *Service) handleLightMsg(stream libp2pnetwork.Stream, msg Message) (err error) {
-	defer func() {
-		_ = stream.Close()
-	}()
+func decodeLightRequest(in []byte) (Message, error) {
+	return newLightRequestFromBytes(in)
+}
 
+// handleLightRequest handles inbound requests of the <protocol-id>/light/2 protocol
+func (*Service) handleLightRequest(_ peer.ID, msg Message) (_ Message, err error) {
 	lr, ok := msg.(*LightRequest)
 	if !ok {
-		return nil
+		return nil, errMessageTypeNotValid
 	}
 
 	resp := NewLightResponse()
@@ -57,21 +75,16 @@ func (s *Service) handleLightMsg(stream libp2pnetwork.Stream, msg Message) (err
 		resp.RemoteReadResponse, err = remoteReadChildResp(lr.RemoteReadChildRequest)
 	default:
 		logger.Warn("ignoring LightRequest without request data")
-		return nil
+		return nil, errEmptyLightRequest
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// TODO(arijit): Remove once we implement the internal APIs. Added to increase code coverage. (#1856)
 	logger.Debugf("LightResponse message: %s", resp)
-
-	err = s.host.writeToStream(stream, resp)
-	if err != nil {
-		logger.Warnf("failed to send LightResponse message to peer %s: %s", stream.Conn().RemotePeer(), err)
-	}
-	return err
+	return resp, nil
 }
 
 // Pair is a pair of arbitrary bytes.
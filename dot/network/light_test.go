@@ -48,20 +48,13 @@ func TestEncodeLightResponse(t *testing.T) {
 	require.Equal(t, testLightResponse, testLightResponse2)
 }
 
-func TestDecodeLightMessage(t *testing.T) {
-	s := &Service{
-		lightRequest: make(map[peer.ID]struct{}),
-	}
-
-	testPeer := peer.ID("noot")
-
+func TestDecodeLightRequest(t *testing.T) {
 	testLightRequest := NewLightRequest()
-	testLightResponse := NewLightResponse()
 
 	reqEnc, err := testLightRequest.Encode()
 	require.NoError(t, err)
 
-	msg, err := s.decodeLightMessage(reqEnc, testPeer, true)
+	msg, err := decodeLightRequest(reqEnc)
 	require.NoError(t, err)
 
 	req, ok := msg.(*LightRequest)
@@ -69,22 +62,36 @@ func TestDecodeLightMessage(t *testing.T) {
 	resEnc, err := req.Encode()
 	require.NoError(t, err)
 	require.Equal(t, reqEnc, resEnc)
+}
 
-	s.lightRequest[testPeer] = struct{}{}
+func TestHandleLightRequest(t *testing.T) {
+	s := &Service{}
+	testPeer := peer.ID("noot")
 
-	respEnc, err := testLightResponse.Encode()
-	require.NoError(t, err)
+	// Testing empty request
+	_, err := s.handleLightRequest(testPeer, &LightRequest{})
+	require.ErrorIs(t, err, errEmptyLightRequest)
+
+	// Testing wrong message type
+	_, err = s.handleLightRequest(testPeer, NewLightResponse())
+	require.ErrorIs(t, err, errMessageTypeNotValid)
+
+	tests := []*LightRequest{
+		{RemoteCallRequest: &RemoteCallRequest{}},
+		{RemoteHeaderRequest: &RemoteHeaderRequest{}},
+		{RemoteChangesRequest: &RemoteChangesRequest{}},
+		{RemoteReadRequest: &RemoteReadRequest{}},
+		{RemoteReadChildRequest: &RemoteReadChildRequest{}},
+	}
 
-	msg, err = s.decodeLightMessage(respEnc, testPeer, true)
-	require.NoError(t, err)
-	resp, ok := msg.(*LightResponse)
-	require.True(t, ok)
-	resEnc, err = resp.Encode()
-	require.NoError(t, err)
-	require.Equal(t, respEnc, resEnc)
+	for _, msg := range tests {
+		resp, err := s.handleLightRequest(testPeer, msg)
+		require.NoError(t, err, msg.String())
+		require.IsType(t, &LightResponse{}, resp)
+	}
 }
 
-func TestHandleLightMessage_Response(t *testing.T) {
+func TestDoLightRequest(t *testing.T) {
 	config := &Config{
 		BasePath:    utils.NewTestBasePath(t, "nodeA"),
 		Port:        7001,
@@ -110,48 +117,19 @@ func TestHandleLightMessage_Response(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	stream, err := s.host.h.NewStream(s.ctx, b.host.id(), s.host.protocolID+lightID)
-	require.NoError(t, err)
-
-	// Testing empty request
-	msg := &LightRequest{}
-	err = s.handleLightMsg(stream, msg)
-	require.NoError(t, err)
-
-	expectedErr := "failed to find any peer in table"
-
-	// Testing remoteCallResp()
-	msg = &LightRequest{
-		RemoteCallRequest: &RemoteCallRequest{},
-	}
-	err = s.handleLightMsg(stream, msg)
-	require.Error(t, err, expectedErr, msg.String())
-
-	// Testing remoteHeaderResp()
-	msg = &LightRequest{
-		RemoteHeaderRequest: &RemoteHeaderRequest{},
+	req := NewLightRequest()
+	req.RemoteHeaderRequest = &RemoteHeaderRequest{
+		Block: []byte{1},
 	}
-	err = s.handleLightMsg(stream, msg)
-	require.Error(t, err, expectedErr, msg.String())
 
-	// Testing remoteChangeResp()
-	msg = &LightRequest{
-		RemoteChangesRequest: &RemoteChangesRequest{},
-	}
-	err = s.handleLightMsg(stream, msg)
-	require.Error(t, err, expectedErr, msg.String())
-
-	// Testing remoteReadResp()
-	msg = &LightRequest{
-		RemoteReadRequest: &RemoteReadRequest{},
-	}
-	err = s.handleLightMsg(stream, msg)
-	require.Error(t, err, expectedErr, msg.String())
+	expected, err := b.handleLightRequest(s.host.id(), req)
+	require.NoError(t, err)
+	expectedEnc, err := expected.Encode()
+	require.NoError(t, err)
 
-	// Testing remoteReadChildResp()
-	msg = &LightRequest{
-		RemoteReadChildRequest: &RemoteReadChildRequest{},
-	}
-	err = s.handleLightMsg(stream, msg)
-	require.Error(t, err, expectedErr, msg.String())
+	resp, err := s.DoLightRequest(b.host.id(), req)
+	require.NoError(t, err)
+	respEnc, err := resp.Encode()
+	require.NoError(t, err)
+	require.Equal(t, expectedEnc, respEnc)
 }
@@ -44,12 +44,18 @@ func (m *mdns) start() {
 		"Starting mDNS discovery service with host %s, period %s and protocol %s...",
 		m.host.id(), MDNSPeriod, m.host.protocolID)
 
+	// nodes of a private network only look for each other, as they can't connect to other nodes
+	serviceTag := string(m.host.protocolID)
+	if m.host.pnetID != "" {
+		serviceTag += "/pnet/" + m.host.pnetID
+	}
+
 	// create and start service
 	mdns, err := libp2pdiscovery.NewMdnsService(
 		m.host.ctx,
 		m.host.h,
 		MDNSPeriod,
-		string(m.host.protocolID),
+		serviceTag,
 	)
 	if err != nil {
 		m.logger.Errorf("Failed to start mDNS discovery service: %s", err)
@@ -0,0 +1,91 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/protocol"
+
+	gssmrmetrics "github.com/ChainSafe/gossamer/dot/metrics"
+)
+
+// directions of the messages and streams, as used in the direction label of the protocol metrics.
+// Messages we receive and streams opened by our peers are inbound, messages we send and streams
+// we open are outbound.
+const (
+	inboundDirection  = "inbound"
+	outboundDirection = "outbound"
+)
+
+// per-protocol metrics, labelled by protocol name (see protocolName) and direction
+var (
+	messagesTotal = gssmrmetrics.NewCounterVec(
+		"network_messages_total",
+		"Number of messages sent and received",
+		"protocol", "direction")
+	bytesTotal = gssmrmetrics.NewCounterVec(
+		"network_bytes_total",
+		"Number of bytes sent and received, including the length prefix of the messages",
+		"protocol", "direction")
+	decodeFailuresTotal = gssmrmetrics.NewCounterVec(
+		"network_decode_failures_total",
+		"Number of received messages that failed to decode, by direction of the stream they were received on",
+		"protocol", "direction")
+	requestDuration = gssmrmetrics.NewHistogramVec(
+		"network_request_duration_seconds",
+		"Time taken to get the response to our requests (outbound) or to answer the requests of our peers (inbound)",
+		[]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20},
+		"protocol", "direction")
+	substreamsOpenedTotal = gssmrmetrics.NewCounterVec(
+		"network_notification_substreams_opened_total",
+		"Number of notifications substreams opened",
+		"protocol", "direction")
+	substreamsClosedTotal = gssmrmetrics.NewCounterVec(
+		"network_notification_substreams_closed_total",
+		"Number of notifications substreams closed",
+		"protocol", "direction")
+	handshakeFailuresTotal = gssmrmetrics.NewCounterVec(
+		"network_handshake_failures_total",
+		"Number of notifications handshakes that failed to be read, decoded or validated",
+		"protocol", "direction")
+)
+
+func direction(inbound bool) string {
+	if inbound {
+		return inboundDirection
+	}
+	return outboundDirection
+}
+
+// recordMessage records a message of n bytes sent or received over the given protocol
+func recordMessage(pid protocol.ID, inbound bool, n int) {
+	labels := []string{protocolName(pid), direction(inbound)}
+	messagesTotal.WithLabelValues(labels...).Inc()
+	bytesTotal.WithLabelValues(labels...).Add(float64(n))
+}
+
+// recordDecodeFailure records a message that failed to decode on a stream of the given protocol
+func recordDecodeFailure(pid protocol.ID, inbound bool) {
+	decodeFailuresTotal.WithLabelValues(protocolName(pid), direction(inbound)).Inc()
+}
+
+// recordRequestDuration records the time elapsed since start to complete a request of the given protocol
+func recordRequestDuration(pid protocol.ID, inbound bool, start time.Time) {
+	requestDuration.WithLabelValues(protocolName(pid), direction(inbound)).Observe(time.Since(start).Seconds())
+}
+
+// recordSubstreamOpened and recordSubstreamClosed record the opening and closing of notifications substreams
+func recordSubstreamOpened(pid protocol.ID, inbound bool) {
+	substreamsOpenedTotal.WithLabelValues(protocolName(pid), direction(inbound)).Inc()
+}
+
+func recordSubstreamClosed(pid protocol.ID, inbound bool) {
+	substreamsClosedTotal.WithLabelValues(protocolName(pid), direction(inbound)).Inc()
+}
+
+// recordHandshakeFailure records a failed handshake on a notifications substream of the given protocol
+func recordHandshakeFailure(pid protocol.ID, inbound bool) {
+	handshakeFailuresTotal.WithLabelValues(protocolName(pid), direction(inbound)).Inc()
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(c *prometheus.CounterVec, labels ...string) float64 {
+	return testutil.ToFloat64(c.WithLabelValues(labels...))
+}
+
+func histogramCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+
+	m := new(dto.Metric)
+	err := h.WithLabelValues(labels...).(prometheus.Metric).Write(m)
+	require.NoError(t, err)
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRequestResponse_Metrics(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	const name = "reqresp" // protocolName(testRequestResponseID)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, req Message) (Message, error) {
+		if string(*req.(*rawMessage)) == "fail" {
+			return nil, errors.New("failed to handle request")
+		}
+		return req, nil
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	sentBefore := counterValue(messagesTotal, name, outboundDirection)
+	receivedBefore := counterValue(messagesTotal, name, inboundDirection)
+	bytesBefore := counterValue(bytesTotal, name, outboundDirection)
+	outboundBefore := histogramCount(t, requestDuration, name, outboundDirection)
+	inboundBefore := histogramCount(t, requestDuration, name, inboundDirection)
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.NoError(t, err)
+
+	// both nodes run in this process, so the request and the response are each counted as sent and received
+	require.Equal(t, sentBefore+2, counterValue(messagesTotal, name, outboundDirection))
+	require.Equal(t, receivedBefore+2, counterValue(messagesTotal, name, inboundDirection))
+	// the messages are prefixed by their length
+	require.Equal(t, bytesBefore+10, counterValue(bytesTotal, name, outboundDirection))
+	require.Equal(t, outboundBefore+1, histogramCount(t, requestDuration, name, outboundDirection))
+	require.Equal(t, inboundBefore+1, histogramCount(t, requestDuration, name, inboundDirection))
+
+	// requests that fail are not timed
+	req = rawMessage("fail")
+	err = client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.Error(t, err)
+	require.Equal(t, outboundBefore+1, histogramCount(t, requestDuration, name, outboundDirection))
+	require.Equal(t, inboundBefore+1, histogramCount(t, requestDuration, name, inboundDirection))
+}
+
+func TestRecordDecodeFailure(t *testing.T) {
+	const pid = "/gossamer/gssmr/0/block-announces/1"
+
+	before := counterValue(decodeFailuresTotal, blockAnnounceProtocolName, inboundDirection)
+	recordDecodeFailure(pid, true)
+	require.Equal(t, before+1, counterValue(decodeFailuresTotal, blockAnnounceProtocolName, inboundDirection))
+}
@@ -8,6 +8,8 @@ import (
 	common "github.com/ChainSafe/gossamer/lib/common"
 	mock "github.com/stretchr/testify/mock"
 
+	runtime "github.com/ChainSafe/gossamer/lib/runtime"
+
 	types "github.com/ChainSafe/gossamer/dot/types"
 )
 
@@ -124,6 +126,29 @@ func (_m *MockBlockState) GetHighestFinalisedHeader() (*types.Header, error) {
 	return r0, r1
 }
 
+// GetRuntime provides a mock function with given fields: hash
+func (_m *MockBlockState) GetRuntime(hash *common.Hash) (runtime.Instance, error) {
+	ret := _m.Called(hash)
+
+	var r0 runtime.Instance
+	if rf, ok := ret.Get(0).(func(*common.Hash) runtime.Instance); ok {
+		r0 = rf(hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(runtime.Instance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*common.Hash) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasBlockBody provides a mock function with given fields: _a0
 func (_m *MockBlockState) HasBlockBody(_a0 common.Hash) (bool, error) {
 	ret := _m.Called(_a0)
@@ -45,6 +45,10 @@ type (
 	// NotificationsMessageBatchHandler is called when a (non-handshake) message is received over a notifications
 	// stream in batch processing mode.
 	NotificationsMessageBatchHandler = func(peer peer.ID, msg NotificationsMessage)
+
+	// GossipFilter is called once before a message is gossiped over a notifications protocol.
+	// It returns the function deciding which peers the message is sent to.
+	GossipFilter = func(msg NotificationsMessage) func(peer peer.ID) bool
 )
 
 // BatchMessage is exported for the mocks of lib/grandpa/mocks/network.go
@@ -61,19 +65,26 @@ type handshakeReader struct {
 }
 
 type notificationsProtocol struct {
+	// protocolID is the preferred ID of the protocol, fallbackProtocolIDs are the other IDs
+	// the protocol is known by, which are negotiated with peers that don't support the preferred one
 	protocolID               protocol.ID
+	fallbackProtocolIDs      []protocol.ID
 	getHandshake             HandshakeGetter
 	handshakeDecoder         HandshakeDecoder
 	handshakeValidator       HandshakeValidator
 	outboundHandshakeMutexes *sync.Map //map[peer.ID]*sync.Mutex
 	inboundHandshakeData     *sync.Map //map[peer.ID]*handshakeData
 	outboundHandshakeData    *sync.Map //map[peer.ID]*handshakeData
+
+	gossipFilterMu sync.RWMutex
+	gossipFilter   GossipFilter
 }
 
-func newNotificationsProtocol(protocolID protocol.ID, handshakeGetter HandshakeGetter,
+func newNotificationsProtocol(protocolIDs []protocol.ID, handshakeGetter HandshakeGetter,
 	handshakeDecoder HandshakeDecoder, handshakeValidator HandshakeValidator) *notificationsProtocol {
 	return &notificationsProtocol{
-		protocolID:               protocolID,
+		protocolID:               protocolIDs[0],
+		fallbackProtocolIDs:      protocolIDs[1:],
 		getHandshake:             handshakeGetter,
 		handshakeValidator:       handshakeValidator,
 		handshakeDecoder:         handshakeDecoder,
@@ -83,6 +94,23 @@ func newNotificationsProtocol(protocolID protocol.ID, handshakeGetter HandshakeG
 	}
 }
 
+// protocolIDs returns all the IDs of the protocol in order of preference
+func (n *notificationsProtocol) protocolIDs() []protocol.ID {
+	return append([]protocol.ID{n.protocolID}, n.fallbackProtocolIDs...)
+}
+
+// gossipFilterFor returns the function deciding which peers the message should be gossiped to
+func (n *notificationsProtocol) gossipFilterFor(msg NotificationsMessage) func(peer.ID) bool {
+	n.gossipFilterMu.RLock()
+	defer n.gossipFilterMu.RUnlock()
+
+	if n.gossipFilter == nil {
+		return func(peer.ID) bool { return true }
+	}
+
+	return n.gossipFilter(msg)
+}
+
 func (n *notificationsProtocol) getInboundHandshakeData(pid peer.ID) (*handshakeData, bool) {
 	var (
 		data interface{}
@@ -191,6 +219,7 @@ func (s *Service) createNotificationsMessageHandler(info *notificationsProtocol,
 
 				err := info.handshakeValidator(peer, hs)
 				if err != nil {
+					recordHandshakeFailure(stream.Protocol(), true)
 					logger.Tracef(
 						"failed to validate handshake from peer %s using protocol %s: %s",
 						peer, info.protocolID, err)
@@ -242,7 +271,7 @@ func (s *Service) createNotificationsMessageHandler(info *notificationsProtocol,
 		}
 
 		// report peer if we get duplicate gossip message.
-		s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+		s.host.reportPeer(peerset.ReputationChange{
 			Value:  peerset.DuplicateGossipValue,
 			Reason: peerset.DuplicateGossipReason,
 		}, peer)
@@ -259,6 +288,7 @@ func closeOutboundStream(info *notificationsProtocol, peerID peer.ID, stream lib
 
 	info.outboundHandshakeData.Delete(peerID)
 	_ = stream.Close()
+	recordSubstreamClosed(stream.Protocol(), false)
 }
 
 func (s *Service) sendData(peer peer.ID, hs Handshake, info *notificationsProtocol, msg NotificationsMessage) {
@@ -267,8 +297,8 @@ func (s *Service) sendData(peer peer.ID, hs Handshake, info *notificationsProtoc
 		return
 	}
 
-	if support, err := s.host.supportsProtocol(peer, info.protocolID); err != nil || !support {
-		s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+	if support, err := s.host.supportsProtocol(peer, info.protocolIDs()...); err != nil || !support {
+		s.host.reportPeer(peerset.ReputationChange{
 			Value:  peerset.BadProtocolValue,
 			Reason: peerset.BadProtocolReason,
 		}, peer)
@@ -312,7 +342,7 @@ func (s *Service) sendData(peer peer.ID, hs Handshake, info *notificationsProtoc
 	}
 
 	logger.Tracef("successfully sent message on protocol %s to peer %s: message=", info.protocolID, peer, msg)
-	s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+	s.host.reportPeer(peerset.ReputationChange{
 		Value:  peerset.GossipSuccessValue,
 		Reason: peerset.GossipSuccessReason,
 	}, peer)
@@ -343,7 +373,7 @@ func (s *Service) sendHandshake(peer peer.ID, hs Handshake, info *notificationsP
 
 	logger.Tracef("sending outbound handshake to peer %s on protocol %s, message: %s",
 		peer, info.protocolID, hs)
-	stream, err := s.host.send(peer, info.protocolID, hs)
+	stream, err := s.host.send(peer, hs, info.protocolIDs()...)
 	if err != nil {
 		logger.Tracef("failed to send message to peer %s: %s", peer, err)
 		// don't need to close the stream here, as it's nil!
@@ -351,18 +381,20 @@ func (s *Service) sendHandshake(peer peer.ID, hs Handshake, info *notificationsP
 	}
 
 	hsData.stream = stream
+	recordSubstreamOpened(stream.Protocol(), false)
 
 	hsTimer := time.NewTimer(handshakeTimeout)
 
 	var resp Handshake
 	select {
 	case <-hsTimer.C:
-		s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+		s.host.reportPeer(peerset.ReputationChange{
 			Value:  peerset.TimeOutValue,
 			Reason: peerset.TimeOutReason,
 		}, peer)
 
 		logger.Tracef("handshake timeout reached for peer %s using protocol %s", peer, info.protocolID)
+		recordHandshakeFailure(stream.Protocol(), false)
 		closeOutboundStream(info, peer, stream)
 		return nil, errHandshakeTimeout
 	case hsResponse := <-s.readHandshake(stream, info.handshakeDecoder):
@@ -372,6 +404,7 @@ func (s *Service) sendHandshake(peer peer.ID, hs Handshake, info *notificationsP
 
 		if hsResponse.err != nil {
 			logger.Tracef("failed to read handshake from peer %s using protocol %s: %s", peer, info.protocolID, err)
+			recordHandshakeFailure(stream.Protocol(), false)
 			closeOutboundStream(info, peer, stream)
 			return nil, hsResponse.err
 		}
@@ -385,6 +418,8 @@ func (s *Service) sendHandshake(peer peer.ID, hs Handshake, info *notificationsP
 		hsData.validated = false
 		hsData.stream = nil
 		_ = stream.Reset()
+		recordHandshakeFailure(stream.Protocol(), false)
+		recordSubstreamClosed(stream.Protocol(), false)
 		info.outboundHandshakeData.Store(peer, hsData)
 		// don't delete handshake data, as we want to store that the handshake for this peer was invalid
 		// and not to exchange messages over this protocol with it
@@ -410,9 +445,10 @@ func (s *Service) broadcastExcluding(info *notificationsProtocol, excluding peer
 		return
 	}
 
+	shouldGossip := info.gossipFilterFor(msg)
 	peers := s.host.peers()
 	for _, peer := range peers {
-		if peer == excluding {
+		if peer == excluding || !shouldGossip(peer) {
 			continue
 		}
 
@@ -430,7 +466,7 @@ func (s *Service) readHandshake(stream libp2pnetwork.Stream, decoder HandshakeDe
 			close(hsC)
 		}()
 
-		tot, err := readStream(stream, msgBytes[:])
+		tot, err := s.host.readStream(stream, msgBytes[:])
 		if err != nil {
 			hsC <- &handshakeReader{hs: nil, err: err}
 			return
@@ -438,7 +474,8 @@ func (s *Service) readHandshake(stream libp2pnetwork.Stream, decoder HandshakeDe
 
 		hs, err := decoder(msgBytes[:tot])
 		if err != nil {
-			s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+			recordDecodeFailure(stream.Protocol(), isInbound(stream))
+			s.host.reportPeer(peerset.ReputationChange{
 				Value:  peerset.BadMessageValue,
 				Reason: peerset.BadMessageReason,
 			}, stream.Conn().RemotePeer())
@@ -0,0 +1,40 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/libp2p/go-libp2p-core/pnet"
+)
+
+// loadSwarmKey reads the pre-shared key of a private network from a swarm key file, in the
+// format used by other libp2p implementations:
+//
+//	/key/swarm/psk/1.0.0/
+//	/base16/
+//	<64 hex characters>
+func loadSwarmKey(fp string) (pnet.PSK, error) {
+	f, err := os.Open(fp) //nolint
+	if err != nil {
+		return nil, fmt.Errorf("failed to open swarm key file: %w", err)
+	}
+	defer f.Close() //nolint
+
+	psk, err := pnet.DecodeV1PSK(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode swarm key: %w", err)
+	}
+
+	return psk, nil
+}
+
+// pskFingerprint returns a short identifier of the private network that doesn't reveal its key
+func pskFingerprint(psk pnet.PSK) string {
+	h := sha256.Sum256(psk)
+	return hex.EncodeToString(h[:8])
+}
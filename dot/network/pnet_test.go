@@ -0,0 +1,127 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/lib/utils"
+)
+
+func writeTestSwarmKey(t *testing.T, dir string, key string) string {
+	t.Helper()
+
+	fp := filepath.Join(dir, "swarm.key")
+	err := os.MkdirAll(dir, os.ModePerm)
+	require.NoError(t, err)
+
+	err = os.WriteFile(fp, []byte("/key/swarm/psk/1.0.0/\n/base16/\n"+key+"\n"), 0600)
+	require.NoError(t, err)
+	return fp
+}
+
+func TestLoadSwarmKey(t *testing.T) {
+	dir := t.TempDir()
+
+	fp := writeTestSwarmKey(t, dir, strings.Repeat("ab", 32))
+	psk, err := loadSwarmKey(fp)
+	require.NoError(t, err)
+	require.Len(t, psk, 32)
+	require.Len(t, pskFingerprint(psk), 16)
+
+	fp = writeTestSwarmKey(t, filepath.Join(dir, "bad"), "nothex")
+	_, err = loadSwarmKey(fp)
+	require.Error(t, err)
+
+	_, err = loadSwarmKey(filepath.Join(dir, "missing.key"))
+	require.Error(t, err)
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+
+	basePathA := utils.NewTestBasePath(t, "nodeA")
+	nodeA := createTestService(t, &Config{
+		BasePath:    basePathA,
+		Port:        7001,
+		NoBootstrap: true,
+		NoMDNS:      true,
+		SwarmKey:    writeTestSwarmKey(t, basePathA, key),
+	})
+	require.NotEmpty(t, nodeA.host.pnetID)
+
+	basePathB := utils.NewTestBasePath(t, "nodeB")
+	nodeB := createTestService(t, &Config{
+		BasePath:    basePathB,
+		Port:        7002,
+		NoBootstrap: true,
+		NoMDNS:      true,
+		SwarmKey:    writeTestSwarmKey(t, basePathB, key),
+	})
+	require.Equal(t, nodeA.host.pnetID, nodeB.host.pnetID)
+
+	// a node without the key
+	nodeC := createTestService(t, &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeC"),
+		Port:        7003,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	})
+
+	// a node with another key
+	basePathD := utils.NewTestBasePath(t, "nodeD")
+	nodeD := createTestService(t, &Config{
+		BasePath:    basePathD,
+		Port:        7004,
+		NoBootstrap: true,
+		NoMDNS:      true,
+		SwarmKey:    writeTestSwarmKey(t, basePathD, strings.Repeat("cd", 32)),
+	})
+	require.NotEqual(t, nodeA.host.pnetID, nodeD.host.pnetID)
+
+	err := nodeA.host.connect(nodeB.host.addrInfo())
+	if failedToDial(err) {
+		time.Sleep(TestBackoffTimeout)
+		err = nodeA.host.connect(nodeB.host.addrInfo())
+	}
+	require.NoError(t, err)
+
+	err = nodeC.host.connect(nodeA.host.addrInfo())
+	require.Error(t, err)
+	err = nodeA.host.connect(nodeC.host.addrInfo())
+	require.Error(t, err)
+	err = nodeD.host.connect(nodeA.host.addrInfo())
+	require.Error(t, err)
+
+	// the nodes of the private network can exchange handshakes, the others can't
+	_, err = nodeA.host.send(nodeB.host.id(), testBlockAnnounceHandshake, nodeA.host.protocolIDs(blockAnnounceID)...)
+	require.NoError(t, err)
+	_, err = nodeC.host.send(nodeA.host.id(), testBlockAnnounceHandshake, nodeC.host.protocolIDs(blockAnnounceID)...)
+	require.Error(t, err)
+
+	require.Equal(t, 1, nodeA.host.peerCount())
+	require.Equal(t, 0, nodeC.host.peerCount())
+	require.Equal(t, 0, nodeD.host.peerCount())
+}
+
+func TestPrivateNetwork_QUIC(t *testing.T) {
+	basePath := utils.NewTestBasePath(t, "node")
+	cfg := &Config{
+		BasePath:    basePath,
+		ListenAddrs: []string{"/ip4/0.0.0.0/udp/7001/quic"},
+		NoBootstrap: true,
+		NoMDNS:      true,
+		SwarmKey:    writeTestSwarmKey(t, basePath, strings.Repeat("ab", 32)),
+		BlockState:  NewMockBlockState(nil),
+	}
+
+	_, err := NewService(cfg)
+	require.ErrorIs(t, err, errQUICPrivateNetwork)
+}
@@ -5,19 +5,23 @@ package network
 
 // sizedBufferPool is a pool of buffers used for reading from streams
 type sizedBufferPool struct {
-	c chan []byte
+	c       chan []byte
+	bufSize int
 }
 
-func newSizedBufferPool(preAllocate, size int) (bp *sizedBufferPool) {
+// newSizedBufferPool creates a pool holding up to size buffers of bufSize bytes,
+// preAllocate of which are allocated upfront.
+func newSizedBufferPool(preAllocate, size, bufSize int) (bp *sizedBufferPool) {
 	bufferCh := make(chan []byte, size)
 
 	for i := 0; i < preAllocate; i++ {
-		buf := make([]byte, maxMessageSize)
+		buf := make([]byte, bufSize)
 		bufferCh <- buf
 	}
 
 	return &sizedBufferPool{
-		c: bufferCh,
+		c:       bufferCh,
+		bufSize: bufSize,
 	}
 }
 
@@ -30,7 +34,7 @@ func (bp *sizedBufferPool) get() (b []byte) {
 		return b
 	default:
 		// create new buffer
-		return make([]byte, maxMessageSize)
+		return make([]byte, bp.bufSize)
 	}
 }
 
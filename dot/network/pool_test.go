@@ -15,7 +15,7 @@ import (
 func Benchmark_sizedBufferPool(b *testing.B) {
 	const preAllocate = 100
 	const poolSize = 200
-	sbp := newSizedBufferPool(preAllocate, poolSize)
+	sbp := newSizedBufferPool(preAllocate, poolSize, maxMessageSize)
 
 	b.RunParallel(func(p *testing.PB) {
 		for p.Next() {
@@ -39,7 +39,7 @@ func Test_sizedBufferPool(t *testing.T) {
 	const poolSize = 2
 	const maxIndex = maxMessageSize - 1
 
-	pool := newSizedBufferPool(preAlloc, poolSize)
+	pool := newSizedBufferPool(preAlloc, poolSize, maxMessageSize)
 
 	first := pool.get() // pre-allocated one
 	first[maxIndex] = 1
@@ -71,7 +71,7 @@ func Test_sizedBufferPool_race(t *testing.T) {
 	const preAlloc = 1
 	const poolSize = 2
 
-	pool := newSizedBufferPool(preAlloc, poolSize)
+	pool := newSizedBufferPool(preAlloc, poolSize, maxMessageSize)
 
 	const parallelism = 4
 
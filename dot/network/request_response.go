@@ -0,0 +1,244 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	libp2pnetwork "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+
+	"github.com/ChainSafe/gossamer/dot/peerset"
+)
+
+// responseBufferPoolSize is the number of response buffers kept around by each request-response protocol
+const responseBufferPoolSize = 8
+
+// requestDecoder decodes an inbound request
+type requestDecoder = func([]byte) (Message, error)
+
+// requestHandler handles an inbound request and returns the response to send back
+type requestHandler = func(from peer.ID, req Message) (Message, error)
+
+// requestResponseConfig is the configuration of a request-response protocol
+type requestResponseConfig struct {
+	// protocolIDs are the IDs of the protocol in order of preference
+	protocolIDs []protocol.ID
+	// maxRequestSize and maxResponseSize are the maximum sizes in bytes of the messages,
+	// larger messages are rejected
+	maxRequestSize  uint64
+	maxResponseSize uint64
+	// requestTimeout is the time allowed for a request to be answered, for both outbound and inbound requests
+	requestTimeout time.Duration
+	// maxInFlightPerPeer is the maximum number of outbound requests awaiting a response from a single peer
+	maxInFlightPerPeer int
+	// maxInboundQueue is the maximum number of inbound requests being handled at once,
+	// further requests are dropped until some are done
+	maxInboundQueue int
+
+	decodeRequest requestDecoder
+	handleRequest requestHandler
+}
+
+// requestResponseProtocol implements a protocol where each request is sent over a new stream,
+// and answered with a single response over that same stream.
+type requestResponseProtocol struct {
+	requestResponseConfig
+	host *host
+
+	requestBufs  *sizedBufferPool
+	responseBufs *sizedBufferPool
+	inboundSlots chan struct{}
+
+	inFlightMu sync.Mutex
+	inFlight   map[peer.ID]int
+}
+
+func newRequestResponseProtocol(h *host, cfg requestResponseConfig) *requestResponseProtocol {
+	return &requestResponseProtocol{
+		requestResponseConfig: cfg,
+		host:                  h,
+		requestBufs:           newSizedBufferPool(0, cfg.maxInboundQueue, int(cfg.maxRequestSize)),
+		responseBufs:          newSizedBufferPool(0, responseBufferPoolSize, int(cfg.maxResponseSize)),
+		inboundSlots:          make(chan struct{}, cfg.maxInboundQueue),
+		inFlight:              make(map[peer.ID]int),
+	}
+}
+
+// register registers the stream handler of the protocol for each of its protocol IDs
+func (p *requestResponseProtocol) register() {
+	p.host.registerStreamHandlers(p.protocolIDs, p.handleStream)
+}
+
+// do sends the request to the given peer and decodes its answer into resp.
+// Peers that send malformed responses, or don't respond in time, are reported.
+func (p *requestResponseProtocol) do(ctx context.Context, to peer.ID, req, resp Message) error {
+	if !p.acquire(to) {
+		return errTooManyRequests
+	}
+	defer p.release(to)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, p.requestTimeout)
+	defer cancel()
+
+	stream, err := p.host.h.NewStream(ctx, to, p.protocolIDs...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	deadline, _ := ctx.Deadline()
+	_ = stream.SetDeadline(deadline)
+
+	if err = p.host.writeToStream(stream, req); err != nil {
+		return err
+	}
+
+	// the request is complete, let the peer know we won't write anything else
+	_ = stream.CloseWrite()
+
+	buf := p.responseBufs.get()
+	defer p.responseBufs.put(buf)
+
+	n, err := p.host.readStream(stream, buf)
+	switch {
+	case isTimeout(err) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		p.host.reportPeer(peerset.ReputationChange{
+			Value:  peerset.TimeOutValue,
+			Reason: peerset.TimeOutReason,
+		}, to)
+		return fmt.Errorf("%w: %s", ErrRequestTimeout, err)
+	case errors.Is(err, errMessageTooLarge):
+		p.host.reportPeer(peerset.ReputationChange{
+			Value:  peerset.BadMessageValue,
+			Reason: peerset.BadMessageReason,
+		}, to)
+		return err
+	case err != nil:
+		return fmt.Errorf("read stream error: %w", err)
+	case n == 0:
+		return errEmptyResponse
+	}
+
+	if err = resp.Decode(buf[:n]); err != nil {
+		recordDecodeFailure(stream.Protocol(), false)
+		p.host.reportPeer(peerset.ReputationChange{
+			Value:  peerset.BadMessageValue,
+			Reason: peerset.BadMessageReason,
+		}, to)
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	recordRequestDuration(stream.Protocol(), false, start)
+	return nil
+}
+
+// acquire reserves an in-flight request slot for the given peer, returning false if there are none left
+func (p *requestResponseProtocol) acquire(to peer.ID) bool {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	if p.inFlight[to] >= p.maxInFlightPerPeer {
+		return false
+	}
+
+	p.inFlight[to]++
+	return true
+}
+
+func (p *requestResponseProtocol) release(to peer.ID) {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	p.inFlight[to]--
+	if p.inFlight[to] <= 0 {
+		delete(p.inFlight, to)
+	}
+}
+
+// handleStream handles an inbound request stream
+func (p *requestResponseProtocol) handleStream(stream libp2pnetwork.Stream) {
+	if stream == nil {
+		return
+	}
+
+	from := stream.Conn().RemotePeer()
+
+	select {
+	case p.inboundSlots <- struct{}{}:
+		defer func() {
+			<-p.inboundSlots
+		}()
+	default:
+		logger.Debugf("dropping request from peer %s using protocol %s: inbound queue is full",
+			from, stream.Protocol())
+		_ = stream.Reset()
+		return
+	}
+
+	if err := p.handleInboundRequest(from, stream); err != nil {
+		logger.Debugf("failed to handle request from peer %s using protocol %s: %s",
+			from, stream.Protocol(), err)
+		_ = stream.Reset()
+		return
+	}
+
+	_ = stream.Close()
+}
+
+func (p *requestResponseProtocol) handleInboundRequest(from peer.ID, stream libp2pnetwork.Stream) error {
+	start := time.Now()
+	_ = stream.SetDeadline(start.Add(p.requestTimeout))
+
+	buf := p.requestBufs.get()
+	defer p.requestBufs.put(buf)
+
+	n, err := p.host.readStream(stream, buf)
+	if err != nil {
+		if errors.Is(err, errMessageTooLarge) {
+			p.host.reportPeer(peerset.ReputationChange{
+				Value:  peerset.BadMessageValue,
+				Reason: peerset.BadMessageReason,
+			}, from)
+		}
+		return fmt.Errorf("read stream error: %w", err)
+	}
+
+	req, err := p.decodeRequest(buf[:n])
+	if err != nil {
+		recordDecodeFailure(stream.Protocol(), true)
+		p.host.reportPeer(peerset.ReputationChange{
+			Value:  peerset.BadMessageValue,
+			Reason: peerset.BadMessageReason,
+		}, from)
+		return fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	resp, err := p.handleRequest(from, req)
+	if err != nil {
+		return err
+	}
+
+	if err = p.host.writeToStream(stream, resp); err != nil {
+		return err
+	}
+
+	recordRequestDuration(stream.Protocol(), true, start)
+	return nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
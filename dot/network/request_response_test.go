@@ -0,0 +1,212 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/lib/utils"
+)
+
+const testRequestResponseID = protocol.ID("/gossamer/test/reqresp/1")
+
+// rawMessage is a message that is sent as is over the wire
+type rawMessage []byte
+
+func (rawMessage) SubProtocol() string       { return "" }
+func (m rawMessage) Encode() ([]byte, error) { return m, nil }
+func (m *rawMessage) Decode(in []byte) error {
+	*m = append(rawMessage{}, in...)
+	return nil
+}
+func (m rawMessage) String() string { return string(m) }
+
+func decodeRawMessage(in []byte) (Message, error) {
+	msg := new(rawMessage)
+	err := msg.Decode(in)
+	return msg, err
+}
+
+func newTestRequestResponseNodes(t *testing.T) (nodeA, nodeB *Service) {
+	t.Helper()
+
+	configA := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeA"),
+		Port:        7001,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+	nodeA = createTestService(t, configA)
+
+	configB := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeB"),
+		Port:        7002,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+	nodeB = createTestService(t, configB)
+
+	addrInfoB := nodeB.host.addrInfo()
+	err := nodeA.host.connect(addrInfoB)
+	// retry connect if "failed to dial" error
+	if failedToDial(err) {
+		time.Sleep(TestBackoffTimeout)
+		err = nodeA.host.connect(addrInfoB)
+	}
+	require.NoError(t, err)
+
+	return nodeA, nodeB
+}
+
+func newTestRequestResponseProtocol(h *host, handler requestHandler) *requestResponseProtocol {
+	p := newRequestResponseProtocol(h, requestResponseConfig{
+		protocolIDs:        []protocol.ID{testRequestResponseID},
+		maxRequestSize:     1024,
+		maxResponseSize:    1024,
+		requestTimeout:     time.Second,
+		maxInFlightPerPeer: 1,
+		maxInboundQueue:    1,
+		decodeRequest:      decodeRawMessage,
+		handleRequest:      handler,
+	})
+	p.register()
+	return p
+}
+
+func requireReputationDrop(t *testing.T, h *host, p peer.ID) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		rep, err := h.cm.peerSetHandler.PeerReputation(p)
+		return err == nil && rep < 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRequestResponse_Do(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, req Message) (Message, error) {
+		resp := append(rawMessage("pong:"), *req.(*rawMessage)...)
+		return &resp, nil
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	req := rawMessage("ping")
+	resp := new(rawMessage)
+	err := client.do(context.Background(), nodeB.host.id(), &req, resp)
+	require.NoError(t, err)
+	require.Equal(t, rawMessage("pong:ping"), *resp)
+}
+
+func TestRequestResponse_TooManyRequests(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	// use up the only in-flight slot for nodeB
+	require.True(t, client.acquire(nodeB.host.id()))
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.ErrorIs(t, err, errTooManyRequests)
+
+	client.release(nodeB.host.id())
+	require.True(t, client.acquire(nodeB.host.id()))
+}
+
+func TestRequestResponse_Timeout(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, req Message) (Message, error) {
+		time.Sleep(2 * time.Second)
+		return req, nil
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.ErrorIs(t, err, ErrRequestTimeout)
+	requireReputationDrop(t, nodeA.host, nodeB.host.id())
+}
+
+func TestRequestResponse_ResponseTooLarge(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, _ Message) (Message, error) {
+		resp := make(rawMessage, 2048)
+		return &resp, nil
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.ErrorIs(t, err, errMessageTooLarge)
+	requireReputationDrop(t, nodeA.host, nodeB.host.id())
+}
+
+func TestRequestResponse_MalformedResponse(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, _ Message) (Message, error) {
+		resp := rawMessage{0xff, 0xff, 0xff}
+		return &resp, nil
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(BlockResponseMessage))
+	require.Error(t, err)
+	requireReputationDrop(t, nodeA.host, nodeB.host.id())
+}
+
+func TestRequestResponse_HandlerError(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, _ Message) (Message, error) {
+		return nil, errors.New("no response")
+	})
+	client := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	req := rawMessage("ping")
+	err := client.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrRequestTimeout)
+}
+
+func TestRequestResponse_InboundQueueFull(t *testing.T) {
+	nodeA, nodeB := newTestRequestResponseNodes(t)
+
+	release := make(chan struct{})
+	handling := make(chan struct{}, 1)
+	newTestRequestResponseProtocol(nodeB.host, func(_ peer.ID, req Message) (Message, error) {
+		handling <- struct{}{}
+		<-release
+		return req, nil
+	})
+
+	// use two clients so the per-peer in-flight limit doesn't interfere
+	clientA := newTestRequestResponseProtocol(nodeA.host, nil)
+	clientB := newTestRequestResponseProtocol(nodeA.host, nil)
+
+	done := make(chan error)
+	go func() {
+		req := rawMessage("first")
+		done <- clientA.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	}()
+	<-handling
+
+	// the only inbound slot is taken, so the second request is dropped
+	req := rawMessage("second")
+	err := clientB.do(context.Background(), nodeB.host.id(), &req, new(rawMessage))
+	require.Error(t, err)
+
+	close(release)
+	require.NoError(t, <-done)
+}
@@ -6,6 +6,7 @@ package network
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	gssmrmetrics "github.com/ChainSafe/gossamer/dot/metrics"
 	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/telemetry"
+	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/services"
@@ -31,6 +33,7 @@ const (
 	// the following are sub-protocols used by the node
 	syncID          = "/sync/2"
 	lightID         = "/light/2"
+	stateID         = "/state/2"
 	blockAnnounceID = "/block-announces/1"
 	transactionsID  = "/transactions/1"
 
@@ -66,17 +69,25 @@ type Service struct {
 	bufPool       *sizedBufferPool
 	streamManager *streamManager
 
+	authorityDiscovery *authorityDiscovery
+
 	notificationsProtocols map[byte]*notificationsProtocol // map of sub-protocol msg ID to protocol info
 	notificationsMu        sync.RWMutex
 
-	lightRequest   map[peer.ID]struct{} // set if we have sent a light request message to the given peer
-	lightRequestMu sync.RWMutex
+	syncProtocol  *requestResponseProtocol
+	lightProtocol *requestResponseProtocol
+	stateProtocol *requestResponseProtocol
 
 	// Service interfaces
 	blockState         BlockState
 	syncer             Syncer
 	transactionHandler TransactionHandler
 
+	// transactions waiting to be gossiped, and the transactions known to each peer
+	pendingTxnsMu sync.Mutex
+	pendingTxns   []types.Extrinsic
+	knownTxns     *knownTransactions
+
 	// Configuration options
 	noBootstrap bool
 	noDiscover  bool
@@ -86,9 +97,6 @@ type Service struct {
 	// telemetry
 	telemetryInterval time.Duration
 	closeCh           chan struct{}
-
-	blockResponseBuf   []byte
-	blockResponseBufMu sync.Mutex
 }
 
 // NewService creates a new network service from the configuration and message channels
@@ -142,7 +150,7 @@ func NewService(cfg *Config) (*Service, error) {
 		preAllocateInPool = 0
 		poolSize = cfg.MinPeers * 3
 	}
-	bufPool := newSizedBufferPool(preAllocateInPool, poolSize)
+	bufPool := newSizedBufferPool(preAllocateInPool, poolSize, maxMessageSize)
 
 	network := &Service{
 		ctx:                    ctx,
@@ -153,16 +161,24 @@ func NewService(cfg *Config) (*Service, error) {
 		gossip:                 newGossip(),
 		blockState:             cfg.BlockState,
 		transactionHandler:     cfg.TransactionHandler,
+		knownTxns:              newKnownTransactions(),
 		noBootstrap:            cfg.NoBootstrap,
 		noMDNS:                 cfg.NoMDNS,
 		syncer:                 cfg.Syncer,
 		notificationsProtocols: make(map[byte]*notificationsProtocol),
-		lightRequest:           make(map[peer.ID]struct{}),
 		telemetryInterval:      cfg.telemetryInterval,
 		closeCh:                make(chan struct{}),
 		bufPool:                bufPool,
 		streamManager:          newStreamManager(ctx),
-		blockResponseBuf:       make([]byte, maxBlockResponseSize),
+	}
+
+	network.syncProtocol = network.newSyncProtocol()
+	network.lightProtocol = network.newLightProtocol()
+	network.stateProtocol = network.newStateProtocol()
+
+	if cfg.Roles&authorityRole != 0 {
+		network.authorityDiscovery = newAuthorityDiscovery(ctx, host.h, cfg.BlockState,
+			cfg.AuthorityDiscoveryKeystore, host.cm.peerSetHandler, host.discovery.validator)
 	}
 
 	return network, err
@@ -192,12 +208,13 @@ func (s *Service) Start() error {
 		s.ctx, s.cancel = context.WithCancel(context.Background())
 	}
 
-	s.host.registerStreamHandler(s.host.protocolID+syncID, s.handleSyncStream)
-	s.host.registerStreamHandler(s.host.protocolID+lightID, s.handleLightStream)
+	s.syncProtocol.register()
+	s.lightProtocol.register()
+	s.stateProtocol.register()
 
 	// register block announce protocol
 	err := s.RegisterNotificationsProtocol(
-		s.host.protocolID+blockAnnounceID,
+		s.host.protocolIDs(blockAnnounceID),
 		BlockAnnounceMsgType,
 		s.getBlockAnnounceHandshake,
 		decodeBlockAnnounceHandshake,
@@ -216,7 +233,7 @@ func (s *Service) Start() error {
 
 	// register transactions protocol
 	err = s.RegisterNotificationsProtocol(
-		s.host.protocolID+transactionsID,
+		s.host.protocolIDs(transactionsID),
 		TransactionMsgType,
 		s.getTransactionHandshake,
 		decodeTransactionHandshake,
@@ -244,10 +261,20 @@ func (s *Service) Start() error {
 	// when a peer gets disconnected, we should clear all handshake data we have for it.
 	s.host.cm.disconnectHandler = func(peerID peer.ID) {
 		for _, prtl := range s.notificationsProtocols {
+			if hsData, has := prtl.getOutboundHandshakeData(peerID); has && hsData.stream != nil {
+				recordSubstreamClosed(hsData.stream.Protocol(), false)
+			}
+
 			prtl.outboundHandshakeMutexes.Delete(peerID)
 			prtl.inboundHandshakeData.Delete(peerID)
 			prtl.outboundHandshakeData.Delete(peerID)
 		}
+
+		s.knownTxns.remove(peerID)
+
+		if len(s.host.h.Network().ConnsToPeer(peerID)) == 0 {
+			s.syncer.HandlePeerDisconnect(peerID)
+		}
 	}
 
 	// log listening addresses to console
@@ -256,6 +283,7 @@ func (s *Service) Start() error {
 	}
 
 	s.startPeerSetHandler()
+	go s.host.cm.trimLoop(s.ctx)
 
 	if !s.noMDNS {
 		s.mdns.start()
@@ -266,6 +294,11 @@ func (s *Service) Start() error {
 			err = s.host.discovery.start()
 			if err != nil {
 				logger.Errorf("failed to begin DHT discovery: %s", err)
+				return
+			}
+
+			if s.authorityDiscovery != nil && s.host.discovery.dht != nil {
+				s.authorityDiscovery.start(s.host.discovery.dht)
 			}
 		}()
 	}
@@ -281,6 +314,7 @@ func (s *Service) Start() error {
 	go s.logPeerCount()
 	go s.publishNetworkTelemetry(s.closeCh)
 	go s.sentBlockIntervalTelemetry()
+	go s.startTransactionGossip()
 	s.streamManager.start()
 
 	return nil
@@ -316,6 +350,8 @@ func (s *Service) collectNetworkMetrics() {
 		totalInboundStreams.Update(s.getTotalStreams(true))
 		totalOutboundStreams.Update(s.getTotalStreams(false))
 
+		s.updateBandwidthMetrics()
+
 		num, err := s.blockState.BestBlockNumber()
 		if err != nil {
 			syncedBlocks.Update(0)
@@ -327,6 +363,23 @@ func (s *Service) collectNetworkMetrics() {
 	}
 }
 
+// updateBandwidthMetrics updates the bytes sent and received in total and per protocol
+func (s *Service) updateBandwidthMetrics() {
+	bw := s.host.bwm.bandwidth()
+
+	metrics.GetOrRegisterGauge("network/bandwidth/total/inbound", metrics.DefaultRegistry).Update(bw.Total.TotalIn)
+	metrics.GetOrRegisterGauge("network/bandwidth/total/outbound", metrics.DefaultRegistry).Update(bw.Total.TotalOut)
+
+	for name, stats := range bw.Protocols {
+		metrics.GetOrRegisterGauge(
+			fmt.Sprintf("network/bandwidth/%s/inbound", name),
+			metrics.DefaultRegistry).Update(stats.TotalIn)
+		metrics.GetOrRegisterGauge(
+			fmt.Sprintf("network/bandwidth/%s/outbound", name),
+			metrics.DefaultRegistry).Update(stats.TotalOut)
+	}
+}
+
 func (s *Service) getTotalStreams(inbound bool) (count int64) {
 	for _, conn := range s.host.h.Network().Conns() {
 		for _, stream := range conn.GetStreams() {
@@ -392,7 +445,7 @@ main:
 			break main
 
 		case <-ticker.C:
-			o := s.host.bwc.GetBandwidthTotals()
+			o := s.host.bwm.bwc.GetBandwidthTotals()
 			err := telemetry.GetInstance().SendMessage(telemetry.NewBandwidthTM(o.RateIn, o.RateOut, s.host.peerCount()))
 			if err != nil {
 				logger.Debugf("problem sending system.interval telemetry message: %s", err)
@@ -473,8 +526,10 @@ mainloop:
 
 // RegisterNotificationsProtocol registers a protocol with the network service with the given handler
 // messageID is a user-defined message ID for the message passed over this protocol.
+// protocolIDs are the IDs the protocol is known by in order of preference, the first one supported
+// by a peer is used when opening a stream to it.
 func (s *Service) RegisterNotificationsProtocol(
-	protocolID protocol.ID,
+	protocolIDs []protocol.ID,
 	messageID byte,
 	handshakeGetter HandshakeGetter,
 	handshakeDecoder HandshakeDecoder,
@@ -490,20 +545,47 @@ func (s *Service) RegisterNotificationsProtocol(
 		return errors.New("notifications protocol with message type already exists")
 	}
 
-	np := newNotificationsProtocol(protocolID, handshakeGetter, handshakeDecoder, handshakeValidator)
+	if len(protocolIDs) == 0 {
+		return errors.New("notifications protocol must have at least one protocol ID")
+	}
+
+	np := newNotificationsProtocol(protocolIDs, handshakeGetter, handshakeDecoder, handshakeValidator)
 	s.notificationsProtocols[messageID] = np
 	decoder := createDecoder(np, handshakeDecoder, messageDecoder)
 	handlerWithValidate := s.createNotificationsMessageHandler(np, messageHandler, batchHandler)
 
-	s.host.registerStreamHandler(protocolID, func(stream libp2pnetwork.Stream) {
-		logger.Tracef("received stream using sub-protocol %s", protocolID)
+	s.host.registerStreamHandlers(protocolIDs, func(stream libp2pnetwork.Stream) {
+		logger.Tracef("received stream using sub-protocol %s", stream.Protocol())
 		s.readStream(stream, decoder, handlerWithValidate)
 	})
 
-	logger.Infof("registered notifications sub-protocol %s", protocolID)
+	logger.Infof("registered notifications sub-protocol %s with fallbacks %v", np.protocolID, np.fallbackProtocolIDs)
 	return nil
 }
 
+// SetGossipFilter sets the filter deciding which peers the messages of the notifications protocol
+// with the given message ID are gossiped to
+func (s *Service) SetGossipFilter(messageID byte, filter GossipFilter) error {
+	s.notificationsMu.Lock()
+	defer s.notificationsMu.Unlock()
+
+	np, has := s.notificationsProtocols[messageID]
+	if !has {
+		return errors.New("notifications protocol with message type does not exist")
+	}
+
+	np.gossipFilterMu.Lock()
+	defer np.gossipFilterMu.Unlock()
+	np.gossipFilter = filter
+	return nil
+}
+
+// ChainProtocolID returns the genesis hash based ID of the given sub-protocol,
+// ie. /<genesis hash>[/<fork id>]/<sub-protocol>
+func (s *Service) ChainProtocolID(sub protocol.ID) protocol.ID {
+	return s.host.chainProtocolID + sub
+}
+
 // IsStopped returns true if the service is stopped
 func (s *Service) IsStopped() bool {
 	return s.ctx.Err() != nil
@@ -518,6 +600,12 @@ func (s *Service) GossipMessage(msg NotificationsMessage) {
 	logger.Debugf("gossiping from host %s message of type %d: %s",
 		s.host.id(), msg.Type(), msg)
 
+	// transactions are gossiped in batches, see gossipTransactions
+	if txMsg, ok := msg.(*TransactionMessage); ok {
+		s.queueTransactions(txMsg.Extrinsics...)
+		return
+	}
+
 	// check if the message is part of a notifications protocol
 	s.notificationsMu.Lock()
 	defer s.notificationsMu.Unlock()
@@ -573,6 +661,11 @@ func (s *Service) NetworkState() common.NetworkState {
 	}
 }
 
+// Bandwidth returns the bytes sent and received in total and per protocol, needed for the rpc server
+func (s *Service) Bandwidth() common.Bandwidth {
+	return s.host.bwm.bandwidth()
+}
+
 // Peers returns information about connected peers needed for the rpc server
 func (s *Service) Peers() []common.PeerInfo {
 	var peers []common.PeerInfo
@@ -613,6 +706,37 @@ func (s *Service) RemoveReservedPeers(addrs ...string) error {
 	return s.host.removeReservedPeers(addrs...)
 }
 
+// BanPeer bans the peer with the given base58-encoded ID for the given duration,
+// refusing any connection with it until the ban expires or is removed.
+// If duration is zero, DefaultBanDuration is used.
+func (s *Service) BanPeer(id, reason string, duration time.Duration) error {
+	peerID, err := peer.Decode(id)
+	if err != nil {
+		return err
+	}
+
+	if duration == 0 {
+		duration = DefaultBanDuration
+	}
+
+	return s.host.banPeer(peerID, reason, duration)
+}
+
+// UnbanPeer removes the ban of the peer with the given base58-encoded ID
+func (s *Service) UnbanPeer(id string) error {
+	peerID, err := peer.Decode(id)
+	if err != nil {
+		return err
+	}
+
+	return s.host.bans.unban(peerID)
+}
+
+// BannedPeers returns the currently banned peers
+func (s *Service) BannedPeers() []common.BannedPeer {
+	return s.host.bans.bannedPeers()
+}
+
 // NodeRoles Returns the roles the node is running as.
 func (s *Service) NodeRoles() byte {
 	return s.cfg.Roles
@@ -649,7 +773,7 @@ func (s *Service) IsSynced() bool {
 
 // ReportPeer reports ReputationChange according to the peer behaviour.
 func (s *Service) ReportPeer(change peerset.ReputationChange, p peer.ID) {
-	s.host.cm.peerSetHandler.ReportPeer(change, p)
+	s.host.reportPeer(change, p)
 }
 
 func (s *Service) startPeerSetHandler() {
@@ -693,6 +817,17 @@ func (s *Service) processMessage(msg peerset.Message) {
 			return
 		}
 		logger.Debugf("connection dropped successfully for peer %s", peerID)
+	case peerset.Ban:
+		// the peerset already disconnected the peer, so only the connection is closed
+		logger.Infof("banning peer %s for %s: %s", peerID, DefaultBanDuration, msg.Reason)
+		if err := s.host.bans.ban(peerID, msg.Reason, DefaultBanDuration); err != nil {
+			logger.Warnf("failed to ban peer %s: %s", peerID, err)
+			return
+		}
+
+		if err := s.host.closePeer(peerID); err != nil {
+			logger.Warnf("failed to close connection with peer %s: %s", peerID, err)
+		}
 	}
 }
 
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/libp2p/go-libp2p-core/peer"
 	mock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -157,7 +159,7 @@ func TestBroadcastMessages(t *testing.T) {
 	defer nodeB.Stop()
 	nodeB.noGossip = true
 	handler := newTestStreamHandler(testBlockAnnounceHandshakeDecoder)
-	nodeB.host.registerStreamHandler(nodeB.host.protocolID+blockAnnounceID, handler.handleStream)
+	nodeB.host.registerStreamHandlers(nodeB.host.protocolIDs(blockAnnounceID), handler.handleStream)
 
 	addrInfoB := nodeB.host.addrInfo()
 	err := nodeA.host.connect(addrInfoB)
@@ -174,6 +176,70 @@ func TestBroadcastMessages(t *testing.T) {
 	require.NotNil(t, handler.messages[nodeA.host.id()])
 }
 
+func TestBroadcastMessages_GossipFilter(t *testing.T) {
+	basePathA := utils.NewTestBasePath(t, "nodeA")
+	configA := &Config{
+		BasePath:    basePathA,
+		Port:        7001,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+
+	nodeA := createTestService(t, configA)
+	defer nodeA.Stop()
+	nodeA.noGossip = true
+
+	basePathB := utils.NewTestBasePath(t, "nodeB")
+	configB := &Config{
+		BasePath:    basePathB,
+		Port:        7002,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+
+	nodeB := createTestService(t, configB)
+	defer nodeB.Stop()
+	nodeB.noGossip = true
+	handler := newTestStreamHandler(testBlockAnnounceHandshakeDecoder)
+	nodeB.host.registerStreamHandlers(nodeB.host.protocolIDs(blockAnnounceID), handler.handleStream)
+
+	addrInfoB := nodeB.host.addrInfo()
+	err := nodeA.host.connect(addrInfoB)
+	// retry connect if "failed to dial" error
+	if failedToDial(err) {
+		time.Sleep(TestBackoffTimeout)
+		err = nodeA.host.connect(addrInfoB)
+	}
+	require.NoError(t, err)
+
+	err = nodeA.SetGossipFilter(0xff, nil)
+	require.Error(t, err)
+
+	var allowed bool
+	var allowedMu sync.Mutex
+	err = nodeA.SetGossipFilter(BlockAnnounceMsgType, func(NotificationsMessage) func(peer.ID) bool {
+		allowedMu.Lock()
+		defer allowedMu.Unlock()
+		gossip := allowed
+		return func(to peer.ID) bool {
+			return to == nodeB.host.id() && gossip
+		}
+	})
+	require.NoError(t, err)
+
+	nodeA.GossipMessage(testBlockAnnounceMessage)
+	time.Sleep(time.Second)
+	require.Nil(t, handler.messages[nodeA.host.id()])
+
+	allowedMu.Lock()
+	allowed = true
+	allowedMu.Unlock()
+
+	nodeA.GossipMessage(testBlockAnnounceMessage)
+	time.Sleep(time.Second * 2)
+	require.NotNil(t, handler.messages[nodeA.host.id()])
+}
+
 func TestBroadcastDuplicateMessage(t *testing.T) {
 	msgCacheTTL = 2 * time.Second
 
@@ -202,7 +268,7 @@ func TestBroadcastDuplicateMessage(t *testing.T) {
 	nodeB.noGossip = true
 
 	handler := newTestStreamHandler(testBlockAnnounceHandshakeDecoder)
-	nodeB.host.registerStreamHandler(nodeB.host.protocolID+blockAnnounceID, handler.handleStream)
+	nodeB.host.registerStreamHandlers(nodeB.host.protocolIDs(blockAnnounceID), handler.handleStream)
 
 	addrInfoB := nodeB.host.addrInfo()
 	err := nodeA.host.connect(addrInfoB)
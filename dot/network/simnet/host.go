@@ -0,0 +1,119 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/connmgr"
+	libp2phost "github.com/libp2p/go-libp2p-core/host"
+	libp2pnetwork "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+// simHost is a host whose streams delay and drop messages according to the latency and packet loss of
+// the network. Only the streams of negotiated protocols are affected, so connections can always be established.
+// Mocknet hosts have neither a connection manager nor a connection gater, so the host applies them itself.
+type simHost struct {
+	libp2phost.Host
+	net   *Network
+	cm    connmgr.ConnManager
+	gater connmgr.ConnectionGater
+}
+
+func newSimHost(h libp2phost.Host, n *Network, cm connmgr.ConnManager, gater connmgr.ConnectionGater) *simHost {
+	sh := &simHost{
+		Host:  h,
+		net:   n,
+		cm:    cm,
+		gater: gater,
+	}
+
+	h.Network().Notify(cm.Notifee())
+	h.Network().Notify(&libp2pnetwork.NotifyBundle{
+		ConnectedF: sh.interceptConn,
+	})
+	return sh
+}
+
+// interceptConn closes the connections the gater doesn't allow, mocknet connections are secured
+// as soon as they are opened so the check is done once they're established
+func (h *simHost) interceptConn(_ libp2pnetwork.Network, c libp2pnetwork.Conn) {
+	if h.gater.InterceptSecured(c.Stat().Direction, c.RemotePeer(), c) {
+		return
+	}
+
+	// the connection can't be closed from within the notification
+	go c.Close() //nolint
+}
+
+// allowDial returns an error if there's no connection to the peer yet and the gater doesn't allow dialing it
+func (h *simHost) allowDial(p peer.ID) error {
+	if len(h.Network().ConnsToPeer(p)) > 0 || h.gater.InterceptPeerDial(p) {
+		return nil
+	}
+
+	return fmt.Errorf("dial to peer %s refused by connection gater", p)
+}
+
+func (h *simHost) ConnManager() connmgr.ConnManager {
+	return h.cm
+}
+
+func (h *simHost) Connect(ctx context.Context, pi peer.AddrInfo) error {
+	if err := h.allowDial(pi.ID); err != nil {
+		return err
+	}
+
+	return h.Host.Connect(ctx, pi)
+}
+
+func (h *simHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (libp2pnetwork.Stream, error) {
+	if err := h.allowDial(p); err != nil {
+		return nil, err
+	}
+
+	s, err := h.Host.NewStream(ctx, p, pids...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &simStream{Stream: s, net: h.net}, nil
+}
+
+func (h *simHost) SetStreamHandler(pid protocol.ID, handler libp2pnetwork.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.wrapHandler(handler))
+}
+
+func (h *simHost) SetStreamHandlerMatch(pid protocol.ID, match func(string) bool,
+	handler libp2pnetwork.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, h.wrapHandler(handler))
+}
+
+func (h *simHost) wrapHandler(handler libp2pnetwork.StreamHandler) libp2pnetwork.StreamHandler {
+	return func(s libp2pnetwork.Stream) {
+		handler(&simStream{Stream: s, net: h.net})
+	}
+}
+
+// simStream delays or drops whole writes, the network service writes each message with a single write.
+// The latency is simulated here rather than with mocknet links, as those deliver buffered
+// messages without delay once the stream is closed.
+type simStream struct {
+	libp2pnetwork.Stream
+	net *Network
+}
+
+func (s *simStream) Write(p []byte) (int, error) {
+	delay, lost := s.net.transmit()
+	time.Sleep(delay)
+	if lost {
+		return len(p), nil
+	}
+
+	return s.Stream.Write(p)
+}
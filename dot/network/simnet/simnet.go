@@ -0,0 +1,282 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+// Package simnet runs several network services, or full nodes, in a single process over an in-memory
+// libp2p network. The latency and packet loss of the network can be configured, and it can be split
+// into partitions to simulate network failures.
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/connmgr"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	libp2phost "github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/ChainSafe/gossamer/dot"
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+)
+
+// Config is used to configure a simulated network
+type Config struct {
+	// Latency is the time it takes for a message to reach the other end of a link
+	Latency time.Duration
+	// PacketLoss is the probability, between 0 and 1, that a message is lost on its way
+	PacketLoss float64
+	// Seed is used to seed the random source deciding which messages are lost, so runs are reproducible
+	Seed int64
+}
+
+type link struct {
+	a, b peer.ID
+}
+
+func newLink(a, b peer.ID) link {
+	if a > b {
+		a, b = b, a
+	}
+	return link{a: a, b: b}
+}
+
+// Network is an in-memory network that hosts are created on
+type Network struct {
+	ctx context.Context
+	mn  mocknet.Mocknet
+
+	sync.Mutex
+	rand       *rand.Rand
+	latency    time.Duration
+	packetLoss float64
+	peers      []peer.ID
+	hosts      map[peer.ID]*simHost
+	partitions map[peer.ID]int // partition of each peer, all peers are in partition 0 unless partitioned
+	severed    map[link]bool   // connections closed by Partition, they are reopened by Heal
+}
+
+// New creates a new simulated network
+func New(ctx context.Context, cfg Config) *Network {
+	return &Network{
+		ctx:        ctx,
+		mn:         mocknet.New(ctx),
+		rand:       rand.New(rand.NewSource(cfg.Seed)), //nolint
+		latency:    cfg.Latency,
+		packetLoss: cfg.PacketLoss,
+		hosts:      make(map[peer.ID]*simHost),
+		partitions: make(map[peer.ID]int),
+		severed:    make(map[link]bool),
+	}
+}
+
+// NewHost creates a new host on the network with the given identity, connection manager and connection gater,
+// it can be used as a network.HostConstructor. The host is linked to all the hosts of the network that are
+// in the same partition, but isn't connected to them.
+func (n *Network) NewHost(key crypto.PrivKey, cm connmgr.ConnManager, gater connmgr.ConnectionGater) (
+	libp2phost.Host, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	// mocknet hosts don't listen on these addresses, they only need to be unique
+	addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 7000+len(n.peers)))
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := n.mn.AddPeer(key, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range n.peers {
+		if n.partitions[p] != 0 {
+			continue
+		}
+
+		if _, err = n.mn.LinkPeers(h.ID(), p); err != nil {
+			return nil, err
+		}
+	}
+
+	sh := newSimHost(h, n, cm, gater)
+	n.peers = append(n.peers, h.ID())
+	n.hosts[h.ID()] = sh
+	return sh, nil
+}
+
+// NewService creates a network service running on the simulated network. The service isn't started.
+func (n *Network) NewService(cfg *network.Config) (*network.Service, error) {
+	cfg.NewHost = n.NewHost
+	cfg.NoBootstrap = true
+	cfg.NoMDNS = true
+	return network.NewService(cfg)
+}
+
+// NewNode creates a full node running on the simulated network. Its state is kept in memory and
+// initialised from the genesis file set in the configuration, see dot.NewInMemoryNode.
+func (n *Network) NewNode(cfg *dot.Config, ks *keystore.GlobalKeystore) (*dot.Node, error) {
+	cfg.Network.NewHost = n.NewHost
+	cfg.Network.NoBootstrap = true
+	cfg.Network.NoMDNS = true
+	return dot.NewInMemoryNode(cfg, ks)
+}
+
+// Connect connects the hosts of the two peers, they must be in the same partition.
+// It returns once the peers have identified each other, so the protocols they support are known.
+func (n *Network) Connect(a, b peer.ID) error {
+	n.Lock()
+	h := n.hosts[a]
+	n.Unlock()
+
+	return n.connect(h, b)
+}
+
+// connect connects the host to the peer, unless the connection gater of the host refuses it
+func (n *Network) connect(h *simHost, p peer.ID) error {
+	return h.Connect(n.ctx, n.mn.Host(p).Peerstore().PeerInfo(p))
+}
+
+// ConnectAll connects all the hosts of the network that are in the same partition to each other
+func (n *Network) ConnectAll() error {
+	n.Lock()
+	peers := append([]peer.ID{}, n.peers...)
+	partitions := make(map[peer.ID]int, len(n.partitions))
+	for p, i := range n.partitions {
+		partitions[p] = i
+	}
+	n.Unlock()
+
+	for i, a := range peers {
+		for _, b := range peers[i+1:] {
+			if partitions[a] != partitions[b] {
+				continue
+			}
+
+			if err := n.Connect(a, b); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetLatency sets the time it takes for a message to reach the other end of a link
+func (n *Network) SetLatency(latency time.Duration) {
+	n.Lock()
+	defer n.Unlock()
+	n.latency = latency
+}
+
+// SetPacketLoss sets the probability, between 0 and 1, that a message is lost on its way
+func (n *Network) SetPacketLoss(rate float64) {
+	n.Lock()
+	defer n.Unlock()
+	n.packetLoss = rate
+}
+
+// Partition splits the network into the given groups of peers, peers of different groups can't reach
+// each other. The peers that aren't in any of the groups form an additional group.
+func (n *Network) Partition(groups ...[]peer.ID) error {
+	n.Lock()
+	defer n.Unlock()
+
+	for _, p := range n.peers {
+		n.partitions[p] = 0
+	}
+
+	for i, group := range groups {
+		for _, p := range group {
+			n.partitions[p] = i + 1
+		}
+	}
+
+	for i, a := range n.peers {
+		for _, b := range n.peers[i+1:] {
+			var err error
+			if n.partitions[a] == n.partitions[b] {
+				err = n.link(a, b)
+			} else {
+				err = n.sever(a, b)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Heal removes all the partitions of the network, the connections closed by Partition are reopened
+func (n *Network) Heal() error {
+	n.Lock()
+	defer n.Unlock()
+
+	for i, a := range n.peers {
+		for _, b := range n.peers[i+1:] {
+			if err := n.link(a, b); err != nil {
+				return err
+			}
+		}
+	}
+
+	for p := range n.partitions {
+		n.partitions[p] = 0
+	}
+
+	for l := range n.severed {
+		if err := n.connect(n.hosts[l.a], l.b); err != nil {
+			return err
+		}
+		delete(n.severed, l)
+	}
+
+	return nil
+}
+
+// link links the two peers, unless they are already linked
+func (n *Network) link(a, b peer.ID) error {
+	if len(n.mn.LinksBetweenPeers(a, b)) > 0 {
+		return nil
+	}
+
+	_, err := n.mn.LinkPeers(a, b)
+	return err
+}
+
+// sever closes the connections between the two peers and removes the links between them
+func (n *Network) sever(a, b peer.ID) error {
+	if len(n.mn.Net(a).ConnsToPeer(b)) > 0 {
+		n.severed[newLink(a, b)] = true
+	}
+
+	if err := n.mn.DisconnectPeers(a, b); err != nil {
+		return err
+	}
+
+	if len(n.mn.LinksBetweenPeers(a, b)) == 0 {
+		return nil
+	}
+
+	return n.mn.UnlinkPeers(a, b)
+}
+
+// transmit returns the delay of the next message sent on the network,
+// and whether it should be dropped
+func (n *Network) transmit() (delay time.Duration, lost bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.packetLoss > 0 {
+		lost = n.rand.Float64() < n.packetLoss
+	}
+
+	return n.latency, lost
+}
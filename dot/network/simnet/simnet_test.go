@@ -0,0 +1,265 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package simnet
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/dot"
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common/variadic"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+)
+
+// newTestSyncer returns a syncer that sends the numbers of the blocks announced to it on the returned channel
+func newTestSyncer() (*network.MockSyncer, chan *big.Int) {
+	announced := make(chan *big.Int, 16)
+
+	syncer := new(network.MockSyncer)
+	syncer.On("HandleBlockAnnounceHandshake", mock.AnythingOfType("peer.ID"),
+		mock.AnythingOfType("*network.BlockAnnounceHandshake")).Return(nil)
+	syncer.On("HandleBlockAnnounce", mock.AnythingOfType("peer.ID"),
+		mock.AnythingOfType("*network.BlockAnnounceMessage")).Return(nil).
+		Run(func(args mock.Arguments) {
+			announced <- args.Get(1).(*network.BlockAnnounceMessage).Number
+		})
+	header := &types.Header{
+		Number: big.NewInt(1),
+		Digest: types.NewDigest(),
+	}
+	syncer.On("CreateBlockResponse", mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(&network.BlockResponseMessage{
+			BlockData: []*types.BlockData{{
+				Hash:   header.Hash(),
+				Header: header,
+			}},
+		}, nil)
+	syncer.On("HandlePeerDisconnect", mock.AnythingOfType("peer.ID"))
+	syncer.On("IsSynced").Return(false)
+
+	return syncer, announced
+}
+
+func newTestService(t *testing.T, net *Network, syncer network.Syncer) *network.Service {
+	t.Helper()
+
+	cfg := &network.Config{
+		BasePath:           t.TempDir(),
+		ProtocolID:         "/gossamer/simnet/0",
+		LogLvl:             4,
+		SlotDuration:       time.Second,
+		BlockState:         network.NewMockBlockState(nil),
+		Syncer:             syncer,
+		TransactionHandler: network.NewMockTransactionHandler(),
+	}
+
+	srvc, err := net.NewService(cfg)
+	require.NoError(t, err)
+
+	err = srvc.Start()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = srvc.Stop()
+	})
+	return srvc
+}
+
+func peerID(t *testing.T, srvc *network.Service) peer.ID {
+	t.Helper()
+
+	id, err := peer.Decode(srvc.NetworkState().PeerID)
+	require.NoError(t, err)
+	return id
+}
+
+func announce(srvc *network.Service, number int64) {
+	srvc.GossipMessage(&network.BlockAnnounceMessage{
+		Number: big.NewInt(number),
+		Digest: types.NewDigest(),
+	})
+}
+
+func requireAnnounced(t *testing.T, announced chan *big.Int, number int64) {
+	t.Helper()
+
+	select {
+	case n := <-announced:
+		require.Equal(t, big.NewInt(number), n)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("block %d was not announced", number)
+	}
+}
+
+func requireNotAnnounced(t *testing.T, announced chan *big.Int) {
+	t.Helper()
+
+	select {
+	case n := <-announced:
+		t.Fatalf("block %d was announced", n)
+	case <-time.After(time.Second):
+	}
+}
+
+func TestNetwork_Gossip(t *testing.T) {
+	net := New(context.Background(), Config{})
+
+	syncerA, _ := newTestSyncer()
+	nodeA := newTestService(t, net, syncerA)
+	syncerB, announcedB := newTestSyncer()
+	nodeB := newTestService(t, net, syncerB)
+
+	require.NoError(t, net.ConnectAll())
+
+	announce(nodeA, 2)
+	requireAnnounced(t, announcedB, 2)
+
+	require.Eventually(t, func() bool {
+		return len(nodeB.Peers()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, peerID(t, nodeA).String(), nodeB.Peers()[0].PeerID)
+}
+
+func TestNetwork_Partition(t *testing.T) {
+	net := New(context.Background(), Config{})
+
+	syncerA, _ := newTestSyncer()
+	nodeA := newTestService(t, net, syncerA)
+	syncerB, announcedB := newTestSyncer()
+	nodeB := newTestService(t, net, syncerB)
+
+	require.NoError(t, net.ConnectAll())
+	announce(nodeA, 2)
+	requireAnnounced(t, announcedB, 2)
+
+	err := net.Partition([]peer.ID{peerID(t, nodeA)})
+	require.NoError(t, err)
+
+	// the nodes can't reach each other
+	err = net.Connect(peerID(t, nodeA), peerID(t, nodeB))
+	require.Error(t, err)
+	announce(nodeA, 3)
+	requireNotAnnounced(t, announcedB)
+
+	err = net.Heal()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		announce(nodeA, 4)
+		select {
+		case n := <-announcedB:
+			return n.Cmp(big.NewInt(4)) == 0
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNetwork_PacketLoss(t *testing.T) {
+	net := New(context.Background(), Config{
+		PacketLoss: 1,
+	})
+
+	syncerA, _ := newTestSyncer()
+	nodeA := newTestService(t, net, syncerA)
+	syncerB, announcedB := newTestSyncer()
+	newTestService(t, net, syncerB)
+
+	require.NoError(t, net.ConnectAll())
+
+	announce(nodeA, 2)
+	requireNotAnnounced(t, announcedB)
+}
+
+func TestNetwork_Latency(t *testing.T) {
+	const latency = 200 * time.Millisecond
+
+	net := New(context.Background(), Config{})
+
+	syncerA, _ := newTestSyncer()
+	nodeA := newTestService(t, net, syncerA)
+	syncerB, _ := newTestSyncer()
+	nodeB := newTestService(t, net, syncerB)
+
+	require.NoError(t, net.ConnectAll())
+	net.SetLatency(latency)
+
+	req := &network.BlockRequestMessage{
+		RequestedData: network.RequestedDataHeader,
+		StartingBlock: *variadic.NewUint64OrHashFromBytes([]byte{1, 1}),
+		Direction:     network.Ascending,
+	}
+
+	start := time.Now()
+	_, err := nodeA.DoBlockRequest(peerID(t, nodeB), req)
+	require.NoError(t, err)
+
+	// the request and the response each take at least the link latency
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(2*latency))
+}
+
+// newTestNode starts a full node on the network, its network service and base path are returned
+func newTestNode(t *testing.T, net *Network) (*network.Service, string) {
+	t.Helper()
+
+	cfg := dot.NewTestConfig(t)
+	genFile := dot.NewTestGenesisRawFile(t, cfg)
+	cfg.Init.Genesis = genFile.Name()
+	cfg.Core.Roles = types.FullNodeRole
+	cfg.Core.BabeAuthority = false
+	cfg.Core.GrandpaAuthority = false
+
+	node, err := net.NewNode(cfg, keystore.NewGlobalKeystore())
+	require.NoError(t, err)
+
+	node.Services.StartAll()
+	t.Cleanup(node.Services.StopAll)
+
+	return node.Services.Get(&network.Service{}).(*network.Service), cfg.Global.BasePath
+}
+
+func TestNetwork_FullNodes(t *testing.T) {
+	net := New(context.Background(), Config{})
+
+	nodeA, basePath := newTestNode(t, net)
+	nodeB, _ := newTestNode(t, net)
+	nodeC, _ := newTestNode(t, net)
+
+	require.NoError(t, net.ConnectAll())
+	for _, node := range []*network.Service{nodeA, nodeB, nodeC} {
+		node := node
+		require.Eventually(t, func() bool {
+			return len(node.Peers()) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// the peer data of the nodes is kept in memory
+	require.NoDirExists(t, filepath.Join(basePath, "libp2p-datastore"))
+
+	// banned peers are disconnected and can't connect again
+	err := nodeA.BanPeer(peerID(t, nodeC).String(), "test", time.Minute)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(nodeA.Peers()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	err = net.Connect(peerID(t, nodeA), peerID(t, nodeC))
+	require.Error(t, err)
+
+	// connections accepted from banned peers are closed
+	err = net.Connect(peerID(t, nodeC), peerID(t, nodeA))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(nodeA.Peers()) == 1 && nodeA.Peers()[0].PeerID == peerID(t, nodeB).String()
+	}, 5*time.Second, 10*time.Millisecond)
+}
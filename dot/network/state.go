@@ -11,6 +11,7 @@ import (
 	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
 )
 
 //go:generate mockery --name BlockState --structname MockBlockState --case underscore --inpackage
@@ -23,6 +24,7 @@ type BlockState interface {
 	HasBlockBody(common.Hash) (bool, error)
 	GetHighestFinalisedHeader() (*types.Header, error)
 	GetHashByNumber(num *big.Int) (common.Hash, error)
+	GetRuntime(hash *common.Hash) (runtime.Instance, error)
 }
 
 //go:generate mockery --name Syncer --structname MockSyncer --case underscore --inpackage
@@ -35,11 +37,17 @@ type Syncer interface {
 	// If a request needs to be sent to the peer to retrieve the full block, this function will return it.
 	HandleBlockAnnounce(from peer.ID, msg *BlockAnnounceMessage) error
 
+	// HandlePeerDisconnect is called once all the connections with the peer are closed.
+	HandlePeerDisconnect(peer.ID)
+
 	// IsSynced exposes the internal synced state
 	IsSynced() bool
 
 	// CreateBlockResponse is called upon receipt of a BlockRequestMessage to create the response
 	CreateBlockResponse(*BlockRequestMessage) (*BlockResponseMessage, error)
+
+	// CreateStateResponse is called upon receipt of a StateRequest to create the response
+	CreateStateResponse(*StateRequest) (*StateResponse, error)
 }
 
 //go:generate mockery --name TransactionHandler --structname MockTransactionHandler --case underscore --inpackage
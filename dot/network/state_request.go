@@ -0,0 +1,333 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+var (
+	maxStateRequestSize  uint64 = 1024 * 64       // 64kb
+	maxStateResponseSize uint64 = 1024 * 1024 * 4 // 4mb
+	stateRequestTimeout         = time.Second * 40
+)
+
+const (
+	// maxStateRequestsPerPeer is the maximum number of state requests awaiting a response from a single peer
+	maxStateRequestsPerPeer = 1
+	// maxInboundStateRequests is the maximum number of state requests we are answering at once
+	maxInboundStateRequests = 4
+
+	// MaxStateResponseEntriesSize is the size in bytes of the keys and values after which
+	// a state response should be cut, so that it fits in the maximum response size
+	MaxStateResponseEntriesSize = 1024 * 1024 * 2 // 2mb
+)
+
+var errInvalidProtobuf = errors.New("invalid protobuf message")
+
+var (
+	_ Message = &StateRequest{}
+	_ Message = &StateResponse{}
+)
+
+// StateRequest is sent to request the storage entries of the state of a block. Its protobuf
+// encoding is the one of the StateRequest message of the <protocol-id>/state/2 protocol.
+type StateRequest struct {
+	// Block is the hash of the block whose state is requested
+	Block common.Hash
+	// Start is the key after which entries are returned, the first key is for the top trie and
+	// the second one, if any, for a child trie. It is empty to request the state from its first key.
+	Start [][]byte
+	// NoProof requests the entries rather than a proof of them
+	NoProof bool
+}
+
+// SubProtocol returns the state sub-protocol
+func (*StateRequest) SubProtocol() string {
+	return stateID
+}
+
+// String formats a StateRequest as a string
+func (sr *StateRequest) String() string {
+	return fmt.Sprintf("StateRequest Block=%s Start=%x NoProof=%t", sr.Block, sr.Start, sr.NoProof)
+}
+
+// Encode returns the protobuf encoded StateRequest
+func (sr *StateRequest) Encode() ([]byte, error) {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, sr.Block[:])
+
+	for _, key := range sr.Start {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, key)
+	}
+
+	if sr.NoProof {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+
+	return b, nil
+}
+
+// Decode decodes the protobuf encoded input into a StateRequest
+func (sr *StateRequest) Decode(in []byte) error {
+	*sr = StateRequest{}
+
+	return decodeProtobufFields(in, func(num protowire.Number, typ protowire.Type, in []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			block, n := protowire.ConsumeBytes(in)
+			if n < 0 || len(block) != len(sr.Block) {
+				return -1, fmt.Errorf("%w: invalid block hash", errInvalidProtobuf)
+			}
+			copy(sr.Block[:], block)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			key, n := protowire.ConsumeBytes(in)
+			if n >= 0 {
+				sr.Start = append(sr.Start, append([]byte{}, key...))
+			}
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(in)
+			sr.NoProof = v != 0
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, in), nil
+		}
+	})
+}
+
+// StateEntry is a key-value pair of the state
+type StateEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// KeyValueStateEntry are the entries of a trie of the state
+type KeyValueStateEntry struct {
+	// StateRoot is the root of the child trie the entries belong to, it is empty for the top trie
+	StateRoot []byte
+	Entries   []StateEntry
+	// Complete is true if these are the last entries of the trie
+	Complete bool
+}
+
+// StateResponse is the response to a StateRequest. Its protobuf encoding is the one of the
+// StateResponse message of the <protocol-id>/state/2 protocol.
+type StateResponse struct {
+	Entries []KeyValueStateEntry
+	Proof   []byte
+}
+
+// SubProtocol returns the state sub-protocol
+func (*StateResponse) SubProtocol() string {
+	return stateID
+}
+
+// String formats a StateResponse as a string
+func (sr *StateResponse) String() string {
+	entries := 0
+	for _, kv := range sr.Entries {
+		entries += len(kv.Entries)
+	}
+	return fmt.Sprintf("StateResponse Tries=%d Entries=%d ProofLen=%d", len(sr.Entries), entries, len(sr.Proof))
+}
+
+// Encode returns the protobuf encoded StateResponse
+func (sr *StateResponse) Encode() ([]byte, error) {
+	var b []byte
+	for _, kv := range sr.Entries {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, kv.encode())
+	}
+
+	if len(sr.Proof) > 0 {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, sr.Proof)
+	}
+
+	return b, nil
+}
+
+// Decode decodes the protobuf encoded input into a StateResponse
+func (sr *StateResponse) Decode(in []byte) error {
+	*sr = StateResponse{}
+
+	return decodeProtobufFields(in, func(num protowire.Number, typ protowire.Type, in []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			enc, n := protowire.ConsumeBytes(in)
+			if n < 0 {
+				return n, nil
+			}
+
+			var kv KeyValueStateEntry
+			if err := kv.decode(enc); err != nil {
+				return -1, err
+			}
+			sr.Entries = append(sr.Entries, kv)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			proof, n := protowire.ConsumeBytes(in)
+			if n >= 0 {
+				sr.Proof = append([]byte{}, proof...)
+			}
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, in), nil
+		}
+	})
+}
+
+func (kv *KeyValueStateEntry) encode() []byte {
+	var b []byte
+	if len(kv.StateRoot) > 0 {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, kv.StateRoot)
+	}
+
+	for _, e := range kv.Entries {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, e.Key)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, e.Value)
+
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	if kv.Complete {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+
+	return b
+}
+
+func (kv *KeyValueStateEntry) decode(in []byte) error {
+	return decodeProtobufFields(in, func(num protowire.Number, typ protowire.Type, in []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			root, n := protowire.ConsumeBytes(in)
+			if n >= 0 {
+				kv.StateRoot = append([]byte{}, root...)
+			}
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			enc, n := protowire.ConsumeBytes(in)
+			if n < 0 {
+				return n, nil
+			}
+
+			var e StateEntry
+			err := decodeProtobufFields(enc, func(num protowire.Number, typ protowire.Type, in []byte) (int, error) {
+				if typ != protowire.BytesType || (num != 1 && num != 2) {
+					return protowire.ConsumeFieldValue(num, typ, in), nil
+				}
+
+				v, n := protowire.ConsumeBytes(in)
+				if n >= 0 && num == 1 {
+					e.Key = append([]byte{}, v...)
+				} else if n >= 0 {
+					e.Value = append([]byte{}, v...)
+				}
+				return n, nil
+			})
+			if err != nil {
+				return -1, err
+			}
+
+			kv.Entries = append(kv.Entries, e)
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(in)
+			kv.Complete = v != 0
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, in), nil
+		}
+	})
+}
+
+// decodeProtobufFields calls decodeField for each field of the protobuf encoded input. decodeField
+// returns the number of bytes of the field value it consumed, or a negative number if it is invalid.
+func decodeProtobufFields(in []byte,
+	decodeField func(num protowire.Number, typ protowire.Type, in []byte) (int, error)) error {
+	for len(in) > 0 {
+		num, typ, n := protowire.ConsumeTag(in)
+		if n < 0 {
+			return fmt.Errorf("%w: %s", errInvalidProtobuf, protowire.ParseError(n))
+		}
+		in = in[n:]
+
+		n, err := decodeField(num, typ, in)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %s", errInvalidProtobuf, protowire.ParseError(n))
+		}
+		in = in[n:]
+	}
+
+	return nil
+}
+
+func (s *Service) newStateProtocol() *requestResponseProtocol {
+	return newRequestResponseProtocol(s.host, requestResponseConfig{
+		protocolIDs:        s.host.protocolIDs(stateID),
+		maxRequestSize:     maxStateRequestSize,
+		maxResponseSize:    maxStateResponseSize,
+		requestTimeout:     stateRequestTimeout,
+		maxInFlightPerPeer: maxStateRequestsPerPeer,
+		maxInboundQueue:    maxInboundStateRequests,
+		decodeRequest:      decodeStateRequest,
+		handleRequest:      s.handleStateRequest,
+	})
+}
+
+// DoStateRequest sends a state request to the given peer and returns its response.
+func (s *Service) DoStateRequest(to peer.ID, req *StateRequest) (*StateResponse, error) {
+	unprotect := s.host.cm.protectRequest(to, syncingTag)
+	defer unprotect()
+
+	resp := new(StateResponse)
+	if err := s.stateProtocol.do(s.ctx, to, req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func decodeStateRequest(in []byte) (Message, error) {
+	msg := new(StateRequest)
+	err := msg.Decode(in)
+	return msg, err
+}
+
+// handleStateRequest handles inbound requests of the <protocol-id>/state/2 protocol
+func (s *Service) handleStateRequest(_ peer.ID, msg Message) (Message, error) {
+	req, ok := msg.(*StateRequest)
+	if !ok {
+		return nil, errMessageTypeNotValid
+	}
+
+	resp, err := s.syncer.CreateStateResponse(req)
+	if err != nil {
+		logger.Debugf("cannot create response for state request: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
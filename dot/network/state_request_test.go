@@ -0,0 +1,98 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeStateRequest(t *testing.T) {
+	block, err := common.HexToHash("0xdcd1346701ca8396496e52aa2785b1748deb6db09551b72159dcb3e08991025b")
+	require.NoError(t, err)
+
+	expected := common.MustHexToBytes("0x0a20dcd1346701ca8396496e52aa2785b1748deb6db09551b72159dcb3e08991025b" +
+		"1202abcd1801")
+
+	req := &StateRequest{
+		Block:   block,
+		Start:   [][]byte{{0xab, 0xcd}},
+		NoProof: true,
+	}
+
+	enc, err := req.Encode()
+	require.NoError(t, err)
+	require.Equal(t, expected, enc)
+
+	res := new(StateRequest)
+	err = res.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, req, res)
+}
+
+func TestDecodeStateRequest_Invalid(t *testing.T) {
+	// the block hash is too short
+	err := new(StateRequest).Decode(common.MustHexToBytes("0x0a02abcd"))
+	require.ErrorIs(t, err, errInvalidProtobuf)
+
+	// the start key is truncated
+	err = new(StateRequest).Decode(common.MustHexToBytes("0x1204ab"))
+	require.ErrorIs(t, err, errInvalidProtobuf)
+}
+
+func TestEncodeStateResponse(t *testing.T) {
+	expected := common.MustHexToBytes("0x0a0a12060a0101120102180112020304")
+
+	resp := &StateResponse{
+		Entries: []KeyValueStateEntry{{
+			Entries: []StateEntry{{
+				Key:   []byte{1},
+				Value: []byte{2},
+			}},
+			Complete: true,
+		}},
+		Proof: []byte{3, 4},
+	}
+
+	enc, err := resp.Encode()
+	require.NoError(t, err)
+	require.Equal(t, expected, enc)
+
+	res := new(StateResponse)
+	err = res.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, resp, res)
+}
+
+func TestDecodeStateResponse_ChildTrie(t *testing.T) {
+	resp := &StateResponse{
+		Entries: []KeyValueStateEntry{
+			{
+				Entries: []StateEntry{
+					{Key: []byte("a"), Value: []byte("1")},
+					{Key: []byte("b"), Value: []byte("2")},
+				},
+			},
+			{
+				StateRoot: []byte{0xde, 0xad},
+				Entries: []StateEntry{
+					{Key: []byte("c"), Value: []byte("3")},
+				},
+				Complete: true,
+			},
+		},
+	}
+
+	enc, err := resp.Encode()
+	require.NoError(t, err)
+
+	res := new(StateResponse)
+	err = res.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, resp, res)
+	require.Equal(t, "StateResponse Tries=2 Entries=3 ProofLen=0", res.String())
+}
@@ -6,16 +6,14 @@ package network
 import (
 	"testing"
 
-	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/stretchr/testify/require"
 )
 
-func TestDecodeSyncMessage(t *testing.T) {
-	testPeer := peer.ID("noot")
+func TestDecodeBlockRequest(t *testing.T) {
 	reqEnc, err := testBlockRequestMessage.Encode()
 	require.NoError(t, err)
 
-	msg, err := decodeSyncMessage(reqEnc, testPeer, true)
+	msg, err := decodeBlockRequest(reqEnc)
 	require.NoError(t, err)
 
 	req, ok := msg.(*BlockRequestMessage)
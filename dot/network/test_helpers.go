@@ -65,6 +65,8 @@ func NewMockSyncer() *MockSyncer {
 		On("CreateBlockResponse",
 			mock.AnythingOfType("*network.BlockRequestMessage")).
 		Return(testBlockResponseMessage(), nil)
+	mocksyncer.
+		On("HandlePeerDisconnect", mock.AnythingOfType("peer.ID"))
 	mocksyncer.
 		On("IsSynced").Return(false)
 	return mocksyncer
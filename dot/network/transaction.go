@@ -6,10 +6,13 @@ package network
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/pkg/scale"
@@ -20,8 +23,23 @@ var (
 	_ NotificationsMessage = &transactionHandshake{}
 )
 
-// txnBatchChTimeout is the timeout for adding a transaction to the batch processing channel
-const txnBatchChTimeout = time.Millisecond * 200
+const (
+	// txnBatchChTimeout is the timeout for adding a transaction to the batch processing channel
+	txnBatchChTimeout = time.Millisecond * 200
+
+	// maxKnownTransactions is the maximum number of transaction hashes remembered for each peer
+	maxKnownTransactions = 10240
+
+	// maxTransactionMessageSize is the maximum size in bytes of the transactions gossiped in a single message
+	maxTransactionMessageSize = 1024 * 1024
+
+	// maxPendingTransactions is the maximum number of transactions waiting to be gossiped,
+	// the oldest are dropped first
+	maxPendingTransactions = 8192
+)
+
+// transactionGossipInterval is the interval at which pending transactions are gossiped to our peers
+var transactionGossipInterval = time.Second * 3
 
 // TransactionMessage is a network message that is sent to notify of new transactions entering the network
 type TransactionMessage struct {
@@ -111,7 +129,7 @@ func decodeTransactionHandshake(_ []byte) (Handshake, error) {
 }
 
 func (s *Service) startTxnBatchProcessing(txnBatchCh chan *BatchMessage) {
-	protocolID := s.host.protocolID + transactionsID
+	protocolIDs := s.host.protocolIDs(transactionsID)
 	ticker := time.NewTicker(s.cfg.SlotDuration)
 	defer ticker.Stop()
 
@@ -129,7 +147,7 @@ func (s *Service) startTxnBatchProcessing(txnBatchCh chan *BatchMessage) {
 				case txnMsg := <-txnBatchCh:
 					propagate, err := s.handleTransactionMessage(txnMsg.peer, txnMsg.msg)
 					if err != nil {
-						s.host.closeProtocolStream(protocolID, txnMsg.peer)
+						s.host.closeProtocolStream(protocolIDs, txnMsg.peer)
 						continue
 					}
 
@@ -137,9 +155,7 @@ func (s *Service) startTxnBatchProcessing(txnBatchCh chan *BatchMessage) {
 						continue
 					}
 
-					if !s.gossip.hasSeen(txnMsg.msg) {
-						s.broadcastExcluding(s.notificationsProtocols[TransactionMsgType], txnMsg.peer, txnMsg.msg)
-					}
+					s.queueTransactions(txnMsg.msg.(*TransactionMessage).Extrinsics...)
 				}
 			}
 		}
@@ -178,11 +194,159 @@ func decodeTransactionMessage(in []byte) (NotificationsMessage, error) {
 	return msg, err
 }
 
+// handleTransactionMessage passes the transactions of the message the peer didn't already send us
+// to the transaction handler, which leaves the ones to propagate in the message.
 func (s *Service) handleTransactionMessage(peerID peer.ID, msg NotificationsMessage) (bool, error) {
 	txMsg, ok := msg.(*TransactionMessage)
 	if !ok {
 		return false, errors.New("invalid transaction type")
 	}
 
+	txMsg.Extrinsics = s.knownTxns.filterAndAdd(peerID, txMsg.Extrinsics)
+	if len(txMsg.Extrinsics) == 0 {
+		s.host.reportPeer(peerset.ReputationChange{
+			Value:  peerset.DuplicateGossipValue,
+			Reason: peerset.DuplicateGossipReason,
+		}, peerID)
+		return false, nil
+	}
+
 	return s.transactionHandler.HandleTransactionMessage(peerID, txMsg)
 }
+
+// queueTransactions adds transactions to be gossiped to our peers on the next gossip interval.
+// If there are too many pending transactions, the oldest are dropped.
+func (s *Service) queueTransactions(exts ...types.Extrinsic) {
+	s.pendingTxnsMu.Lock()
+	defer s.pendingTxnsMu.Unlock()
+
+	s.pendingTxns = append(s.pendingTxns, exts...)
+	if dropped := len(s.pendingTxns) - maxPendingTransactions; dropped > 0 {
+		logger.Debugf("dropping %d pending transactions, too many transactions to gossip", dropped)
+		s.pendingTxns = append([]types.Extrinsic(nil), s.pendingTxns[dropped:]...)
+	}
+}
+
+// takePendingTransactions removes all the transactions to gossip from the queue
+func (s *Service) takePendingTransactions() []types.Extrinsic {
+	s.pendingTxnsMu.Lock()
+	defer s.pendingTxnsMu.Unlock()
+
+	exts := s.pendingTxns
+	s.pendingTxns = nil
+	return exts
+}
+
+// transactionBatches splits the transactions into the batches gossiped in a single message, each batch
+// is at most maxTransactionMessageSize bytes unless it consists of a single larger transaction
+func transactionBatches(exts []types.Extrinsic) [][]types.Extrinsic {
+	var batches [][]types.Extrinsic
+	for len(exts) > 0 {
+		var size, i int
+		for ; i < len(exts); i++ {
+			size += len(exts[i])
+			if size > maxTransactionMessageSize && i > 0 {
+				break
+			}
+		}
+
+		batches = append(batches, exts[:i])
+		exts = exts[i:]
+	}
+
+	return batches
+}
+
+func (s *Service) startTransactionGossip() {
+	ticker := time.NewTicker(transactionGossipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.gossipTransactions()
+		}
+	}
+}
+
+// gossipTransactions sends all the pending transactions to each of our peers in as many batches as needed,
+// leaving out the transactions a peer is already known to have
+func (s *Service) gossipTransactions() {
+	pending := s.takePendingTransactions()
+	if len(pending) == 0 {
+		return
+	}
+
+	s.notificationsMu.Lock()
+	info, has := s.notificationsProtocols[TransactionMsgType]
+	s.notificationsMu.Unlock()
+	if !has {
+		return
+	}
+
+	hs, err := info.getHandshake()
+	if err != nil {
+		logger.Errorf("failed to get handshake using protocol %s: %s", info.protocolID, err)
+		return
+	}
+
+	for _, p := range s.host.peers() {
+		exts := s.knownTxns.filterAndAdd(p, pending)
+		if len(exts) == 0 {
+			continue
+		}
+
+		go func(p peer.ID) {
+			for _, batch := range transactionBatches(exts) {
+				s.sendData(p, hs, info, &TransactionMessage{Extrinsics: batch})
+			}
+		}(p)
+	}
+}
+
+// knownTransactions keeps track of the transactions each peer is known to have,
+// either because it sent them to us or because we sent them to it
+type knownTransactions struct {
+	sync.Mutex
+	peers map[peer.ID]*lru.Cache // map[peer.ID]lru.Cache[common.Hash]
+}
+
+func newKnownTransactions() *knownTransactions {
+	return &knownTransactions{
+		peers: make(map[peer.ID]*lru.Cache),
+	}
+}
+
+// filterAndAdd returns the transactions not known to the peer yet, and marks them as known
+func (k *knownTransactions) filterAndAdd(p peer.ID, exts []types.Extrinsic) []types.Extrinsic {
+	k.Lock()
+	defer k.Unlock()
+
+	known, has := k.peers[p]
+	if !has {
+		// only fails if the size is not positive
+		known, _ = lru.New(maxKnownTransactions)
+		k.peers[p] = known
+	}
+
+	var unknown []types.Extrinsic
+	for _, ext := range exts {
+		if has, _ := known.ContainsOrAdd(ext.Hash(), struct{}{}); has {
+			continue
+		}
+
+		unknown = append(unknown, ext)
+	}
+
+	return unknown
+}
+
+// remove forgets about the transactions known to the peer
+func (k *knownTransactions) remove(p peer.ID) {
+	k.Lock()
+	defer k.Unlock()
+
+	delete(k.peers, p)
+}
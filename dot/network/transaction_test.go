@@ -53,3 +53,132 @@ func TestHandleTransactionMessage(t *testing.T) {
 	mockhandler.AssertCalled(t, "HandleTransactionMessage",
 		mock.AnythingOfType("peer.ID"), msg)
 }
+
+func TestHandleTransactionMessage_KnownTransactions(t *testing.T) {
+	mockhandler := &MockTransactionHandler{}
+	mockhandler.On("HandleTransactionMessage",
+		mock.AnythingOfType("peer.ID"),
+		mock.AnythingOfType("*network.TransactionMessage")).
+		Return(true, nil)
+	mockhandler.On("TransactionsCount").Return(0)
+
+	config := &Config{
+		BasePath:           utils.NewTestBasePath(t, "nodeA"),
+		Port:               7001,
+		NoBootstrap:        true,
+		NoMDNS:             true,
+		TransactionHandler: mockhandler,
+	}
+
+	s := createTestService(t, config)
+	testPeer := peer.ID("noot")
+
+	msg := &TransactionMessage{
+		Extrinsics: []types.Extrinsic{{1, 1}, {2, 2}},
+	}
+	propagate, err := s.handleTransactionMessage(testPeer, msg)
+	require.NoError(t, err)
+	require.True(t, propagate)
+
+	// only the transaction the peer didn't send before is handled
+	msg = &TransactionMessage{
+		Extrinsics: []types.Extrinsic{{1, 1}, {3, 3}},
+	}
+	propagate, err = s.handleTransactionMessage(testPeer, msg)
+	require.NoError(t, err)
+	require.True(t, propagate)
+	mockhandler.AssertCalled(t, "HandleTransactionMessage", testPeer,
+		&TransactionMessage{Extrinsics: []types.Extrinsic{{3, 3}}})
+
+	// the same transactions from another peer are handled
+	msg = &TransactionMessage{
+		Extrinsics: []types.Extrinsic{{1, 1}, {2, 2}},
+	}
+	propagate, err = s.handleTransactionMessage(peer.ID("other"), msg)
+	require.NoError(t, err)
+	require.True(t, propagate)
+
+	// a message with only known transactions isn't handled
+	msg = &TransactionMessage{
+		Extrinsics: []types.Extrinsic{{2, 2}, {3, 3}},
+	}
+	propagate, err = s.handleTransactionMessage(testPeer, msg)
+	require.NoError(t, err)
+	require.False(t, propagate)
+	mockhandler.AssertNumberOfCalls(t, "HandleTransactionMessage", 3)
+}
+
+func TestKnownTransactions(t *testing.T) {
+	known := newKnownTransactions()
+	peerA, peerB := peer.ID("a"), peer.ID("b")
+
+	exts := []types.Extrinsic{{1}, {2}}
+	require.Equal(t, exts, known.filterAndAdd(peerA, exts))
+	require.Empty(t, known.filterAndAdd(peerA, exts))
+	require.Equal(t, []types.Extrinsic{{3}}, known.filterAndAdd(peerA, []types.Extrinsic{{1}, {3}}))
+	require.Equal(t, exts, known.filterAndAdd(peerB, exts))
+
+	known.remove(peerA)
+	require.Equal(t, exts, known.filterAndAdd(peerA, exts))
+}
+
+func TestKnownTransactions_Bounded(t *testing.T) {
+	known := newKnownTransactions()
+	p := peer.ID("noot")
+
+	first := types.Extrinsic{0, 0, 0, 0}
+	require.Len(t, known.filterAndAdd(p, []types.Extrinsic{first}), 1)
+
+	exts := make([]types.Extrinsic, maxKnownTransactions)
+	for i := range exts {
+		exts[i] = types.Extrinsic{1, byte(i >> 16), byte(i >> 8), byte(i)}
+	}
+	require.Len(t, known.filterAndAdd(p, exts), maxKnownTransactions)
+
+	// the oldest transaction was evicted
+	require.Len(t, known.filterAndAdd(p, []types.Extrinsic{first}), 1)
+}
+
+func TestTransactionBatches(t *testing.T) {
+	require.Empty(t, transactionBatches(nil))
+
+	half := make(types.Extrinsic, maxTransactionMessageSize/2)
+	small := types.Extrinsic{1}
+	require.Equal(t, [][]types.Extrinsic{{half, half}, {small}}, transactionBatches([]types.Extrinsic{half, half, small}))
+
+	// a single transaction larger than the maximum size is still gossiped
+	large := make(types.Extrinsic, maxTransactionMessageSize+1)
+	require.Equal(t, [][]types.Extrinsic{{large}, {small}}, transactionBatches([]types.Extrinsic{large, small}))
+}
+
+func TestQueueTransactions_Bounded(t *testing.T) {
+	s := &Service{}
+	require.Empty(t, s.takePendingTransactions())
+
+	exts := make([]types.Extrinsic, maxPendingTransactions+2)
+	for i := range exts {
+		exts[i] = types.Extrinsic{byte(i >> 16), byte(i >> 8), byte(i)}
+	}
+	s.queueTransactions(exts[:2]...)
+	s.queueTransactions(exts[2:]...)
+
+	// the oldest transactions were dropped
+	require.Equal(t, exts[2:], s.takePendingTransactions())
+	require.Empty(t, s.takePendingTransactions())
+}
+
+func TestGossipMessage_QueuesTransactions(t *testing.T) {
+	config := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeA"),
+		Port:        7001,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+
+	s := createTestService(t, config)
+
+	exts := []types.Extrinsic{{1, 1}, {2, 2}}
+	s.GossipMessage(&TransactionMessage{Extrinsics: exts[:1]})
+	s.GossipMessage(&TransactionMessage{Extrinsics: exts[1:]})
+	require.Equal(t, exts, s.takePendingTransactions())
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/libp2p/go-libp2p"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// quicTransport is the libp2p option enabling the QUIC transport.
+// It is only set when building with the `quic` build tag, since the QUIC
+// implementation used by our libp2p version (quic-go v0.21) only builds
+// with Go 1.16 and 1.17.
+var quicTransport libp2p.Option
+
+// parseListenAddrs parses the given strings into multiaddrs, and checks that
+// each of them uses a supported transport (TCP, WebSocket or QUIC).
+func parseListenAddrs(addrs []string) ([]ma.Multiaddr, error) {
+	maddrs := make([]ma.Multiaddr, len(addrs))
+	for i, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %s: %w", addr, err)
+		}
+
+		if !isQUICAddr(maddr) && !isWSAddr(maddr) && !isTCPAddr(maddr) {
+			return nil, fmt.Errorf("%w: %s", errUnsupportedTransport, addr)
+		}
+
+		maddrs[i] = maddr
+	}
+
+	return maddrs, nil
+}
+
+// transportOptions returns the libp2p options required to listen on the given addresses.
+// The libp2p default transports (TCP and WebSocket) are used unless a QUIC address is given.
+func transportOptions(addrs []ma.Multiaddr) ([]libp2p.Option, error) {
+	needsQUIC := false
+	for _, addr := range addrs {
+		if isQUICAddr(addr) {
+			needsQUIC = true
+		}
+	}
+
+	if !needsQUIC {
+		return nil, nil
+	}
+
+	if quicTransport == nil {
+		return nil, errQUICNotSupported
+	}
+
+	return []libp2p.Option{libp2p.DefaultTransports, quicTransport}, nil
+}
+
+// publicAddrs returns the given listen addresses with their IP address replaced by the given public IP,
+// so that each of the transports we listen on is advertised to our peers.
+func publicAddrs(listenAddrs []ma.Multiaddr, ip net.IP) ([]ma.Multiaddr, error) {
+	ipAddr, err := manet.FromIP(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]ma.Multiaddr, 0, len(listenAddrs))
+	for _, addr := range listenAddrs {
+		_, rest := ma.SplitFirst(addr)
+		if rest == nil {
+			continue
+		}
+
+		addrs = append(addrs, ipAddr.Encapsulate(rest))
+	}
+
+	return addrs, nil
+}
+
+func isQUICAddr(addr ma.Multiaddr) bool {
+	return hasProtocol(addr, ma.P_QUIC)
+}
+
+func isWSAddr(addr ma.Multiaddr) bool {
+	return hasProtocol(addr, ma.P_TCP) && hasProtocol(addr, ma.P_WS)
+}
+
+func isTCPAddr(addr ma.Multiaddr) bool {
+	return hasProtocol(addr, ma.P_TCP)
+}
+
+func hasProtocol(addr ma.Multiaddr, code int) bool {
+	_, err := addr.ValueForProtocol(code)
+	return err == nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+//go:build quic
+// +build quic
+
+package network
+
+import (
+	"github.com/libp2p/go-libp2p"
+	quic "github.com/libp2p/go-libp2p-quic-transport"
+)
+
+func init() {
+	quicTransport = libp2p.Transport(quic.NewTransport)
+}
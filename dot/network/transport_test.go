@@ -0,0 +1,120 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/lib/utils"
+)
+
+func TestParseListenAddrs(t *testing.T) {
+	addrs, err := parseListenAddrs([]string{
+		"/ip4/0.0.0.0/tcp/7001",
+		"/ip4/0.0.0.0/tcp/7002/ws",
+		"/ip4/0.0.0.0/udp/7003/quic",
+	})
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+
+	require.True(t, isTCPAddr(addrs[0]))
+	require.False(t, isWSAddr(addrs[0]))
+	require.True(t, isWSAddr(addrs[1]))
+	require.True(t, isQUICAddr(addrs[2]))
+
+	_, err = parseListenAddrs([]string{"/ip4/0.0.0.0/udp/7003"})
+	require.True(t, errors.Is(err, errUnsupportedTransport))
+
+	_, err = parseListenAddrs([]string{"not-a-multiaddr"})
+	require.Error(t, err)
+}
+
+func TestTransportOptions(t *testing.T) {
+	addrs, err := parseListenAddrs([]string{"/ip4/0.0.0.0/tcp/7001", "/ip4/0.0.0.0/tcp/7002/ws"})
+	require.NoError(t, err)
+
+	opts, err := transportOptions(addrs)
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	addrs, err = parseListenAddrs([]string{"/ip4/0.0.0.0/udp/7003/quic"})
+	require.NoError(t, err)
+
+	opts, err = transportOptions(addrs)
+	if quicTransport == nil {
+		require.Equal(t, errQUICNotSupported, err)
+		return
+	}
+
+	require.NoError(t, err)
+	require.Len(t, opts, 2)
+}
+
+func TestPublicAddrs(t *testing.T) {
+	addrs, err := parseListenAddrs([]string{
+		"/ip4/0.0.0.0/tcp/7001",
+		"/ip4/0.0.0.0/tcp/7002/ws",
+		"/ip4/0.0.0.0/udp/7003/quic",
+	})
+	require.NoError(t, err)
+
+	public, err := publicAddrs(addrs, net.ParseIP("1.2.3.4"))
+	require.NoError(t, err)
+
+	expected := []string{
+		"/ip4/1.2.3.4/tcp/7001",
+		"/ip4/1.2.3.4/tcp/7002/ws",
+		"/ip4/1.2.3.4/udp/7003/quic",
+	}
+	require.Len(t, public, len(expected))
+	for i, addr := range public {
+		require.Equal(t, expected[i], addr.String())
+	}
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	configA := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeA"),
+		Port:        7001,
+		ListenAddrs: []string{"/ip4/127.0.0.1/tcp/7001", "/ip4/127.0.0.1/tcp/7002/ws"},
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+	nodeA := createTestService(t, configA)
+	nodeA.noGossip = true
+
+	var wsAddr ma.Multiaddr
+	for _, addr := range nodeA.host.multiaddrs() {
+		if isWSAddr(addr) {
+			wsAddr = addr
+		}
+	}
+	require.NotNil(t, wsAddr)
+
+	configB := &Config{
+		BasePath:    utils.NewTestBasePath(t, "nodeB"),
+		Port:        7003,
+		RandSeed:    2,
+		NoBootstrap: true,
+		NoMDNS:      true,
+	}
+	nodeB := createTestService(t, configB)
+	nodeB.noGossip = true
+
+	addrInfo, err := peer.AddrInfoFromP2pAddr(wsAddr)
+	require.NoError(t, err)
+
+	err = nodeB.host.connect(*addrInfo)
+	require.NoError(t, err)
+
+	conns := nodeB.host.h.Network().ConnsToPeer(nodeA.host.id())
+	require.NotEmpty(t, conns)
+	require.True(t, isWSAddr(conns[0].RemoteMultiaddr()))
+}
@@ -196,12 +196,7 @@ func readStream(stream libp2pnetwork.Stream, buf []byte) (int, error) {
 
 	if length > uint64(len(buf)) {
 		logger.Warnf("received message with size %d greater than allocated message buffer size %d", length, len(buf))
-		return 0, fmt.Errorf("message size greater than allocated message buffer: got %d", length)
-	}
-
-	if length > maxBlockResponseSize {
-		logger.Warnf("received message with size %d greater than maxBlockResponseSize, closing stream", length)
-		return 0, fmt.Errorf("message size greater than maximum: got %d", length)
+		return 0, fmt.Errorf("%w: got %d, maximum is %d", errMessageTooLarge, length, len(buf))
 	}
 
 	tot = 0
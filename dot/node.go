@@ -28,6 +28,7 @@ import (
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/services"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/lib/utils"
 )
 
@@ -49,30 +50,9 @@ func InitNode(cfg *Config) error {
 		"🕸️ initialising node with name %s, id %s, base path %s and genesis %s...",
 		cfg.Global.Name, cfg.Global.ID, cfg.Global.BasePath, cfg.Init.Genesis)
 
-	// create genesis from configuration file
-	gen, err := genesis.NewGenesisFromJSONRaw(cfg.Init.Genesis)
-	if err != nil {
-		return fmt.Errorf("failed to load genesis from file: %w", err)
-	}
-
-	if !gen.IsRaw() {
-		// genesis is human-readable, convert to raw
-		err = gen.ToRaw()
-		if err != nil {
-			return fmt.Errorf("failed to convert genesis-spec to raw genesis: %w", err)
-		}
-	}
-
-	// create trie from genesis
-	t, err := genesis.NewTrieFromGenesis(gen)
+	gen, t, header, err := loadGenesis(cfg.Init.Genesis)
 	if err != nil {
-		return fmt.Errorf("failed to create trie from genesis: %w", err)
-	}
-
-	// create genesis block from trie
-	header, err := genesis.NewGenesisBlockFromTrie(t)
-	if err != nil {
-		return fmt.Errorf("failed to create genesis block from trie: %w", err)
+		return err
 	}
 
 	config := state.Config{
@@ -105,6 +85,37 @@ func InitNode(cfg *Config) error {
 	return nil
 }
 
+// loadGenesis loads the genesis data, the genesis trie and the genesis block header from the JSON formatted genesis file
+func loadGenesis(genesisPath string) (*genesis.Genesis, *trie.Trie, *types.Header, error) {
+	// create genesis from configuration file
+	gen, err := genesis.NewGenesisFromJSONRaw(genesisPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load genesis from file: %w", err)
+	}
+
+	if !gen.IsRaw() {
+		// genesis is human-readable, convert to raw
+		err = gen.ToRaw()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert genesis-spec to raw genesis: %w", err)
+		}
+	}
+
+	// create trie from genesis
+	t, err := genesis.NewTrieFromGenesis(gen)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create trie from genesis: %w", err)
+	}
+
+	// create genesis block from trie
+	header, err := genesis.NewGenesisBlockFromTrie(t)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create genesis block from trie: %w", err)
+	}
+
+	return gen, t, header, nil
+}
+
 // NodeInitialized returns true if, within the configured data directory for the
 // node, the state database has been created and the genesis data has been loaded
 func NodeInitialized(basepath string) bool {
@@ -186,6 +197,47 @@ func NewNode(cfg *Config, ks *keystore.GlobalKeystore) (*Node, error) {
 		return nil, ErrNoKeysProvided
 	}
 
+	stateSrvc, err := createStateService(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state service: %s", err)
+	}
+
+	return newNode(cfg, ks, stateSrvc)
+}
+
+// NewInMemoryNode creates a new dot node that keeps its state in an in-memory database.
+// The state is initialised from the genesis file set in the node configuration, so the node
+// doesn't need to be initialised with InitNode beforehand. Nothing is persisted once it stops.
+func NewInMemoryNode(cfg *Config, ks *keystore.GlobalKeystore) (*Node, error) {
+	logger.Patch(log.SetLevel(cfg.Global.LogLvl))
+
+	// if authority node, should have at least 1 key in keystore
+	if cfg.Core.Roles == types.AuthorityRole && (ks.Babe.Size() == 0 || ks.Gran.Size() == 0) {
+		return nil, ErrNoKeysProvided
+	}
+
+	gen, t, header, err := loadGenesis(cfg.Init.Genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	stateSrvc := state.NewService(newStateConfig(cfg))
+	stateSrvc.UseMemDB()
+
+	err = stateSrvc.Initialise(gen, header, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise state service: %s", err)
+	}
+
+	if err = startStateService(cfg, stateSrvc); err != nil {
+		return nil, err
+	}
+
+	return newNode(cfg, ks, stateSrvc)
+}
+
+// newNode creates the services of a dot node on top of the given state service
+func newNode(cfg *Config, ks *keystore.GlobalKeystore, stateSrvc *state.Service) (*Node, error) {
 	logger.Infof(
 		"🕸️ initialising node services with global configuration name %s, id %s and base path %s...",
 		cfg.Global.Name, cfg.Global.ID, cfg.Global.BasePath)
@@ -193,21 +245,17 @@ func NewNode(cfg *Config, ks *keystore.GlobalKeystore) (*Node, error) {
 	var (
 		nodeSrvcs   []services.Service
 		networkSrvc *network.Service
+		err         error
 	)
 
 	if cfg.Pprof.Enabled {
 		nodeSrvcs = append(nodeSrvcs, createPprofService(cfg.Pprof.Settings))
 	}
 
-	stateSrvc, err := createStateService(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create state service: %s", err)
-	}
-
 	// check if network service is enabled
 	if enabled := networkServiceEnabled(cfg); enabled {
 		// create network service and append network service to node services
-		networkSrvc, err = createNetworkService(cfg, stateSrvc)
+		networkSrvc, err = createNetworkService(cfg, stateSrvc, ks.Audi)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create network service: %s", err)
 		}
@@ -251,7 +299,7 @@ func NewNode(cfg *Config, ks *keystore.GlobalKeystore) (*Node, error) {
 	}
 	nodeSrvcs = append(nodeSrvcs, fg)
 
-	syncer, err := newSyncService(cfg, stateSrvc, fg, ver, coreSrvc, networkSrvc)
+	syncer, err := newSyncService(cfg, stateSrvc, fg, ver, coreSrvc, networkSrvc, dh)
 	if err != nil {
 		return nil, err
 	}
@@ -405,6 +453,24 @@ func (n *Node) Stop() {
 func loadRuntime(cfg *Config, ns *runtime.NodeStorage,
 	stateSrvc *state.Service, ks *keystore.GlobalKeystore,
 	net *network.Service) error {
+	// the blocks imported by an unfinished fast sync don't have a state, the runtime of the best block
+	// is created from the state of the block fast sync started from, and fast sync resumes from it
+	fastSyncStart, err := stateSrvc.Block.FastSyncStart()
+	if err != nil {
+		return err
+	}
+
+	if fastSyncStart != nil {
+		root := fastSyncStart.StateRoot
+		code, err := stateSrvc.Storage.LoadCode(&root)
+		if err != nil {
+			return err
+		}
+
+		_, err = createRuntime(cfg, *ns, stateSrvc, ks, net, code, &root)
+		return err
+	}
+
 	blocks := stateSrvc.Block.GetNonFinalisedBlocks()
 	runtimeCode := make(map[string]runtime.Instance)
 	for i := range blocks {
@@ -424,7 +490,7 @@ func loadRuntime(cfg *Config, ns *runtime.NodeStorage,
 			continue
 		}
 
-		rt, err := createRuntime(cfg, *ns, stateSrvc, ks, net, code)
+		rt, err := createRuntime(cfg, *ns, stateSrvc, ks, net, code, nil)
 		if err != nil {
 			return err
 		}
@@ -18,7 +18,6 @@ import (
 	"github.com/ChainSafe/gossamer/lib/grandpa"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/trie"
-	"github.com/ChainSafe/gossamer/lib/utils"
 
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/stretchr/testify/require"
@@ -31,8 +30,6 @@ func TestInitNode(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -46,8 +43,6 @@ func TestInitNode_GenesisSpec(t *testing.T) {
 	genFile := NewTestGenesisFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -62,8 +57,6 @@ func TestNodeInitialized(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	expected := NodeInitialized(cfg.Global.BasePath)
@@ -84,8 +77,6 @@ func TestNewNode(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -115,8 +106,6 @@ func TestNewNode_Authority(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -149,8 +138,6 @@ func TestStartNode(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 	cfg.Core.GrandpaAuthority = false
 	cfg.Core.BABELead = true
@@ -186,8 +173,6 @@ func TestInitNode_LoadGenesisData(t *testing.T) {
 	genPath := NewTestGenesisAndRuntime(t)
 	require.NotNil(t, genPath)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genPath
 	cfg.Core.GrandpaAuthority = false
 
@@ -252,8 +237,6 @@ func TestInitNode_LoadStorageRoot(t *testing.T) {
 	genPath := NewTestGenesisAndRuntime(t)
 	require.NotNil(t, genPath)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -314,8 +297,6 @@ func TestInitNode_LoadBalances(t *testing.T) {
 	genPath := NewTestGenesisAndRuntime(t)
 	require.NotNil(t, genPath)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -366,8 +347,6 @@ func TestNode_PersistGlobalName_WhenInitialize(t *testing.T) {
 	genPath := NewTestGenesisAndRuntime(t)
 	require.NotNil(t, genPath)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
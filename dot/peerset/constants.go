@@ -62,4 +62,29 @@ const (
 	BadJustificationValue Reputation = -(1 << 16)
 	// BadJustificationReason is used when peer send invalid justification.
 	BadJustificationReason = "Bad justification"
+
+	// GoodBlockResponseValue is used when peer answers a block request faster than most of our peers.
+	GoodBlockResponseValue Reputation = 1 << 4
+	// GoodBlockResponseReason is used when peer answers a block request faster than most of our peers.
+	GoodBlockResponseReason = "Good block response"
+
+	// SlowBlockResponseValue is used when peer answers block requests much slower than our other peers.
+	SlowBlockResponseValue Reputation = -(1 << 6)
+	// SlowBlockResponseReason is used when peer answers block requests much slower than our other peers.
+	SlowBlockResponseReason = "Slow block response"
+
+	// BadBlockResponseValue is used when peer answers a block request with blocks that don't form a chain.
+	BadBlockResponseValue Reputation = -(1 << 12)
+	// BadBlockResponseReason is used when peer answers a block request with blocks that don't form a chain.
+	BadBlockResponseReason = "Bad block response"
+
+	// BadBlockValue is used when peer announces or sends us a block on the list of bad blocks, or descending from one.
+	BadBlockValue Reputation = -(1 << 29)
+	// BadBlockReason is used when peer announces or sends us a block on the list of bad blocks, or descending from one.
+	BadBlockReason = "Bad block"
+
+	// OutOfViewMessageValue is used when peer repeatedly sends messages that are outside of our view.
+	OutOfViewMessageValue Reputation = -(1 << 8)
+	// OutOfViewMessageReason is used when peer repeatedly sends messages that are outside of our view.
+	OutOfViewMessageReason = "Out of view message"
 )
@@ -110,6 +110,8 @@ const (
 	Accept
 	// Reject incoming connect request.
 	Reject
+	// Ban the given peer, whose reputation dropped below BannedThresholdValue.
+	Ban
 )
 
 // Message that will be sent by the peerSet.
@@ -119,6 +121,9 @@ type Message struct {
 	setID  uint64
 	// PeerID peer in message.
 	PeerID peer.ID
+	// Reason of the reputation change that made the peer reputation drop below
+	// BannedThresholdValue, only set for Ban messages.
+	Reason string
 }
 
 // Reputation represents reputation value of the node
@@ -331,6 +336,12 @@ func (ps *PeerSet) reportPeer(change ReputationChange, peers ...peer.ID) error {
 			return nil
 		}
 
+		ps.resultMsgCh <- Message{
+			Status: Ban,
+			PeerID: pid,
+			Reason: change.Reason,
+		}
+
 		setLen := ps.peerState.getSetLength()
 		for i := 0; i < setLen; i++ {
 			if ps.peerState.peerStatus(i, pid) == connectedPeer {
@@ -24,11 +24,12 @@ func TestPeerSetBanned(t *testing.T) {
 	require.NoError(t, err)
 
 	// we ban a node by setting its reputation under the threshold.
-	rpc := newReputationChange(BannedThresholdValue-1, "")
+	rpc := newReputationChange(BannedThresholdValue-1, "bad peer")
 	// we need one for the message to be processed.
 	handler.ReportPeer(rpc, peer1)
 	time.Sleep(time.Millisecond * 100)
 
+	require.Equal(t, Message{Status: Ban, PeerID: peer1, Reason: "bad peer"}, <-ps.resultMsgCh)
 	checkMessageStatus(t, <-ps.resultMsgCh, Drop)
 
 	// check that an incoming connection from that node gets refused.
@@ -130,6 +131,7 @@ func TestReAllocAfterBanned(t *testing.T) {
 	handler.ReportPeer(rep, peer1)
 	time.Sleep(time.Millisecond * 100)
 
+	checkMessageStatus(t, <-ps.resultMsgCh, Ban)
 	checkMessageStatus(t, <-ps.resultMsgCh, Drop)
 
 	// Check that an incoming connection from that node gets refused.
@@ -5,6 +5,7 @@ package modules
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ChainSafe/gossamer/dot/core"
 	"github.com/ChainSafe/gossamer/dot/state"
@@ -54,6 +55,7 @@ type BlockAPI interface {
 	RegisterRuntimeUpdatedChannel(ch chan<- runtime.Version) (uint32, error)
 	UnregisterRuntimeUpdatedChannel(id uint32) bool
 	GetRuntime(hash *common.Hash) (runtime.Instance, error)
+	AddBadBlock(hash common.Hash) error
 }
 
 //go:generate mockery --name NetworkAPI --structname NetworkAPI --case underscore --keeptree
@@ -62,6 +64,7 @@ type BlockAPI interface {
 type NetworkAPI interface {
 	Health() common.Health
 	NetworkState() common.NetworkState
+	Bandwidth() common.Bandwidth
 	Peers() []common.PeerInfo
 	NodeRoles() byte
 	Stop() error
@@ -71,6 +74,9 @@ type NetworkAPI interface {
 	StartingBlock() int64
 	AddReservedPeers(addrs ...string) error
 	RemoveReservedPeers(addrs ...string) error
+	BanPeer(id, reason string, duration time.Duration) error
+	UnbanPeer(id string) error
+	BannedPeers() []common.BannedPeer
 }
 
 // BlockProducerAPI is the interface for BlockProducer methods
@@ -71,7 +71,11 @@ func (cs *ChildStateModule) GetKeys(_ *http.Request, req *GetKeysRequest, res *[
 		return err
 	}
 
-	keys := trie.GetKeysWithPrefix(req.Prefix)
+	keys, err := trie.GetKeysWithPrefix(req.Prefix)
+	if err != nil {
+		return err
+	}
+
 	hexKeys := make([]string, len(keys))
 	for idx, k := range keys {
 		hexKeys[idx] = common.BytesToHex(k)
@@ -18,6 +18,20 @@ type BlockAPI struct {
 	mock.Mock
 }
 
+// AddBadBlock provides a mock function with given fields: hash
+func (_m *BlockAPI) AddBadBlock(hash common.Hash) error {
+	ret := _m.Called(hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(common.Hash) error); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // BestBlockHash provides a mock function with given fields:
 func (_m *BlockAPI) BestBlockHash() common.Hash {
 	ret := _m.Called()
@@ -5,6 +5,8 @@ package mocks
 import (
 	common "github.com/ChainSafe/gossamer/lib/common"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // NetworkAPI is an autogenerated mock type for the NetworkAPI type
@@ -32,6 +34,50 @@ func (_m *NetworkAPI) AddReservedPeers(addrs ...string) error {
 	return r0
 }
 
+// BanPeer provides a mock function with given fields: id, reason, duration
+func (_m *NetworkAPI) BanPeer(id string, reason string, duration time.Duration) error {
+	ret := _m.Called(id, reason, duration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) error); ok {
+		r0 = rf(id, reason, duration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Bandwidth provides a mock function with given fields:
+func (_m *NetworkAPI) Bandwidth() common.Bandwidth {
+	ret := _m.Called()
+
+	var r0 common.Bandwidth
+	if rf, ok := ret.Get(0).(func() common.Bandwidth); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(common.Bandwidth)
+	}
+
+	return r0
+}
+
+// BannedPeers provides a mock function with given fields:
+func (_m *NetworkAPI) BannedPeers() []common.BannedPeer {
+	ret := _m.Called()
+
+	var r0 []common.BannedPeer
+	if rf, ok := ret.Get(0).(func() []common.BannedPeer); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]common.BannedPeer)
+		}
+	}
+
+	return r0
+}
+
 // Health provides a mock function with given fields:
 func (_m *NetworkAPI) Health() common.Health {
 	ret := _m.Called()
@@ -179,3 +225,17 @@ func (_m *NetworkAPI) Stop() error {
 
 	return r0
 }
+
+// UnbanPeer provides a mock function with given fields: id
+func (_m *NetworkAPI) UnbanPeer(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -12,6 +12,9 @@ var (
 	UnsafeMethods = []string{
 		"system_addReservedPeer",
 		"system_removeReservedPeer",
+		"system_banPeer",
+		"system_unbanPeer",
+		"system_addBadBlock",
 		"author_submitExtrinsic",
 		"author_removeExtrinsic",
 		"author_insertKey",
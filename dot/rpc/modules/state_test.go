@@ -459,6 +459,7 @@ func TestStateModuleGetRuntimeVersion(t *testing.T) {
 		0,
 		[]runtime.APIItem{testAPIItem},
 		5,
+		0,
 	)
 
 	mockCoreAPI := new(mocks.CoreAPI)
@@ -71,6 +71,7 @@ func NewStateSync(gData *genesis.Data, storageAPI StorageAPI) (SyncStateAPI, err
 	tmpGen.ID = gData.ID
 	tmpGen.Bootnodes = common.BytesToStringArray(gData.Bootnodes)
 	tmpGen.ProtocolID = gData.ProtocolID
+	tmpGen.ForkID = gData.ForkID
 	return syncState{chainSpecification: tmpGen}, nil
 }
 
@@ -6,9 +6,11 @@ package modules
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/crypto"
@@ -40,6 +42,7 @@ type SystemHealthResponse common.Health
 type NetworkStateString struct {
 	PeerID     string
 	Multiaddrs []string
+	Bandwidth  common.Bandwidth
 }
 
 // SystemNetworkStateResponse struct to marshal json
@@ -58,6 +61,21 @@ type StringRequest struct {
 	String string
 }
 
+// BanPeerRequest holds the request of the system_banPeer rpc call
+type BanPeerRequest struct {
+	PeerID string
+	Reason string
+	// Duration of the ban in seconds, defaults to network.DefaultBanDuration
+	Duration uint64
+}
+
+// BannedPeerResponse holds a banned peer returned by the system_bannedPeers rpc call
+type BannedPeerResponse struct {
+	PeerID  string `json:"peerId"`
+	Reason  string `json:"reason"`
+	Expires int64  `json:"expires"`
+}
+
 // SyncStateResponse is the struct to return on the system_syncState rpc call
 type SyncStateResponse struct {
 	CurrentBlock  uint32 `json:"currentBlock"`
@@ -122,6 +140,7 @@ func (sm *SystemModule) NetworkState(r *http.Request, req *EmptyRequest, res *Sy
 	for _, v := range networkState.Multiaddrs {
 		res.NetworkState.Multiaddrs = append(res.NetworkState.Multiaddrs, v.String())
 	}
+	res.NetworkState.Bandwidth = sm.networkAPI.Bandwidth()
 	return nil
 }
 
@@ -286,3 +305,54 @@ func (sm *SystemModule) RemoveReservedPeer(r *http.Request, req *StringRequest,
 
 	return sm.networkAPI.RemoveReservedPeers(req.String)
 }
+
+// BanPeer bans a peer, refusing connections with it until the ban expires. The peer should be given by its PeerId
+func (sm *SystemModule) BanPeer(r *http.Request, req *BanPeerRequest, res *[]byte) error {
+	if strings.TrimSpace(req.PeerID) == "" {
+		return errors.New("cannot ban an empty peer")
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "Banned by RPC"
+	}
+
+	return sm.networkAPI.BanPeer(req.PeerID, reason, time.Duration(req.Duration)*time.Second)
+}
+
+// UnbanPeer removes the ban of a peer. The string should encode only the PeerId
+func (sm *SystemModule) UnbanPeer(r *http.Request, req *StringRequest, res *[]byte) error {
+	if strings.TrimSpace(req.String) == "" {
+		return errors.New("cannot unban an empty peer")
+	}
+
+	return sm.networkAPI.UnbanPeer(req.String)
+}
+
+// BannedPeers returns the currently banned peers, with the reason and expiry (unix timestamp) of their ban
+func (sm *SystemModule) BannedPeers(r *http.Request, req *EmptyRequest, res *[]BannedPeerResponse) error {
+	banned := sm.networkAPI.BannedPeers()
+
+	peers := make([]BannedPeerResponse, len(banned))
+	for i, p := range banned {
+		peers[i] = BannedPeerResponse{
+			PeerID:  p.PeerID,
+			Reason:  p.Reason,
+			Expires: p.Expires.Unix(),
+		}
+	}
+
+	*res = peers
+	return nil
+}
+
+// AddBadBlock adds a block to the bad blocks, which are refused by the node along with their descendants
+// until it's restarted. The string should encode the block hash
+func (sm *SystemModule) AddBadBlock(r *http.Request, req *StringRequest, res *[]byte) error {
+	hash, err := common.HexToBytes(req.String)
+	if err != nil || len(hash) != len(common.Hash{}) {
+		return fmt.Errorf("invalid block hash %q", req.String)
+	}
+
+	return sm.blockAPI.AddBadBlock(common.NewHash(hash))
+}
@@ -5,8 +5,10 @@ package modules
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/ChainSafe/gossamer/dot/rpc/modules/mocks"
 	testdata "github.com/ChainSafe/gossamer/dot/rpc/modules/test_data"
@@ -105,6 +107,7 @@ func TestSystemModule_HealthTest(t *testing.T) {
 func TestSystemModule_NetworkStateTest(t *testing.T) {
 	mockNetworkAPI := new(mocks.NetworkAPI)
 	mockNetworkAPI.On("NetworkState").Return(common.NetworkState{}, nil)
+	mockNetworkAPI.On("Bandwidth").Return(common.Bandwidth{})
 	sm := NewSystemModule(mockNetworkAPI, new(mocks.SystemAPI), new(mocks.CoreAPI),
 		new(mocks.StorageAPI), new(mocks.TransactionStateAPI), new(mocks.BlockAPI))
 
@@ -115,6 +118,27 @@ func TestSystemModule_NetworkStateTest(t *testing.T) {
 	require.Equal(t, SystemNetworkStateResponse{}, networkStateRes)
 }
 
+func TestSystemModule_NetworkState_Bandwidth(t *testing.T) {
+	bw := common.Bandwidth{
+		Total: common.BandwidthStats{TotalIn: 300, TotalOut: 120, RateIn: 30, RateOut: 12},
+		Protocols: map[string]common.BandwidthStats{
+			"sync":    {TotalIn: 200, TotalOut: 20, RateIn: 20, RateOut: 2},
+			"grandpa": {TotalIn: 100, TotalOut: 100, RateIn: 10, RateOut: 10},
+		},
+	}
+
+	mockNetworkAPI := new(mocks.NetworkAPI)
+	mockNetworkAPI.On("NetworkState").Return(common.NetworkState{PeerID: "jimbo"})
+	mockNetworkAPI.On("Bandwidth").Return(bw)
+	sm := NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil)
+
+	var res SystemNetworkStateResponse
+	err := sm.NetworkState(nil, &EmptyRequest{}, &res)
+	require.NoError(t, err)
+	require.Equal(t, "jimbo", res.NetworkState.PeerID)
+	require.Equal(t, bw, res.NetworkState.Bandwidth)
+}
+
 func TestSystemModule_PeersTest(t *testing.T) {
 	mockNetworkAPI := new(mocks.NetworkAPI)
 	mockNetworkAPI.On("Peers").Return([]common.PeerInfo{}, nil)
@@ -601,3 +625,104 @@ func TestSystemModule_RemoveReservedPeer(t *testing.T) {
 		})
 	}
 }
+
+func TestSystemModule_BanPeer(t *testing.T) {
+	mockNetworkAPI := new(mocks.NetworkAPI)
+	mockNetworkAPI.On("BanPeer", "jimbo", "Banned by RPC", time.Duration(0)).Return(nil)
+	mockNetworkAPI.On("BanPeer", "jimbo", "spam", time.Minute).Return(nil)
+
+	mockNetworkAPIErr := new(mocks.NetworkAPI)
+	mockNetworkAPIErr.On("BanPeer", "jimbo", "Banned by RPC", time.Duration(0)).Return(errors.New("banPeer error"))
+
+	tests := []struct {
+		name      string
+		sysModule *SystemModule
+		req       *BanPeerRequest
+		expErr    error
+	}{
+		{
+			name:      "OK",
+			sysModule: NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil),
+			req:       &BanPeerRequest{PeerID: "jimbo"},
+		},
+		{
+			name:      "OK with reason and duration",
+			sysModule: NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil),
+			req:       &BanPeerRequest{PeerID: "jimbo", Reason: "spam", Duration: 60},
+		},
+		{
+			name:      "BanPeer Error",
+			sysModule: NewSystemModule(mockNetworkAPIErr, nil, nil, nil, nil, nil),
+			req:       &BanPeerRequest{PeerID: "jimbo"},
+			expErr:    errors.New("banPeer error"),
+		},
+		{
+			name:      "Empty PeerID Error",
+			sysModule: NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil),
+			req:       &BanPeerRequest{},
+			expErr:    errors.New("cannot ban an empty peer"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := []byte(nil)
+			err := tt.sysModule.BanPeer(nil, tt.req, &res)
+			if tt.expErr != nil {
+				assert.EqualError(t, err, tt.expErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSystemModule_UnbanPeer(t *testing.T) {
+	mockNetworkAPI := new(mocks.NetworkAPI)
+	mockNetworkAPI.On("UnbanPeer", "jimbo").Return(nil)
+
+	sm := NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil)
+
+	res := []byte(nil)
+	err := sm.UnbanPeer(nil, &StringRequest{"jimbo"}, &res)
+	require.NoError(t, err)
+
+	err = sm.UnbanPeer(nil, &StringRequest{""}, &res)
+	require.EqualError(t, err, "cannot unban an empty peer")
+}
+
+func TestSystemModule_BannedPeers(t *testing.T) {
+	expires := time.Unix(1700000000, 0)
+
+	mockNetworkAPI := new(mocks.NetworkAPI)
+	mockNetworkAPI.On("BannedPeers").Return([]common.BannedPeer{
+		{PeerID: "jimbo", Reason: "Bad message", Expires: expires},
+	})
+
+	sm := NewSystemModule(mockNetworkAPI, nil, nil, nil, nil, nil)
+
+	var res []BannedPeerResponse
+	err := sm.BannedPeers(nil, &EmptyRequest{}, &res)
+	require.NoError(t, err)
+	require.Equal(t, []BannedPeerResponse{
+		{PeerID: "jimbo", Reason: "Bad message", Expires: expires.Unix()},
+	}, res)
+}
+
+func TestSystemModule_AddBadBlock(t *testing.T) {
+	hash := common.Hash{0xa, 0xb}
+	mockBlockAPI := new(mocks.BlockAPI)
+	mockBlockAPI.On("AddBadBlock", hash).Return(nil)
+
+	sm := NewSystemModule(nil, nil, nil, nil, nil, mockBlockAPI)
+
+	res := []byte(nil)
+	err := sm.AddBadBlock(nil, &StringRequest{hash.String()}, &res)
+	require.NoError(t, err)
+	mockBlockAPI.AssertCalled(t, "AddBadBlock", hash)
+
+	for _, invalid := range []string{"", "0x0a0b", hash.String()[2:]} {
+		err = sm.AddBadBlock(nil, &StringRequest{invalid}, &res)
+		require.EqualError(t, err, fmt.Sprintf("invalid block hash %q", invalid))
+	}
+	mockBlockAPI.AssertNumberOfCalls(t, "AddBadBlock", 1)
+}
@@ -45,27 +45,62 @@ func newInMemoryDB(path string) (chaindb.Database, error) {
 func createStateService(cfg *Config) (*state.Service, error) {
 	logger.Debug("creating state service...")
 
-	config := state.Config{
-		Path:     cfg.Global.BasePath,
-		LogLevel: cfg.Log.StateLvl,
+	stateSrvc := state.NewService(newStateConfig(cfg))
+	if err := startStateService(cfg, stateSrvc); err != nil {
+		return nil, err
 	}
 
-	stateSrvc := state.NewService(config)
+	return stateSrvc, nil
+}
+
+// newStateConfig returns the configuration of the state service
+func newStateConfig(cfg *Config) state.Config {
+	return state.Config{
+		Path:             cfg.Global.BasePath,
+		LogLevel:         cfg.Log.StateLvl,
+		TrieCacheSize:    cfg.State.TrieCacheSize,
+		StorageCacheSize: cfg.State.StorageCacheSize,
+	}
+}
 
+// startStateService starts the state service, rewinds it if configured to, and refuses the bad blocks of the
+// genesis and of the configuration
+func startStateService(cfg *Config, stateSrvc *state.Service) error {
 	// start state service (initialise state database)
 	err := stateSrvc.Start()
 	if err != nil {
-		return nil, fmt.Errorf("failed to start state service: %s", err)
+		return fmt.Errorf("failed to start state service: %s", err)
 	}
 
 	if cfg.State.Rewind != 0 {
 		err = stateSrvc.Rewind(int64(cfg.State.Rewind))
 		if err != nil {
-			return nil, fmt.Errorf("failed to rewind state: %w", err)
+			return fmt.Errorf("failed to rewind state: %w", err)
 		}
 	}
 
-	return stateSrvc, nil
+	genesisData, err := stateSrvc.Base.LoadGenesisData()
+	if err != nil {
+		return fmt.Errorf("failed to load genesis data: %w", err)
+	}
+
+	badBlocks, err := genesisData.BadBlockHashes()
+	if err != nil {
+		return err
+	}
+
+	badBlocks = append(badBlocks, cfg.State.BadBlocks...)
+	for _, hash := range badBlocks {
+		if err = stateSrvc.Block.AddBadBlock(hash); err != nil {
+			return fmt.Errorf("failed to add bad block %s: %w", hash, err)
+		}
+	}
+
+	if len(badBlocks) > 0 {
+		logger.Infof("refusing %d bad blocks and their descendants", len(badBlocks))
+	}
+
+	return nil
 }
 
 func createRuntimeStorage(st *state.Service) (*runtime.NodeStorage, error) {
@@ -81,8 +116,10 @@ func createRuntimeStorage(st *state.Service) (*runtime.NodeStorage, error) {
 	}, nil
 }
 
+// createRuntime creates the runtime of the best block with the given code. Its storage is the state with the
+// given root, or the state of the best block if root is nil.
 func createRuntime(cfg *Config, ns runtime.NodeStorage, st *state.Service,
-	ks *keystore.GlobalKeystore, net *network.Service, code []byte) (
+	ks *keystore.GlobalKeystore, net *network.Service, code []byte, root *common.Hash) (
 	runtime.Instance, error) {
 	logger.Info("creating runtime with interpreter " + cfg.Core.WasmInterpreter + "...")
 
@@ -100,12 +137,12 @@ func createRuntime(cfg *Config, ns runtime.NodeStorage, st *state.Service,
 		code = common.MustHexToBytes(codeString)
 	}
 
-	ts, err := st.Storage.TrieState(nil)
+	ts, err := st.Storage.TrieState(root)
 	if err != nil {
 		return nil, err
 	}
 
-	codeHash, err := st.Storage.LoadCodeHash(nil)
+	codeHash, err := st.Storage.LoadCodeHash(root)
 	if err != nil {
 		return nil, err
 	}
@@ -246,7 +283,7 @@ func createCoreService(cfg *Config, ks *keystore.GlobalKeystore,
 // Network Service
 
 // createNetworkService creates a network service from the command configuration and genesis data
-func createNetworkService(cfg *Config, stateSrvc *state.Service) (*network.Service, error) {
+func createNetworkService(cfg *Config, stateSrvc *state.Service, ks keystore.Keystore) (*network.Service, error) {
 	logger.Debugf(
 		"creating network service with roles %d, port %d, bootnodes %s, protocol ID %s, nobootstrap=%t and noMDNS=%t...",
 		cfg.Core.Roles, cfg.Network.Port, strings.Join(cfg.Network.Bootnodes, ","), cfg.Network.ProtocolID,
@@ -264,8 +301,10 @@ func createNetworkService(cfg *Config, stateSrvc *state.Service) (*network.Servi
 		BasePath:          cfg.Global.BasePath,
 		Roles:             cfg.Core.Roles,
 		Port:              cfg.Network.Port,
+		ListenAddrs:       cfg.Network.ListenAddrs,
 		Bootnodes:         cfg.Network.Bootnodes,
 		ProtocolID:        cfg.Network.ProtocolID,
+		ForkID:            cfg.Network.ForkID,
 		NoBootstrap:       cfg.Network.NoBootstrap,
 		NoMDNS:            cfg.Network.NoMDNS,
 		MinPeers:          cfg.Network.MinPeers,
@@ -275,6 +314,11 @@ func createNetworkService(cfg *Config, stateSrvc *state.Service) (*network.Servi
 		DiscoveryInterval: cfg.Network.DiscoveryInterval,
 		SlotDuration:      slotDuration,
 		PublicIP:          cfg.Network.PublicIP,
+		BandwidthLimits:   cfg.Network.BandwidthLimits,
+		SwarmKey:          cfg.Network.SwarmKey,
+		NewHost:           cfg.Network.NewHost,
+
+		AuthorityDiscoveryKeystore: ks,
 	}
 
 	networkSrvc, err := network.NewService(&networkConfig)
@@ -401,7 +445,7 @@ func createBlockVerifier(st *state.Service) (*babe.VerificationManager, error) {
 }
 
 func newSyncService(cfg *Config, st *state.Service, fg sync.FinalityGadget,
-	verifier *babe.VerificationManager, cs *core.Service, net *network.Service) (
+	verifier *babe.VerificationManager, cs *core.Service, net *network.Service, dh *digest.Handler) (
 	*sync.Service, error) {
 	slotDuration, err := st.Epoch.GetSlotDuration()
 	if err != nil {
@@ -413,13 +457,16 @@ func newSyncService(cfg *Config, st *state.Service, fg sync.FinalityGadget,
 		Network:            net,
 		BlockState:         st.Block,
 		StorageState:       st.Storage,
+		GrandpaState:       st.Grandpa,
 		TransactionState:   st.Transaction,
 		FinalityGadget:     fg,
 		BabeVerifier:       verifier,
 		BlockImportHandler: cs,
+		DigestHandler:      dh,
 		MinPeers:           cfg.Network.MinPeers,
 		MaxPeers:           cfg.Network.MaxPeers,
 		SlotDuration:       slotDuration,
+		Mode:               cfg.Global.SyncMode,
 	}
 
 	return sync.NewService(syncCfg)
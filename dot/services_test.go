@@ -14,7 +14,6 @@ import (
 	"github.com/ChainSafe/gossamer/internal/pprof"
 	"github.com/ChainSafe/gossamer/lib/grandpa"
 	"github.com/ChainSafe/gossamer/lib/keystore"
-	"github.com/ChainSafe/gossamer/lib/utils"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
@@ -28,8 +27,6 @@ func TestCreateStateService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -48,8 +45,6 @@ func TestCreateCoreService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -83,8 +78,6 @@ func TestCreateBlockVerifier(t *testing.T) {
 	genFile := NewTestGenesisFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -104,8 +97,6 @@ func TestCreateSyncService(t *testing.T) {
 	genFile := NewTestGenesisFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -126,7 +117,7 @@ func TestCreateSyncService(t *testing.T) {
 	coreSrvc, err := createCoreService(cfg, ks, stateSrvc, &network.Service{}, dh)
 	require.NoError(t, err)
 
-	_, err = newSyncService(cfg, stateSrvc, &grandpa.Service{}, ver, coreSrvc, &network.Service{})
+	_, err = newSyncService(cfg, stateSrvc, &grandpa.Service{}, ver, coreSrvc, &network.Service{}, nil)
 	require.NoError(t, err)
 }
 
@@ -138,8 +129,6 @@ func TestCreateNetworkService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Init.Genesis = genFile.Name()
 
 	err := InitNode(cfg)
@@ -148,7 +137,8 @@ func TestCreateNetworkService(t *testing.T) {
 	stateSrvc, err := createStateService(cfg)
 	require.NoError(t, err)
 
-	networkSrvc, err := createNetworkService(cfg, stateSrvc)
+	ks := keystore.NewGlobalKeystore()
+	networkSrvc, err := createNetworkService(cfg, stateSrvc, ks.Audi)
 	require.NoError(t, err)
 	require.NotNil(t, networkSrvc)
 }
@@ -161,8 +151,6 @@ func TestCreateRPCService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -206,8 +194,6 @@ func TestCreateBABEService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Init.Genesis = genFile.Name()
 
@@ -245,8 +231,6 @@ func TestCreateGrandpaService(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.AuthorityRole
 	cfg.Init.Genesis = genFile.Name()
 
@@ -270,7 +254,7 @@ func TestCreateGrandpaService(t *testing.T) {
 	dh, err := createDigestHandler(stateSrvc)
 	require.NoError(t, err)
 
-	networkSrvc, err := createNetworkService(cfg, stateSrvc)
+	networkSrvc, err := createNetworkService(cfg, stateSrvc, ks.Audi)
 	require.NoError(t, err)
 
 	gs, err := createGRANDPAService(cfg, stateSrvc, dh, ks.Gran, networkSrvc)
@@ -312,8 +296,6 @@ func TestNewWebSocketServer(t *testing.T) {
 	genFile := NewTestGenesisRawFile(t, cfg)
 	require.NotNil(t, genFile)
 
-	defer utils.RemoveTestDir(t)
-
 	cfg.Core.Roles = types.FullNodeRole
 	cfg.Core.BabeAuthority = false
 	cfg.Core.GrandpaAuthority = false
@@ -0,0 +1,52 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package dot
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/ChainSafe/gossamer/internal/log"
+)
+
+// ExportStateSnapshot writes the snapshot of the state of the block with the given hash, along with its header,
+// epoch data and GRANDPA set, from the database of the node with the given config to w. If hash is nil, the
+// state of the highest finalised block is exported.
+func ExportStateSnapshot(cfg *Config, hash *common.Hash, w io.Writer) error {
+	srv := state.NewService(state.Config{
+		Path:          cfg.Global.BasePath,
+		LogLevel:      log.Info,
+		TrieCacheSize: cfg.State.TrieCacheSize,
+	})
+
+	if err := srv.Start(); err != nil {
+		return fmt.Errorf("failed to start state service: %w", err)
+	}
+	defer stopService(srv, "state")
+
+	if hash == nil {
+		finalised, err := srv.Block.GetHighestFinalisedHash()
+		if err != nil {
+			return err
+		}
+		hash = &finalised
+	}
+
+	return srv.ExportSnapshot(*hash, w)
+}
+
+// ImportStateSnapshot imports the state snapshot written by ExportStateSnapshot read from r to the database
+// with the given path, once its checksum and state root are verified. The block of the snapshot becomes the
+// highest finalised block of the node.
+func ImportStateSnapshot(basepath string, r io.Reader) error {
+	srv := state.NewService(state.Config{
+		Path:     basepath,
+		LogLevel: log.Info,
+	})
+
+	return srv.ImportSnapshot(r)
+}
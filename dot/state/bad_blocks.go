@@ -0,0 +1,151 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// maxBadBlockDescendants is the number of known descendants of the bad blocks which are remembered,
+// the least recently seen ones are forgotten first
+const maxBadBlockDescendants = 4096
+
+// badBlocks are the blocks which are refused by the node, along with their known descendants
+type badBlocks struct {
+	sync.Mutex
+	// blocks are the bad blocks added to the node, they're always remembered
+	blocks map[common.Hash]struct{}
+	// descendants are the known descendants of the bad blocks, there's no bound to how many peers announce
+	descendants *simplelru.LRU
+}
+
+func newBadBlocks() (*badBlocks, error) {
+	descendants, err := simplelru.NewLRU(maxBadBlockDescendants, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &badBlocks{
+		blocks:      make(map[common.Hash]struct{}),
+		descendants: descendants,
+	}, nil
+}
+
+// hasLocked returns true if the block with the given hash is a bad block or a known descendant of one,
+// without marking it as recently seen
+func (b *badBlocks) hasLocked(hash common.Hash) bool {
+	if _, has := b.blocks[hash]; has {
+		return true
+	}
+
+	return b.descendants.Contains(hash)
+}
+
+// AddBadBlock adds the block with the given hash to the bad blocks, which are refused by the node along
+// with their descendants. If the block was already imported, it is removed from the blocktree and deleted
+// along with its descendants, which are added to the bad blocks too, so that the best block can't be on
+// its fork. A bad block which is already finalised can't be reverted. The bad blocks aren't stored in the
+// database.
+func (bs *BlockState) AddBadBlock(hash common.Hash) error {
+	bs.Lock()
+	defer bs.Unlock()
+
+	bs.badBlocks.Lock()
+	defer bs.badBlocks.Unlock()
+
+	bs.badBlocks.blocks[hash] = struct{}{}
+
+	header, has := bs.getUnfinalisedHeader(hash)
+	if !has {
+		return nil
+	}
+
+	// the blocks up to the finalised block are kept in memory too, but they can't be reverted
+	finalised, err := bs.GetHighestFinalisedHeader()
+	if err != nil {
+		return err
+	}
+
+	if header.Number.Cmp(finalised.Number) <= 0 {
+		logger.Errorf("bad block with hash %s is already finalised", hash)
+		return nil
+	}
+
+	removed, err := bs.bt.RemoveSubtree(hash)
+	if err != nil {
+		return fmt.Errorf("failed to remove bad block %s from the blocktree: %w", hash, err)
+	}
+
+	logger.Warnf("bad block with hash %s was already imported, removed it along with %d descendants",
+		hash, len(removed)-1)
+
+	batch := bs.db.NewBatch()
+	for _, h := range removed {
+		if h != hash {
+			bs.badBlocks.descendants.Add(h, nil)
+		}
+
+		block, has := bs.getAndDeleteUnfinalisedBlock(h)
+		if !has {
+			continue
+		}
+
+		if err = deleteBlock(batch, h); err != nil {
+			return err
+		}
+
+		go func(header *types.Header) {
+			bs.pruneKeyCh <- header
+		}(&block.Header)
+	}
+
+	if err = bs.writeBlockTree(batch); err != nil {
+		return err
+	}
+
+	if err = batch.Flush(); err != nil {
+		return fmt.Errorf("failed to delete bad block %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+// HasBadBlock returns true if the block with the given hash is a bad block, or a known descendant of one
+func (bs *BlockState) HasBadBlock(hash common.Hash) bool {
+	bs.badBlocks.Lock()
+	defer bs.badBlocks.Unlock()
+
+	return bs.badBlocks.hasLocked(hash)
+}
+
+// IsBadBlock returns true if the block with the given header is a bad block, or descends from one. If its
+// parent is a bad block, the block is added to the bad blocks, so that its own descendants are refused too.
+func (bs *BlockState) IsBadBlock(header *types.Header) bool {
+	hash := header.Hash()
+
+	bs.badBlocks.Lock()
+	defer bs.badBlocks.Unlock()
+
+	if _, has := bs.badBlocks.blocks[hash]; has {
+		return true
+	}
+
+	// the descendants which are still seen are kept
+	if _, has := bs.badBlocks.descendants.Get(hash); has {
+		return true
+	}
+
+	if !bs.badBlocks.hasLocked(header.ParentHash) {
+		return false
+	}
+
+	bs.badBlocks.descendants.Add(hash, nil)
+	return true
+}
@@ -22,7 +22,8 @@ func TestTrie_StoreAndLoadFromDB(t *testing.T) {
 	for _, test := range rt {
 		tt.Put(test.Key(), test.Value())
 
-		val := tt.Get(test.Key())
+		val, err := tt.Get(test.Key())
+		require.NoError(t, err)
 		if !bytes.Equal(val, test.Value()) {
 			t.Errorf("Fail to get key %x with value %x: got %x", test.Key(), test.Value(), val)
 		}
@@ -36,6 +36,8 @@ var (
 	receiptPrefix       = []byte("rcp") // receiptPrefix + hash -> receipt
 	messageQueuePrefix  = []byte("mqp") // messageQueuePrefix + hash -> message queue
 	justificationPrefix = []byte("jcp") // justificationPrefix + hash -> justification
+	leavesKey           = []byte("lvs") // leavesKey -> leaves of the blocktree
+	fastSyncStartKey    = []byte("fss") // fastSyncStartKey -> header of the block an unfinished fast sync started from
 
 	errNilBlockBody = errors.New("block body is nil")
 )
@@ -61,11 +63,25 @@ type BlockState struct {
 	runtimeUpdateSubscriptions     map[uint32]chan<- runtime.Version
 
 	pruneKeyCh chan *types.Header
+
+	// forkChoice weighs the blocks of the blocktree to choose the best block, it's kept to rebuild
+	// the blocktree when the chain is rewound
+	forkChoice blocktree.ForkChoice
+
+	// badBlocks are the blocks which are refused by the node, along with their known descendants
+	badBlocks *badBlocks
 }
 
-// NewBlockState will create a new BlockState backed by the database located at basePath
-func NewBlockState(db chaindb.Database) (*BlockState, error) {
+// NewBlockState will create a new BlockState backed by the database located at basePath,
+// whose best block is chosen by the given fork choice
+func NewBlockState(db chaindb.Database, forkChoice blocktree.ForkChoice) (*BlockState, error) {
+	bad, err := newBadBlocks()
+	if err != nil {
+		return nil, err
+	}
+
 	bs := &BlockState{
+		forkChoice:                 forkChoice,
 		dbPath:                     db.Path(),
 		baseState:                  NewBaseState(db),
 		db:                         chaindb.NewTable(db, blockPrefix),
@@ -74,6 +90,7 @@ func NewBlockState(db chaindb.Database) (*BlockState, error) {
 		finalised:                  make(map[chan *types.FinalisationInfo]struct{}),
 		pruneKeyCh:                 make(chan *types.Header, pruneKeyBufferSize),
 		runtimeUpdateSubscriptions: make(map[uint32]chan<- runtime.Version),
+		badBlocks:                  bad,
 	}
 
 	gh, err := bs.db.Get(headerHashKey(0))
@@ -89,15 +106,22 @@ func NewBlockState(db chaindb.Database) (*BlockState, error) {
 
 	bs.genesisHash = genesisHash
 	bs.lastFinalised = header.Hash()
-	bs.bt = blocktree.NewBlockTreeFromRoot(header)
+	bs.bt = blocktree.NewBlockTreeFromRoot(header, forkChoice)
 	return bs, nil
 }
 
 // NewBlockStateFromGenesis initialises a BlockState from a genesis header,
-// saving it to the database located at basePath
-func NewBlockStateFromGenesis(db chaindb.Database, header *types.Header) (*BlockState, error) {
+// saving it to the database located at basePath, whose best block is chosen by the given fork choice
+func NewBlockStateFromGenesis(db chaindb.Database, header *types.Header,
+	forkChoice blocktree.ForkChoice) (*BlockState, error) {
+	bad, err := newBadBlocks()
+	if err != nil {
+		return nil, err
+	}
+
 	bs := &BlockState{
-		bt:                         blocktree.NewBlockTreeFromRoot(header),
+		bt:                         blocktree.NewBlockTreeFromRoot(header, forkChoice),
+		forkChoice:                 forkChoice,
 		baseState:                  NewBaseState(db),
 		db:                         chaindb.NewTable(db, blockPrefix),
 		unfinalisedBlocks:          new(sync.Map),
@@ -105,6 +129,7 @@ func NewBlockStateFromGenesis(db chaindb.Database, header *types.Header) (*Block
 		finalised:                  make(map[chan *types.FinalisationInfo]struct{}),
 		pruneKeyCh:                 make(chan *types.Header, pruneKeyBufferSize),
 		runtimeUpdateSubscriptions: make(map[uint32]chan<- runtime.Version),
+		badBlocks:                  bad,
 		genesisHash:                header.Hash(),
 		lastFinalised:              header.Hash(),
 	}
@@ -396,12 +421,30 @@ func (bs *BlockState) AddBlock(block *types.Block) error {
 
 // AddBlockWithArrivalTime adds a block to the blocktree and the DB with the given arrival time
 func (bs *BlockState) AddBlockWithArrivalTime(block *types.Block, arrivalTime time.Time) error {
+	return bs.addBlockWithBatch(block, arrivalTime, bs.db.NewBatch())
+}
+
+// addBlockWithBatch writes a block to the given batch of the block table, along with the leaves and best
+// block the blocktree has once the block is added, and flushes the batch. The batch may already hold other
+// data of the block, such as its state trie nodes, so that everything is written at once. The block is only
+// added to the blocktree once the batch is written, otherwise the batch is reset.
+func (bs *BlockState) addBlockWithBatch(block *types.Block, arrivalTime time.Time, batch chaindb.Batch) error {
 	if block.Body == nil {
 		return errNilBlockBody
 	}
 
-	// add block to blocktree
-	if err := bs.bt.AddBlock(&block.Header, arrivalTime); err != nil {
+	// the blocktree isn't changed by other writers until the block is added, since they hold the lock
+	leaves, best, err := bs.bt.LeavesWith(&block.Header, arrivalTime)
+	if err != nil {
+		return err
+	}
+
+	if err = writeBlockWithTree(batch, block, arrivalTime, leaves, best); err != nil {
+		batch.Reset()
+		return err
+	}
+
+	if err = bs.bt.AddBlock(&block.Header, arrivalTime); err != nil {
 		return err
 	}
 
@@ -410,6 +453,80 @@ func (bs *BlockState) AddBlockWithArrivalTime(block *types.Block, arrivalTime ti
 	return nil
 }
 
+// writeBlockWithTree writes the block, and the leaves and best block of the blocktree once it's added,
+// then flushes the batch
+func writeBlockWithTree(batch chaindb.Batch, block *types.Block, arrivalTime time.Time,
+	leaves []common.Hash, best common.Hash) error {
+	if err := writeBlock(batch, block, arrivalTime); err != nil {
+		return err
+	}
+
+	if err := writeLeaves(batch, leaves, best); err != nil {
+		return err
+	}
+
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("failed to write block %s: %w", block.Header.Hash(), err)
+	}
+
+	return nil
+}
+
+// writeBlock writes the header, body and arrival time of the block to the batch
+func writeBlock(batch chaindb.Batch, block *types.Block, arrivalTime time.Time) error {
+	hash := block.Header.Hash()
+	header, err := scale.Marshal(block.Header)
+	if err != nil {
+		return err
+	}
+
+	if err = batch.Put(headerKey(hash), header); err != nil {
+		return err
+	}
+
+	body, err := scale.Marshal(block.Body)
+	if err != nil {
+		return err
+	}
+
+	if err = batch.Put(blockBodyKey(hash), body); err != nil {
+		return err
+	}
+
+	return batch.Put(arrivalTimeKey(hash), encodeArrivalTime(arrivalTime))
+}
+
+// deleteBlock deletes the header, body and arrival time of the block from the batch
+func deleteBlock(batch chaindb.Batch, hash common.Hash) error {
+	for _, key := range [][]byte{headerKey(hash), blockBodyKey(hash), arrivalTimeKey(hash)} {
+		if err := batch.Del(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBlockTree writes the leaves and the best block of the blocktree to the batch, the unfinalised
+// blocks are loaded back from them when the node is restarted
+func (bs *BlockState) writeBlockTree(batch chaindb.Batch) error {
+	return writeLeaves(batch, bs.bt.Leaves(), bs.bt.BestBlockHash())
+}
+
+// writeLeaves writes the given leaves and best block of the blocktree to the batch
+func writeLeaves(batch chaindb.Batch, leaves []common.Hash, best common.Hash) error {
+	enc, err := scale.Marshal(leaves)
+	if err != nil {
+		return err
+	}
+
+	if err = batch.Put(leavesKey, enc); err != nil {
+		return err
+	}
+
+	return batch.Put(common.BestBlockHashKey, best[:])
+}
+
 // AddBlockToBlockTree adds the given block to the blocktree. It does not write it to the database.
 // TODO: remove this func and usage from sync (after sync refactor?)
 func (bs *BlockState) AddBlockToBlockTree(block *types.Block) error {
@@ -422,7 +539,16 @@ func (bs *BlockState) AddBlockToBlockTree(block *types.Block) error {
 	}
 
 	bs.storeUnfinalisedBlock(block)
-	return bs.bt.AddBlock(&block.Header, arrivalTime)
+	if err = bs.bt.AddBlock(&block.Header, arrivalTime); err != nil {
+		return err
+	}
+
+	batch := bs.db.NewBatch()
+	if err = bs.writeBlockTree(batch); err != nil {
+		return err
+	}
+
+	return batch.Flush()
 }
 
 // GetAllBlocksAtNumber returns all unfinalised blocks with the given number
@@ -469,7 +595,7 @@ func (bs *BlockState) BestBlockHash() common.Hash {
 		return common.Hash{}
 	}
 
-	return bs.bt.DeepestBlockHash()
+	return bs.bt.BestBlockHash()
 }
 
 // BestBlockHeader returns the block header of the current head of the chain
@@ -573,14 +699,23 @@ func (bs *BlockState) GetArrivalTime(hash common.Hash) (time.Time, error) {
 		return time.Time{}, err
 	}
 
-	ns := binary.LittleEndian.Uint64(arrivalTime)
-	return time.Unix(0, int64(ns)), nil
+	return decodeArrivalTime(arrivalTime), nil
 }
 
 func (bs *BlockState) setArrivalTime(hash common.Hash, arrivalTime time.Time) error {
+	return bs.db.Put(arrivalTimeKey(hash), encodeArrivalTime(arrivalTime))
+}
+
+// encodeArrivalTime encodes the arrival time as the number of nanoseconds since the Unix epoch
+func encodeArrivalTime(arrivalTime time.Time) []byte {
 	buf := make([]byte, 8)
 	binary.LittleEndian.PutUint64(buf, uint64(arrivalTime.UnixNano()))
-	return bs.db.Put(arrivalTimeKey(hash), buf)
+	return buf
+}
+
+func decodeArrivalTime(enc []byte) time.Time {
+	ns := binary.LittleEndian.Uint64(enc)
+	return time.Unix(0, int64(ns))
 }
 
 // HandleRuntimeChanges handles the update in runtime.
@@ -599,7 +734,10 @@ func (bs *BlockState) HandleRuntimeChanges(newState *rtstorage.TrieState,
 
 	logger.Infof("🔄 detected runtime code change, upgrading with block %s from previous code hash %s to new code hash %s...", //nolint:lll
 		bHash, codeHash, currCodeHash)
-	code := newState.LoadCode()
+	code, err := newState.LoadCode()
+	if err != nil {
+		return fmt.Errorf("cannot load new :code: %w", err)
+	}
 	if len(code) == 0 {
 		return errors.New("new :code is empty")
 	}
@@ -0,0 +1,48 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"errors"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// SetFastSyncStart records the header of the block fast sync starts from. The blocks imported by fast sync
+// don't have a state until the state of its target block is downloaded, so if the node is restarted in the
+// meantime, it loads the state of this block instead and fast sync resumes from it.
+// The header itself is stored, as the block may be pruned once fast sync finalises another fork.
+func (bs *BlockState) SetFastSyncStart(header *types.Header) error {
+	enc, err := scale.Marshal(*header)
+	if err != nil {
+		return err
+	}
+
+	return bs.db.Put(fastSyncStartKey, enc)
+}
+
+// FastSyncStart returns the header of the block an unfinished fast sync started from, or nil if there's none
+func (bs *BlockState) FastSyncStart() (*types.Header, error) {
+	enc, err := bs.db.Get(fastSyncStartKey)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	header := types.NewEmptyHeader()
+	if err = scale.Unmarshal(enc, header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// ClearFastSyncStart removes the record of the block fast sync started from, once the state of its
+// target block is stored
+func (bs *BlockState) ClearFastSyncStart() error {
+	return bs.db.Del(fastSyncStartKey)
+}
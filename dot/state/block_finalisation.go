@@ -144,6 +144,8 @@ func (bs *BlockState) SetFinalisedHash(hash common.Hash, round, setID uint64) er
 		bs.notifyFinalized(hash, round, setID)
 	}
 
+	// the blocks on pruned forks are deleted from the database along with the new leaves being written
+	batch := bs.db.NewBatch()
 	pruned := bs.bt.Prune(hash)
 	for _, hash := range pruned {
 		block, has := bs.getAndDeleteUnfinalisedBlock(hash)
@@ -152,12 +154,23 @@ func (bs *BlockState) SetFinalisedHash(hash common.Hash, round, setID uint64) er
 		}
 
 		logger.Tracef("pruned block number %s with hash %s", block.Header.Number, hash)
+		if err := deleteBlock(batch, hash); err != nil {
+			return err
+		}
 
 		go func(header *types.Header) {
 			bs.pruneKeyCh <- header
 		}(&block.Header)
 	}
 
+	if err := bs.writeBlockTree(batch); err != nil {
+		return err
+	}
+
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("failed to delete pruned blocks: %w", err)
+	}
+
 	// if nothing was previously finalised, set the first slot of the network to the
 	// slot number of block 1, which is now being set as final
 	if bs.lastFinalised.Equal(bs.genesisHash) && !hash.Equal(bs.genesisHash) {
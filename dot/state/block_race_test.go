@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
 	"github.com/ChainSafe/gossamer/lib/trie"
 
 	"github.com/ChainSafe/chaindb"
@@ -29,7 +30,7 @@ func TestConcurrencySetHeader(t *testing.T) {
 		go func(index int) {
 			defer pend.Done()
 
-			bs, err := NewBlockStateFromGenesis(dbs[index], testGenesisHeader)
+			bs, err := NewBlockStateFromGenesis(dbs[index], testGenesisHeader, blocktree.BABEWeight{})
 			require.NoError(t, err)
 
 			header := &types.Header{
@@ -0,0 +1,198 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// tableBatch prefixes the keys written to a batch which is shared by several tables,
+// so that the data of all of them is written at once when the batch is flushed
+type tableBatch struct {
+	chaindb.Batch
+	prefix []byte
+}
+
+func newTableBatch(batch chaindb.Batch, prefix string) chaindb.Batch {
+	return &tableBatch{
+		Batch:  batch,
+		prefix: []byte(prefix),
+	}
+}
+
+// Put adds the key with the table prefix to the batch
+func (b *tableBatch) Put(key, value []byte) error {
+	return b.Batch.Put(append(append([]byte{}, b.prefix...), key...), value)
+}
+
+// Del deletes the key with the table prefix in the batch
+func (b *tableBatch) Del(key []byte) error {
+	return b.Batch.Del(append(append([]byte{}, b.prefix...), key...))
+}
+
+// recoveredBlock is an unfinalised block loaded from the database
+type recoveredBlock struct {
+	block       *types.Block
+	arrivalTime time.Time
+}
+
+// loadUnfinalisedBlocks adds the blocks imported above the highest finalised block before the node was
+// stopped back to the blocktree, by following the stored leaves of the blocktree down to the finalised
+// block. Every block is written in a single batch along with its state trie, so a block is only missing
+// some of its data if the node was killed while the batch was partially written to disk. Such a block is
+// rolled back along with its descendants, and they are deleted so that they are imported again.
+func (bs *BlockState) loadUnfinalisedBlocks(hasState func(root common.Hash) (bool, error)) error {
+	enc, err := bs.db.Get(leavesKey)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var leaves []common.Hash
+	if err = scale.Unmarshal(enc, &leaves); err != nil {
+		return fmt.Errorf("failed to decode blocktree leaves: %w", err)
+	}
+
+	fin, err := bs.GetHighestFinalisedHeader()
+	if err != nil {
+		return err
+	}
+
+	batch := bs.db.NewBatch()
+	for _, leaf := range leaves {
+		chain, err := bs.loadChain(leaf, fin, hasState, batch)
+		if err != nil {
+			return err
+		}
+
+		// the chain is ordered from the leaf down, and the leaves may share ancestors
+		for i := len(chain) - 1; i >= 0; i-- {
+			err = bs.bt.AddBlock(&chain[i].block.Header, chain[i].arrivalTime)
+			if errors.Is(err, blocktree.ErrBlockExists) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			bs.storeUnfinalisedBlock(chain[i].block)
+		}
+	}
+
+	best := bs.bt.BestBlockHash()
+	stored, err := bs.db.Get(common.BestBlockHashKey)
+	if err != nil && !errors.Is(err, chaindb.ErrKeyNotFound) {
+		return err
+	}
+
+	if stored != nil && !common.NewHash(stored).Equal(best) {
+		logger.Warnf("rolled back best block from %s to %s", common.NewHash(stored), best)
+	}
+
+	if err = bs.writeBlockTree(batch); err != nil {
+		return err
+	}
+
+	return batch.Flush()
+}
+
+// loadChain returns the blocks from the given leaf down to the finalised block, excluding it. Blocks
+// missing some of their data are deleted in the batch along with their descendants, as are the blocks
+// on forks that were pruned by the finalisation of another block.
+func (bs *BlockState) loadChain(leaf common.Hash, fin *types.Header,
+	hasState func(root common.Hash) (bool, error), batch chaindb.Batch) ([]*recoveredBlock, error) {
+	var chain []*recoveredBlock
+	rollback := func(hash common.Hash) error {
+		if len(chain) > 0 {
+			logger.Warnf("rolling back %d blocks above block %s", len(chain), hash)
+		}
+
+		for _, rb := range chain {
+			if err := deleteBlock(batch, rb.block.Header.Hash()); err != nil {
+				return err
+			}
+		}
+
+		chain = nil
+		return nil
+	}
+
+	finHash := fin.Hash()
+	for hash := leaf; !hash.Equal(finHash); {
+		header, err := bs.GetHeader(hash)
+		if errors.Is(err, chaindb.ErrKeyNotFound) {
+			// the ancestors of the block are unknown, so none of the blocks above it can be loaded
+			return nil, rollback(hash)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if header.Number.Cmp(fin.Number) <= 0 {
+			return nil, rollback(hash)
+		}
+
+		rb, err := bs.loadBlock(header, hasState)
+		if err != nil {
+			return nil, err
+		}
+
+		if rb == nil {
+			logger.Warnf("block number %s with hash %s was partially written", header.Number, hash)
+			if err = rollback(hash); err != nil {
+				return nil, err
+			}
+
+			if err = deleteBlock(batch, hash); err != nil {
+				return nil, err
+			}
+		} else {
+			chain = append(chain, rb)
+		}
+
+		hash = header.ParentHash
+	}
+
+	return chain, nil
+}
+
+// loadBlock returns the block with the given header, or nil if any of its body, arrival time
+// or state trie is missing
+func (bs *BlockState) loadBlock(header *types.Header,
+	hasState func(root common.Hash) (bool, error)) (*recoveredBlock, error) {
+	hash := header.Hash()
+	has, err := hasState(header.StateRoot)
+	if err != nil || !has {
+		return nil, err
+	}
+
+	body, err := bs.GetBlockBody(hash)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	arrivalTime, err := bs.db.Get(arrivalTimeKey(hash))
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &recoveredBlock{
+		block: &types.Block{
+			Header: *header,
+			Body:   *body,
+		},
+		arrivalTime: decodeArrivalTime(arrivalTime),
+	}, nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/ChainSafe/gossamer/lib/utils"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/stretchr/testify/require"
+)
+
+// killedImportDirEnv is set to the database directory of the node importing blocks in
+// TestService_KilledMidImport, which runs in a child process until it's killed
+const killedImportDirEnv = "GOSSAMER_KILLED_IMPORT_DIR"
+
+// newTestRecoveryService returns a state service whose database in the given directory holds a genesis
+// block with a small state. The database is written directly, since the genesis runtime isn't needed.
+func newTestRecoveryService(t *testing.T, dir string) *Service {
+	db, err := utils.SetupDatabase(dir, false)
+	require.NoError(t, err)
+
+	tr := trie.NewEmptyTrie()
+	tr.Put([]byte("key"), bytes.Repeat([]byte("value"), 10))
+	err = tr.Store(chaindb.NewTable(db, storagePrefix))
+	require.NoError(t, err)
+
+	header, err := types.NewHeader(common.Hash{}, tr.MustHash(), trie.EmptyHash, big.NewInt(0), types.NewDigest())
+	require.NoError(t, err)
+
+	_, err = NewBlockStateFromGenesis(db, header, blocktree.BABEWeight{})
+	require.NoError(t, err)
+
+	base := NewBaseState(db)
+	require.NoError(t, base.storePruningData(pruner.Config{}))
+	require.NoError(t, base.storeEpochLength(10))
+	require.NoError(t, base.storeSkipToEpoch(0))
+	require.NoError(t, db.Close())
+
+	return NewService(Config{
+		Path:     dir,
+		LogLevel: log.Info,
+	})
+}
+
+// importTestBlock imports a block on top of the best block, with a random change to its state. The block
+// has a BABE pre-digest, since the slot of the first block is stored when it's finalised.
+func importTestBlock(t *testing.T, serv *Service) *types.Block {
+	best, err := serv.Block.BestBlockHeader()
+	require.NoError(t, err)
+
+	parent := best.Hash()
+	block, ts := generateBlockWithRandomTrie(t, serv, &parent, best.Number.Int64()+1)
+
+	di, err := types.NewBabeSecondaryPlainPreDigest(0, block.Header.Number.Uint64()).ToPreRuntimeDigest()
+	require.NoError(t, err)
+	block.Header.Digest = types.NewDigest()
+	err = block.Header.Digest.Add(*di)
+	require.NoError(t, err)
+
+	err = serv.Storage.StoreBlock(ts, block)
+	require.NoError(t, err)
+	return block
+}
+
+// requireBlocksLoaded checks that each unfinalised block, and the best block, can be imported upon
+func requireBlocksLoaded(t *testing.T, serv *Service) {
+	for _, hash := range serv.Block.GetNonFinalisedBlocks() {
+		block, err := serv.Block.GetBlockByHash(hash)
+		require.NoError(t, err)
+
+		_, err = serv.Storage.TrieState(&block.Header.StateRoot)
+		require.NoError(t, err)
+	}
+
+	_, err := serv.Storage.TrieState(nil)
+	require.NoError(t, err)
+}
+
+func TestService_LoadUnfinalisedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	serv := newTestRecoveryService(t, dir)
+	err := serv.Start()
+	require.NoError(t, err)
+
+	var blocks []*types.Block
+	for i := 0; i < 5; i++ {
+		blocks = append(blocks, importTestBlock(t, serv))
+	}
+
+	err = serv.Block.SetFinalisedHash(blocks[1].Header.Hash(), 1, 0)
+	require.NoError(t, err)
+
+	err = serv.Stop()
+	require.NoError(t, err)
+
+	serv = NewService(Config{Path: dir, LogLevel: log.Info})
+	err = serv.Start()
+	require.NoError(t, err)
+	defer serv.Stop()
+
+	// the blocks above the finalised block are loaded back into the blocktree
+	require.Equal(t, blocks[4].Header.Hash(), serv.Block.BestBlockHash())
+	require.ElementsMatch(t, []common.Hash{
+		blocks[1].Header.Hash(),
+		blocks[2].Header.Hash(),
+		blocks[3].Header.Hash(),
+		blocks[4].Header.Hash(),
+	}, serv.Block.GetNonFinalisedBlocks())
+	requireBlocksLoaded(t, serv)
+
+	importTestBlock(t, serv)
+}
+
+var errTestFlush = errors.New("disk full")
+
+// failingFlushDB is a database whose batches can't be written
+type failingFlushDB struct {
+	chaindb.Database
+}
+
+func (db *failingFlushDB) NewBatch() chaindb.Batch {
+	return &failingFlushBatch{Batch: db.Database.NewBatch()}
+}
+
+type failingFlushBatch struct {
+	chaindb.Batch
+}
+
+func (b *failingFlushBatch) Flush() error {
+	return errTestFlush
+}
+
+func TestService_StoreBlock_FailedWrite(t *testing.T) {
+	serv := newTestRecoveryService(t, t.TempDir())
+	err := serv.Start()
+	require.NoError(t, err)
+	defer serv.Stop()
+
+	best := serv.Block.BestBlockHash()
+	block, ts := generateBlockWithRandomTrie(t, serv, &best, 1)
+	hash := block.Header.Hash()
+
+	db := serv.Block.baseState.db
+	serv.Block.baseState = NewBaseState(&failingFlushDB{Database: db})
+	err = serv.Storage.StoreBlock(ts, block)
+	require.ErrorIs(t, err, errTestFlush)
+
+	// nothing of the block is kept in memory
+	has, err := serv.Block.HasHeader(hash)
+	require.NoError(t, err)
+	require.False(t, has)
+	require.Equal(t, best, serv.Block.BestBlockHash())
+	require.Equal(t, []common.Hash{best}, serv.Block.Leaves())
+
+	_, err = serv.Storage.TrieState(&block.Header.StateRoot)
+	require.Error(t, err)
+
+	// the state trie nodes are still dirty, so they're written once the database works again
+	inserted, err := ts.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	require.NotEmpty(t, inserted)
+
+	serv.Block.baseState = NewBaseState(db)
+	err = serv.Storage.StoreBlock(ts, block)
+	require.NoError(t, err)
+	require.Equal(t, hash, serv.Block.BestBlockHash())
+	requireBlocksLoaded(t, serv)
+}
+
+func TestService_RollbackPartiallyWrittenBlock(t *testing.T) {
+	dir := t.TempDir()
+	serv := newTestRecoveryService(t, dir)
+	err := serv.Start()
+	require.NoError(t, err)
+
+	importTestBlock(t, serv)
+	last := importTestBlock(t, serv)
+
+	// the state trie of the next block is missing, as if the node had been killed after only part
+	// of its batch made it to disk, and another block was imported on top of it
+	lastHash := last.Header.Hash()
+	partial, _ := generateBlockWithRandomTrie(t, serv, &lastHash, last.Header.Number.Int64()+1)
+	err = serv.Block.AddBlock(partial)
+	require.NoError(t, err)
+
+	child := &types.Block{
+		Header: types.Header{
+			ParentHash: partial.Header.Hash(),
+			Number:     big.NewInt(partial.Header.Number.Int64() + 1),
+			StateRoot:  partial.Header.StateRoot,
+		},
+		Body: types.Body{},
+	}
+	err = serv.Block.AddBlock(child)
+	require.NoError(t, err)
+
+	err = serv.Stop()
+	require.NoError(t, err)
+
+	serv = NewService(Config{Path: dir, LogLevel: log.Info})
+	err = serv.Start()
+	require.NoError(t, err)
+	defer serv.Stop()
+
+	// the partially written block and its descendant are rolled back, and deleted so they are imported again
+	require.Equal(t, last.Header.Hash(), serv.Block.BestBlockHash())
+	for _, hash := range []common.Hash{partial.Header.Hash(), child.Header.Hash()} {
+		has, err := serv.Block.HasHeader(hash)
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+	requireBlocksLoaded(t, serv)
+}
+
+func TestService_ResumeFastSync(t *testing.T) {
+	dir := t.TempDir()
+	serv := newTestRecoveryService(t, dir)
+	err := serv.Start()
+	require.NoError(t, err)
+
+	start, err := serv.Block.BestBlockHeader()
+	require.NoError(t, err)
+	err = serv.Block.SetFastSyncStart(start)
+	require.NoError(t, err)
+
+	// fast sync imports the blocks without their state, and finalises some of them
+	var blocks []*types.Block
+	parent := start
+	for i := 1; i <= 3; i++ {
+		di, err := types.NewBabeSecondaryPlainPreDigest(0, uint64(i)).ToPreRuntimeDigest()
+		require.NoError(t, err)
+		digest := types.NewDigest()
+		require.NoError(t, digest.Add(*di))
+
+		block := &types.Block{
+			Header: types.Header{
+				ParentHash: parent.Hash(),
+				Number:     big.NewInt(int64(i)),
+				StateRoot:  common.Hash{byte(i)},
+				Digest:     digest,
+			},
+			Body: types.Body{},
+		}
+		err = serv.Block.AddBlock(block)
+		require.NoError(t, err)
+
+		blocks = append(blocks, block)
+		parent = &block.Header
+	}
+
+	err = serv.Block.SetFinalisedHash(blocks[1].Header.Hash(), 1, 0)
+	require.NoError(t, err)
+
+	err = serv.Stop()
+	require.NoError(t, err)
+
+	// the node restarts from the state of the start block, and keeps the blocks imported by fast sync
+	serv = NewService(Config{Path: dir, LogLevel: log.Info})
+	err = serv.Start()
+	require.NoError(t, err)
+
+	resumed, err := serv.Block.FastSyncStart()
+	require.NoError(t, err)
+	require.Equal(t, start.Hash(), resumed.Hash())
+	require.Equal(t, blocks[2].Header.Hash(), serv.Block.BestBlockHash())
+
+	_, err = serv.Storage.TrieState(&start.StateRoot)
+	require.NoError(t, err)
+
+	// once the state of the target block is stored, the node restarts from it
+	err = serv.Block.ClearFastSyncStart()
+	require.NoError(t, err)
+
+	resumed, err = serv.Block.FastSyncStart()
+	require.NoError(t, err)
+	require.Nil(t, resumed)
+
+	err = serv.Stop()
+	require.NoError(t, err)
+}
+
+// TestService_KilledMidImport runs a node importing blocks in a child process, kills it, then checks
+// that the node resumes from a block with all its data
+func TestService_KilledMidImport(t *testing.T) {
+	if dir := os.Getenv(killedImportDirEnv); dir != "" {
+		importUntilKilled(t, dir)
+		return
+	}
+
+	if testing.Short() {
+		t.Skip("skipping test which runs a child process")
+	}
+
+	dir := t.TempDir()
+	serv := newTestRecoveryService(t, dir)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestService_KilledMidImport$")
+	cmd.Env = append(os.Environ(), killedImportDirEnv+"="+dir)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+
+	err = cmd.Start()
+	require.NoError(t, err)
+
+	// kill the child process once it's busy importing blocks
+	const killAfter = 40
+	scanner := bufio.NewScanner(stdout)
+	var imported int
+	for imported < killAfter && scanner.Scan() {
+		_, _ = fmt.Sscanf(scanner.Text(), "imported block %d", &imported)
+	}
+
+	err = cmd.Process.Kill()
+	require.NoError(t, err)
+	_ = cmd.Wait()
+	require.GreaterOrEqual(t, imported, killAfter, "child process stopped before importing blocks")
+
+	serv = NewService(Config{Path: dir, LogLevel: log.Info})
+	err = serv.Start()
+	require.NoError(t, err)
+	defer serv.Stop()
+
+	best, err := serv.Block.BestBlockNumber()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, best.Int64(), int64(killAfter))
+	requireBlocksLoaded(t, serv)
+
+	// the node keeps importing blocks where it was killed
+	block := importTestBlock(t, serv)
+	require.Equal(t, new(big.Int).Add(best, big.NewInt(1)), block.Header.Number)
+}
+
+// importUntilKilled imports blocks into the node with the given database until the process is killed,
+// finalising some of them on the way
+func importUntilKilled(t *testing.T, dir string) {
+	serv := NewService(Config{Path: dir, LogLevel: log.Info})
+	err := serv.Start()
+	require.NoError(t, err)
+
+	for i := 1; i < 100000; i++ {
+		block := importTestBlock(t, serv)
+		if i%10 == 0 {
+			err = serv.Block.SetFinalisedHash(block.Header.ParentHash, uint64(i), 0)
+			require.NoError(t, err)
+		}
+
+		fmt.Printf("imported block %d\n", i)
+	}
+}
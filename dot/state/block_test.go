@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/pkg/scale"
@@ -30,7 +31,7 @@ func newTestBlockState(t *testing.T, header *types.Header) *BlockState {
 		header = testGenesisHeader
 	}
 
-	bs, err := NewBlockStateFromGenesis(db, header)
+	bs, err := NewBlockStateFromGenesis(db, header, blocktree.BABEWeight{})
 	require.NoError(t, err)
 	return bs
 }
@@ -466,6 +467,74 @@ func TestAddBlockToBlockTree(t *testing.T) {
 	require.Equal(t, bs.BestBlockHash(), header.Hash())
 }
 
+// addBlockWithPreDigest adds a block on top of the given parent to the block state, claimed by a BABE
+// primary or secondary pre-runtime digest, and returns its hash
+func addBlockWithPreDigest(t *testing.T, bs *BlockState, parent common.Hash, number int64,
+	primary bool) common.Hash {
+	t.Helper()
+
+	prd, err := types.NewBabeSecondaryPlainPreDigest(0, uint64(number)).ToPreRuntimeDigest()
+	if primary {
+		prd, err = types.NewBabePrimaryPreDigest(0, uint64(number), [32]byte{}, [64]byte{}).ToPreRuntimeDigest()
+	}
+	require.NoError(t, err)
+
+	digest := types.NewDigest()
+	err = digest.Add(*prd)
+	require.NoError(t, err)
+
+	block := &types.Block{
+		Header: types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(number),
+			StateRoot:  trie.EmptyHash,
+			Digest:     digest,
+		},
+		Body: types.Body{},
+	}
+
+	err = bs.AddBlock(block)
+	require.NoError(t, err)
+	return block.Header.Hash()
+}
+
+func TestBlockState_BestBlockHash_BABEWeight(t *testing.T) {
+	bs := newTestBlockState(t, testGenesisHeader)
+
+	// the longest chain is authored in secondary slots
+	hash := testGenesisHeader.Hash()
+	for i := int64(1); i <= 3; i++ {
+		hash = addBlockWithPreDigest(t, bs, hash, i, false)
+	}
+	require.Equal(t, hash, bs.BestBlockHash())
+
+	// the shorter chain has a block authored in a primary slot
+	primary := addBlockWithPreDigest(t, bs, testGenesisHeader.Hash(), 1, true)
+	require.Equal(t, primary, bs.BestBlockHash())
+
+	head, err := bs.BestBlockHeader()
+	require.NoError(t, err)
+	require.Equal(t, primary, head.Hash())
+
+	hash, err = bs.GetHashByNumber(big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, primary, hash)
+}
+
+func TestBlockState_BestBlockHash_ForkChoice(t *testing.T) {
+	bs, err := NewBlockStateFromGenesis(NewInMemoryDB(t), testGenesisHeader, blocktree.LongestChain{})
+	require.NoError(t, err)
+
+	hash := testGenesisHeader.Hash()
+	for i := int64(1); i <= 3; i++ {
+		hash = addBlockWithPreDigest(t, bs, hash, i, false)
+	}
+
+	// the block authored in a primary slot doesn't outweigh the longest chain
+	addBlockWithPreDigest(t, bs, testGenesisHeader.Hash(), 1, true)
+	require.Equal(t, hash, bs.BestBlockHash())
+}
+
 func TestNumberIsFinalised(t *testing.T) {
 	bs := newTestBlockState(t, testGenesisHeader)
 	fin, err := bs.NumberIsFinalised(big.NewInt(0))
@@ -530,3 +599,97 @@ func TestNumberIsFinalised(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, fin)
 }
+
+func TestBlockState_BadBlocks(t *testing.T) {
+	bs := newTestBlockState(t, testGenesisHeader)
+	chain, _ := AddBlocksToState(t, bs, 5, false)
+
+	// the descendants of an imported bad block are bad blocks too
+	err := bs.AddBadBlock(chain[2].Hash())
+	require.NoError(t, err)
+	require.False(t, bs.HasBadBlock(chain[1].Hash()))
+	for _, header := range chain[2:] {
+		require.True(t, bs.HasBadBlock(header.Hash()))
+	}
+
+	// so are the blocks descending from a bad block we haven't imported
+	bad := common.Hash{0xa}
+	err = bs.AddBadBlock(bad)
+	require.NoError(t, err)
+
+	child := &types.Header{
+		ParentHash: bad,
+		Number:     big.NewInt(1),
+		Digest:     types.NewDigest(),
+	}
+	grandchild := &types.Header{
+		ParentHash: child.Hash(),
+		Number:     big.NewInt(2),
+		Digest:     types.NewDigest(),
+	}
+	require.True(t, bs.IsBadBlock(child))
+	require.True(t, bs.IsBadBlock(grandchild))
+	require.True(t, bs.HasBadBlock(grandchild.Hash()))
+	require.False(t, bs.IsBadBlock(chain[1]))
+}
+
+func TestBlockState_BadBlocks_Best(t *testing.T) {
+	bs := newTestBlockState(t, testGenesisHeader)
+	chain, _ := AddBlocksToState(t, bs, 5, false)
+
+	// a shorter fork from block 2
+	fork := &types.Header{
+		ParentHash: chain[1].Hash(),
+		Number:     big.NewInt(0).Add(chain[1].Number, big.NewInt(1)),
+		StateRoot:  common.Hash{0xf},
+		Digest:     types.NewDigest(),
+	}
+	err := bs.AddBlock(&types.Block{Header: *fork, Body: types.Body{}})
+	require.NoError(t, err)
+	require.Equal(t, chain[4].Hash(), bs.BestBlockHash())
+
+	// the best block descends from the bad block, so the blocks of its fork are removed
+	err = bs.AddBadBlock(chain[2].Hash())
+	require.NoError(t, err)
+	require.Equal(t, fork.Hash(), bs.BestBlockHash())
+	require.ElementsMatch(t, []common.Hash{fork.Hash()}, bs.Leaves())
+
+	for _, header := range chain[2:] {
+		require.True(t, bs.HasBadBlock(header.Hash()))
+
+		has, err := bs.HasHeader(header.Hash())
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+
+	// the removed blocks are refused when they are announced again
+	require.True(t, bs.IsBadBlock(chain[3]))
+
+	stored, err := bs.db.Get(common.BestBlockHashKey)
+	require.NoError(t, err)
+	require.Equal(t, fork.Hash(), common.NewHash(stored))
+}
+
+func TestBlockState_BadBlocks_BoundedDescendants(t *testing.T) {
+	bs := newTestBlockState(t, testGenesisHeader)
+
+	bad := common.Hash{0xa}
+	err := bs.AddBadBlock(bad)
+	require.NoError(t, err)
+
+	children := make([]*types.Header, maxBadBlockDescendants+1)
+	for i := range children {
+		children[i] = &types.Header{
+			ParentHash: bad,
+			Number:     big.NewInt(int64(i) + 1),
+			Digest:     types.NewDigest(),
+		}
+		require.True(t, bs.IsBadBlock(children[i]))
+	}
+
+	// the oldest descendant is forgotten, but the bad block itself is still refused
+	require.False(t, bs.HasBadBlock(children[0].Hash()))
+	require.True(t, bs.HasBadBlock(children[1].Hash()))
+	require.True(t, bs.HasBadBlock(bad))
+	require.True(t, bs.IsBadBlock(children[0]))
+}
@@ -63,7 +63,7 @@ func (s *Service) Initialise(gen *genesis.Genesis, header *types.Header, t *trie
 	}
 
 	// create block state from genesis block
-	blockState, err := NewBlockStateFromGenesis(db, header)
+	blockState, err := NewBlockStateFromGenesis(db, header, s.forkChoice)
 	if err != nil {
 		return fmt.Errorf("failed to create block state from genesis: %s", err)
 	}
@@ -122,7 +122,10 @@ func (s *Service) loadBabeConfigurationFromRuntime(r runtime.Instance) (*types.B
 }
 
 func loadGrandpaAuthorities(t *trie.Trie) ([]types.GrandpaVoter, error) {
-	authsRaw := t.Get(runtime.GrandpaAuthoritiesKey)
+	authsRaw, err := t.Get(runtime.GrandpaAuthoritiesKey)
+	if err != nil {
+		return nil, err
+	}
 	if authsRaw == nil {
 		return []types.GrandpaVoter{}, nil
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ChainSafe/chaindb"
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/lib/utils"
@@ -41,8 +42,8 @@ func NewOfflinePruner(inputDBPath, prunedDBPath string, bloomSize uint64,
 		return nil, fmt.Errorf("failed to load DB %w", err)
 	}
 
-	// create blockState state
-	blockState, err := NewBlockState(db)
+	// create blockState state, the pruner only reads the finalised chain so no block is ever weighed
+	blockState, err := NewBlockState(db, blocktree.LongestChain{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create block state: %w", err)
 	}
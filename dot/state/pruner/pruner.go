@@ -50,17 +50,28 @@ type Config struct {
 
 // Pruner is implemented by FullNode and ArchiveNode.
 type Pruner interface {
-	StoreJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64) error
+	// StoreJournalRecord writes the journal record of the block to the given batch of the database the
+	// pruner was created with, so that it's written along with the state trie nodes of the block.
+	StoreJournalRecord(batch chaindb.Batch, deleted, inserted []common.Hash, blockHash common.Hash,
+		blockNum int64) error
+	// AddJournalRecord schedules the pruning of the nodes deleted by the block, once the batch holding its
+	// journal record is flushed.
+	AddJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64)
 }
 
 // ArchiveNode is a no-op since we don't prune nodes in archive mode.
 type ArchiveNode struct{}
 
 // StoreJournalRecord for archive node doesn't do anything.
-func (a *ArchiveNode) StoreJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64) error {
+func (a *ArchiveNode) StoreJournalRecord(_ chaindb.Batch, deleted, inserted []common.Hash, blockHash common.Hash,
+	blockNum int64) error {
 	return nil
 }
 
+// AddJournalRecord for archive node doesn't do anything.
+func (a *ArchiveNode) AddJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64) {
+}
+
 type deathRecord struct {
 	blockHash   common.Hash
 	deletedKeys map[common.Hash]int64 // Mapping from deleted key hash to block number.
@@ -135,21 +146,27 @@ func NewFullNode(db, storageDB chaindb.Database, retainBlocks int64, l log.Level
 	return p, nil
 }
 
-// StoreJournalRecord stores journal record into DB and add deathRow into deathList
-func (p *FullNode) StoreJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64) error {
+// StoreJournalRecord writes the journal record to the batch. The batch must belong to the database the
+// pruner was created with.
+func (p *FullNode) StoreJournalRecord(batch chaindb.Batch, deleted, inserted []common.Hash, blockHash common.Hash,
+	blockNum int64) error {
 	jr := newJournalRecord(blockHash, inserted, deleted)
 
 	key := &journalKey{blockNum, blockHash}
-	err := p.storeJournal(key, jr)
+	err := p.storeJournal(batch, key, jr)
 	if err != nil {
 		return fmt.Errorf("failed to store journal record for %d: %w", blockNum, err)
 	}
 
-	p.logger.Debugf("journal record stored for block number %d", blockNum)
-	p.addDeathRow(jr, blockNum)
 	return nil
 }
 
+// AddJournalRecord adds the deathRow of the journal record into deathList, once the record is written
+func (p *FullNode) AddJournalRecord(deleted, inserted []common.Hash, blockHash common.Hash, blockNum int64) {
+	p.logger.Debugf("journal record stored for block number %d", blockNum)
+	p.addDeathRow(newJournalRecord(blockHash, inserted, deleted), blockNum)
+}
+
 func (p *FullNode) addDeathRow(jr *journalRecord, blockNum int64) {
 	if blockNum == 0 {
 		return
@@ -280,7 +297,7 @@ func (p *FullNode) start() {
 	}
 }
 
-func (p *FullNode) storeJournal(key *journalKey, jr *journalRecord) error {
+func (p *FullNode) storeJournal(batch chaindb.Batch, key *journalKey, jr *journalRecord) error {
 	encKey, err := scale.Marshal(*key)
 	if err != nil {
 		return fmt.Errorf("failed to encode journal key block num %d: %w", key.blockNum, err)
@@ -291,7 +308,8 @@ func (p *FullNode) storeJournal(key *journalKey, jr *journalRecord) error {
 		return fmt.Errorf("failed to encode journal record block num %d: %w", key.blockNum, err)
 	}
 
-	err = p.journalDB.Put(encKey, encRecord)
+	// the batch isn't a batch of the journal table, so the key is prefixed as the table does
+	err = batch.Put(append([]byte(journalPrefix), encKey...), encRecord)
 	if err != nil {
 		return err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/lib/utils"
 
@@ -22,6 +23,10 @@ const (
 	readyPoolTransactionsMetrics   = "gossamer/ready/pool/transaction/metrics"
 	readyPriorityQueueTransactions = "gossamer/ready/queue/transaction/metrics"
 	substrateNumberLeaves          = "gossamer/substrate_number_leaves/metrics"
+	storageCacheHits               = "gossamer/storage/cache/hits/metrics"
+	storageCacheMisses             = "gossamer/storage/cache/misses/metrics"
+	storageCacheHitRate            = "gossamer/storage/cache/hit_rate/metrics"
+	storageCacheSize               = "gossamer/storage/cache/size/metrics"
 )
 
 var logger = log.NewFromGlobal(
@@ -48,6 +53,10 @@ type Service struct {
 
 	// Below are for state trie online pruner
 	PrunerCfg pruner.Config
+
+	trieCacheSize    int
+	storageCacheSize int
+	forkChoice       blocktree.ForkChoice
 }
 
 // Config is the default configuration used by state service.
@@ -55,12 +64,26 @@ type Config struct {
 	Path      string
 	LogLevel  log.Level
 	PrunerCfg pruner.Config
+	// TrieCacheSize is the number of state trie nodes kept in memory when the state tries are loaded
+	// lazily from the database. If it's zero, the state tries are fully loaded into memory.
+	TrieCacheSize int
+	// StorageCacheSize is the size in bytes of the cache of the storage values of the best block.
+	// If it's zero, the storage values aren't cached.
+	StorageCacheSize int
+	// ForkChoice weighs the unfinalised blocks to choose the best block. If it's nil, the fork choice
+	// of BABE is used.
+	ForkChoice blocktree.ForkChoice
 }
 
 // NewService create a new instance of Service
 func NewService(config Config) *Service {
 	logger.Patch(log.SetLevel(config.LogLevel))
 
+	forkChoice := config.ForkChoice
+	if forkChoice == nil {
+		forkChoice = blocktree.BABEWeight{}
+	}
+
 	return &Service{
 		dbPath:    config.Path,
 		logLvl:    config.LogLevel,
@@ -70,6 +93,10 @@ func NewService(config Config) *Service {
 		Block:     nil,
 		closeCh:   make(chan interface{}),
 		PrunerCfg: config.PrunerCfg,
+
+		trieCacheSize:    config.TrieCacheSize,
+		storageCacheSize: config.StorageCacheSize,
+		forkChoice:       forkChoice,
 	}
 }
 
@@ -112,7 +139,7 @@ func (s *Service) Start() error {
 	var err error
 
 	// create block state
-	s.Block, err = NewBlockState(db)
+	s.Block, err = NewBlockState(db, s.forkChoice)
 	if err != nil {
 		return fmt.Errorf("failed to create block state: %w", err)
 	}
@@ -124,6 +151,27 @@ func (s *Service) Start() error {
 	}
 
 	stateRoot := bestHeader.StateRoot
+
+	// the blocks imported by an unfinished fast sync don't have a state, so we start from the state
+	// of the block it started from, and fast sync resumes from it
+	fastSyncStart, err := s.Block.FastSyncStart()
+	if err != nil {
+		return fmt.Errorf("failed to get start of fast sync: %w", err)
+	}
+
+	hasState := func(root common.Hash) (bool, error) {
+		return s.Storage.hasTrie(root)
+	}
+
+	if fastSyncStart != nil {
+		logger.Infof("resuming fast sync from block number %s with hash %s",
+			fastSyncStart.Number, fastSyncStart.Hash())
+		stateRoot = fastSyncStart.StateRoot
+		hasState = func(common.Hash) (bool, error) {
+			return true, nil
+		}
+	}
+
 	logger.Debugf("start with latest state root: %s", stateRoot)
 
 	pr, err := s.Base.loadPruningData()
@@ -137,12 +185,30 @@ func (s *Service) Start() error {
 		return fmt.Errorf("failed to create storage state: %w", err)
 	}
 
+	if s.trieCacheSize > 0 {
+		if err = s.Storage.UseLazyTries(s.trieCacheSize); err != nil {
+			return err
+		}
+	}
+
 	// load current storage state trie into memory
 	_, err = s.Storage.LoadFromDB(stateRoot)
 	if err != nil {
 		return fmt.Errorf("failed to load storage trie from database: %w", err)
 	}
 
+	// load the blocks imported above the finalised block, rolling back the ones partially written
+	// if the node was killed while importing them
+	if err = s.Block.loadUnfinalisedBlocks(hasState); err != nil {
+		return fmt.Errorf("failed to load unfinalised blocks: %w", err)
+	}
+
+	if s.storageCacheSize > 0 {
+		if err = s.Storage.UseStorageCache(s.storageCacheSize); err != nil {
+			return err
+		}
+	}
+
 	// create transaction queue
 	s.Transaction = NewTransactionState()
 
@@ -185,7 +251,7 @@ func (s *Service) Rewind(toBlock int64) error {
 		return err
 	}
 
-	s.Block.bt = blocktree.NewBlockTreeFromRoot(&root.Header)
+	s.Block.bt = blocktree.NewBlockTreeFromRoot(&root.Header, s.Block.forkChoice)
 
 	header, err := s.Block.BestBlockHeader()
 	if err != nil {
@@ -348,11 +414,25 @@ func (s *Service) Import(header *types.Header, t *trie.Trie, firstSlot uint64) e
 	return s.db.Close()
 }
 
-// CollectGauge exports 2 metrics related to valid transaction pool and queue
+// CollectGauge exports the metrics related to valid transaction pool and queue, the leaves of the block tree
+// and the storage cache
 func (s *Service) CollectGauge() map[string]int64 {
-	return map[string]int64{
+	gauges := map[string]int64{
 		readyPoolTransactionsMetrics:   int64(s.Transaction.pool.Len()),
 		readyPriorityQueueTransactions: int64(s.Transaction.queue.Len()),
 		substrateNumberLeaves:          int64(len(s.Block.Leaves())),
 	}
+
+	if s.Storage != nil && s.Storage.storageCache != nil {
+		hits, misses, size := s.Storage.storageCache.stats()
+		gauges[storageCacheHits] = hits
+		gauges[storageCacheMisses] = misses
+		gauges[storageCacheSize] = int64(size)
+		if hits+misses > 0 {
+			// in percent, since the gauges are integers
+			gauges[storageCacheHitRate] = hits * 100 / (hits + misses)
+		}
+	}
+
+	return gauges
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/genesis"
 	"github.com/ChainSafe/gossamer/lib/transaction"
@@ -62,6 +63,14 @@ func TestService_Start(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestNewService_ForkChoice(t *testing.T) {
+	state := NewService(Config{})
+	require.Equal(t, blocktree.BABEWeight{}, state.forkChoice)
+
+	state = NewService(Config{ForkChoice: blocktree.LongestChain{}})
+	require.Equal(t, blocktree.LongestChain{}, state.forkChoice)
+}
+
 func TestService_Initialise(t *testing.T) {
 	state := newTestService(t)
 	defer utils.RemoveTestDir(t)
@@ -0,0 +1,559 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/ChainSafe/gossamer/lib/utils"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+
+	"github.com/ChainSafe/chaindb"
+	"golang.org/x/crypto/blake2b"
+)
+
+// A state snapshot is written as:
+//   - the snapshot magic bytes
+//   - the SCALE encoded snapshotMetadata
+//   - the SCALE encoded snapshotEntry of each entry of the state trie and of its child tries, each preceded
+//     by a 1 byte, in lexicographic order of their keys with the entries of a child trie following its key
+//   - a 0 byte
+//   - the blake2b-256 checksum of all the previous bytes
+var snapshotMagic = []byte("gssmrsnp")
+
+const snapshotVersion uint32 = 1
+
+// snapshotBatchSize is the size of the trie nodes read from a snapshot above which they're written to the database
+const snapshotBatchSize = 64 << 20
+
+var (
+	errInvalidSnapshot           = errors.New("invalid state snapshot")
+	errSnapshotChecksumMismatch  = errors.New("state snapshot checksum mismatch")
+	errSnapshotRootMismatch      = errors.New("state snapshot trie root does not match header state root")
+	errSnapshotChildRootMismatch = errors.New("state snapshot child trie root does not match its value in the state trie")
+)
+
+// snapshotMetadata is the data needed to import the state of the block of a snapshot, besides its state trie
+type snapshotMetadata struct {
+	Version   uint32
+	Header    types.Header
+	Body      types.Body
+	FirstSlot uint64
+	// the BABE epoch of the block, its data and config data, and the data of the next epoch if it's known
+	Epoch         uint64
+	EpochData     types.EpochDataRaw
+	NextEpochData *types.EpochDataRaw
+	ConfigData    types.ConfigData
+	// the GRANDPA set of the block, and the SCALE encoded authorities of each set up to this one along
+	// with the numbers of the blocks where they started
+	SetID        uint64
+	Authorities  [][]byte
+	SetIDChanges []uint64
+}
+
+// snapshotEntry is an entry of the state trie or of one of its child tries
+type snapshotEntry struct {
+	Child       []byte // the key of the child trie in the state trie, empty for the entries of the state trie
+	Key         []byte
+	Value       []byte
+	HashedValue bool // the value is stored by its hash, with the state version V1
+}
+
+// ExportSnapshot writes the snapshot of the state of the block with the given hash to w. The state trie entries
+// are streamed from the trie, which is loaded lazily if the state tries are loaded lazily.
+func (s *Service) ExportSnapshot(hash common.Hash, w io.Writer) error {
+	header, err := s.Block.GetHeader(hash)
+	if err != nil {
+		return fmt.Errorf("failed to get header of block %s: %w", hash, err)
+	}
+
+	t, err := s.Storage.loadTrie(&header.StateRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load state of block %s: %w", hash, err)
+	}
+
+	meta, err := s.newSnapshotMetadata(header)
+	if err != nil {
+		return err
+	}
+
+	body, err := s.Block.GetBlockBody(hash)
+	if err != nil {
+		return fmt.Errorf("failed to get body of block %s: %w", hash, err)
+	}
+	meta.Body = *body
+
+	enc, err := scale.Marshal(*meta)
+	if err != nil {
+		return err
+	}
+
+	hasher, err := blake2b.New256(nil)
+	if err != nil {
+		return err
+	}
+
+	hw := io.MultiWriter(w, hasher)
+	if _, err = hw.Write(snapshotMagic); err != nil {
+		return err
+	}
+
+	if _, err = hw.Write(enc); err != nil {
+		return err
+	}
+
+	entries := 0
+	err = t.WalkEntries(func(key, value []byte, hashedValue bool) error {
+		entries++
+		if err := writeSnapshotEntry(hw, nil, key, value, hashedValue); err != nil {
+			return err
+		}
+
+		if !bytes.HasPrefix(key, trie.ChildStorageKeyPrefix) {
+			return nil
+		}
+
+		keyToChild := key[len(trie.ChildStorageKeyPrefix):]
+		child, err := t.GetChild(keyToChild)
+		if err != nil {
+			return err
+		}
+
+		// the child tries of the tries loaded from the database aren't loaded along with them
+		if child == nil {
+			child = trie.NewEmptyTrie()
+			if err = child.Load(s.Storage.db, common.BytesToHash(value)); err != nil {
+				return fmt.Errorf("failed to load child trie at key 0x%x: %w", keyToChild, err)
+			}
+		}
+
+		return child.WalkEntries(func(key, value []byte, hashedValue bool) error {
+			entries++
+			return writeSnapshotEntry(hw, keyToChild, key, value, hashedValue)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write state entries: %w", err)
+	}
+
+	if _, err = hw.Write([]byte{0}); err != nil {
+		return err
+	}
+
+	if _, err = w.Write(hasher.Sum(nil)); err != nil {
+		return err
+	}
+
+	logger.Infof("exported %d state entries of block number %s with hash %s", entries, header.Number, hash)
+	return nil
+}
+
+// newSnapshotMetadata returns the metadata of the snapshot of the state of the block with the given header
+func (s *Service) newSnapshotMetadata(header *types.Header) (*snapshotMetadata, error) {
+	firstSlot, err := s.Base.loadFirstSlot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load first slot: %w", err)
+	}
+
+	epoch, err := s.Epoch.GetEpochForBlock(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get epoch of block: %w", err)
+	}
+
+	epochData, err := s.Epoch.GetEpochData(epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data of epoch %d: %w", epoch, err)
+	}
+
+	meta := &snapshotMetadata{
+		Version:   snapshotVersion,
+		Header:    *header,
+		FirstSlot: firstSlot,
+		Epoch:     epoch,
+		EpochData: *epochData.ToEpochDataRaw(),
+	}
+
+	has, err := s.Epoch.HasEpochData(epoch + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if has {
+		nextEpochData, err := s.Epoch.GetEpochData(epoch + 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data of epoch %d: %w", epoch+1, err)
+		}
+		meta.NextEpochData = nextEpochData.ToEpochDataRaw()
+	}
+
+	// the config data is only set for the epochs where it changes
+	for configEpoch := epoch; ; configEpoch-- {
+		has, err := s.Epoch.HasConfigData(configEpoch)
+		if err != nil {
+			return nil, err
+		}
+
+		if has {
+			configData, err := s.Epoch.GetConfigData(configEpoch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get config data of epoch %d: %w", configEpoch, err)
+			}
+			meta.ConfigData = *configData
+			break
+		}
+
+		if configEpoch == 0 {
+			return nil, fmt.Errorf("failed to find config data of epoch %d", epoch)
+		}
+	}
+
+	meta.SetID, err = s.Grandpa.GetSetIDByBlockNumber(header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GRANDPA set ID of block: %w", err)
+	}
+
+	for setID := uint64(0); setID <= meta.SetID; setID++ {
+		authorities, err := s.Grandpa.GetAuthorities(setID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get authorities of GRANDPA set %d: %w", setID, err)
+		}
+
+		enc, err := types.EncodeGrandpaVoters(authorities)
+		if err != nil {
+			return nil, err
+		}
+		meta.Authorities = append(meta.Authorities, enc)
+
+		change, err := s.Grandpa.GetSetIDChange(setID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block number of GRANDPA set %d: %w", setID, err)
+		}
+		meta.SetIDChanges = append(meta.SetIDChanges, change.Uint64())
+	}
+
+	return meta, nil
+}
+
+func writeSnapshotEntry(w io.Writer, keyToChild, key, value []byte, hashedValue bool) error {
+	enc, err := scale.Marshal(snapshotEntry{
+		Child:       keyToChild,
+		Key:         key,
+		Value:       value,
+		HashedValue: hashedValue,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write([]byte{1}); err != nil {
+		return err
+	}
+
+	_, err = w.Write(enc)
+	return err
+}
+
+// readSnapshot reads a snapshot written by ExportSnapshot from r, and returns its metadata once the checksum of
+// the snapshot and the root of its state trie are verified. The nodes of the state trie and of its child tries
+// are written to the database as the entries are read, so the trie isn't kept in memory. The nodes of an invalid
+// snapshot may be left in the database, where they aren't referenced by any state root.
+func readSnapshot(r io.Reader, db chaindb.Database) (*snapshotMetadata, error) {
+	hasher, err := blake2b.New256(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hr := io.TeeReader(r, hasher)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err = io.ReadFull(hr, magic); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot magic: %w", err)
+	}
+
+	if !bytes.Equal(magic, snapshotMagic) {
+		return nil, fmt.Errorf("%w: unknown magic 0x%x", errInvalidSnapshot, magic)
+	}
+
+	dec := scale.NewDecoder(hr)
+	meta := &snapshotMetadata{
+		Header: *types.NewEmptyHeader(),
+	}
+
+	if err = dec.Decode(meta); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot metadata: %w", err)
+	}
+
+	if meta.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errInvalidSnapshot, meta.Version)
+	}
+
+	batch := db.NewBatch()
+	builder := trie.NewBuilder(batch)
+
+	// the entries of a child trie follow its key in the state trie, whose value is the root of the child trie
+	var (
+		child             *trie.Builder
+		keyToChild        []byte
+		expectedChildRoot []byte
+	)
+	finishChild := func() error {
+		if child == nil {
+			return nil
+		}
+
+		root, err := child.Root()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(root[:], expectedChildRoot) {
+			return fmt.Errorf("%w: child trie at key 0x%x", errSnapshotChildRootMismatch, keyToChild)
+		}
+
+		child = nil
+		return nil
+	}
+
+	for {
+		var next byte
+		if err = dec.Decode(&next); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot entry: %w", err)
+		}
+
+		if next == 0 {
+			break
+		}
+
+		if next != 1 {
+			return nil, fmt.Errorf("%w: unexpected byte %d before entry", errInvalidSnapshot, next)
+		}
+
+		var entry snapshotEntry
+		if err = dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot entry: %w", err)
+		}
+
+		// the version is set for each entry, since the state may have been written with both versions
+		version := trie.V0
+		if entry.HashedValue {
+			version = trie.V1
+		}
+
+		if len(entry.Child) > 0 {
+			if child == nil || !bytes.Equal(entry.Child, keyToChild) {
+				return nil, fmt.Errorf("%w: entry of child trie 0x%x not following its key",
+					errInvalidSnapshot, entry.Child)
+			}
+
+			err = child.Put(entry.Key, entry.Value, version)
+		} else {
+			if err = finishChild(); err != nil {
+				return nil, err
+			}
+
+			if bytes.HasPrefix(entry.Key, trie.ChildStorageKeyPrefix) {
+				child = trie.NewBuilder(batch)
+				keyToChild = entry.Key[len(trie.ChildStorageKeyPrefix):]
+				expectedChildRoot = entry.Value
+			}
+
+			err = builder.Put(entry.Key, entry.Value, version)
+		}
+
+		if errors.Is(err, trie.ErrUnsortedKey) {
+			return nil, fmt.Errorf("%w: %s", errInvalidSnapshot, err)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if batch.ValueSize() >= snapshotBatchSize {
+			if err = batch.Flush(); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+	}
+
+	checksum := make([]byte, blake2b.Size256)
+	if _, err = io.ReadFull(r, checksum); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot checksum: %w", err)
+	}
+
+	if !bytes.Equal(checksum, hasher.Sum(nil)) {
+		return nil, errSnapshotChecksumMismatch
+	}
+
+	if err = finishChild(); err != nil {
+		return nil, err
+	}
+
+	root, err := builder.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	if root != meta.Header.StateRoot {
+		return nil, fmt.Errorf("%w: expected %s, got %s", errSnapshotRootMismatch, meta.Header.StateRoot, root)
+	}
+
+	if err = batch.Flush(); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// ImportSnapshot imports the state snapshot written by ExportSnapshot read from r, and sets its block as the
+// highest finalised block. The node must be initialised with the genesis of the chain of the snapshot.
+func (s *Service) ImportSnapshot(r io.Reader) error {
+	var err error
+	s.db, err = utils.SetupDatabase(s.dbPath, s.isMemDB)
+	if err != nil {
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+
+	logger.Info("importing state snapshot...")
+	meta, err := readSnapshot(r, chaindb.NewTable(s.db, storagePrefix))
+	if err != nil {
+		return err
+	}
+
+	header := &meta.Header
+	hash := header.Hash()
+	logger.Infof("imported state of block number %s with hash %s and state root %s",
+		header.Number, hash, header.StateRoot)
+
+	block := &BlockState{
+		db: chaindb.NewTable(s.db, blockPrefix),
+	}
+
+	s.Base = NewBaseState(s.db)
+	if err = s.Base.storeFirstSlot(meta.FirstSlot); err != nil {
+		return err
+	}
+
+	if err = s.importSnapshotEpoch(meta, block); err != nil {
+		return fmt.Errorf("failed to import epoch data: %w", err)
+	}
+
+	if err = s.importSnapshotGrandpa(meta); err != nil {
+		return fmt.Errorf("failed to import GRANDPA set: %w", err)
+	}
+
+	if err = block.SetHeader(header); err != nil {
+		return err
+	}
+
+	if err = block.SetBlockBody(hash, &meta.Body); err != nil {
+		return err
+	}
+
+	if err = block.db.Put(headerHashKey(header.Number.Uint64()), hash[:]); err != nil {
+		return err
+	}
+
+	// the block is the first block of the imported chain, finalised in the first round of its set
+	if err = block.db.Put(finalisedHashKey(0, meta.SetID), hash[:]); err != nil {
+		return err
+	}
+
+	if err = block.db.Put(highestRoundAndSetIDKey, roundAndSetIDToBytes(0, meta.SetID)); err != nil {
+		return err
+	}
+
+	// the block is the only leaf of the block tree, and the best block
+	leaves, err := scale.Marshal([]common.Hash{hash})
+	if err != nil {
+		return err
+	}
+
+	if err = block.db.Put(leavesKey, leaves); err != nil {
+		return err
+	}
+
+	if err = block.db.Put(common.BestBlockHashKey, hash[:]); err != nil {
+		return err
+	}
+
+	if err = s.db.Flush(); err != nil {
+		return err
+	}
+
+	logger.Info("finished state snapshot import")
+	if s.isMemDB {
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+func (s *Service) importSnapshotEpoch(meta *snapshotMetadata, block *BlockState) error {
+	epoch, err := NewEpochState(s.db, block)
+	if err != nil {
+		return err
+	}
+
+	if err = epoch.SetCurrentEpoch(meta.Epoch); err != nil {
+		return err
+	}
+
+	epochData, err := meta.EpochData.ToEpochData()
+	if err != nil {
+		return err
+	}
+
+	if err = epoch.SetEpochData(meta.Epoch, epochData); err != nil {
+		return err
+	}
+
+	if meta.NextEpochData != nil {
+		nextEpochData, err := meta.NextEpochData.ToEpochData()
+		if err != nil {
+			return err
+		}
+
+		if err = epoch.SetEpochData(meta.Epoch+1, nextEpochData); err != nil {
+			return err
+		}
+	}
+
+	return epoch.SetConfigData(meta.Epoch, &meta.ConfigData)
+}
+
+func (s *Service) importSnapshotGrandpa(meta *snapshotMetadata) error {
+	grandpa, err := NewGrandpaState(s.db)
+	if err != nil {
+		return err
+	}
+
+	if len(meta.Authorities) != len(meta.SetIDChanges) {
+		return fmt.Errorf("%w: %d authority sets for %d set changes",
+			errInvalidSnapshot, len(meta.Authorities), len(meta.SetIDChanges))
+	}
+
+	for setID, enc := range meta.Authorities {
+		authorities, err := types.DecodeGrandpaVoters(enc)
+		if err != nil {
+			return err
+		}
+
+		if err = grandpa.setAuthorities(uint64(setID), authorities); err != nil {
+			return err
+		}
+
+		number := new(big.Int).SetUint64(meta.SetIDChanges[setID])
+		if err = grandpa.setSetIDChangeAtBlock(uint64(setID), number); err != nil {
+			return err
+		}
+	}
+
+	return grandpa.setCurrentSetID(meta.SetID)
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/crypto/ed25519"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/ChainSafe/gossamer/lib/utils"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSnapshotTrie returns a genesis state with a child trie, and values written with both state versions
+func newTestSnapshotTrie(t *testing.T) *trie.Trie {
+	tr := trie.NewEmptyTrie()
+	tr.Put([]byte("key"), []byte("value"))
+	tr.Put([]byte("long v0"), bytes.Repeat([]byte{1}, trie.MaxInlineValueSize+1))
+
+	child := trie.NewEmptyTrie()
+	child.Put([]byte("child key"), []byte("child value"))
+	err := tr.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	tr.SetVersion(trie.V1)
+	tr.Put([]byte("long v1"), bytes.Repeat([]byte{2}, trie.MaxInlineValueSize+1))
+	return tr
+}
+
+// newTestSnapshotService returns a state service whose database in the given directory holds a genesis block
+// with the given state, along with its epoch data and GRANDPA set. The database is written directly, since the
+// genesis runtime isn't needed.
+func newTestSnapshotService(t *testing.T, dir string, tr *trie.Trie) *Service {
+	db, err := utils.SetupDatabase(dir, false)
+	require.NoError(t, err)
+
+	err = tr.WriteDirty(chaindb.NewTable(db, storagePrefix))
+	require.NoError(t, err)
+
+	header, err := types.NewHeader(common.Hash{}, tr.MustHash(), trie.EmptyHash, big.NewInt(0), types.NewDigest())
+	require.NoError(t, err)
+
+	bs, err := NewBlockStateFromGenesis(db, header, blocktree.BABEWeight{})
+	require.NoError(t, err)
+
+	_, err = NewEpochStateFromGenesis(db, bs, genesisBABEConfig)
+	require.NoError(t, err)
+
+	_, err = NewGrandpaStateFromGenesis(db, testAuths)
+	require.NoError(t, err)
+
+	require.NoError(t, NewBaseState(db).storePruningData(pruner.Config{}))
+	require.NoError(t, db.Close())
+
+	return NewService(Config{
+		Path:     dir,
+		LogLevel: log.Info,
+	})
+}
+
+func TestService_ExportImportSnapshot(t *testing.T) {
+	genesisTrie := newTestSnapshotTrie(t)
+	serv := newTestSnapshotService(t, t.TempDir(), genesisTrie)
+	err := serv.Start()
+	require.NoError(t, err)
+
+	// the body of the block is imported along with its header
+	parent := serv.Block.BestBlockHash()
+	block, ts := generateBlockWithRandomTrie(t, serv, &parent, 1)
+	block.Body = *types.NewBody([]types.Extrinsic{{1, 2, 3}})
+
+	di, err := types.NewBabeSecondaryPlainPreDigest(0, 1).ToPreRuntimeDigest()
+	require.NoError(t, err)
+	block.Header.Digest = types.NewDigest()
+	err = block.Header.Digest.Add(*di)
+	require.NoError(t, err)
+
+	err = serv.Storage.StoreBlock(ts, block)
+	require.NoError(t, err)
+	hash := block.Header.Hash()
+
+	nextEpochData := &types.EpochData{
+		Authorities: []types.Authority{},
+		Randomness:  [types.RandomnessLength]byte{0x1},
+	}
+	err = serv.Epoch.SetEpochData(1, nextEpochData)
+	require.NoError(t, err)
+
+	// the block is in the second GRANDPA set
+	nextAuths := []types.GrandpaVoter{
+		{Key: *kr.Bob().Public().(*ed25519.PublicKey), ID: 0},
+	}
+	err = serv.Grandpa.SetNextChange(nextAuths, big.NewInt(0))
+	require.NoError(t, err)
+	err = serv.Grandpa.IncrementSetID()
+	require.NoError(t, err)
+
+	var snapshot bytes.Buffer
+	err = serv.ExportSnapshot(hash, &snapshot)
+	require.NoError(t, err)
+
+	expectedEntries, err := serv.Storage.Entries(&block.Header.StateRoot)
+	require.NoError(t, err)
+
+	err = serv.Stop()
+	require.NoError(t, err)
+
+	// the snapshot is imported by a node with the same genesis
+	imported := newTestSnapshotService(t, t.TempDir(), newTestSnapshotTrie(t))
+	err = imported.ImportSnapshot(bytes.NewReader(snapshot.Bytes()))
+	require.NoError(t, err)
+
+	err = imported.Start()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, imported.Stop())
+	}()
+
+	finalised, err := imported.Block.GetHighestFinalisedHeader()
+	require.NoError(t, err)
+	require.Equal(t, &block.Header, finalised)
+	require.Equal(t, hash, imported.Block.BestBlockHash())
+
+	number, err := imported.Block.GetHashByNumber(block.Header.Number)
+	require.NoError(t, err)
+	require.Equal(t, hash, number)
+
+	body, err := imported.Block.GetBlockBody(hash)
+	require.NoError(t, err)
+	require.Equal(t, &block.Body, body)
+
+	entries, err := imported.Storage.Entries(&block.Header.StateRoot)
+	require.NoError(t, err)
+	require.Equal(t, expectedEntries, entries)
+
+	// the child tries aren't loaded along with the state trie, so the child trie is loaded by its root
+	childRoot, err := imported.Storage.GetStorage(&block.Header.StateRoot, append(trie.ChildStorageKeyPrefix, "child"...))
+	require.NoError(t, err)
+	child := trie.NewEmptyTrie()
+	err = child.Load(imported.Storage.db, common.BytesToHash(childRoot))
+	require.NoError(t, err)
+	value, err := child.Get([]byte("child key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("child value"), value)
+
+	epoch, err := imported.Epoch.GetCurrentEpoch()
+	require.NoError(t, err)
+	require.Zero(t, epoch)
+
+	epochData, err := imported.Epoch.GetEpochData(1)
+	require.NoError(t, err)
+	require.Equal(t, nextEpochData, epochData)
+
+	configData, err := imported.Epoch.GetConfigData(0)
+	require.NoError(t, err)
+	require.Equal(t, genesisBABEConfig.C2, configData.C2)
+
+	setID, err := imported.Grandpa.GetCurrentSetID()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), setID)
+
+	setID, err = imported.Grandpa.GetSetIDByBlockNumber(big.NewInt(2))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), setID)
+
+	auths, err := imported.Grandpa.GetAuthorities(setID)
+	require.NoError(t, err)
+	require.Equal(t, nextAuths, auths)
+
+	// the authorities of the previous sets are imported too
+	auths, err = imported.Grandpa.GetAuthorities(0)
+	require.NoError(t, err)
+	require.Equal(t, testAuths, auths)
+}
+
+func TestReadSnapshot_Invalid(t *testing.T) {
+	serv := newTestSnapshotService(t, t.TempDir(), newTestSnapshotTrie(t))
+	err := serv.Start()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, serv.Stop())
+	}()
+
+	block := importTestBlock(t, serv)
+
+	var buf bytes.Buffer
+	err = serv.ExportSnapshot(block.Header.Hash(), &buf)
+	require.NoError(t, err)
+	snapshot := buf.Bytes()
+
+	db, err := utils.SetupDatabase(t.TempDir(), true)
+	require.NoError(t, err)
+
+	_, err = readSnapshot(bytes.NewReader(snapshot), db)
+	require.NoError(t, err)
+
+	// the last byte of the snapshot is part of the checksum
+	corrupted := append([]byte{}, snapshot...)
+	corrupted[len(corrupted)-1]++
+	_, err = readSnapshot(bytes.NewReader(corrupted), db)
+	require.ErrorIs(t, err, errSnapshotChecksumMismatch)
+
+	corrupted = append([]byte{}, snapshot...)
+	corrupted[0]++
+	_, err = readSnapshot(bytes.NewReader(corrupted), db)
+	require.ErrorIs(t, err, errInvalidSnapshot)
+
+	_, err = readSnapshot(bytes.NewReader(snapshot[:len(snapshot)-1]), db)
+	require.Error(t, err)
+}
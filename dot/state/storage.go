@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ChainSafe/chaindb"
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
@@ -33,6 +34,8 @@ type StorageState struct {
 	tries      *sync.Map // map[common.Hash]*trie.Trie // map of root -> trie
 
 	db chaindb.Database
+	// baseDB is the database holding the storage table, the pruner writes its journal records to it
+	baseDB chaindb.Database
 	sync.RWMutex
 
 	// change notifiers
@@ -40,6 +43,11 @@ type StorageState struct {
 	observerList []Observer
 	pruner       pruner.Pruner
 	syncing      bool
+
+	// nodeCache is set when the state tries are loaded lazily from the database
+	nodeCache *trie.NodeCache
+	// storageCache is set when the storage values of the best block are cached
+	storageCache *storageCache
 }
 
 // NewStorageState creates a new StorageState backed by the given trie and database located at basePath.
@@ -73,11 +81,43 @@ func NewStorageState(db chaindb.Database, blockState *BlockState,
 		blockState:   blockState,
 		tries:        tries,
 		db:           storageTable,
+		baseDB:       db,
 		observerList: []Observer{},
 		pruner:       p,
 	}, nil
 }
 
+// UseLazyTries makes the storage state load the state tries lazily from the database, rather than fully
+// into memory, keeping up to cacheSize of the loaded trie nodes in memory. It should be called before the
+// state tries are loaded.
+func (s *StorageState) UseLazyTries(cacheSize int) error {
+	cache, err := trie.NewNodeCache(cacheSize)
+	if err != nil {
+		return fmt.Errorf("cannot create trie node cache: %w", err)
+	}
+
+	s.nodeCache = cache
+	return nil
+}
+
+// UseStorageCache makes the storage state cache up to maxSize bytes of the storage values of the best block,
+// which are kept as the following blocks are imported unless they change them. It should be called once the
+// blocks are loaded.
+func (s *StorageState) UseStorageCache(maxSize int) error {
+	root, err := s.blockState.BestBlockStateRoot()
+	if err != nil {
+		return fmt.Errorf("cannot get best block state root: %w", err)
+	}
+
+	cache, err := newStorageCache(root, maxSize)
+	if err != nil {
+		return fmt.Errorf("cannot create storage cache: %w", err)
+	}
+
+	s.storageCache = cache
+	return nil
+}
+
 // SetSyncing sets whether the node is currently syncing or not
 func (s *StorageState) SetSyncing(syncing bool) {
 	s.syncing = syncing
@@ -91,6 +131,121 @@ func (s *StorageState) pruneKey(keyHeader *types.Header) {
 func (s *StorageState) StoreTrie(ts *rtstorage.TrieState, header *types.Header) error {
 	root := ts.MustRoot()
 
+	if _, ok := s.pruner.(*pruner.FullNode); header == nil && ok {
+		return fmt.Errorf("block cannot be empty for Full node pruner")
+	}
+
+	// the trie nodes are written along with the journal record of the block
+	batch := s.baseDB.NewBatch()
+	record, err := s.writeTrie(batch, ts, header)
+	if err != nil {
+		logger.Warnf("failed to write trie with root %s to database: %s", root, err)
+		return err
+	}
+
+	if err = batch.Flush(); err != nil {
+		logger.Warnf("failed to write trie with root %s to database: %s", root, err)
+		return err
+	}
+
+	s.trieWritten(ts, record)
+	logger.Tracef("cached trie in storage state: %s", root)
+
+	if err = s.releaseTrie(root); err != nil {
+		return err
+	}
+
+	go s.notifyAll(root)
+	return nil
+}
+
+// StoreBlock stores the state trie of the block and adds the block to the block state. The trie nodes,
+// the journal record of the block, the block and the new leaves and best block of the blocktree are
+// written in a single database batch, so the node can't be stopped with some of them written and the
+// others missing. The state is only kept in memory once the batch is written.
+func (s *StorageState) StoreBlock(ts *rtstorage.TrieState, block *types.Block) error {
+	root := ts.MustRoot()
+
+	// the batch is shared by the storage, journal and block tables, it's flushed once the block is added
+	batch := s.blockState.baseState.db.NewBatch()
+	record, err := s.writeTrie(batch, ts, &block.Header)
+	if err != nil {
+		logger.Warnf("failed to write trie with root %s to database: %s", root, err)
+		return err
+	}
+
+	s.blockState.Lock()
+	err = s.blockState.addBlockWithBatch(block, time.Now(), newTableBatch(batch, blockPrefix))
+	s.blockState.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.trieWritten(ts, record)
+
+	if err = s.releaseTrie(root); err != nil {
+		return err
+	}
+
+	s.updateStorageCache(ts, &block.Header)
+
+	logger.Tracef("stored trie with root %s along with block %s", root, block.Header.Hash())
+	go s.notifyAll(root)
+	return nil
+}
+
+// journalRecord holds the state trie nodes inserted and deleted by a block, for the pruner
+type journalRecord struct {
+	inserted, deleted []common.Hash
+	hash              common.Hash
+	number            int64
+}
+
+// writeTrie writes the dirty nodes of the trie of the state to the batch of the database, along with the
+// journal record of the block if the header isn't nil. Once the batch is flushed, trieWritten must be called
+// with the returned journal record.
+func (s *StorageState) writeTrie(batch chaindb.Batch, ts *rtstorage.TrieState,
+	header *types.Header) (*journalRecord, error) {
+	var record *journalRecord
+	if header != nil {
+		inserted, err := ts.GetInsertedNodeHashes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state trie inserted keys: block %s %w", header.Hash(), err)
+		}
+
+		record = &journalRecord{
+			inserted: inserted,
+			deleted:  ts.GetDeletedNodeHashes(),
+			hash:     header.Hash(),
+			number:   header.Number.Int64(),
+		}
+
+		err = s.pruner.StoreJournalRecord(batch, record.deleted, record.inserted, record.hash, record.number)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ts.Trie().WriteDirtyToBatch(newTableBatch(batch, storagePrefix)); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// trieWritten keeps the trie of the state in memory, with its nodes set to clean, and adds the journal
+// record to the pruner, once they're written to the database by writeTrie
+func (s *StorageState) trieWritten(ts *rtstorage.TrieState, record *journalRecord) {
+	ts.Trie().SetClean()
+	s.cacheTrie(ts)
+
+	if record != nil {
+		s.pruner.AddJournalRecord(record.deleted, record.inserted, record.hash, record.number)
+	}
+}
+
+// cacheTrie keeps the trie of the given state in memory
+func (s *StorageState) cacheTrie(ts *rtstorage.TrieState) {
 	if s.syncing {
 		// keep only the trie at the head of the chain when syncing
 		// TODO: probably remove this when memory usage improves (#1494)
@@ -100,34 +255,53 @@ func (s *StorageState) StoreTrie(ts *rtstorage.TrieState, header *types.Header)
 		})
 	}
 
-	_, _ = s.tries.LoadOrStore(root, ts.Trie())
+	_, _ = s.tries.LoadOrStore(ts.MustRoot(), ts.Trie())
+}
 
-	if _, ok := s.pruner.(*pruner.FullNode); header == nil && ok {
-		return fmt.Errorf("block cannot be empty for Full node pruner")
+// releaseTrie replaces the cached trie with the given root, once written to the database, by the lazy trie
+// loaded from the database, so that the nodes written by the block aren't kept in memory by the tries of its
+// descendants. It does nothing if the state tries aren't loaded lazily.
+func (s *StorageState) releaseTrie(root common.Hash) error {
+	if s.nodeCache == nil {
+		return nil
 	}
 
-	if header != nil {
-		insKeys, err := ts.GetInsertedNodeHashes()
-		if err != nil {
-			return fmt.Errorf("failed to get state trie inserted keys: block %s %w", header.Hash(), err)
-		}
+	t, err := trie.NewLazyTrie(s.db, root, s.nodeCache)
+	if err != nil {
+		return fmt.Errorf("failed to load trie with root %s: %w", root, err)
+	}
 
-		delKeys := ts.GetDeletedNodeHashes()
-		err = s.pruner.StoreJournalRecord(delKeys, insKeys, header.Hash(), header.Number.Int64())
-		if err != nil {
-			return err
-		}
+	s.tries.Store(root, t)
+	return nil
+}
+
+// updateStorageCache moves the storage cache to the state of the stored block if it's the new best block,
+// keeping the values of its parent state which it didn't change
+func (s *StorageState) updateStorageCache(ts *rtstorage.TrieState, header *types.Header) {
+	if s.storageCache == nil || s.blockState.BestBlockHash() != header.Hash() {
+		return
 	}
 
-	logger.Tracef("cached trie in storage state: %s", root)
+	// the cache is cleared if the parent state root can't be found
+	var parentRoot common.Hash
+	parent, err := s.blockState.GetHeader(header.ParentHash)
+	if err != nil {
+		logger.Warnf("failed to get parent of block %s: %s", header.Hash(), err)
+	} else {
+		parentRoot = parent.StateRoot
+	}
 
-	if err := ts.Trie().WriteDirty(s.db); err != nil {
-		logger.Warnf("failed to write trie with root %s to database: %s", root, err)
-		return err
+	changedKeys, clearedPrefixes := ts.ChangedKeys()
+	s.storageCache.importBlock(parentRoot, header.StateRoot, changedKeys, clearedPrefixes)
+}
+
+// hasTrie returns true if the root node of the trie with the given root is in the database
+func (s *StorageState) hasTrie(root common.Hash) (bool, error) {
+	if root.Equal(trie.EmptyHash) {
+		return true, nil
 	}
 
-	go s.notifyAll(root)
-	return nil
+	return s.db.Has(root[:])
 }
 
 // TrieState returns the TrieState for a given state root.
@@ -164,12 +338,30 @@ func (s *StorageState) TrieState(root *common.Hash) (*rtstorage.TrieState, error
 		return nil, err
 	}
 
+	if s.storageCache != nil {
+		next.SetCache(&storageCacheView{
+			cache: s.storageCache,
+			root:  *root,
+		})
+	}
+
 	logger.Tracef("returning trie with root %s to be modified", root)
 	return next, nil
 }
 
-// LoadFromDB loads an encoded trie from the DB where the key is `root`
+// LoadFromDB loads an encoded trie from the DB where the key is `root`. If the state tries are loaded lazily,
+// only the root node is loaded.
 func (s *StorageState) LoadFromDB(root common.Hash) (*trie.Trie, error) {
+	if s.nodeCache != nil {
+		t, err := trie.NewLazyTrie(s.db, root, s.nodeCache)
+		if err != nil {
+			return nil, err
+		}
+
+		_, _ = s.tries.LoadOrStore(root, t)
+		return t, nil
+	}
+
 	t := trie.NewEmptyTrie()
 	err := t.Load(s.db, root)
 	if err != nil {
@@ -219,12 +411,30 @@ func (s *StorageState) GetStorage(root *common.Hash, key []byte) ([]byte, error)
 		root = &sr
 	}
 
+	if s.storageCache != nil {
+		if val, has := s.storageCache.get(*root, key); has {
+			return val, nil
+		}
+	}
+
+	var (
+		val []byte
+		err error
+	)
 	if t, has := s.tries.Load(*root); has {
-		val := t.(*trie.Trie).Get(key)
-		return val, nil
+		val, err = t.(*trie.Trie).Get(key)
+	} else {
+		val, err = trie.GetFromDB(s.db, *root, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.storageCache != nil {
+		s.storageCache.add(*root, key, val)
 	}
 
-	return trie.GetFromDB(s.db, *root, key)
+	return val, nil
 }
 
 // GetStorageByBlockHash returns the value at the given key at the given block hash
@@ -278,7 +488,7 @@ func (s *StorageState) Entries(root *common.Hash) (map[string][]byte, error) {
 		return nil, err
 	}
 
-	return tr.Entries(), nil
+	return tr.Entries()
 }
 
 // GetKeysWithPrefix returns all that match the given prefix for the given hash
@@ -289,7 +499,7 @@ func (s *StorageState) GetKeysWithPrefix(root *common.Hash, prefix []byte) ([][]
 		return nil, err
 	}
 
-	return tr.GetKeysWithPrefix(prefix), nil
+	return tr.GetKeysWithPrefix(prefix)
 }
 
 // GetStorageChild returns a child trie, if it exists
@@ -0,0 +1,148 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"bytes"
+	"math"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// storageCache is a size bounded cache of the storage values of the state at a block of the canonical chain.
+// When a child of that block is imported as the best block, the values which weren't changed by the child are
+// kept, so the values read repeatedly across blocks, like the code or the accounts, aren't loaded again from
+// the state trie at every block. Any other change of the best block clears the cache.
+type storageCache struct {
+	sync.Mutex
+	root    common.Hash // the state root of the block whose values are cached
+	values  *simplelru.LRU
+	size    int // the size in bytes of the cached keys and values
+	maxSize int
+	hits    int64
+	misses  int64
+}
+
+// newStorageCache returns a cache of the values of the state with the given root, holding up to maxSize bytes
+// of keys and values
+func newStorageCache(root common.Hash, maxSize int) (*storageCache, error) {
+	c := &storageCache{
+		root:    root,
+		maxSize: maxSize,
+	}
+
+	// the number of values is only bounded by their size
+	values, err := simplelru.NewLRU(math.MaxInt32, func(key, value interface{}) {
+		c.size -= len(key.(string)) + len(value.([]byte))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.values = values
+	return c, nil
+}
+
+// get returns the cached value of the key in the state with the given root. A nil value is returned for the
+// keys cached as not being in the state.
+func (c *storageCache) get(root common.Hash, key []byte) (value []byte, has bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if root != c.root {
+		return nil, false
+	}
+
+	v, has := c.values.Get(string(key))
+	if !has {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	return v.([]byte), true
+}
+
+// add caches the value of the key in the state with the given root, if the values of that state are cached
+func (c *storageCache) add(root common.Hash, key, value []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	entrySize := len(key) + len(value)
+	if root != c.root || entrySize > c.maxSize {
+		return
+	}
+
+	// the value is copied since the caller may modify it
+	if value != nil {
+		value = append(make([]byte, 0, len(value)), value...)
+	}
+
+	// the replaced value is removed first, for its size to be subtracted
+	c.values.Remove(string(key))
+	c.values.Add(string(key), value)
+	c.size += entrySize
+
+	for c.size > c.maxSize {
+		c.values.RemoveOldest()
+	}
+}
+
+// importBlock moves the cache to the state with the given root, imported on top of the state with the given
+// parent root. The values of the changed keys and of the keys with the cleared prefixes are removed if the
+// values of the parent state are cached, otherwise the cache is cleared.
+func (c *storageCache) importBlock(parentRoot, root common.Hash, changedKeys, clearedPrefixes [][]byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	if parentRoot != c.root {
+		c.values.Purge()
+		c.root = root
+		return
+	}
+
+	for _, key := range changedKeys {
+		c.values.Remove(string(key))
+	}
+
+	if len(clearedPrefixes) > 0 {
+		for _, key := range c.values.Keys() {
+			for _, prefix := range clearedPrefixes {
+				if bytes.HasPrefix([]byte(key.(string)), prefix) {
+					c.values.Remove(key)
+					break
+				}
+			}
+		}
+	}
+
+	c.root = root
+}
+
+// stats returns the number of cache hits and misses, and the size in bytes of the cached keys and values
+func (c *storageCache) stats() (hits, misses int64, size int) {
+	c.Lock()
+	defer c.Unlock()
+	return c.hits, c.misses, c.size
+}
+
+// storageCacheView is the cache of the values of the state with the given root, which is empty if the values
+// of that state aren't cached. It's used by the TrieStates created from that state.
+type storageCacheView struct {
+	cache *storageCache
+	root  common.Hash
+}
+
+// Get returns the cached value of the key
+func (v *storageCacheView) Get(key []byte) (value []byte, has bool) {
+	return v.cache.get(v.root, key)
+}
+
+// Add caches the value of the key
+func (v *storageCacheView) Add(key, value []byte) {
+	v.cache.add(v.root, key, value)
+}
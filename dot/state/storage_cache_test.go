@@ -0,0 +1,182 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageCache(t *testing.T) {
+	root := common.Hash{0x1}
+	cache, err := newStorageCache(root, 16)
+	require.NoError(t, err)
+
+	cache.add(root, []byte("key1"), []byte("value1"))
+	cache.add(root, []byte("key2"), nil)
+	cache.add(common.Hash{0x2}, []byte("key3"), []byte("value3"))
+
+	value, has := cache.get(root, []byte("key1"))
+	require.True(t, has)
+	require.Equal(t, []byte("value1"), value)
+
+	value, has = cache.get(root, []byte("key2"))
+	require.True(t, has)
+	require.Nil(t, value)
+
+	// the values of the other states aren't cached
+	_, has = cache.get(common.Hash{0x2}, []byte("key3"))
+	require.False(t, has)
+	_, has = cache.get(root, []byte("key3"))
+	require.False(t, has)
+
+	hits, misses, size := cache.stats()
+	require.Equal(t, int64(2), hits)
+	require.Equal(t, int64(1), misses)
+	require.Equal(t, 14, size)
+
+	// the least recently used values are evicted above the maximum size
+	cache.add(root, []byte("key4"), []byte("v4"))
+	_, has = cache.get(root, []byte("key1"))
+	require.False(t, has)
+	_, has = cache.get(root, []byte("key4"))
+	require.True(t, has)
+
+	_, _, size = cache.stats()
+	require.Equal(t, 10, size)
+
+	cache.add(root, []byte("key4"), []byte("v4"))
+	_, _, size = cache.stats()
+	require.Equal(t, 10, size)
+
+	// values larger than the cache aren't cached
+	cache.add(root, []byte("key5"), make([]byte, 16))
+	_, has = cache.get(root, []byte("key5"))
+	require.False(t, has)
+}
+
+func TestStorageCache_ImportBlock(t *testing.T) {
+	root := common.Hash{0x1}
+	cache, err := newStorageCache(root, 1024)
+	require.NoError(t, err)
+
+	for _, key := range []string{"key1", "key2", "prefix:key1", "prefix:key2"} {
+		cache.add(root, []byte(key), []byte("value"))
+	}
+
+	// the values changed by the child aren't kept
+	child := common.Hash{0x2}
+	cache.importBlock(root, child, [][]byte{[]byte("key1")}, [][]byte{[]byte("prefix:")})
+
+	_, has := cache.get(root, []byte("key2"))
+	require.False(t, has)
+
+	for key, cached := range map[string]bool{"key1": false, "key2": true, "prefix:key1": false, "prefix:key2": false} {
+		_, has = cache.get(child, []byte(key))
+		require.Equal(t, cached, has, key)
+	}
+
+	// the cache is cleared if the block isn't a child of the cached block
+	other := common.Hash{0x3}
+	cache.importBlock(root, other, nil, nil)
+
+	_, has = cache.get(other, []byte("key2"))
+	require.False(t, has)
+
+	_, _, size := cache.stats()
+	require.Zero(t, size)
+}
+
+func TestStorage_StorageCache(t *testing.T) {
+	storage := newTestStorageState(t)
+	err := storage.UseStorageCache(1024)
+	require.NoError(t, err)
+
+	storeBlock := func(parent *types.Header, changes map[string][]byte) *types.Header {
+		ts, err := storage.TrieState(&parent.StateRoot)
+		require.NoError(t, err)
+
+		for key, value := range changes {
+			if value == nil {
+				ts.Delete([]byte(key))
+				continue
+			}
+			ts.Set([]byte(key), value)
+		}
+
+		body, err := types.NewBodyFromBytes([]byte{})
+		require.NoError(t, err)
+
+		block := &types.Block{
+			Header: types.Header{
+				ParentHash: parent.Hash(),
+				Number:     big.NewInt(0).Add(parent.Number, big.NewInt(1)),
+				StateRoot:  ts.MustRoot(),
+				Digest:     types.NewDigest(),
+			},
+			Body: *body,
+		}
+
+		err = storage.StoreBlock(ts, block)
+		require.NoError(t, err)
+		return &block.Header
+	}
+
+	header1 := storeBlock(testGenesisHeader, map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+	})
+	require.Equal(t, header1.Hash(), storage.blockState.BestBlockHash())
+
+	// the values read by the runtime and the RPCs are cached
+	ts, err := storage.TrieState(&header1.StateRoot)
+	require.NoError(t, err)
+	value, err := ts.Get([]byte("key1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), value)
+
+	value, err = storage.GetStorage(&header1.StateRoot, []byte("key2"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value2"), value)
+
+	value, err = storage.GetStorage(nil, []byte("key1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), value)
+
+	hits, misses, _ := storage.storageCache.stats()
+	require.Equal(t, int64(1), hits)
+	require.Equal(t, int64(2), misses)
+
+	// the values which aren't changed by the next block are kept
+	header2 := storeBlock(header1, map[string][]byte{
+		"key1": nil,
+	})
+
+	value, err = storage.GetStorage(&header2.StateRoot, []byte("key2"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value2"), value)
+
+	value, err = storage.GetStorage(&header2.StateRoot, []byte("key1"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	hits, misses, _ = storage.storageCache.stats()
+	require.Equal(t, int64(2), hits)
+	require.Equal(t, int64(3), misses)
+
+	// the values of the other states are read from their trie
+	value, err = storage.GetStorage(&header1.StateRoot, []byte("key1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), value)
+
+	value, err = storage.GetStorage(&trie.EmptyHash, []byte("key2"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
@@ -95,7 +95,10 @@ func (s *StorageState) notifyObserver(root common.Hash, o Observer) error {
 	}
 	if len(o.GetFilter()) == 0 {
 		// no filter, so send all changes
-		ent := t.TrieEntries()
+		ent, err := t.TrieEntries()
+		if err != nil {
+			return err
+		}
 		for k, v := range ent {
 			if k != ":code" {
 				// currently we're ignoring :code since this is a lot of data
@@ -109,7 +112,10 @@ func (s *StorageState) notifyObserver(root common.Hash, o Observer) error {
 	} else {
 		// filter result to include only interested keys
 		for k, cachedValue := range o.GetFilter() {
-			value := t.Get(common.MustHexToBytes(k))
+			value, err := t.Get(common.MustHexToBytes(k))
+			if err != nil {
+				return err
+			}
 			if !reflect.DeepEqual(cachedValue, value) {
 				kv := &KeyValue{
 					Key:   common.MustHexToBytes(k),
@@ -183,3 +183,74 @@ func TestStorage_StoreTrie_NotSyncing(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 2, syncMapLen(storage.tries))
 }
+
+func TestStorage_LazyTries(t *testing.T) {
+	storage := newTestStorageState(t)
+	err := storage.UseLazyTries(16)
+	require.NoError(t, err)
+
+	ts, err := storage.TrieState(&trie.EmptyHash)
+	require.NoError(t, err)
+
+	keys := [][]byte{[]byte("key1"), []byte("key2"), []byte("xyzKey1")}
+	for _, key := range keys {
+		ts.Set(key, append([]byte("value of "), key...))
+	}
+
+	child := trie.NewEmptyTrie()
+	child.Put([]byte("childkey"), []byte("childvalue"))
+	err = ts.SetChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	root := ts.MustRoot()
+	err = storage.StoreTrie(ts, nil)
+	require.NoError(t, err)
+
+	// the stored trie is replaced by the trie loaded lazily from the database
+	tr, has := storage.tries.Load(root)
+	require.True(t, has)
+	require.True(t, tr.(*trie.Trie).IsLazy())
+	require.Equal(t, root, tr.(*trie.Trie).MustHash())
+
+	value, err := storage.GetStorage(&root, keys[0])
+	require.NoError(t, err)
+	require.Equal(t, []byte("value of key1"), value)
+
+	prefixed, err := storage.GetKeysWithPrefix(&root, []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, keys[:2], prefixed)
+
+	value, err = storage.GetStorageFromChild(&root, []byte("child"), []byte("childkey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("childvalue"), value)
+
+	// the state of the next block is written to the lazy trie
+	next, err := storage.TrieState(&root)
+	require.NoError(t, err)
+	nextKey, err := next.NextKey(keys[0])
+	require.NoError(t, err)
+	require.Equal(t, keys[1], nextKey)
+
+	err = next.Delete(keys[0])
+	require.NoError(t, err)
+	err = next.SetChildStorage([]byte("child"), []byte("childkey"), []byte("newvalue"))
+	require.NoError(t, err)
+
+	nextRoot := next.MustRoot()
+	require.NotEqual(t, root, nextRoot)
+	err = storage.StoreTrie(next, nil)
+	require.NoError(t, err)
+
+	value, err = storage.GetStorage(&root, keys[0])
+	require.NoError(t, err)
+	require.Equal(t, []byte("value of key1"), value)
+
+	value, err = storage.GetStorage(&nextRoot, keys[0])
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	storage.tries.Delete(nextRoot)
+	value, err = storage.GetStorageFromChild(&nextRoot, []byte("child"), []byte("childkey"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("newvalue"), value)
+}
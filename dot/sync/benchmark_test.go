@@ -0,0 +1,150 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/babe"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+
+	"github.com/stretchr/testify/require"
+)
+
+// measureImportThroughput imports the given blocks by verifying their headers and executing them one after
+// the other, or through an import pipeline if workers is positive, and returns the number of blocks imported
+// per second. It is used to compare the throughput of the import pipeline with sequential imports.
+func measureImportThroughput(blocks []*types.BlockData, workers int,
+	verify func(*types.Header) error, importBlock func(*types.BlockData, error)) float64 {
+	start := time.Now()
+
+	if workers <= 0 {
+		for _, bd := range blocks {
+			importBlock(bd, verify(bd.Header))
+		}
+		return float64(len(blocks)) / time.Since(start).Seconds()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := newBlockQueue(len(blocks))
+	for _, bd := range blocks {
+		queue.push(bd)
+	}
+
+	imported := 0
+	pipeline := newImportPipeline(ctx, workers, maxBlocksInFlight, verify)
+	pipeline.run(func() *types.BlockData {
+		return queue.take(ctx)
+	}, func(bd *types.BlockData, verifyErr error) {
+		importBlock(bd, verifyErr)
+		imported++
+		if imported == len(blocks) {
+			cancel()
+		}
+	})
+
+	return float64(len(blocks)) / time.Since(start).Seconds()
+}
+
+// newBenchmarkBlocks builds a chain of n blocks on top of the genesis block with the runtime,
+// each of them sealed by the given BABE authority
+func newBenchmarkBlocks(b *testing.B, kp *sr25519.Keypair, n int) []*types.BlockData {
+	builder, _ := newTestSyncerWithState(b)
+
+	parent, err := builder.blockState.BestBlockHeader()
+	require.NoError(b, err)
+
+	rt, err := builder.blockState.GetRuntime(nil)
+	require.NoError(b, err)
+
+	blocks := make([]*types.BlockData, n)
+	for i := range blocks {
+		block := BuildBlock(b, rt, parent, nil)
+
+		hash := block.Header.Hash()
+		sig, err := kp.Sign(hash[:])
+		require.NoError(b, err)
+
+		err = block.Header.Digest.Add(types.SealDigest{
+			ConsensusEngineID: types.BabeEngineID,
+			Data:              sig,
+		})
+		require.NoError(b, err)
+
+		blocks[i] = &types.BlockData{
+			Hash:   block.Header.Hash(),
+			Header: &block.Header,
+			Body:   &block.Body,
+		}
+		parent = &block.Header
+	}
+
+	return blocks
+}
+
+// newBenchmarkImporter returns a chain processor at genesis, along with a BABE verifier for which
+// the given keypair is the only authority
+func newBenchmarkImporter(b *testing.B, kp *sr25519.Keypair) (*chainProcessor, *babe.VerificationManager) {
+	syncer, stateSrvc := newTestSyncerWithState(b)
+
+	// the benchmark blocks are all claimed in the same secondary slot by the first authority
+	err := stateSrvc.Epoch.SetEpochData(0, &types.EpochData{
+		Authorities: []types.Authority{*types.NewAuthority(kp.Public(), 1)},
+	})
+	require.NoError(b, err)
+
+	err = stateSrvc.Epoch.SetConfigData(0, &types.ConfigData{
+		C1:             1,
+		C2:             4,
+		SecondarySlots: 1,
+	})
+	require.NoError(b, err)
+
+	err = stateSrvc.Epoch.SetFirstSlot(1)
+	require.NoError(b, err)
+
+	verifier, err := babe.NewVerificationManager(stateSrvc.Block, stateSrvc.Epoch)
+	require.NoError(b, err)
+
+	return syncer.chainProcessor.(*chainProcessor), verifier
+}
+
+// BenchmarkImportThroughput compares the import of blocks whose headers are verified by BABE before
+// they are executed by the runtime one after the other, with their import through the pipeline
+func BenchmarkImportThroughput(b *testing.B) {
+	kp, err := sr25519.GenerateKeypair()
+	require.NoError(b, err)
+
+	blocks := newBenchmarkBlocks(b, kp, maxResponseSize)
+
+	run := func(name string, workers int) {
+		b.Run(name, func(b *testing.B) {
+			var bps float64
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				processor, verifier := newBenchmarkImporter(b, kp)
+				b.StartTimer()
+
+				bps += measureImportThroughput(blocks, workers, verifier.VerifyBlock,
+					func(bd *types.BlockData, verifyErr error) {
+						require.NoError(b, verifyErr)
+						err := processor.processVerifiedBlockData(bd, true)
+						require.NoError(b, err)
+					})
+			}
+			b.ReportMetric(bps/float64(b.N), "blocks/s")
+		})
+	}
+
+	run("sequential", 0)
+	for workers := 1; workers <= verificationWorkers; workers *= 2 {
+		run(fmt.Sprintf("%d workers", workers), workers)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"errors"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
+
+	"github.com/ChainSafe/gossamer/internal/log"
+)
+
+// BlockImporter imports blocks that weren't received from the network, eg. blocks read from a file.
+// The blocks go through the same verification and execution as the blocks processed by the chainProcessor.
+type BlockImporter struct {
+	processor *chainProcessor
+}
+
+// NewBlockImporter returns a new *BlockImporter. The Network, DigestHandler and peer related fields
+// of the config are not used.
+func NewBlockImporter(cfg *Config) (*BlockImporter, error) {
+	if err := cfg.checkImport(); err != nil {
+		return nil, err
+	}
+
+	logger.Patch(log.SetLevel(cfg.LogLvl))
+
+	// the blocks are imported one at a time by the caller, so there are no ready or pending blocks
+	processor := newChainProcessor(nil, nil,
+		cfg.BlockState, cfg.StorageState, cfg.TransactionState,
+		cfg.BabeVerifier, cfg.FinalityGadget, cfg.BlockImportHandler, nil)
+
+	return &BlockImporter{
+		processor: processor,
+	}, nil
+}
+
+// ImportBlock verifies, executes and stores the given block, then verifies and stores its justification
+// if it isn't nil. The parent of the block must already be imported. Blocks that are already imported
+// are skipped, but their justification is still imported. An error wrapping ErrInvalidJustification
+// is returned if the justification is invalid.
+func (bi *BlockImporter) ImportBlock(block *types.Block, justification *[]byte) error {
+	finalised, err := bi.isFinalised(&block.Header)
+	if err != nil {
+		return err
+	}
+
+	// finalised blocks aren't in the block tree anymore, so they can't go through the chain processor
+	if !finalised {
+		if err = bi.processor.processBlockData(block.ToBlockData()); err != nil {
+			return err
+		}
+	}
+
+	if justification == nil {
+		return nil
+	}
+
+	return bi.processor.importJustification(&block.Header, *justification)
+}
+
+// isFinalised returns true if the given block is already imported and finalised
+func (bi *BlockImporter) isFinalised(header *types.Header) (bool, error) {
+	highest, err := bi.processor.blockState.GetHighestFinalisedHeader()
+	if err != nil {
+		return false, err
+	}
+
+	if header.Number.Cmp(highest.Number) > 0 {
+		return false, nil
+	}
+
+	hash, err := bi.processor.blockState.GetHashByNumber(header.Number)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return hash == header.Hash(), nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/state"
+	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBlockImporter(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+	cfg := &Config{
+		BlockState:         bs,
+		StorageState:       ss,
+		TransactionState:   state.NewTransactionState(),
+		FinalityGadget:     new(syncmocks.FinalityGadget),
+		BabeVerifier:       new(syncmocks.BabeVerifier),
+		BlockImportHandler: new(syncmocks.BlockImportHandler),
+	}
+
+	// the network isn't needed to import blocks
+	_, err := NewBlockImporter(cfg)
+	require.NoError(t, err)
+
+	cfg.BabeVerifier = nil
+	_, err = NewBlockImporter(cfg)
+	require.ErrorIs(t, err, errNilVerifier)
+}
+
+func TestBlockImporter_ImportBlock_UnknownParent(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+
+	verifier := new(syncmocks.BabeVerifier)
+	verifier.On("VerifyBlock", mock.AnythingOfType("*types.Header")).Return(nil)
+
+	importer, err := NewBlockImporter(&Config{
+		BlockState:         bs,
+		StorageState:       ss,
+		TransactionState:   state.NewTransactionState(),
+		FinalityGadget:     new(syncmocks.FinalityGadget),
+		BabeVerifier:       verifier,
+		BlockImportHandler: new(syncmocks.BlockImportHandler),
+	})
+	require.NoError(t, err)
+
+	header := &types.Header{
+		ParentHash: common.Hash{1},
+		Number:     big.NewInt(2),
+		Digest:     types.NewDigest(),
+	}
+	block := types.NewBlock(*header, types.Body{{1, 2, 3}})
+
+	err = importer.ImportBlock(&block, nil)
+	require.ErrorIs(t, err, errFailedToGetParent)
+	verifier.AssertNumberOfCalls(t, "VerifyBlock", 1)
+}
+
+func TestBlockImporter_ImportBlock_Finalised(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+
+	verifier := new(syncmocks.BabeVerifier)
+	importer, err := NewBlockImporter(&Config{
+		BlockState:         bs,
+		StorageState:       ss,
+		TransactionState:   state.NewTransactionState(),
+		FinalityGadget:     new(syncmocks.FinalityGadget),
+		BabeVerifier:       verifier,
+		BlockImportHandler: new(syncmocks.BlockImportHandler),
+	})
+	require.NoError(t, err)
+
+	// the genesis block isn't in the block tree's nodes, but it's already imported
+	genesis, err := bs.GetBlockByHash(bs.GenesisHash())
+	require.NoError(t, err)
+
+	err = importer.ImportBlock(genesis, nil)
+	require.NoError(t, err)
+	verifier.AssertNotCalled(t, "VerifyBlock", mock.Anything)
+}
+
+func TestBlockImporter_ImportBlock_InvalidJustification(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+
+	finalityGadget := new(syncmocks.FinalityGadget)
+	// using []uint8 instead of []byte: https://github.com/stretchr/testify/pull/969
+	finalityGadget.On("VerifyBlockJustification", mock.AnythingOfType("common.Hash"),
+		mock.AnythingOfType("[]uint8")).Return(errors.New("invalid signature"))
+
+	importer, err := NewBlockImporter(&Config{
+		BlockState:         bs,
+		StorageState:       ss,
+		TransactionState:   state.NewTransactionState(),
+		FinalityGadget:     finalityGadget,
+		BabeVerifier:       new(syncmocks.BabeVerifier),
+		BlockImportHandler: new(syncmocks.BlockImportHandler),
+	})
+	require.NoError(t, err)
+
+	header := &types.Header{
+		ParentHash: bs.GenesisHash(),
+		Number:     big.NewInt(1),
+		Digest:     types.NewDigest(),
+	}
+	block := types.NewBlock(*header, types.Body{{1, 2, 3}})
+
+	// the block is already imported, but its justification still fails the import
+	err = bs.AddBlock(&block)
+	require.NoError(t, err)
+
+	justification := []byte("justification")
+	err = importer.ImportBlock(&block, &justification)
+	require.ErrorIs(t, err, ErrInvalidJustification)
+
+	has, err := bs.HasJustification(header.Hash())
+	require.NoError(t, err)
+	require.False(t, has)
+}
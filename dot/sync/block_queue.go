@@ -4,6 +4,7 @@
 package sync
 
 import (
+	"context"
 	"sync"
 
 	"github.com/ChainSafe/gossamer/dot/types"
@@ -37,11 +38,27 @@ func (q *blockQueue) push(bd *types.BlockData) {
 
 // pop pops an item from the queue. it blocks if the queue is empty.
 func (q *blockQueue) pop() *types.BlockData {
-	bd := <-q.ch
+	bd := q.take(context.Background())
+	q.remove(bd.Hash)
+	return bd
+}
+
+// take pops an item from the queue, but the queue still has it until it's removed.
+// it blocks if the queue is empty, and returns nil once the context is done.
+func (q *blockQueue) take(ctx context.Context) *types.BlockData {
+	select {
+	case bd := <-q.ch:
+		return bd
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// remove removes an item that was taken from the queue.
+func (q *blockQueue) remove(hash common.Hash) {
 	q.Lock()
-	delete(q.blocks, bd.Hash)
+	delete(q.blocks, hash)
 	q.Unlock()
-	return bd
 }
 
 func (q *blockQueue) has(hash common.Hash) bool {
@@ -39,25 +39,31 @@ type chainProcessor struct {
 	babeVerifier       BabeVerifier
 	finalityGadget     FinalityGadget
 	blockImportHandler BlockImportHandler
+
+	// the blocks whose justification failed to be verified are requested again from our peers,
+	// it's nil when blocks are imported without the network
+	missingJustifications *missingJustifications
 }
 
 func newChainProcessor(readyBlocks *blockQueue, pendingBlocks DisjointBlockSet,
 	blockState BlockState, storageState StorageState,
 	transactionState TransactionState, babeVerifier BabeVerifier,
-	finalityGadget FinalityGadget, blockImportHandler BlockImportHandler) *chainProcessor {
+	finalityGadget FinalityGadget, blockImportHandler BlockImportHandler,
+	missingJustifications *missingJustifications) *chainProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &chainProcessor{
-		ctx:                ctx,
-		cancel:             cancel,
-		readyBlocks:        readyBlocks,
-		pendingBlocks:      pendingBlocks,
-		blockState:         blockState,
-		storageState:       storageState,
-		transactionState:   transactionState,
-		babeVerifier:       babeVerifier,
-		finalityGadget:     finalityGadget,
-		blockImportHandler: blockImportHandler,
+		ctx:                   ctx,
+		cancel:                cancel,
+		readyBlocks:           readyBlocks,
+		pendingBlocks:         pendingBlocks,
+		blockState:            blockState,
+		storageState:          storageState,
+		transactionState:      transactionState,
+		babeVerifier:          babeVerifier,
+		finalityGadget:        finalityGadget,
+		blockImportHandler:    blockImportHandler,
+		missingJustifications: missingJustifications,
 	}
 }
 
@@ -69,30 +75,33 @@ func (s *chainProcessor) stop() {
 	s.cancel()
 }
 
+// processReadyBlocks imports the ready blocks through the import pipeline, so that their headers are
+// verified in parallel while the blocks before them are executed
 func (s *chainProcessor) processReadyBlocks() {
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		default:
-		}
+	pipeline := newImportPipeline(s.ctx, verificationWorkers, maxBlocksInFlight, s.babeVerifier.VerifyBlock)
+	next := func() *types.BlockData {
+		return s.readyBlocks.take(s.ctx)
+	}
 
-		bd := s.readyBlocks.pop()
-		if bd == nil {
-			continue
-		}
+	pipeline.run(next, func(bd *types.BlockData, verifyErr error) {
+		defer s.readyBlocks.remove(bd.Hash)
+		s.handleReadyBlockData(bd, verifyErr == nil)
+	})
+}
 
-		if err := s.processBlockData(bd); err != nil {
-			logger.Errorf("block data processing for block with hash %s failed: %s", bd.Hash, err)
-
-			// depending on the error, we might want to save this block for later
-			if errors.Is(err, errFailedToGetParent) {
-				if err := s.pendingBlocks.addBlock(&types.Block{
-					Header: *bd.Header,
-					Body:   *bd.Body,
-				}); err != nil {
-					logger.Debugf("failed to re-add block to pending blocks: %s", err)
-				}
+// handleReadyBlockData processes the given block data, and saves it for later if its parent is unknown.
+// headerVerified is true if its header was already verified by the import pipeline.
+func (s *chainProcessor) handleReadyBlockData(bd *types.BlockData, headerVerified bool) {
+	if err := s.processVerifiedBlockData(bd, headerVerified); err != nil {
+		logger.Errorf("block data processing for block with hash %s failed: %s", bd.Hash, err)
+
+		// depending on the error, we might want to save this block for later
+		if errors.Is(err, errFailedToGetParent) {
+			if err := s.pendingBlocks.addBlock(&types.Block{
+				Header: *bd.Header,
+				Body:   *bd.Body,
+			}); err != nil {
+				logger.Debugf("failed to re-add block to pending blocks: %s", err)
 			}
 		}
 	}
@@ -102,6 +111,13 @@ func (s *chainProcessor) processReadyBlocks() {
 // eturns the index of the last BlockData it handled on success,
 // or the index of the block data that errored on failure.
 func (s *chainProcessor) processBlockData(bd *types.BlockData) error {
+	return s.processVerifiedBlockData(bd, false)
+}
+
+// processVerifiedBlockData processes the BlockData, its header is verified unless headerVerified is true.
+// Headers that failed to be verified ahead of time are verified again, as the epoch data they need may
+// have been missing until the blocks before them were imported.
+func (s *chainProcessor) processVerifiedBlockData(bd *types.BlockData, headerVerified bool) error {
 	if bd == nil {
 		return ErrNilBlockData
 	}
@@ -155,8 +171,10 @@ func (s *chainProcessor) processBlockData(bd *types.BlockData) error {
 	logger.Debugf("processing block data with hash %s", bd.Hash)
 
 	if bd.Header != nil && bd.Body != nil {
-		if err := s.handleHeader(bd.Header); err != nil {
-			return err
+		if !headerVerified {
+			if err := s.handleHeader(bd.Header); err != nil {
+				return err
+			}
 		}
 
 		s.handleBody(bd.Body)
@@ -209,6 +227,11 @@ func (s *chainProcessor) handleBlock(block *types.Block) error {
 		return errors.New("block or body is nil")
 	}
 
+	// bad blocks are never executed, whichever way they reach the processor
+	if s.blockState.IsBadBlock(&block.Header) {
+		return fmt.Errorf("%w: hash %s", errBadBlock, block.Header.Hash())
+	}
+
 	parent, err := s.blockState.GetHeader(block.Header.ParentHash)
 	if err != nil {
 		return fmt.Errorf("%w: %s", errFailedToGetParent, err)
@@ -263,17 +286,33 @@ func (s *chainProcessor) handleJustification(header *types.Header, justification
 		return
 	}
 
-	err := s.finalityGadget.VerifyBlockJustification(header.Hash(), justification)
-	if err != nil {
+	err := s.importJustification(header, justification)
+	if errors.Is(err, ErrInvalidJustification) {
 		logger.Warnf("failed to verify block number %s and hash %s justification: %s", header.Number, header.Hash(), err)
+		if s.missingJustifications != nil {
+			s.missingJustifications.add(header.Hash(), header.Number)
+		}
+		return
+	} else if err != nil {
+		logger.Errorf("failed to import justification: %s", err)
 		return
 	}
 
+	logger.Infof("🔨 finalised block number %s with hash %s", header.Number, header.Hash())
+}
+
+// importJustification verifies the justification of the given block and stores it.
+// It returns an error wrapping ErrInvalidJustification if the verification fails.
+func (s *chainProcessor) importJustification(header *types.Header, justification []byte) error {
+	err := s.finalityGadget.VerifyBlockJustification(header.Hash(), justification)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidJustification, err)
+	}
+
 	err = s.blockState.SetJustification(header.Hash(), justification)
 	if err != nil {
-		logger.Errorf("failed tostore justification: %s", err)
-		return
+		return fmt.Errorf("failed to store justification: %w", err)
 	}
 
-	logger.Infof("🔨 finalised block number %s with hash %s", header.Number, header.Hash())
+	return nil
 }
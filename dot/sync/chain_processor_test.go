@@ -11,6 +11,7 @@ import (
 
 	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/state"
+	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common/variadic"
 	"github.com/ChainSafe/gossamer/lib/transaction"
@@ -249,3 +250,24 @@ func TestChainProcessor_processReadyBlocks_errFailedToGetParent(t *testing.T) {
 	time.Sleep(time.Millisecond * 100)
 	require.True(t, processor.pendingBlocks.hasBlock(header.Hash()))
 }
+
+func TestChainProcessor_handleBlock_BadBlock(t *testing.T) {
+	block := &types.Block{
+		Header: types.Header{
+			Number: big.NewInt(1),
+			Digest: types.NewDigest(),
+		},
+		Body: types.Body{},
+	}
+
+	bs := new(syncmocks.BlockState)
+	bs.On("IsBadBlock", &block.Header).Return(true)
+
+	// the block is refused before its parent state is looked up, nor is it executed
+	processor := &chainProcessor{
+		blockState: bs,
+	}
+	err := processor.handleBlock(block)
+	require.True(t, errors.Is(err, errBadBlock))
+	bs.AssertExpectations(t)
+}
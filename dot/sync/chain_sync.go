@@ -5,7 +5,6 @@ package sync
 
 import (
 	"context"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"math/big"
@@ -89,6 +88,9 @@ type ChainSync interface {
 	// called upon receiving a BlockAnnounceHandshake
 	setPeerHead(p peer.ID, hash common.Hash, number *big.Int) error
 
+	// called once all the connections with the peer are closed
+	removePeer(p peer.ID)
+
 	// syncState returns the current syncing state
 	syncState() chainSyncState
 }
@@ -112,6 +114,17 @@ type chainSync struct {
 	peerState   map[peer.ID]*peerState
 	ignorePeers map[peer.ID]struct{}
 
+	// tracks how well our peers answer our block requests
+	peerScores *peerScores
+
+	// the highest common ancestor found with each peer when requesting a fork
+	commonAncestors *commonAncestors
+
+	// finalisable blocks we have without a justification, which are requested from our peers
+	missingJustifications *missingJustifications
+	grandpaState          GrandpaState
+	finalityGadget        FinalityGadget
+
 	// current workers that are attempting to obtain blocks
 	workerState *workerState
 
@@ -149,35 +162,43 @@ type chainSync struct {
 }
 
 type chainSyncConfig struct {
-	bs                 BlockState
-	net                Network
-	readyBlocks        *blockQueue
-	pendingBlocks      DisjointBlockSet
-	minPeers, maxPeers int
-	slotDuration       time.Duration
+	bs                    BlockState
+	net                   Network
+	gs                    GrandpaState
+	finalityGadget        FinalityGadget
+	readyBlocks           *blockQueue
+	pendingBlocks         DisjointBlockSet
+	missingJustifications *missingJustifications
+	minPeers, maxPeers    int
+	slotDuration          time.Duration
 }
 
 func newChainSync(cfg *chainSyncConfig) *chainSync {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &chainSync{
-		ctx:              ctx,
-		cancel:           cancel,
-		blockState:       cfg.bs,
-		network:          cfg.net,
-		workQueue:        make(chan *peerState, 1024),
-		resultQueue:      make(chan *worker, 1024),
-		peerState:        make(map[peer.ID]*peerState),
-		ignorePeers:      make(map[peer.ID]struct{}),
-		workerState:      newWorkerState(),
-		readyBlocks:      cfg.readyBlocks,
-		pendingBlocks:    cfg.pendingBlocks,
-		state:            bootstrap,
-		handler:          newBootstrapSyncer(cfg.bs),
-		benchmarker:      newSyncBenchmarker(),
-		finalisedCh:      cfg.bs.GetFinalisedNotifierChannel(),
-		minPeers:         cfg.minPeers,
-		maxWorkerRetries: uint16(cfg.maxPeers),
-		slotDuration:     cfg.slotDuration,
+		ctx:                   ctx,
+		cancel:                cancel,
+		blockState:            cfg.bs,
+		network:               cfg.net,
+		workQueue:             make(chan *peerState, 1024),
+		resultQueue:           make(chan *worker, 1024),
+		peerState:             make(map[peer.ID]*peerState),
+		ignorePeers:           make(map[peer.ID]struct{}),
+		peerScores:            newPeerScores(),
+		commonAncestors:       newCommonAncestors(),
+		missingJustifications: cfg.missingJustifications,
+		grandpaState:          cfg.gs,
+		finalityGadget:        cfg.finalityGadget,
+		workerState:           newWorkerState(),
+		readyBlocks:           cfg.readyBlocks,
+		pendingBlocks:         cfg.pendingBlocks,
+		state:                 bootstrap,
+		handler:               newBootstrapSyncer(cfg.bs),
+		benchmarker:           newSyncBenchmarker(),
+		finalisedCh:           cfg.bs.GetFinalisedNotifierChannel(),
+		minPeers:              cfg.minPeers,
+		maxWorkerRetries:      uint16(cfg.maxPeers),
+		slotDuration:          cfg.slotDuration,
 	}
 }
 
@@ -198,6 +219,7 @@ func (cs *chainSync) start() {
 	go cs.pendingBlocks.run(pendingBlockDoneCh)
 	go cs.sync()
 	go cs.logSyncSpeed()
+	go cs.requestJustifications()
 }
 
 func (cs *chainSync) stop() {
@@ -212,6 +234,11 @@ func (cs *chainSync) syncState() chainSyncState {
 }
 
 func (cs *chainSync) setBlockAnnounce(from peer.ID, header *types.Header) error {
+	if cs.blockState.IsBadBlock(header) {
+		cs.reportBadBlock(from)
+		return fmt.Errorf("%w: announced block hash %s", errBadBlock, header.Hash())
+	}
+
 	// check if we already know of this block, if not,
 	// add to pendingBlocks set
 	has, err := cs.blockState.HasHeader(header.Hash())
@@ -236,6 +263,11 @@ func (cs *chainSync) setBlockAnnounce(from peer.ID, header *types.Header) error
 
 // setPeerHead sets a peer's best known block and potentially adds the peer's state to the workQueue
 func (cs *chainSync) setPeerHead(p peer.ID, hash common.Hash, number *big.Int) error {
+	if cs.blockState.HasBadBlock(hash) {
+		cs.reportBadBlock(p)
+		return fmt.Errorf("%w: peer head hash %s", errBadBlock, hash)
+	}
+
 	ps := &peerState{
 		who:    p,
 		hash:   hash,
@@ -276,12 +308,14 @@ func (cs *chainSync) setPeerHead(p peer.ID, hash common.Hash, number *big.Int) e
 		// chain), and also the highest finalised block is higher than that number.
 		// thus the peer is on an invalid chain
 		if fin.Number.Cmp(ps.number) >= 0 {
-			// TODO: downscore this peer, or temporarily don't sync from them? (#1399)
-			// perhaps we need another field in `peerState` to mark whether the state is valid or not
 			cs.network.ReportPeer(peerset.ReputationChange{
 				Value:  peerset.BadBlockAnnouncementValue,
 				Reason: peerset.BadBlockAnnouncementReason,
 			}, p)
+
+			// don't sync from them until they send us a valid response again
+			cs.peerScores.recordInvalid(p)
+			cs.ignorePeer(p)
 			return errPeerOnInvalidFork
 		}
 
@@ -309,6 +343,17 @@ func (cs *chainSync) setPeerHead(p peer.ID, hash common.Hash, number *big.Int) e
 	return nil
 }
 
+// reportBadBlock lowers the reputation of a peer which announced or sent us a bad block,
+// and stops syncing from it
+func (cs *chainSync) reportBadBlock(who peer.ID) {
+	cs.network.ReportPeer(peerset.ReputationChange{
+		Value:  peerset.BadBlockValue,
+		Reason: peerset.BadBlockReason,
+	}, who)
+	cs.peerScores.recordInvalid(who)
+	cs.ignorePeer(who)
+}
+
 func (cs *chainSync) logSyncSpeed() {
 	t := time.NewTicker(time.Second * 5)
 	defer t.Stop()
@@ -380,6 +425,12 @@ func (cs *chainSync) ignorePeer(who peer.ID) {
 	cs.Unlock()
 }
 
+// removePeer forgets about what we learnt of the peer, once it's disconnected
+func (cs *chainSync) removePeer(who peer.ID) {
+	cs.peerScores.remove(who)
+	cs.commonAncestors.delete(who)
+}
+
 func (cs *chainSync) sync() {
 	// set to slot time
 	ticker := time.NewTicker(cs.slotDuration)
@@ -418,6 +469,9 @@ func (cs *chainSync) sync() {
 					Reason: peerset.TimeOutReason,
 				}, res.err.who)
 				cs.ignorePeer(res.err.who)
+			case errors.Is(res.err.err, network.ErrRequestTimeout):
+				// the network service already lowered the peer's reputation
+				cs.ignorePeer(res.err.who)
 			case strings.Contains(res.err.err.Error(), "dial backoff"):
 				cs.ignorePeer(res.err.who)
 				continue
@@ -628,7 +682,21 @@ func (cs *chainSync) dispatchWorker(w *worker) {
 		cs.resultQueue <- w
 	}()
 
-	reqs, err := workerToRequests(w)
+	// keep sending requests to the same peer as long as it's one of the best we have
+	var who peer.ID
+
+	// a fork is only requested down to the block after the highest common ancestor with the peer
+	requested := w
+	if w.findAncestor {
+		var workerErr *workerError
+		requested, who, workerErr = cs.ancestorWorker(w)
+		if workerErr != nil {
+			w.err = workerErr
+			return
+		}
+	}
+
+	reqs, err := workerToRequests(requested)
 	if err != nil {
 		// if we are creating valid workers, this should not happen
 		logger.Criticalf("failed to create requests from worker id %d: %s", w.id, err)
@@ -639,21 +707,25 @@ func (cs *chainSync) dispatchWorker(w *worker) {
 	}
 
 	for _, req := range reqs {
-		// TODO: if we find a good peer, do sync with them, right now it re-selects a peer each time (#1399)
-		if err := cs.doSync(req, w.peersTried); err != nil {
+		var workerErr *workerError
+		who, workerErr = cs.doSync(req, w.peersTried, who)
+		if workerErr != nil {
 			// failed to sync, set worker error and put into result queue
-			w.err = err
+			w.err = workerErr
 			return
 		}
 	}
 }
 
-func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[peer.ID]struct{}) *workerError {
+// doSync sends the request to one of the peers that likely have the blocks, the preferred peer is picked
+// if its score is good enough. It returns the peer the request was sent to.
+func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[peer.ID]struct{},
+	preferred peer.ID) (peer.ID, *workerError) {
 	// determine which peers have the blocks we want to request
 	peers := cs.determineSyncPeers(req, peersTried)
 
 	if len(peers) == 0 {
-		return &workerError{
+		return "", &workerError{
 			err: errNoPeers,
 		}
 	}
@@ -661,19 +733,23 @@ func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[pee
 	// send out request and potentially receive response, error if timeout
 	logger.Tracef("sending out block request: %s", req)
 
-	// TODO: use scoring to determine what peer to try to sync from first (#1399)
-	idx, _ := rand.Int(rand.Reader, big.NewInt(int64(len(peers))))
-	who := peers[idx.Int64()]
+	who := cs.peerScores.selectPeer(peers, preferred)
+	start := time.Now()
 	resp, err := cs.network.DoBlockRequest(who, req)
 	if err != nil {
-		return &workerError{
+		if errors.Is(err, network.ErrRequestTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			cs.peerScores.recordTimeout(who)
+		}
+
+		return who, &workerError{
 			err: err,
 			who: who,
 		}
 	}
+	latency := time.Since(start)
 
 	if resp == nil {
-		return &workerError{
+		return who, &workerError{
 			err: errNilResponse,
 			who: who,
 		}
@@ -686,12 +762,20 @@ func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[pee
 
 	// perform some pre-validation of response, error if failure
 	if err := cs.validateResponse(req, resp, who); err != nil {
-		return &workerError{
+		// blocks with an unknown parent may be on a fork we don't know yet, that isn't the peer's fault
+		if !errors.Is(err, errUnknownParent) {
+			cs.peerScores.recordInvalid(who)
+		}
+
+		return who, &workerError{
 			err: err,
 			who: who,
 		}
 	}
 
+	cs.peerScores.recordResponse(who, latency, len(resp.BlockData))
+	cs.reportPeerScore(who)
+
 	logger.Trace("success! placing block response data in ready queue")
 
 	// response was validated! place into ready block queue
@@ -700,7 +784,24 @@ func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[pee
 		handleReadyBlock(bd, cs.pendingBlocks, cs.readyBlocks)
 	}
 
-	return nil
+	return who, nil
+}
+
+// reportPeerScore adjusts the reputation of the peer according to its score compared to our other peers
+func (cs *chainSync) reportPeerScore(who peer.ID) {
+	score, avg := cs.peerScores.score(who), cs.peerScores.average()
+	switch {
+	case score >= avg:
+		cs.network.ReportPeer(peerset.ReputationChange{
+			Value:  peerset.GoodBlockResponseValue,
+			Reason: peerset.GoodBlockResponseReason,
+		}, who)
+	case score < slowPeerRatio*avg:
+		cs.network.ReportPeer(peerset.ReputationChange{
+			Value:  peerset.SlowBlockResponseValue,
+			Reason: peerset.SlowBlockResponseReason,
+		}, who)
+	}
 }
 
 func handleReadyBlock(bd *types.BlockData, pendingBlocks DisjointBlockSet, readyBlocks *blockQueue) {
@@ -736,10 +837,15 @@ func handleReadyBlock(bd *types.BlockData, pendingBlocks DisjointBlockSet, ready
 }
 
 // determineSyncPeers returns a list of peers that likely have the blocks in the given block request.
+// If some peers reported a best block past the end of the request, only those are returned.
 func (cs *chainSync) determineSyncPeers(req *network.BlockRequestMessage, peersTried map[peer.ID]struct{}) []peer.ID {
-	var start uint64
+	var start, end uint64
 	if req.StartingBlock.IsUint64() {
 		start = req.StartingBlock.Uint64()
+		end = start
+		if req.Direction == network.Ascending && req.Max != nil && *req.Max > 0 {
+			end = start + uint64(*req.Max) - 1
+		}
 	}
 
 	cs.RLock()
@@ -757,6 +863,7 @@ func (cs *chainSync) determineSyncPeers(req *network.BlockRequestMessage, peersT
 	}
 
 	peers := make([]peer.ID, 0, len(cs.peerState))
+	peersWithTarget := make([]peer.ID, 0, len(cs.peerState))
 
 	for p, state := range cs.peerState {
 		if _, has := cs.ignorePeers[p]; has {
@@ -774,6 +881,13 @@ func (cs *chainSync) determineSyncPeers(req *network.BlockRequestMessage, peersT
 		}
 
 		peers = append(peers, p)
+		if state.number.Uint64() >= end {
+			peersWithTarget = append(peersWithTarget, p)
+		}
+	}
+
+	if len(peersWithTarget) > 0 {
+		return peersWithTarget
 	}
 
 	return peers
@@ -806,6 +920,10 @@ func (cs *chainSync) validateResponse(req *network.BlockRequestMessage,
 
 		if headerRequested {
 			curr = bd.Header
+			if cs.blockState.IsBadBlock(curr) {
+				cs.reportBadBlock(p)
+				return fmt.Errorf("%w: hash %s", errBadBlock, curr.Hash())
+			}
 		} else {
 			// if this is a justification-only request, make sure we have the block for the justification
 			if err = cs.validateJustification(bd); err != nil {
@@ -867,6 +985,10 @@ func (cs *chainSync) validateResponse(req *network.BlockRequestMessage,
 					}
 				}
 			}
+			cs.network.ReportPeer(peerset.ReputationChange{
+				Value:  peerset.BadBlockResponseValue,
+				Reason: peerset.BadBlockResponseReason,
+			}, p)
 			return errResponseIsNotChain
 		}
 
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
@@ -39,6 +40,8 @@ func newTestChainSync(t *testing.T) (*chainSync, *blockQueue) {
 	bs.On("BestBlockHeader").Return(header, nil)
 	bs.On("GetFinalisedNotifierChannel").Return(make(chan *types.FinalisationInfo, 128), nil)
 	bs.On("HasHeader", mock.AnythingOfType("common.Hash")).Return(true, nil)
+	bs.On("HasBadBlock", mock.AnythingOfType("common.Hash")).Return(false)
+	bs.On("IsBadBlock", mock.AnythingOfType("*types.Header")).Return(false)
 
 	net := new(syncmocks.Network)
 	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"),
@@ -81,6 +84,7 @@ func TestChainSync_SetPeerHead(t *testing.T) {
 
 	// test case where peer has a lower head than us, but they are on the same chain as us
 	cs.blockState = new(syncmocks.BlockState)
+	cs.blockState.(*syncmocks.BlockState).On("HasBadBlock", mock.AnythingOfType("common.Hash")).Return(false)
 	header, err := types.NewHeader(common.NewHash([]byte{0}),
 		trie.EmptyHash, trie.EmptyHash, big.NewInt(1000), types.NewDigest())
 	require.NoError(t, err)
@@ -108,6 +112,7 @@ func TestChainSync_SetPeerHead(t *testing.T) {
 
 	// test case where peer has a lower head than us, and they are on an invalid fork
 	cs.blockState = new(syncmocks.BlockState)
+	cs.blockState.(*syncmocks.BlockState).On("HasBadBlock", mock.AnythingOfType("common.Hash")).Return(false)
 	cs.blockState.(*syncmocks.BlockState).On("BestBlockHeader").Return(header, nil)
 	fin, err = types.NewHeader(common.NewHash([]byte{0}), trie.EmptyHash,
 		trie.EmptyHash, big.NewInt(1000), types.NewDigest())
@@ -132,6 +137,7 @@ func TestChainSync_SetPeerHead(t *testing.T) {
 
 	// test case where peer has a lower head than us, but they are on a valid fork (that is not our chain)
 	cs.blockState = new(syncmocks.BlockState)
+	cs.blockState.(*syncmocks.BlockState).On("HasBadBlock", mock.AnythingOfType("common.Hash")).Return(false)
 	cs.blockState.(*syncmocks.BlockState).On("BestBlockHeader").Return(header, nil)
 	fin, err = types.NewHeader(
 		common.NewHash([]byte{0}), trie.EmptyHash, trie.EmptyHash,
@@ -603,6 +609,7 @@ func TestChainSync_validateResponse_firstBlock(t *testing.T) {
 	cs, _ := newTestChainSync(t)
 	bs := new(syncmocks.BlockState)
 	bs.On("HasHeader", mock.AnythingOfType("common.Hash")).Return(false, nil)
+	bs.On("IsBadBlock", mock.AnythingOfType("*types.Header")).Return(false)
 	cs.blockState = bs
 
 	req := &network.BlockRequestMessage{
@@ -635,6 +642,59 @@ func TestChainSync_validateResponse_firstBlock(t *testing.T) {
 	require.NotNil(t, bd.justification)
 }
 
+func TestChainSync_setBlockAnnounce_BadBlock(t *testing.T) {
+	cs, _ := newTestChainSync(t)
+	header := &types.Header{
+		Number: big.NewInt(2),
+		Digest: types.NewDigest(),
+	}
+
+	bs := new(syncmocks.BlockState)
+	bs.On("IsBadBlock", header).Return(true)
+	cs.blockState = bs
+
+	testPeer := peer.ID("noot")
+	err := cs.setBlockAnnounce(testPeer, header)
+	require.True(t, errors.Is(err, errBadBlock))
+	require.False(t, cs.pendingBlocks.hasBlock(header.Hash()))
+	require.Contains(t, cs.ignorePeers, testPeer)
+	cs.network.(*syncmocks.Network).AssertCalled(t, "ReportPeer", peerset.ReputationChange{
+		Value:  peerset.BadBlockValue,
+		Reason: peerset.BadBlockReason,
+	}, testPeer)
+}
+
+func TestChainSync_validateResponse_BadBlock(t *testing.T) {
+	cs, _ := newTestChainSync(t)
+	bad := &types.Header{
+		ParentHash: common.Hash{0xa},
+		Number:     big.NewInt(2),
+	}
+
+	bs := new(syncmocks.BlockState)
+	bs.On("IsBadBlock", bad).Return(true)
+	cs.blockState = bs
+
+	req := &network.BlockRequestMessage{
+		RequestedData: bootstrapRequestData,
+	}
+
+	resp := &network.BlockResponseMessage{
+		BlockData: []*types.BlockData{
+			{
+				Hash:   bad.Hash(),
+				Header: bad,
+				Body:   &types.Body{},
+			},
+		},
+	}
+
+	err := cs.validateResponse(req, resp, "noot")
+	require.True(t, errors.Is(err, errBadBlock))
+	require.False(t, cs.pendingBlocks.hasBlock(bad.Hash()))
+	require.Contains(t, cs.ignorePeers, peer.ID("noot"))
+}
+
 func TestChainSync_doSync(t *testing.T) {
 	cs, readyBlocks := newTestChainSync(t)
 
@@ -647,7 +707,7 @@ func TestChainSync_doSync(t *testing.T) {
 		Max:           &max,
 	}
 
-	workerErr := cs.doSync(req, make(map[peer.ID]struct{}))
+	_, workerErr := cs.doSync(req, make(map[peer.ID]struct{}), "")
 	require.NotNil(t, workerErr)
 	require.Equal(t, errNoPeers, workerErr.err)
 
@@ -655,7 +715,7 @@ func TestChainSync_doSync(t *testing.T) {
 		number: big.NewInt(100),
 	}
 
-	workerErr = cs.doSync(req, make(map[peer.ID]struct{}))
+	_, workerErr = cs.doSync(req, make(map[peer.ID]struct{}), "")
 	require.NotNil(t, workerErr)
 	require.Equal(t, errNilResponse, workerErr.err)
 
@@ -674,8 +734,10 @@ func TestChainSync_doSync(t *testing.T) {
 	cs.network.(*syncmocks.Network).On("DoBlockRequest",
 		mock.AnythingOfType("peer.ID"),
 		mock.AnythingOfType("*network.BlockRequestMessage")).Return(resp, nil)
+	cs.network.(*syncmocks.Network).On("ReportPeer",
+		mock.AnythingOfType("peerset.ReputationChange"), mock.AnythingOfType("peer.ID"))
 
-	workerErr = cs.doSync(req, make(map[peer.ID]struct{}))
+	_, workerErr = cs.doSync(req, make(map[peer.ID]struct{}), "")
 	require.Nil(t, workerErr)
 	bd := readyBlocks.pop()
 	require.NotNil(t, bd)
@@ -708,7 +770,9 @@ func TestChainSync_doSync(t *testing.T) {
 	cs.network.(*syncmocks.Network).On("DoBlockRequest",
 		mock.AnythingOfType("peer.ID"),
 		mock.AnythingOfType("*network.BlockRequestMessage")).Return(resp, nil)
-	workerErr = cs.doSync(req, make(map[peer.ID]struct{}))
+	cs.network.(*syncmocks.Network).On("ReportPeer",
+		mock.AnythingOfType("peerset.ReputationChange"), mock.AnythingOfType("peer.ID"))
+	_, workerErr = cs.doSync(req, make(map[peer.ID]struct{}), "")
 	require.Nil(t, workerErr)
 
 	bd = readyBlocks.pop()
@@ -823,4 +887,17 @@ func TestChainSync_determineSyncPeers(t *testing.T) {
 	peers = cs.determineSyncPeers(req, peersTried)
 	require.Equal(t, 1, len(peers))
 	require.Equal(t, []peer.ID{testPeerB}, peers)
+
+	// test peers that have the whole range are preferred
+	max := uint32(128)
+	req.StartingBlock = *variadic.MustNewUint64OrHash(100)
+	req.Direction = network.Ascending
+	req.Max = &max
+	peers = cs.determineSyncPeers(req, make(map[peer.ID]struct{}))
+	require.Equal(t, []peer.ID{testPeerB}, peers)
+
+	// test peers that have part of the range are used if no peer has all of it
+	max = 256
+	peers = cs.determineSyncPeers(req, make(map[peer.ID]struct{}))
+	require.Equal(t, 2, len(peers))
 }
@@ -0,0 +1,225 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/common/variadic"
+)
+
+// commonAncestor is a block that both we and a peer have
+type commonAncestor struct {
+	hash   common.Hash
+	number *big.Int
+}
+
+// commonAncestors caches the highest common ancestor found with each peer, so the next search
+// with the same peer can start from it
+type commonAncestors struct {
+	sync.Mutex
+	ancestors map[peer.ID]*commonAncestor
+}
+
+func newCommonAncestors() *commonAncestors {
+	return &commonAncestors{
+		ancestors: make(map[peer.ID]*commonAncestor),
+	}
+}
+
+func (c *commonAncestors) get(who peer.ID) *commonAncestor {
+	c.Lock()
+	defer c.Unlock()
+	return c.ancestors[who]
+}
+
+func (c *commonAncestors) set(who peer.ID, ancestor *commonAncestor) {
+	c.Lock()
+	defer c.Unlock()
+	c.ancestors[who] = ancestor
+}
+
+func (c *commonAncestors) delete(who peer.ID) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.ancestors, who)
+}
+
+// findCommonAncestor returns the highest block that we have, which the peer has on its best chain below
+// the given block number. Since our highest finalised block is on the chain of every valid peer, and we
+// have every ancestor of the blocks we have, the search is a binary search over the block numbers between
+// our highest finalised block and our best block, requesting a single header from the peer at each step.
+// The result is cached, and is checked with a single request on the next search with the same peer.
+func (cs *chainSync) findCommonAncestor(who peer.ID, below *big.Int) (*commonAncestor, error) {
+	fin, err := cs.blockState.GetHighestFinalisedHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	best, err := cs.blockState.BestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	low := &commonAncestor{
+		hash:   fin.Hash(),
+		number: fin.Number,
+	}
+
+	high := new(big.Int).Sub(below, big.NewInt(1))
+	if best.Number.Cmp(high) < 0 {
+		high = new(big.Int).Set(best.Number)
+	}
+
+	if cached := cs.commonAncestors.get(who); cached != nil &&
+		cached.number.Cmp(low.number) > 0 && cached.number.Cmp(high) <= 0 {
+		valid, err := cs.isCommonAncestor(who, cached)
+		if err != nil {
+			return nil, err
+		}
+
+		if valid {
+			low = cached
+		} else {
+			cs.commonAncestors.delete(who)
+		}
+	}
+
+	for low.number.Cmp(high) < 0 {
+		// round up, so that the range shrinks when low and high are next to each other
+		mid := new(big.Int).Add(low.number, high)
+		mid.Add(mid, big.NewInt(1))
+		mid.Rsh(mid, 1)
+
+		header, err := cs.requestHeader(who, mid)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := header.Hash()
+		has, err := cs.blockState.HasHeader(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if has {
+			low = &commonAncestor{
+				hash:   hash,
+				number: mid,
+			}
+		} else {
+			high = mid.Sub(mid, big.NewInt(1))
+		}
+	}
+
+	logger.Debugf("found common ancestor number %s with hash %s with peer %s", low.number, low.hash, who)
+	cs.commonAncestors.set(who, low)
+	return low, nil
+}
+
+// isCommonAncestor returns true if we still have the cached ancestor, which is pruned if we finalised
+// a different fork, and if it's still on the best chain of the peer, which may have re-orged below it
+func (cs *chainSync) isCommonAncestor(who peer.ID, cached *commonAncestor) (bool, error) {
+	has, err := cs.blockState.HasHeader(cached.hash)
+	if err != nil || !has {
+		return false, err
+	}
+
+	header, err := cs.requestHeader(who, cached.number)
+	if err != nil {
+		return false, err
+	}
+
+	return header.Hash().Equal(cached.hash), nil
+}
+
+// requestHeader requests the header of the block with the given number on the best chain of the peer
+func (cs *chainSync) requestHeader(who peer.ID, number *big.Int) (*types.Header, error) {
+	max := uint32(1)
+	req := &network.BlockRequestMessage{
+		RequestedData: network.RequestedDataHeader,
+		StartingBlock: *variadic.MustNewUint64OrHash(number.Uint64()),
+		Direction:     network.Ascending,
+		Max:           &max,
+	}
+
+	resp, err := cs.network.DoBlockRequest(who, req)
+	if err != nil {
+		if errors.Is(err, network.ErrRequestTimeout) {
+			cs.peerScores.recordTimeout(who)
+		}
+		return nil, err
+	}
+
+	if resp == nil || len(resp.BlockData) != 1 || resp.BlockData[0].Header == nil ||
+		resp.BlockData[0].Header.Number == nil || resp.BlockData[0].Header.Number.Cmp(number) != 0 {
+		cs.peerScores.recordInvalid(who)
+		return nil, fmt.Errorf("%w: for block number %s", errInvalidHeaderResponse, number)
+	}
+
+	return resp.BlockData[0].Header, nil
+}
+
+// selectAncestorPeer returns the peer to search for a common ancestor with, before requesting the fork
+// the worker starts on. Peers whose best block is the pending block the worker was created for are
+// preferred, since they announced the fork.
+func (cs *chainSync) selectAncestorPeer(w *worker) (peer.ID, error) {
+	start := variadic.MustNewUint64OrHash(w.startNumber.Uint64())
+	peers := cs.determineSyncPeers(&network.BlockRequestMessage{
+		StartingBlock: *start,
+		Direction:     network.Ascending,
+	}, w.peersTried)
+	if len(peers) == 0 {
+		return "", errNoPeers
+	}
+
+	if w.pendingBlock != nil {
+		cs.RLock()
+		for _, p := range peers {
+			if cs.peerState[p].hash.Equal(w.pendingBlock.hash) {
+				cs.RUnlock()
+				return p, nil
+			}
+		}
+		cs.RUnlock()
+	}
+
+	return cs.peerScores.selectPeer(peers, ""), nil
+}
+
+// ancestorWorker returns a copy of the fork worker with its target raised to the highest common ancestor
+// with the returned peer, so that only the blocks of the fork above it are requested.
+func (cs *chainSync) ancestorWorker(w *worker) (*worker, peer.ID, *workerError) {
+	who, err := cs.selectAncestorPeer(w)
+	if err != nil {
+		return nil, "", &workerError{
+			err: err,
+		}
+	}
+
+	ancestor, err := cs.findCommonAncestor(who, w.startNumber)
+	if err != nil {
+		return nil, who, &workerError{
+			err: err,
+			who: who,
+		}
+	}
+
+	// the target of a descending worker is the block below the last block requested
+	fork := *w
+	if ancestor.number.Cmp(fork.targetNumber) > 0 && ancestor.number.Cmp(fork.startNumber) < 0 {
+		fork.targetNumber = ancestor.number
+		fork.targetHash = common.Hash{}
+	}
+
+	return &fork, who, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/state"
+	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestForkChain returns n headers descending from parent, the fork byte makes them differ from the
+// headers of other forks. The headers are added to the block state if bs isn't nil.
+func newTestForkChain(t *testing.T, bs *state.BlockState, parent *types.Header, n int, fork byte) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		headers[i] = &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			StateRoot:  common.Hash{fork, byte(i)},
+			Digest:     types.NewDigest(),
+		}
+
+		if bs != nil {
+			err := bs.AddBlock(&types.Block{
+				Header: *headers[i],
+				Body:   types.Body{},
+			})
+			require.NoError(t, err)
+		}
+
+		parent = headers[i]
+	}
+
+	return headers
+}
+
+// newTestPeerNetwork returns a network whose only peer has the given chain, indexed by block number
+func newTestPeerNetwork(t *testing.T, chain []*types.Header) *syncmocks.Network {
+	net := new(syncmocks.Network)
+	net.On("ReportPeer", mock.AnythingOfType("peerset.ReputationChange"), mock.AnythingOfType("peer.ID"))
+	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(func(_ peer.ID, req *network.BlockRequestMessage) *network.BlockResponseMessage {
+			if req.StartingBlock.IsUint64() {
+				header := chain[req.StartingBlock.Uint64()]
+				return &network.BlockResponseMessage{
+					BlockData: []*types.BlockData{{
+						Hash:   header.Hash(),
+						Header: header,
+					}},
+				}
+			}
+
+			// descending request for a fork, starting at its head
+			var start int
+			for i, header := range chain {
+				if header.Hash().Equal(req.StartingBlock.Value().(common.Hash)) {
+					start = i
+				}
+			}
+
+			resp := new(network.BlockResponseMessage)
+			for i := start; i > start-int(*req.Max); i-- {
+				resp.BlockData = append(resp.BlockData, &types.BlockData{
+					Hash:   chain[i].Hash(),
+					Header: chain[i],
+					Body:   types.NewBody([]types.Extrinsic{}),
+				})
+			}
+			return resp
+		}, nil)
+
+	return net
+}
+
+// newTestForkedChainSync returns a chainSync with 20 blocks, and the chain of a peer sharing its first
+// 12 blocks then forking up to block 25
+func newTestForkedChainSync(t *testing.T) (*chainSync, []*types.Header, []*types.Header) {
+	bs, _ := newTestFastSyncState(t, trie.NewEmptyTrie())
+	genesis, err := bs.BestBlockHeader()
+	require.NoError(t, err)
+
+	ours := append([]*types.Header{genesis}, newTestForkChain(t, bs, genesis, 20, 1)...)
+	theirs := append(append([]*types.Header{}, ours[:13]...), newTestForkChain(t, nil, ours[12], 13, 2)...)
+
+	cs := newChainSync(&chainSyncConfig{
+		bs:            bs,
+		net:           newTestPeerNetwork(t, theirs),
+		readyBlocks:   newBlockQueue(maxResponseSize),
+		pendingBlocks: newDisjointBlockSet(pendingBlocksLimit),
+		minPeers:      defaultMinPeers,
+		maxPeers:      defaultMaxPeers,
+		slotDuration:  defaultSlotDuration,
+	})
+
+	return cs, ours, theirs
+}
+
+func TestChainSync_findCommonAncestor(t *testing.T) {
+	cs, ours, theirs := newTestForkedChainSync(t)
+	net := cs.network.(*syncmocks.Network)
+
+	ancestor, err := cs.findCommonAncestor("noot", theirs[25].Number)
+	require.NoError(t, err)
+	require.Equal(t, ours[12].Hash(), ancestor.hash)
+	require.Equal(t, big.NewInt(12), ancestor.number)
+
+	// the search is between our finalised block 0 and our best block 20
+	net.AssertNumberOfCalls(t, "DoBlockRequest", 4)
+	require.Equal(t, ancestor, cs.commonAncestors.get("noot"))
+
+	// the next search starts from the cached ancestor, once the peer confirmed it's still on its chain,
+	// so it's between blocks 12 and 20
+	ancestor, err = cs.findCommonAncestor("noot", theirs[25].Number)
+	require.NoError(t, err)
+	require.Equal(t, ours[12].Hash(), ancestor.hash)
+	net.AssertNumberOfCalls(t, "DoBlockRequest", 4+1+3)
+
+	// the peer re-orged below the cached ancestor
+	reorged := append(append([]*types.Header{}, ours[:9]...), newTestForkChain(t, nil, ours[8], 17, 3)...)
+	cs.network = newTestPeerNetwork(t, reorged)
+
+	ancestor, err = cs.findCommonAncestor("noot", reorged[25].Number)
+	require.NoError(t, err)
+	require.Equal(t, ours[8].Hash(), ancestor.hash)
+	require.Equal(t, big.NewInt(8), ancestor.number)
+}
+
+func TestChainSync_findCommonAncestor_PrunedCache(t *testing.T) {
+	cs, ours, theirs := newTestForkedChainSync(t)
+	net := cs.network.(*syncmocks.Network)
+
+	// the cached ancestor is on a fork we pruned since the last search
+	cs.commonAncestors.set("noot", &commonAncestor{
+		hash:   common.Hash{0xff},
+		number: big.NewInt(15),
+	})
+
+	ancestor, err := cs.findCommonAncestor("noot", theirs[25].Number)
+	require.NoError(t, err)
+	require.Equal(t, ours[12].Hash(), ancestor.hash)
+
+	// the cached ancestor isn't checked with the peer, the search is between blocks 0 and 20
+	net.AssertNumberOfCalls(t, "DoBlockRequest", 4)
+	require.Equal(t, ancestor, cs.commonAncestors.get("noot"))
+}
+
+func TestChainSync_findCommonAncestor_InvalidResponse(t *testing.T) {
+	cs, _, _ := newTestForkedChainSync(t)
+
+	net := new(syncmocks.Network)
+	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(&network.BlockResponseMessage{
+			BlockData: []*types.BlockData{{
+				Header: &types.Header{Number: big.NewInt(1)},
+			}},
+		}, nil)
+	cs.network = net
+
+	_, err := cs.findCommonAncestor("noot", big.NewInt(25))
+	require.ErrorIs(t, err, errInvalidHeaderResponse)
+	require.Nil(t, cs.commonAncestors.get("noot"))
+}
+
+func TestChainSync_dispatchWorker_findAncestor(t *testing.T) {
+	cs, ours, theirs := newTestForkedChainSync(t)
+	cs.peerState["noot"] = &peerState{
+		who:    "noot",
+		hash:   theirs[25].Hash(),
+		number: theirs[25].Number,
+	}
+	// another peer on a different chain, with a better score
+	cs.peerState["alice"] = &peerState{
+		who:    "alice",
+		hash:   common.Hash{0xaa},
+		number: big.NewInt(30),
+	}
+	cs.peerScores.recordResponse("alice", time.Millisecond, maxResponseSize)
+
+	// the worker created by the tip syncer for a pending block whose parent is unknown
+	block := &pendingBlock{
+		hash:   theirs[25].Hash(),
+		number: theirs[25].Number,
+		header: theirs[25],
+		body:   types.NewBody([]types.Extrinsic{}),
+	}
+	w := &worker{
+		startHash:    theirs[25].ParentHash,
+		startNumber:  theirs[24].Number,
+		targetNumber: ours[0].Number,
+		direction:    network.Descending,
+		requestData:  bootstrapRequestData,
+		pendingBlock: block,
+		findAncestor: true,
+	}
+	cs.workerState.add(w)
+	cs.dispatchWorker(w)
+
+	res := <-cs.resultQueue
+	require.Nil(t, res.err)
+
+	// the common ancestor is searched with the peer that announced the pending block, and only the
+	// 12 blocks of the fork below the pending block are requested, in a single request
+	var last *network.BlockRequestMessage
+	for _, call := range cs.network.(*syncmocks.Network).Calls {
+		if call.Method == "DoBlockRequest" {
+			require.Equal(t, peer.ID("noot"), call.Arguments.Get(0))
+			last = call.Arguments.Get(1).(*network.BlockRequestMessage)
+		}
+	}
+	require.Equal(t, network.Descending, last.Direction)
+	require.Equal(t, uint32(12), *last.Max)
+	require.Nil(t, last.EndBlockHash)
+
+	for _, header := range theirs[13:25] {
+		bd := cs.readyBlocks.pop()
+		require.Equal(t, header.Hash(), bd.Hash)
+	}
+}
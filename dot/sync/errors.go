@@ -16,6 +16,8 @@ var (
 	errNilNetwork            = errors.New("cannot have nil Network")
 	errNilFinalityGadget     = errors.New("cannot have nil FinalityGadget")
 	errNilTransactionState   = errors.New("cannot have nil TransactionState")
+	errNilDigestHandler      = errors.New("cannot have nil DigestHandler")
+	errNilGrandpaState       = errors.New("cannot have nil GrandpaState")
 
 	// ErrNilBlockData is returned when trying to process a BlockResponseMessage with nil BlockData
 	ErrNilBlockData = errors.New("got nil BlockData")
@@ -26,6 +28,9 @@ var (
 	// ErrInvalidBlock is returned when a block cannot be verified
 	ErrInvalidBlock = errors.New("could not verify block")
 
+	// ErrInvalidJustification is returned when a justification cannot be verified
+	ErrInvalidJustification = errors.New("could not verify justification")
+
 	// ErrInvalidBlockRequest is returned when an invalid block request is received
 	ErrInvalidBlockRequest        = errors.New("invalid block request")
 	errInvalidRequestDirection    = errors.New("invalid request direction")
@@ -50,6 +55,18 @@ var (
 	errNilDescendantNumber          = errors.New("descendant number is nil")
 	errStartAndEndMismatch          = errors.New("request start and end hash are not on the same chain")
 	errFailedToGetDescendant        = errors.New("failed to find descendant block")
+	errInvalidHeaderResponse        = errors.New("response is not the single requested header")
+	errInvalidJustificationResponse = errors.New("response is not the justification of the requested block")
+	errBadBlock                     = errors.New("block is a bad block or descends from one")
+
+	// CreateStateResponse errors
+	errStateProofNotSupported = errors.New("state requests for proofs are not supported")
+	errChildStateNotSupported = errors.New("state requests for child tries are not supported")
+
+	// fast sync errors
+	errEmptyStateResponse = errors.New("state response has no entries")
+	errStateNotOrdered    = errors.New("state response entries are not ordered after the start key")
+	errStateRootMismatch  = errors.New("state root of downloaded entries does not match block's state root")
 )
 
 // ErrNilChannel is returned if a channel is nil
@@ -0,0 +1,699 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/common/variadic"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+const (
+	// FullMode syncs the chain by importing and executing every block
+	FullMode = Mode("full")
+	// FastMode syncs the chain by importing the blocks without executing them up to the latest
+	// finalised block, then downloading the state of that block from our peers
+	FastMode = Mode("fast")
+)
+
+// Mode is the way the node syncs the chain
+type Mode string
+
+// IsValid checks whether the sync mode is valid
+func (m Mode) IsValid() bool {
+	switch m {
+	case FullMode, FastMode:
+		return true
+	default:
+		return false
+	}
+}
+
+// fastSyncRetryInterval is how long we wait for peers to sync from before checking again,
+// and the initial interval before retrying fast sync after a failure
+var fastSyncRetryInterval = time.Second
+
+// maxFastSyncRetryInterval is the maximum interval before retrying fast sync after a failure
+const maxFastSyncRetryInterval = time.Minute
+
+// fastSyncer imports the blocks up to the best block of our peers without executing them. Their headers
+// are verified, their consensus digests are handled and their justifications are verified, so the block,
+// epoch and grandpa states end up as if the blocks were fully imported. The state of the highest finalised
+// block is then downloaded from our peers, and the blocks following it are executed.
+type fastSyncer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	blockState     BlockState
+	storageState   StorageState
+	network        Network
+	babeVerifier   BabeVerifier
+	finalityGadget FinalityGadget
+	digestHandler  DigestHandler
+
+	// peer heads received while fast syncing, they are handed over to chainSync once done
+	sync.Mutex
+	peerState map[peer.ID]*peerState
+	done      bool
+	minPeers  int
+
+	peerScores *peerScores
+
+	// the block fast sync started from and its runtime, they are kept when fast sync is retried
+	// as the blocks imported since then don't have a state
+	start        *types.Header
+	startRuntime runtime.Instance
+}
+
+func newFastSyncer(cfg *Config) *fastSyncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &fastSyncer{
+		ctx:            ctx,
+		cancel:         cancel,
+		blockState:     cfg.BlockState,
+		storageState:   cfg.StorageState,
+		network:        cfg.Network,
+		babeVerifier:   cfg.BabeVerifier,
+		finalityGadget: cfg.FinalityGadget,
+		digestHandler:  cfg.DigestHandler,
+		peerState:      make(map[peer.ID]*peerState),
+		minPeers:       cfg.MinPeers,
+		peerScores:     newPeerScores(),
+	}
+}
+
+func (fs *fastSyncer) stop() {
+	fs.cancel()
+}
+
+// setPeerHead records the head of the given peer. It returns false once fast sync is done, in
+// which case the peer head should be given to chainSync instead.
+func (fs *fastSyncer) setPeerHead(p peer.ID, hash common.Hash, number *big.Int) bool {
+	fs.Lock()
+	defer fs.Unlock()
+
+	if fs.done {
+		return false
+	}
+
+	fs.peerState[p] = &peerState{
+		who:    p,
+		hash:   hash,
+		number: number,
+	}
+	return true
+}
+
+// finish marks fast sync as done and calls handOver with each peer head received so far. Peer heads
+// received concurrently wait for it to return, so they are given to chainSync after the older ones.
+func (fs *fastSyncer) finish(handOver func(ps *peerState)) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	fs.done = true
+	for _, ps := range fs.peerState {
+		handOver(ps)
+	}
+	fs.peerState = nil
+}
+
+// peersWithBlock returns the peers whose best block number is at least the given number
+func (fs *fastSyncer) peersWithBlock(number *big.Int) []peer.ID {
+	fs.Lock()
+	defer fs.Unlock()
+
+	peers := []peer.ID{}
+	for _, ps := range fs.peerState {
+		if ps.number.Cmp(number) >= 0 {
+			peers = append(peers, ps.who)
+		}
+	}
+	return peers
+}
+
+// wait waits for the retry interval, it returns an error if fast sync was stopped in the meantime
+func (fs *fastSyncer) wait() error {
+	return fs.waitFor(fastSyncRetryInterval)
+}
+
+// waitFor waits for the given duration, it returns an error if fast sync was stopped in the meantime
+func (fs *fastSyncer) waitFor(d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-fs.ctx.Done():
+		return ErrServiceStopped
+	}
+}
+
+// nextRetryInterval doubles the retry interval, up to maxFastSyncRetryInterval
+func nextRetryInterval(interval time.Duration) time.Duration {
+	interval *= 2
+	if interval > maxFastSyncRetryInterval {
+		return maxFastSyncRetryInterval
+	}
+	return interval
+}
+
+// sync fast syncs the chain up to the best block of our peers. It can be called again if it fails,
+// in which case it carries on from the block it first started from.
+func (fs *fastSyncer) sync() error {
+	// wait until we have received at least `minPeers` peer heads
+	for {
+		fs.Lock()
+		n := len(fs.peerState)
+		fs.Unlock()
+		if n >= fs.minPeers {
+			break
+		}
+
+		if err := fs.wait(); err != nil {
+			return err
+		}
+	}
+
+	if fs.start == nil {
+		if err := fs.setStart(); err != nil {
+			return err
+		}
+	}
+
+	start, rt := fs.start, fs.startRuntime
+	logger.Infof("fast syncing from block number %s with hash %s", start.Number, start.Hash())
+
+	if err := fs.importBlocks(); err != nil {
+		return err
+	}
+
+	target, err := fs.blockState.GetHighestFinalisedHeader()
+	if err != nil {
+		return err
+	}
+
+	if target.Number.Cmp(start.Number) <= 0 {
+		// nothing was finalised since we started, we have the state of the block we started from
+		target = start
+	} else {
+		if err = fs.importState(target, rt); err != nil {
+			return err
+		}
+	}
+
+	// the target block has a state, so the node doesn't need to resume from the start block anymore
+	if err = fs.blockState.ClearFastSyncStart(); err != nil {
+		return err
+	}
+
+	return fs.executeBlocks(target)
+}
+
+// setStart sets the block fast sync starts from, along with its runtime. The start block is recorded in the
+// block state, so that if the node is restarted before the state of the target block is stored, it loads the
+// state of the start block and fast sync resumes from it.
+func (fs *fastSyncer) setStart() error {
+	start, err := fs.blockState.FastSyncStart()
+	if err != nil {
+		return err
+	}
+
+	if start != nil {
+		// the runtime of the best block was created from the state of the start block when the node started
+		rt, err := fs.blockState.GetRuntime(nil)
+		if err != nil {
+			return err
+		}
+
+		fs.start, fs.startRuntime = start, rt
+		return nil
+	}
+
+	start, err = fs.blockState.BestBlockHeader()
+	if err != nil {
+		return err
+	}
+
+	// the runtime of the block we start from is kept, as it is pruned from the block tree once its
+	// descendants are finalised, and it is needed to instantiate the runtime of the downloaded state
+	startHash := start.Hash()
+	rt, err := fs.blockState.GetRuntime(&startHash)
+	if err != nil {
+		return err
+	}
+
+	if err = fs.blockState.SetFastSyncStart(start); err != nil {
+		return err
+	}
+
+	fs.start, fs.startRuntime = start, rt
+	return nil
+}
+
+// importBlocks imports the blocks up to the best block of our peers without executing them
+func (fs *fastSyncer) importBlocks() error {
+	var (
+		who    peer.ID
+		rewind bool
+	)
+
+	for {
+		best, err := fs.blockState.BestBlockHeader()
+		if err != nil {
+			return err
+		}
+
+		from := big.NewInt(0).Add(best.Number, big.NewInt(1))
+		if rewind {
+			// our best block isn't on the chain of our peers, request the blocks following
+			// our finalised block instead, the best chain will switch to theirs once imported
+			finalised, err := fs.blockState.GetHighestFinalisedHeader()
+			if err != nil {
+				return err
+			}
+			from = big.NewInt(0).Add(finalised.Number, big.NewInt(1))
+		}
+
+		peers := fs.peersWithBlock(from)
+		if len(peers) == 0 {
+			if !rewind {
+				// none of our peers have blocks higher than our best block
+				return nil
+			}
+
+			rewind = false
+			if err = fs.wait(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		who = fs.peerScores.selectPeer(peers, who)
+		rewound := rewind
+		rewind, err = fs.importBlocksFrom(who, from)
+		if rewind && rewound {
+			// the chain of the peer doesn't follow our finalised block either
+			fs.peerScores.recordInvalid(who)
+			rewind, err = false, errPeerOnInvalidFork
+		}
+		if err != nil {
+			logger.Debugf("failed to fast sync blocks from peer %s: %s", who, err)
+			if err = fs.wait(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// importBlocksFrom requests the blocks starting at the given number from the given peer and imports
+// them. It returns true if the parent of the first block is unknown to us.
+func (fs *fastSyncer) importBlocksFrom(who peer.ID, from *big.Int) (bool, error) {
+	max := uint32(maxResponseSize)
+	req := &network.BlockRequestMessage{
+		RequestedData: network.RequestedDataHeader + network.RequestedDataBody + network.RequestedDataJustification,
+		StartingBlock: *variadic.MustNewUint64OrHash(from.Uint64()),
+		Direction:     network.Ascending,
+		Max:           &max,
+	}
+
+	start := time.Now()
+	resp, err := fs.network.DoBlockRequest(who, req)
+	if err != nil {
+		fs.peerScores.recordTimeout(who)
+		return false, err
+	}
+	latency := time.Since(start)
+
+	if err = fs.validateResponse(resp); err != nil {
+		if errors.Is(err, errUnknownParent) {
+			return true, nil
+		}
+
+		fs.peerScores.recordInvalid(who)
+		fs.network.ReportPeer(peerset.ReputationChange{
+			Value:  peerset.BadBlockResponseValue,
+			Reason: peerset.BadBlockResponseReason,
+		}, who)
+		return false, err
+	}
+
+	fs.peerScores.recordResponse(who, latency, len(resp.BlockData))
+
+	for _, bd := range resp.BlockData {
+		if err = fs.importBlock(bd); err != nil {
+			switch {
+			case errors.Is(err, ErrInvalidBlock):
+				fs.peerScores.recordInvalid(who)
+				fs.network.ReportPeer(peerset.ReputationChange{
+					Value:  peerset.BadBlockAnnouncementValue,
+					Reason: peerset.BadBlockAnnouncementReason,
+				}, who)
+			case errors.Is(err, ErrInvalidJustification):
+				fs.peerScores.recordInvalid(who)
+				fs.network.ReportPeer(peerset.ReputationChange{
+					Value:  peerset.BadJustificationValue,
+					Reason: peerset.BadJustificationReason,
+				}, who)
+			}
+			return false, err
+		}
+	}
+
+	last := resp.BlockData[len(resp.BlockData)-1].Header
+	logger.Infof("fast sync imported blocks up to number %s with hash %s", last.Number, last.Hash())
+	return false, nil
+}
+
+// validateResponse checks that the response is a chain of blocks following one we know
+func (fs *fastSyncer) validateResponse(resp *network.BlockResponseMessage) error {
+	if resp == nil || len(resp.BlockData) == 0 {
+		return errEmptyBlockData
+	}
+
+	var prev *types.Header
+	for _, bd := range resp.BlockData {
+		if bd.Header == nil {
+			return errNilHeaderInResponse
+		}
+
+		if bd.Body == nil {
+			return errNilBodyInResponse
+		}
+
+		if prev != nil && bd.Header.ParentHash != prev.Hash() {
+			return errResponseIsNotChain
+		}
+		prev = bd.Header
+	}
+
+	has, err := fs.blockState.HasHeader(resp.BlockData[0].Header.ParentHash)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return errUnknownParent
+	}
+
+	return nil
+}
+
+// importBlock verifies the header of the block and adds it to the block state without executing it
+func (fs *fastSyncer) importBlock(bd *types.BlockData) error {
+	header := bd.Header
+	hash := header.Hash()
+
+	if fs.blockState.IsBadBlock(header) {
+		return fmt.Errorf("%w: hash %s", errBadBlock, hash)
+	}
+
+	has, err := fs.blockState.HasHeader(hash)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		if err = fs.babeVerifier.VerifyBlock(header); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidBlock, err)
+		}
+
+		err = fs.blockState.AddBlock(&types.Block{
+			Header: *header,
+			Body:   *bd.Body,
+		})
+		if err != nil && !errors.Is(err, blocktree.ErrBlockExists) {
+			return err
+		}
+
+		fs.digestHandler.HandleDigests(header)
+		logger.Tracef("fast sync imported block number %s with hash %s", header.Number, hash)
+	}
+
+	if bd.Justification == nil || len(*bd.Justification) == 0 {
+		return nil
+	}
+
+	// verifying the justification also finalises the block, the following blocks of the response
+	// aren't imported if it fails as the finalised chain must be known to verify their justifications
+	if err = fs.finalityGadget.VerifyBlockJustification(hash, *bd.Justification); err != nil {
+		return fmt.Errorf("%w for block number %s with hash %s: %s",
+			ErrInvalidJustification, header.Number, hash, err)
+	}
+
+	if err = fs.blockState.SetJustification(hash, *bd.Justification); err != nil {
+		return err
+	}
+
+	logger.Debugf("fast sync finalised block number %s with hash %s", header.Number, hash)
+	return nil
+}
+
+// importState downloads the state of the target block, then stores it along with the runtime of the block.
+// rt is the runtime of a block we previously imported, it is reused if the runtime code didn't change.
+func (fs *fastSyncer) importState(target *types.Header, rt runtime.Instance) error {
+	hash := target.Hash()
+	logger.Infof("fast sync downloading state of block number %s with hash %s", target.Number, hash)
+
+	excluded := make(map[peer.ID]struct{})
+	retryInterval := fastSyncRetryInterval
+	for {
+		t, senders, err := fs.downloadState(target, excluded)
+		if errors.Is(err, errStateRootMismatch) {
+			// we can't tell which of the peers sent the wrong entries, usually there's only one,
+			// so none of them is used again and the whole state is downloaded again
+			logger.Warnf("failed to download state of block %s: %s", hash, err)
+			for _, p := range senders {
+				fs.network.ReportPeer(peerset.ReputationChange{
+					Value:  peerset.BadMessageValue,
+					Reason: peerset.BadMessageReason,
+				}, p)
+				excluded[p] = struct{}{}
+			}
+
+			if err = fs.waitFor(retryInterval); err != nil {
+				return err
+			}
+			retryInterval = nextRetryInterval(retryInterval)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ts, err := rtstorage.NewTrieState(t)
+		if err != nil {
+			return err
+		}
+
+		fs.storageState.Lock()
+		defer fs.storageState.Unlock()
+
+		if err = fs.storageState.StoreTrie(ts, target); err != nil {
+			return err
+		}
+
+		if err = fs.blockState.HandleRuntimeChanges(ts, rt, hash); err != nil {
+			return err
+		}
+
+		logger.Infof("fast sync imported state of block number %s with hash %s", target.Number, hash)
+		return nil
+	}
+}
+
+// downloadState requests the entries of the state of the target block from our peers, except the excluded ones.
+// It returns the state along with the peers that sent its entries.
+func (fs *fastSyncer) downloadState(target *types.Header, excluded map[peer.ID]struct{}) (
+	*trie.Trie, []peer.ID, error) {
+	var (
+		t       = trie.NewEmptyTrie()
+		start   []byte
+		who     peer.ID
+		senders []peer.ID
+	)
+
+	// the response sizes are unrelated to the ones of block responses, so peers are scored separately
+	scores := newPeerScores()
+
+	for {
+		var peers []peer.ID
+		for _, p := range fs.peersWithBlock(target.Number) {
+			if _, has := excluded[p]; !has {
+				peers = append(peers, p)
+			}
+		}
+
+		if len(peers) == 0 {
+			if err := fs.wait(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		who = scores.selectPeer(peers, who)
+		req := &network.StateRequest{
+			Block:   target.Hash(),
+			NoProof: true,
+		}
+		if start != nil {
+			req.Start = [][]byte{start}
+		}
+
+		began := time.Now()
+		resp, err := fs.network.DoStateRequest(who, req)
+		if err != nil {
+			logger.Debugf("failed to request state from peer %s: %s", who, err)
+			scores.recordTimeout(who)
+			if err = fs.wait(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		kv, err := validateStateResponse(resp, start)
+		if err != nil {
+			logger.Debugf("invalid state response from peer %s: %s", who, err)
+			scores.recordInvalid(who)
+			fs.network.ReportPeer(peerset.ReputationChange{
+				Value:  peerset.BadMessageValue,
+				Reason: peerset.BadMessageReason,
+			}, who)
+			continue
+		}
+
+		scores.recordResponse(who, time.Since(began), len(kv.Entries))
+		if len(senders) == 0 || senders[len(senders)-1] != who {
+			senders = append(senders, who)
+		}
+
+		for _, e := range kv.Entries {
+			if err := t.Put(e.Key, e.Value); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if kv.Complete {
+			break
+		}
+
+		start = kv.Entries[len(kv.Entries)-1].Key
+		logger.Debugf("fast sync downloaded state entries up to key 0x%x", start)
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if root != target.StateRoot {
+		return nil, senders, fmt.Errorf("%w: expected %s, got %s", errStateRootMismatch, target.StateRoot, root)
+	}
+
+	return t, senders, nil
+}
+
+// validateStateResponse checks that the response has entries of the top trie ordered after the start key,
+// and returns them
+func validateStateResponse(resp *network.StateResponse, start []byte) (*network.KeyValueStateEntry, error) {
+	if resp == nil || len(resp.Entries) == 0 {
+		return nil, errEmptyStateResponse
+	}
+
+	kv := &resp.Entries[0]
+	if len(kv.StateRoot) != 0 {
+		return nil, errChildStateNotSupported
+	}
+
+	if len(kv.Entries) == 0 && !kv.Complete {
+		return nil, errEmptyStateResponse
+	}
+
+	prev := start
+	for _, e := range kv.Entries {
+		if prev != nil && bytes.Compare(e.Key, prev) <= 0 {
+			return nil, errStateNotOrdered
+		}
+		prev = e.Key
+	}
+
+	return kv, nil
+}
+
+// executeBlocks executes the blocks following the given block on the best chain, whose state we have
+func (fs *fastSyncer) executeBlocks(from *types.Header) error {
+	hashes, err := fs.blockState.SubChain(from.Hash(), fs.blockState.BestBlockHash())
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes[1:] {
+		if err = fs.ctx.Err(); err != nil {
+			return ErrServiceStopped
+		}
+
+		block, err := fs.blockState.GetBlockByHash(hash)
+		if err != nil {
+			return err
+		}
+
+		if err = fs.executeBlock(block); err != nil {
+			return err
+		}
+	}
+
+	if len(hashes) > 1 {
+		logger.Infof("fast sync executed blocks up to number %s with hash %s",
+			big.NewInt(0).Add(from.Number, big.NewInt(int64(len(hashes)-1))), hashes[len(hashes)-1])
+	}
+
+	return nil
+}
+
+// executeBlock executes a block that was imported without being executed, and stores its state and runtime
+func (fs *fastSyncer) executeBlock(block *types.Block) error {
+	parent, err := fs.blockState.GetHeader(block.Header.ParentHash)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFailedToGetParent, err)
+	}
+
+	fs.storageState.Lock()
+	defer fs.storageState.Unlock()
+
+	ts, err := fs.storageState.TrieState(&parent.StateRoot)
+	if err != nil {
+		return err
+	}
+
+	parentHash := parent.Hash()
+	rt, err := fs.blockState.GetRuntime(&parentHash)
+	if err != nil {
+		return err
+	}
+
+	rt.SetContextStorage(ts)
+
+	if _, err = rt.ExecuteBlock(block); err != nil {
+		return fmt.Errorf("failed to execute block %d: %w", block.Header.Number, err)
+	}
+
+	if err = fs.storageState.StoreTrie(ts, &block.Header); err != nil {
+		return err
+	}
+
+	return fs.blockState.HandleRuntimeChanges(ts, rt, block.Header.Hash())
+}
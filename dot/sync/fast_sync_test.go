@@ -0,0 +1,413 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/common"
+	runtimemocks "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	"github.com/ChainSafe/gossamer/lib/trie"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFastSyncState returns block and storage states whose genesis block has the state of the given trie
+func newTestFastSyncState(t *testing.T, tr *trie.Trie) (*state.BlockState, *state.StorageState) {
+	db := state.NewInMemoryDB(t)
+
+	header, err := types.NewHeader(common.NewHash([]byte{0}), tr.MustHash(), trie.EmptyHash,
+		big.NewInt(0), types.NewDigest())
+	require.NoError(t, err)
+
+	bs, err := state.NewBlockStateFromGenesis(db, header, blocktree.BABEWeight{})
+	require.NoError(t, err)
+
+	ss, err := state.NewStorageState(db, bs, tr, pruner.Config{})
+	require.NoError(t, err)
+
+	return bs, ss
+}
+
+// newTestStateTrie returns a trie whose entries don't fit in a single state response
+func newTestStateTrie() *trie.Trie {
+	tr := trie.NewEmptyTrie()
+	for i := 0; i < 5; i++ {
+		tr.Put([]byte{byte(i)}, bytes.Repeat([]byte{byte(i)}, network.MaxStateResponseEntriesSize/2))
+	}
+
+	for i := 0; i < 100; i++ {
+		tr.Put([]byte{0xff, byte(i)}, []byte{byte(i)})
+	}
+
+	return tr
+}
+
+func TestService_CreateStateResponse(t *testing.T) {
+	tr := newTestStateTrie()
+	bs, ss := newTestFastSyncState(t, tr)
+	s := &Service{
+		blockState:   bs,
+		storageState: ss,
+	}
+
+	req := &network.StateRequest{
+		Block:   bs.GenesisHash(),
+		NoProof: true,
+	}
+
+	received := trie.NewEmptyTrie()
+	responses := 0
+	for {
+		resp, err := s.CreateStateResponse(req)
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, 1)
+		responses++
+
+		kv := resp.Entries[0]
+		for _, e := range kv.Entries {
+			received.Put(e.Key, e.Value)
+		}
+
+		if kv.Complete {
+			break
+		}
+		req.Start = [][]byte{kv.Entries[len(kv.Entries)-1].Key}
+	}
+
+	require.Equal(t, 3, responses)
+	require.Equal(t, tr.MustHash(), received.MustHash())
+}
+
+func TestService_CreateStateResponse_Invalid(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+	s := &Service{
+		blockState:   bs,
+		storageState: ss,
+	}
+
+	_, err := s.CreateStateResponse(&network.StateRequest{
+		Block: bs.GenesisHash(),
+	})
+	require.ErrorIs(t, err, errStateProofNotSupported)
+
+	_, err = s.CreateStateResponse(&network.StateRequest{
+		Block:   bs.GenesisHash(),
+		Start:   [][]byte{{1}, {2}},
+		NoProof: true,
+	})
+	require.ErrorIs(t, err, errChildStateNotSupported)
+
+	_, err = s.CreateStateResponse(&network.StateRequest{
+		Block:   common.Hash{1},
+		NoProof: true,
+	})
+	require.Error(t, err)
+}
+
+func TestValidateStateResponse(t *testing.T) {
+	entries := func(keys ...byte) []network.StateEntry {
+		es := make([]network.StateEntry, len(keys))
+		for i, k := range keys {
+			es[i] = network.StateEntry{Key: []byte{k}}
+		}
+		return es
+	}
+
+	testCases := []struct {
+		name  string
+		resp  *network.StateResponse
+		start []byte
+		err   error
+	}{
+		{
+			name: "nil response",
+			err:  errEmptyStateResponse,
+		},
+		{
+			name: "no entries",
+			resp: &network.StateResponse{},
+			err:  errEmptyStateResponse,
+		},
+		{
+			name: "incomplete without entries",
+			resp: &network.StateResponse{Entries: []network.KeyValueStateEntry{{}}},
+			err:  errEmptyStateResponse,
+		},
+		{
+			name: "complete without entries",
+			resp: &network.StateResponse{Entries: []network.KeyValueStateEntry{{Complete: true}}},
+		},
+		{
+			name: "child trie",
+			resp: &network.StateResponse{Entries: []network.KeyValueStateEntry{{
+				StateRoot: []byte{1},
+				Entries:   entries(1),
+			}}},
+			err: errChildStateNotSupported,
+		},
+		{
+			name:  "ordered",
+			resp:  &network.StateResponse{Entries: []network.KeyValueStateEntry{{Entries: entries(2, 3, 4)}}},
+			start: []byte{1},
+		},
+		{
+			name: "not ordered",
+			resp: &network.StateResponse{Entries: []network.KeyValueStateEntry{{Entries: entries(2, 4, 3)}}},
+			err:  errStateNotOrdered,
+		},
+		{
+			name:  "start key returned",
+			resp:  &network.StateResponse{Entries: []network.KeyValueStateEntry{{Entries: entries(1, 2)}}},
+			start: []byte{1},
+			err:   errStateNotOrdered,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			kv, err := validateStateResponse(tc.resp, tc.start)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, &tc.resp.Entries[0], kv)
+		})
+	}
+}
+
+func TestFastSyncer_DownloadState(t *testing.T) {
+	tr := newTestStateTrie()
+	srcBlockState, srcStorageState := newTestFastSyncState(t, tr)
+	src := &Service{
+		blockState:   srcBlockState,
+		storageState: srcStorageState,
+	}
+
+	net := new(syncmocks.Network)
+	net.On("DoStateRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.StateRequest")).
+		Return(func(_ peer.ID, req *network.StateRequest) *network.StateResponse {
+			resp, err := src.CreateStateResponse(req)
+			require.NoError(t, err)
+			return resp
+		}, nil)
+
+	fs := newFastSyncer(&Config{Network: net})
+	require.True(t, fs.setPeerHead(peer.ID("noot"), srcBlockState.GenesisHash(), big.NewInt(0)))
+
+	target, err := srcBlockState.BestBlockHeader()
+	require.NoError(t, err)
+
+	downloaded, senders, err := fs.downloadState(target, nil)
+	require.NoError(t, err)
+	require.Equal(t, tr.MustHash(), downloaded.MustHash())
+	require.Equal(t, []peer.ID{"noot"}, senders)
+	net.AssertNumberOfCalls(t, "DoStateRequest", 3)
+
+	// the state root of the block doesn't match the entries we received
+	target.StateRoot = trie.EmptyHash
+	_, senders, err = fs.downloadState(target, nil)
+	require.ErrorIs(t, err, errStateRootMismatch)
+	require.Equal(t, []peer.ID{"noot"}, senders)
+
+	// the excluded peers aren't requested
+	require.True(t, fs.setPeerHead(peer.ID("other"), srcBlockState.GenesisHash(), big.NewInt(0)))
+	_, senders, err = fs.downloadState(target, map[peer.ID]struct{}{"noot": {}})
+	require.ErrorIs(t, err, errStateRootMismatch)
+	require.Equal(t, []peer.ID{"other"}, senders)
+}
+
+func TestNextRetryInterval(t *testing.T) {
+	require.Equal(t, 2*time.Second, nextRetryInterval(time.Second))
+	require.Equal(t, maxFastSyncRetryInterval, nextRetryInterval(maxFastSyncRetryInterval/2+time.Second))
+	require.Equal(t, maxFastSyncRetryInterval, nextRetryInterval(maxFastSyncRetryInterval))
+}
+
+// newTestFastSyncBlockData returns the given number of blocks following the parent, the second one having
+// the given justification if it isn't nil
+func newTestFastSyncBlockData(parent *types.Header, n int, justification []byte) []*types.BlockData {
+	var blockData []*types.BlockData
+	for i := 1; i <= n; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(0).Add(parent.Number, big.NewInt(1)),
+			StateRoot:  common.Hash{byte(i)},
+			Digest:     types.NewDigest(),
+		}
+
+		bd := types.NewEmptyBlockData()
+		bd.Hash = header.Hash()
+		bd.Header = header
+		bd.Body = types.NewBody([]types.Extrinsic{})
+		blockData = append(blockData, bd)
+		parent = header
+	}
+
+	if justification != nil {
+		blockData[1].Justification = &justification
+	}
+	return blockData
+}
+
+func TestFastSyncer_ImportBlocks(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+	genesis, err := bs.BestBlockHeader()
+	require.NoError(t, err)
+
+	justification := []byte("justification")
+	blockData := newTestFastSyncBlockData(genesis, 3, justification)
+
+	net := new(syncmocks.Network)
+	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(func(_ peer.ID, req *network.BlockRequestMessage) *network.BlockResponseMessage {
+			start, ok := req.StartingBlock.Value().(uint64)
+			require.True(t, ok)
+			return &network.BlockResponseMessage{
+				BlockData: blockData[start-1:],
+			}
+		}, nil)
+
+	verifier := new(syncmocks.BabeVerifier)
+	verifier.On("VerifyBlock", mock.AnythingOfType("*types.Header")).Return(nil)
+	finalityGadget := new(syncmocks.FinalityGadget)
+	finalityGadget.On("VerifyBlockJustification", blockData[1].Hash, justification).Return(nil)
+	digestHandler := new(syncmocks.DigestHandler)
+	digestHandler.On("HandleDigests", mock.AnythingOfType("*types.Header"))
+
+	fs := newFastSyncer(&Config{
+		BlockState:     bs,
+		StorageState:   ss,
+		Network:        net,
+		BabeVerifier:   verifier,
+		FinalityGadget: finalityGadget,
+		DigestHandler:  digestHandler,
+	})
+	fs.setPeerHead(peer.ID("noot"), blockData[2].Hash, big.NewInt(3))
+
+	err = fs.importBlocks()
+	require.NoError(t, err)
+
+	require.Equal(t, blockData[2].Hash, bs.BestBlockHash())
+	verifier.AssertNumberOfCalls(t, "VerifyBlock", 3)
+	digestHandler.AssertNumberOfCalls(t, "HandleDigests", 3)
+	finalityGadget.AssertExpectations(t)
+
+	stored, err := bs.GetJustification(blockData[1].Hash)
+	require.NoError(t, err)
+	require.Equal(t, justification, stored)
+
+	// peer heads are handed over to chainSync once fast sync is done
+	var handedOver []*peerState
+	fs.finish(func(ps *peerState) {
+		handedOver = append(handedOver, ps)
+	})
+	require.Len(t, handedOver, 1)
+	require.Equal(t, blockData[2].Hash, handedOver[0].hash)
+	require.False(t, fs.setPeerHead(peer.ID("noot"), blockData[2].Hash, big.NewInt(3)))
+}
+
+func TestFastSyncer_ImportBlocksFrom_InvalidJustification(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+	genesis, err := bs.BestBlockHeader()
+	require.NoError(t, err)
+
+	justification := []byte("justification")
+	blockData := newTestFastSyncBlockData(genesis, 3, justification)
+
+	net := new(syncmocks.Network)
+	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(&network.BlockResponseMessage{BlockData: blockData}, nil)
+	net.On("ReportPeer", mock.AnythingOfType("peerset.ReputationChange"), mock.AnythingOfType("peer.ID"))
+
+	verifier := new(syncmocks.BabeVerifier)
+	verifier.On("VerifyBlock", mock.AnythingOfType("*types.Header")).Return(nil)
+	finalityGadget := new(syncmocks.FinalityGadget)
+	finalityGadget.On("VerifyBlockJustification", blockData[1].Hash, justification).
+		Return(errors.New("invalid justification"))
+	digestHandler := new(syncmocks.DigestHandler)
+	digestHandler.On("HandleDigests", mock.AnythingOfType("*types.Header"))
+
+	fs := newFastSyncer(&Config{
+		BlockState:     bs,
+		StorageState:   ss,
+		Network:        net,
+		BabeVerifier:   verifier,
+		FinalityGadget: finalityGadget,
+		DigestHandler:  digestHandler,
+	})
+
+	who := peer.ID("noot")
+	_, err = fs.importBlocksFrom(who, big.NewInt(1))
+	require.ErrorIs(t, err, ErrInvalidJustification)
+
+	net.AssertCalled(t, "ReportPeer", peerset.ReputationChange{
+		Value:  peerset.BadJustificationValue,
+		Reason: peerset.BadJustificationReason,
+	}, who)
+	require.Equal(t, float64(1), fs.peerScores.scores[who].invalid)
+
+	// the block isn't finalised, and the blocks following it aren't imported
+	finalised, err := bs.GetHighestFinalisedHeader()
+	require.NoError(t, err)
+	require.Equal(t, genesis.Hash(), finalised.Hash())
+
+	has, err := bs.HasHeader(blockData[2].Hash)
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestFastSyncer_SetStart(t *testing.T) {
+	bs, ss := newTestFastSyncState(t, trie.NewEmptyTrie())
+	genesis, err := bs.BestBlockHeader()
+	require.NoError(t, err)
+
+	rt := new(runtimemocks.Instance)
+	bs.StoreRuntime(genesis.Hash(), rt)
+
+	cfg := &Config{
+		BlockState:   bs,
+		StorageState: ss,
+	}
+
+	fs := newFastSyncer(cfg)
+	err = fs.setStart()
+	require.NoError(t, err)
+	require.Equal(t, genesis.Hash(), fs.start.Hash())
+	require.Equal(t, rt, fs.startRuntime)
+
+	start, err := bs.FastSyncStart()
+	require.NoError(t, err)
+	require.Equal(t, genesis.Hash(), start.Hash())
+
+	// a fast sync resumed after a restart starts from the recorded block rather than the best block,
+	// the runtime of the best block being created from the state of the recorded block
+	blockData := newTestFastSyncBlockData(genesis, 2, nil)
+	for _, bd := range blockData {
+		err = bs.AddBlock(&types.Block{Header: *bd.Header, Body: *bd.Body})
+		require.NoError(t, err)
+	}
+	bs.StoreRuntime(blockData[1].Hash, rt)
+
+	fs = newFastSyncer(cfg)
+	err = fs.setStart()
+	require.NoError(t, err)
+	require.Equal(t, genesis.Hash(), fs.start.Hash())
+	require.Equal(t, rt, fs.startRuntime)
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+)
+
+var (
+	// verificationWorkers is the number of goroutines verifying block headers ahead of their execution
+	verificationWorkers = runtime.NumCPU()
+	// maxBlocksInFlight is the number of blocks that can be in the import pipeline at once,
+	// ie. taken from the ready queue but not yet imported
+	maxBlocksInFlight = maxResponseSize
+)
+
+// verificationJob is a block going through the import pipeline
+type verificationJob struct {
+	bd *types.BlockData
+	// err is the result of the verification of the block header, it is set before done is closed
+	err  error
+	done chan struct{}
+}
+
+// importPipeline imports blocks in stages. The decoded blocks are taken from the ready queue by
+// the dispatcher, their headers are verified in parallel by a pool of workers, then the blocks are
+// executed and committed one at a time, in the order they were taken.
+//
+// The number of blocks between the dispatcher and the import stage is bounded by the capacity of
+// the ordered channel, so the dispatcher stops taking blocks while the import stage is behind.
+type importPipeline struct {
+	ctx     context.Context
+	workers int
+	verify  func(*types.Header) error
+
+	// jobs are the blocks waiting for a verification worker
+	jobs chan *verificationJob
+	// ordered are the blocks waiting to be imported, in the order they were taken
+	ordered chan *verificationJob
+}
+
+func newImportPipeline(ctx context.Context, workers, depth int, verify func(*types.Header) error) *importPipeline {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &importPipeline{
+		ctx:     ctx,
+		workers: workers,
+		verify:  verify,
+		jobs:    make(chan *verificationJob, depth),
+		ordered: make(chan *verificationJob, depth),
+	}
+}
+
+// run passes the blocks returned by next through the pipeline, and calls importBlock with each of them
+// in order along with the result of the verification of its header. It returns once the context is done.
+func (p *importPipeline) run(next func() *types.BlockData, importBlock func(*types.BlockData, error)) {
+	for i := 0; i < p.workers; i++ {
+		go p.verifyHeaders()
+	}
+
+	go p.dispatch(next)
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.ordered:
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-job.done:
+			}
+
+			importBlock(job.bd, job.err)
+		}
+	}
+}
+
+// dispatch takes the blocks returned by next and hands them to the verification workers and to the
+// import stage. It blocks while the import stage has maxBlocksInFlight blocks waiting.
+func (p *importPipeline) dispatch(next func() *types.BlockData) {
+	for {
+		bd := next()
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if bd == nil {
+			continue
+		}
+
+		job := &verificationJob{
+			bd:   bd,
+			done: make(chan struct{}),
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case p.ordered <- job:
+		}
+
+		// block data without a header, ie. a justification, has nothing to verify
+		if bd.Header == nil {
+			close(job.done)
+			continue
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case p.jobs <- job:
+		}
+	}
+}
+
+func (p *importPipeline) verifyHeaders() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			job.err = p.verify(job.bd.Header)
+			close(job.done)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPipelineBlocks(n int) []*types.BlockData {
+	blocks := make([]*types.BlockData, n)
+	for i := range blocks {
+		header := &types.Header{
+			Number: big.NewInt(int64(i + 1)),
+			Digest: types.NewDigest(),
+		}
+		blocks[i] = &types.BlockData{
+			Hash:   header.Hash(),
+			Header: header,
+			Body:   types.NewBody([]types.Extrinsic{}),
+		}
+	}
+	return blocks
+}
+
+func TestImportPipeline_Order(t *testing.T) {
+	blocks := newTestPipelineBlocks(100)
+	// a justification without a header isn't verified
+	justification := &types.BlockData{Hash: common.Hash{1}}
+	blocks = append(blocks, justification)
+
+	errInvalid := errors.New("invalid header")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := make(chan *types.BlockData, len(blocks))
+	for _, bd := range blocks {
+		queue <- bd
+	}
+
+	pipeline := newImportPipeline(ctx, 4, 8, func(header *types.Header) error {
+		// verifications complete out of order
+		time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond) //nolint:gosec
+		if header.Number.Int64() == 50 {
+			return errInvalid
+		}
+		return nil
+	})
+
+	var imported []*types.BlockData
+	pipeline.run(func() *types.BlockData {
+		select {
+		case bd := <-queue:
+			return bd
+		case <-ctx.Done():
+			return nil
+		}
+	}, func(bd *types.BlockData, err error) {
+		if bd.Header != nil && bd.Header.Number.Int64() == 50 {
+			require.ErrorIs(t, err, errInvalid)
+		} else {
+			require.NoError(t, err)
+		}
+
+		imported = append(imported, bd)
+		if len(imported) == len(blocks) {
+			cancel()
+		}
+	})
+
+	require.Equal(t, blocks, imported)
+}
+
+func TestImportPipeline_BackPressure(t *testing.T) {
+	const depth = 4
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	blocks := newTestPipelineBlocks(100)
+	var taken, imported int32
+	next := func() *types.BlockData {
+		i := atomic.AddInt32(&taken, 1)
+		if int(i) > len(blocks) {
+			<-ctx.Done()
+			return nil
+		}
+		return blocks[i-1]
+	}
+
+	release := make(chan struct{})
+	pipeline := newImportPipeline(ctx, 2, depth, func(*types.Header) error {
+		return nil
+	})
+	go pipeline.run(next, func(*types.BlockData, error) {
+		// the import stage is stuck on the first block
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		atomic.AddInt32(&imported, 1)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	// the block being imported, the ones waiting to be imported, and the one waiting to be dispatched
+	require.Equal(t, int32(depth+2), atomic.LoadInt32(&taken))
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&imported) == int32(len(blocks))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestImportPipeline_StopWithEmptyQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := newBlockQueue(maxResponseSize)
+	stopped := make(chan struct{})
+	next := func() *types.BlockData {
+		bd := queue.take(ctx)
+		if bd == nil {
+			close(stopped)
+		}
+		return bd
+	}
+
+	pipeline := newImportPipeline(ctx, 2, 4, func(*types.Header) error {
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		pipeline.run(next, func(*types.BlockData, error) {})
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	for _, ch := range []chan struct{}{done, stopped} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("import pipeline did not stop")
+		}
+	}
+}
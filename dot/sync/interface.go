@@ -31,6 +31,7 @@ type BlockState interface {
 	SetHeader(*types.Header) error
 	GetHeader(common.Hash) (*types.Header, error)
 	HasHeader(hash common.Hash) (bool, error)
+	HasJustification(hash common.Hash) (bool, error)
 	SubChain(start, end common.Hash) ([]common.Hash, error)
 	GetReceipt(common.Hash) ([]byte, error)
 	GetMessageQueue(common.Hash) ([]byte, error)
@@ -47,11 +48,18 @@ type BlockState interface {
 	GetHeaderByNumber(num *big.Int) (*types.Header, error)
 	GetAllBlocksAtNumber(num *big.Int) ([]common.Hash, error)
 	IsDescendantOf(parent, child common.Hash) (bool, error)
+	HandleRuntimeChanges(newState *rtstorage.TrieState, in runtime.Instance, bHash common.Hash) error
+	HasBadBlock(hash common.Hash) bool
+	IsBadBlock(header *types.Header) bool
+	SetFastSyncStart(header *types.Header) error
+	FastSyncStart() (*types.Header, error)
+	ClearFastSyncStart() error
 }
 
 // StorageState is the interface for the storage state
 type StorageState interface {
 	TrieState(root *common.Hash) (*rtstorage.TrieState, error)
+	StoreTrie(*rtstorage.TrieState, *types.Header) error
 	LoadCodeHash(*common.Hash) (common.Hash, error)
 	SetSyncing(bool)
 	sync.Locker
@@ -82,6 +90,21 @@ type FinalityGadget interface {
 	VerifyBlockJustification(common.Hash, []byte) error
 }
 
+//go:generate mockery --name GrandpaState --structname GrandpaState --case underscore --keeptree
+
+// GrandpaState is the interface for the GRANDPA authority set state
+type GrandpaState interface {
+	GetCurrentSetID() (uint64, error)
+	GetSetIDChange(setID uint64) (*big.Int, error)
+}
+
+//go:generate mockery --name DigestHandler --structname DigestHandler --case underscore --keeptree
+
+// DigestHandler is the interface for the handler of the consensus digests of imported blocks
+type DigestHandler interface {
+	HandleDigests(header *types.Header)
+}
+
 //go:generate mockery --name BlockImportHandler --structname BlockImportHandler --case underscore --keeptree
 
 // BlockImportHandler is the interface for the handler of newly imported blocks
@@ -98,6 +121,9 @@ type Network interface {
 	// it is returned, otherwise an error is returned.
 	DoBlockRequest(to peer.ID, req *network.BlockRequestMessage) (*network.BlockResponseMessage, error)
 
+	// DoStateRequest sends a state request to the given peer and returns its response
+	DoStateRequest(to peer.ID, req *network.StateRequest) (*network.StateResponse, error)
+
 	// Peers returns a list of currently connected peers
 	Peers() []common.PeerInfo
 
@@ -0,0 +1,264 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/common/variadic"
+)
+
+// maxJustificationRequests is the maximum number of peers a justification is requested from on each tick
+const maxJustificationRequests = 3
+
+// missingJustification is a finalisable block that we have without a justification
+type missingJustification struct {
+	hash   common.Hash
+	number *big.Int
+}
+
+// missingJustifications tracks the finalisable blocks near the head which we have without a justification,
+// such as the blocks enacting an authority set change, which must be finalised before the blocks above them
+type missingJustifications struct {
+	sync.Mutex
+	blocks map[common.Hash]*missingJustification
+}
+
+func newMissingJustifications() *missingJustifications {
+	return &missingJustifications{
+		blocks: make(map[common.Hash]*missingJustification),
+	}
+}
+
+func (m *missingJustifications) add(hash common.Hash, number *big.Int) {
+	m.Lock()
+	defer m.Unlock()
+	if _, has := m.blocks[hash]; has {
+		return
+	}
+
+	logger.Debugf("tracking missing justification for block number %s with hash %s", number, hash)
+	m.blocks[hash] = &missingJustification{
+		hash:   hash,
+		number: new(big.Int).Set(number),
+	}
+}
+
+func (m *missingJustifications) remove(hash common.Hash) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.blocks, hash)
+}
+
+// list returns the tracked blocks, lowest first
+func (m *missingJustifications) list() []*missingJustification {
+	m.Lock()
+	defer m.Unlock()
+
+	blocks := make([]*missingJustification, 0, len(m.blocks))
+	for _, block := range m.blocks {
+		blocks = append(blocks, block)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].number.Cmp(blocks[j].number) < 0
+	})
+	return blocks
+}
+
+// requestJustifications requests the missing justifications on every slot while in tip mode
+func (cs *chainSync) requestJustifications() {
+	ticker := time.NewTicker(cs.slotDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if cs.state != tip {
+				continue
+			}
+
+			if err := cs.handleMissingJustifications(); err != nil {
+				logger.Debugf("failed to handle missing justifications: %s", err)
+			}
+		case <-cs.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleMissingJustifications updates the tracked blocks, then requests the justification of each of them
+// from the peers that have them, and finalises the blocks whose justification is valid.
+func (cs *chainSync) handleMissingJustifications() error {
+	if err := cs.trackSetChangeBlock(); err != nil {
+		return err
+	}
+
+	fin, err := cs.blockState.GetHighestFinalisedHeader()
+	if err != nil {
+		return err
+	}
+
+	for _, block := range cs.missingJustifications.list() {
+		// the block may have been finalised by a commit message, or be on a pruned fork
+		if block.number.Cmp(fin.Number) <= 0 {
+			cs.missingJustifications.remove(block.hash)
+			continue
+		}
+
+		has, err := cs.blockState.HasJustification(block.hash)
+		if err != nil {
+			return err
+		}
+
+		if has {
+			cs.missingJustifications.remove(block.hash)
+			continue
+		}
+
+		if err = cs.requestJustification(block); err != nil {
+			logger.Debugf("failed to get justification for block number %s with hash %s: %s",
+				block.number, block.hash, err)
+		}
+	}
+
+	return nil
+}
+
+// trackSetChangeBlock tracks the block on our best chain which enacts the next authority set change,
+// if we have it without a justification. The current set ID is only incremented once it's finalised.
+func (cs *chainSync) trackSetChangeBlock() error {
+	setID, err := cs.grandpaState.GetCurrentSetID()
+	if err != nil {
+		return err
+	}
+
+	number, err := cs.grandpaState.GetSetIDChange(setID + 1)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	best, err := cs.blockState.BestBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	if number.Cmp(best) > 0 {
+		return nil
+	}
+
+	hash, err := cs.blockState.GetHashByNumber(number)
+	if err != nil {
+		return err
+	}
+
+	has, err := cs.blockState.HasJustification(hash)
+	if err != nil || has {
+		return err
+	}
+
+	cs.missingJustifications.add(hash, number)
+	return nil
+}
+
+// requestJustification requests the justification of the block from up to maxJustificationRequests
+// peers that have it, until one of them sends a valid one, which finalises the block
+func (cs *chainSync) requestJustification(block *missingJustification) error {
+	max := uint32(1)
+	req := &network.BlockRequestMessage{
+		RequestedData: network.RequestedDataJustification,
+		StartingBlock: *variadic.MustNewUint64OrHash(block.hash),
+		Direction:     network.Ascending,
+		Max:           &max,
+	}
+
+	// only the peers whose best block is at least the number of the block may have it
+	byNumber := &network.BlockRequestMessage{
+		StartingBlock: *variadic.MustNewUint64OrHash(block.number.Uint64()),
+		Direction:     network.Ascending,
+	}
+
+	peersTried := make(map[peer.ID]struct{})
+	for i := 0; i < maxJustificationRequests; i++ {
+		who := cs.peerScores.selectPeer(cs.determineSyncPeers(byNumber, peersTried), "")
+		if who == "" {
+			return errNoPeers
+		}
+		peersTried[who] = struct{}{}
+
+		justification, err := cs.doJustificationRequest(who, req, block.hash)
+		if err != nil {
+			logger.Debugf("failed to get justification from peer %s: %s", who, err)
+			continue
+		}
+
+		// the peer doesn't have the justification either
+		if justification == nil {
+			continue
+		}
+
+		if err = cs.finalityGadget.VerifyBlockJustification(block.hash, justification); err != nil {
+			logger.Debugf("failed to verify justification from peer %s for block number %s with hash %s: %s",
+				who, block.number, block.hash, err)
+			cs.peerScores.recordInvalid(who)
+			cs.network.ReportPeer(peerset.ReputationChange{
+				Value:  peerset.BadJustificationValue,
+				Reason: peerset.BadJustificationReason,
+			}, who)
+			continue
+		}
+
+		if err = cs.blockState.SetJustification(block.hash, justification); err != nil {
+			return fmt.Errorf("failed to store justification: %w", err)
+		}
+
+		cs.missingJustifications.remove(block.hash)
+		logger.Infof("🔨 finalised block number %s with hash %s", block.number, block.hash)
+		return nil
+	}
+
+	return nil
+}
+
+// doJustificationRequest sends the justification request to the peer, and returns the justification
+// in its response, which is nil if the peer doesn't have it
+func (cs *chainSync) doJustificationRequest(who peer.ID, req *network.BlockRequestMessage,
+	hash common.Hash) ([]byte, error) {
+	resp, err := cs.network.DoBlockRequest(who, req)
+	if err != nil {
+		if errors.Is(err, network.ErrRequestTimeout) {
+			cs.peerScores.recordTimeout(who)
+		}
+		return nil, err
+	}
+
+	if resp == nil || len(resp.BlockData) == 0 {
+		return nil, nil
+	}
+
+	bd := resp.BlockData[0]
+	if len(resp.BlockData) != 1 || !bd.Hash.Equal(hash) {
+		cs.peerScores.recordInvalid(who)
+		return nil, fmt.Errorf("%w: for block hash %s", errInvalidJustificationResponse, hash)
+	}
+
+	if bd.Justification == nil || len(*bd.Justification) == 0 {
+		return nil, nil
+	}
+
+	return *bd.Justification, nil
+}
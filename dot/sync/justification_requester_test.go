@@ -0,0 +1,157 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/network"
+	syncmocks "github.com/ChainSafe/gossamer/dot/sync/mocks"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJustificationChainSync returns a chainSync with 20 blocks, whose next authority set change
+// is enacted by block 15, and a peer with the same chain which has the justification of every block
+func newTestJustificationChainSync(t *testing.T) (*chainSync, []*types.Header) {
+	cs, ours, _ := newTestForkedChainSync(t)
+	cs.missingJustifications = newMissingJustifications()
+	cs.peerState["noot"] = &peerState{
+		who:    "noot",
+		hash:   ours[20].Hash(),
+		number: ours[20].Number,
+	}
+
+	gs := new(syncmocks.GrandpaState)
+	gs.On("GetCurrentSetID").Return(uint64(0), nil)
+	gs.On("GetSetIDChange", uint64(1)).Return(big.NewInt(15), nil)
+	cs.grandpaState = gs
+
+	net := new(syncmocks.Network)
+	net.On("ReportPeer", mock.AnythingOfType("peerset.ReputationChange"), mock.AnythingOfType("peer.ID"))
+	net.On("DoBlockRequest", mock.AnythingOfType("peer.ID"), mock.AnythingOfType("*network.BlockRequestMessage")).
+		Return(func(_ peer.ID, req *network.BlockRequestMessage) *network.BlockResponseMessage {
+			justification := []byte("justification")
+			return &network.BlockResponseMessage{
+				BlockData: []*types.BlockData{{
+					Hash:          req.StartingBlock.Value().(common.Hash),
+					Justification: &justification,
+				}},
+			}
+		}, nil)
+	cs.network = net
+
+	return cs, ours
+}
+
+func TestChainSync_handleMissingJustifications(t *testing.T) {
+	cs, ours := newTestJustificationChainSync(t)
+
+	fg := new(syncmocks.FinalityGadget)
+	fg.On("VerifyBlockJustification", ours[15].Hash(), []byte("justification")).Return(nil)
+	cs.finalityGadget = fg
+
+	err := cs.handleMissingJustifications()
+	require.NoError(t, err)
+
+	// only the justification was requested, for the block enacting the set change
+	net := cs.network.(*syncmocks.Network)
+	net.AssertNumberOfCalls(t, "DoBlockRequest", 1)
+	req := net.Calls[0].Arguments.Get(1).(*network.BlockRequestMessage)
+	require.Equal(t, network.RequestedDataJustification, req.RequestedData)
+	require.Equal(t, ours[15].Hash(), req.StartingBlock.Value())
+
+	justification, err := cs.blockState.GetJustification(ours[15].Hash())
+	require.NoError(t, err)
+	require.Equal(t, []byte("justification"), justification)
+	require.Empty(t, cs.missingJustifications.list())
+
+	// the block isn't requested again once we have its justification
+	err = cs.handleMissingJustifications()
+	require.NoError(t, err)
+	net.AssertNumberOfCalls(t, "DoBlockRequest", 1)
+}
+
+func TestChainSync_handleMissingJustifications_InvalidJustification(t *testing.T) {
+	cs, ours := newTestJustificationChainSync(t)
+
+	fg := new(syncmocks.FinalityGadget)
+	fg.On("VerifyBlockJustification", ours[15].Hash(), []byte("justification")).
+		Return(errors.New("invalid justification"))
+	cs.finalityGadget = fg
+
+	err := cs.handleMissingJustifications()
+	require.NoError(t, err)
+
+	net := cs.network.(*syncmocks.Network)
+	net.AssertNumberOfCalls(t, "ReportPeer", 1)
+
+	has, err := cs.blockState.HasJustification(ours[15].Hash())
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// the block is still tracked, to be requested again on the next tick
+	missing := cs.missingJustifications.list()
+	require.Len(t, missing, 1)
+	require.Equal(t, ours[15].Hash(), missing[0].hash)
+}
+
+func TestChainSync_trackSetChangeBlock(t *testing.T) {
+	cs, ours := newTestJustificationChainSync(t)
+
+	// there is no upcoming set change
+	gs := new(syncmocks.GrandpaState)
+	gs.On("GetCurrentSetID").Return(uint64(0), nil)
+	gs.On("GetSetIDChange", uint64(1)).Return(nil, chaindb.ErrKeyNotFound)
+	cs.grandpaState = gs
+
+	err := cs.trackSetChangeBlock()
+	require.NoError(t, err)
+	require.Empty(t, cs.missingJustifications.list())
+
+	// the set change is enacted by a block we don't have yet
+	gs = new(syncmocks.GrandpaState)
+	gs.On("GetCurrentSetID").Return(uint64(0), nil)
+	gs.On("GetSetIDChange", uint64(1)).Return(big.NewInt(21), nil)
+	cs.grandpaState = gs
+
+	err = cs.trackSetChangeBlock()
+	require.NoError(t, err)
+	require.Empty(t, cs.missingJustifications.list())
+
+	// the blocks are listed lowest first
+	cs.missingJustifications.add(ours[17].Hash(), ours[17].Number)
+	cs.missingJustifications.add(ours[12].Hash(), ours[12].Number)
+	missing := cs.missingJustifications.list()
+	require.Len(t, missing, 2)
+	require.Equal(t, ours[12].Hash(), missing[0].hash)
+	require.Equal(t, ours[17].Hash(), missing[1].hash)
+}
+
+func TestChainProcessor_handleJustification_TracksInvalid(t *testing.T) {
+	fg := new(syncmocks.FinalityGadget)
+	fg.On("VerifyBlockJustification", mock.AnythingOfType("common.Hash"), []byte("justification")).
+		Return(errors.New("invalid justification"))
+
+	missing := newMissingJustifications()
+	processor := newChainProcessor(nil, nil, nil, nil, nil, nil, fg, nil, missing)
+
+	header := &types.Header{
+		Number: big.NewInt(5),
+		Digest: types.NewDigest(),
+	}
+	processor.handleJustification(header, []byte("justification"))
+
+	list := missing.list()
+	require.Len(t, list, 1)
+	require.Equal(t, header.Hash(), list[0].hash)
+	require.Equal(t, big.NewInt(5), list[0].number)
+}
@@ -422,3 +422,61 @@ func (s *Service) getBlockData(hash common.Hash, requestedData byte) (*types.Blo
 
 	return blockData, nil
 }
+
+// CreateStateResponse creates a state response from a state request. The response holds the entries of the
+// state of the requested block following the start key, up to network.MaxStateResponseEntriesSize bytes.
+func (s *Service) CreateStateResponse(req *network.StateRequest) (*network.StateResponse, error) {
+	if !req.NoProof {
+		return nil, errStateProofNotSupported
+	}
+
+	// child tries are not stored separately from the top trie, so they cannot be served
+	if len(req.Start) > 1 {
+		return nil, errChildStateNotSupported
+	}
+
+	header, err := s.blockState.GetHeader(req.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	s.storageState.Lock()
+	defer s.storageState.Unlock()
+
+	ts, err := s.storageState.TrieState(&header.StateRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if len(req.Start) == 1 {
+		key = req.Start[0]
+	}
+
+	kv := network.KeyValueStateEntry{}
+	size := 0
+	for size < network.MaxStateResponseEntriesSize {
+		key, err = ts.NextKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get next key: %w", err)
+		}
+		if key == nil {
+			kv.Complete = true
+			break
+		}
+
+		value, err := ts.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get value of key 0x%x: %w", key, err)
+		}
+		kv.Entries = append(kv.Entries, network.StateEntry{
+			Key:   key,
+			Value: value,
+		})
+		size += len(key) + len(value)
+	}
+
+	return &network.StateResponse{
+		Entries: []network.KeyValueStateEntry{kv},
+	}, nil
+}
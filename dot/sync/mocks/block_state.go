@@ -10,6 +10,8 @@ import (
 
 	runtime "github.com/ChainSafe/gossamer/lib/runtime"
 
+	storage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+
 	types "github.com/ChainSafe/gossamer/dot/types"
 )
 
@@ -108,6 +110,20 @@ func (_m *BlockState) BestBlockNumber() (*big.Int, error) {
 	return r0, r1
 }
 
+// ClearFastSyncStart provides a mock function with given fields:
+func (_m *BlockState) ClearFastSyncStart() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CompareAndSetBlockData provides a mock function with given fields: bd
 func (_m *BlockState) CompareAndSetBlockData(bd *types.BlockData) error {
 	ret := _m.Called(bd)
@@ -122,6 +138,29 @@ func (_m *BlockState) CompareAndSetBlockData(bd *types.BlockData) error {
 	return r0
 }
 
+// FastSyncStart provides a mock function with given fields:
+func (_m *BlockState) FastSyncStart() (*types.Header, error) {
+	ret := _m.Called()
+
+	var r0 *types.Header
+	if rf, ok := ret.Get(0).(func() *types.Header); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Header)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllBlocksAtNumber provides a mock function with given fields: num
 func (_m *BlockState) GetAllBlocksAtNumber(num *big.Int) ([]common.Hash, error) {
 	ret := _m.Called(num)
@@ -414,6 +453,34 @@ func (_m *BlockState) GetRuntime(_a0 *common.Hash) (runtime.Instance, error) {
 	return r0, r1
 }
 
+// HandleRuntimeChanges provides a mock function with given fields: newState, in, bHash
+func (_m *BlockState) HandleRuntimeChanges(newState *storage.TrieState, in runtime.Instance, bHash common.Hash) error {
+	ret := _m.Called(newState, in, bHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*storage.TrieState, runtime.Instance, common.Hash) error); ok {
+		r0 = rf(newState, in, bHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HasBadBlock provides a mock function with given fields: hash
+func (_m *BlockState) HasBadBlock(hash common.Hash) bool {
+	ret := _m.Called(hash)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(common.Hash) bool); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // HasBlockBody provides a mock function with given fields: hash
 func (_m *BlockState) HasBlockBody(hash common.Hash) (bool, error) {
 	ret := _m.Called(hash)
@@ -456,6 +523,41 @@ func (_m *BlockState) HasHeader(hash common.Hash) (bool, error) {
 	return r0, r1
 }
 
+// HasJustification provides a mock function with given fields: hash
+func (_m *BlockState) HasJustification(hash common.Hash) (bool, error) {
+	ret := _m.Called(hash)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(common.Hash) bool); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(common.Hash) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsBadBlock provides a mock function with given fields: header
+func (_m *BlockState) IsBadBlock(header *types.Header) bool {
+	ret := _m.Called(header)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*types.Header) bool); ok {
+		r0 = rf(header)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // IsDescendantOf provides a mock function with given fields: parent, child
 func (_m *BlockState) IsDescendantOf(parent common.Hash, child common.Hash) (bool, error) {
 	ret := _m.Called(parent, child)
@@ -477,6 +579,20 @@ func (_m *BlockState) IsDescendantOf(parent common.Hash, child common.Hash) (boo
 	return r0, r1
 }
 
+// SetFastSyncStart provides a mock function with given fields: header
+func (_m *BlockState) SetFastSyncStart(header *types.Header) error {
+	ret := _m.Called(header)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.Header) error); ok {
+		r0 = rf(header)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetFinalisedHash provides a mock function with given fields: hash, round, setID
 func (_m *BlockState) SetFinalisedHash(hash common.Hash, round uint64, setID uint64) error {
 	ret := _m.Called(hash, round, setID)
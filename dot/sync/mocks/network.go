@@ -41,6 +41,29 @@ func (_m *Network) DoBlockRequest(to peer.ID, req *network.BlockRequestMessage)
 	return r0, r1
 }
 
+// DoStateRequest provides a mock function with given fields: to, req
+func (_m *Network) DoStateRequest(to peer.ID, req *network.StateRequest) (*network.StateResponse, error) {
+	ret := _m.Called(to, req)
+
+	var r0 *network.StateResponse
+	if rf, ok := ret.Get(0).(func(peer.ID, *network.StateRequest) *network.StateResponse); ok {
+		r0 = rf(to, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*network.StateResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(peer.ID, *network.StateRequest) error); ok {
+		r1 = rf(to, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Peers provides a mock function with given fields:
 func (_m *Network) Peers() []common.PeerInfo {
 	ret := _m.Called()
@@ -0,0 +1,245 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	// scoreSmoothing is the weight of the latest response in the moving averages of a peer's score
+	scoreSmoothing = 0.2
+
+	// invalidResponseWeight and timeoutWeight are how much a single invalid response or timeout divides
+	// the score of a peer. They are forgotten progressively as the peer sends us good responses.
+	invalidResponseWeight = 4
+	timeoutWeight         = 1
+
+	// stickyPeerRatio is the ratio of the best score a worker's previous peer must have to be reused
+	stickyPeerRatio = 0.75
+
+	// slowPeerRatio is the ratio of the average score under which a peer is considered slow
+	slowPeerRatio = 0.25
+)
+
+// peerScore tracks how well a peer answers our block requests
+type peerScore struct {
+	latency   time.Duration // moving average of the response time
+	blocks    float64       // moving average of the number of blocks per response
+	invalid   float64       // decaying number of invalid responses
+	timeouts  float64       // decaying number of requests that timed out
+	responses uint64
+}
+
+// throughput returns the number of blocks per second the peer sends us
+func (s *peerScore) throughput() float64 {
+	latency := s.latency
+	if latency < time.Millisecond {
+		latency = time.Millisecond
+	}
+
+	return s.blocks / latency.Seconds()
+}
+
+// value returns the score of the peer, which is its throughput lowered by its failures
+func (s *peerScore) value() float64 {
+	return s.throughput() / (1 + invalidResponseWeight*s.invalid + timeoutWeight*s.timeouts)
+}
+
+// peerScores keeps track of the scores of our sync peers
+type peerScores struct {
+	sync.RWMutex
+	scores map[peer.ID]*peerScore
+
+	// sum and count of the scores of the peers that answered at least one request, so the average
+	// doesn't need to be computed over all the peers every time a peer is scored
+	sum   float64
+	count int
+}
+
+func newPeerScores() *peerScores {
+	return &peerScores{
+		scores: make(map[peer.ID]*peerScore),
+	}
+}
+
+// update applies the change to the score of the peer, keeping the sum of the scores up to date
+func (ps *peerScores) update(p peer.ID, change func(s *peerScore)) {
+	s, has := ps.scores[p]
+	if !has {
+		s = new(peerScore)
+		ps.scores[p] = s
+	}
+
+	ps.removeFromSum(s)
+	change(s)
+	if s.responses > 0 {
+		ps.sum += s.value()
+		ps.count++
+	}
+}
+
+func (ps *peerScores) removeFromSum(s *peerScore) {
+	if s.responses == 0 {
+		return
+	}
+
+	ps.sum -= s.value()
+	ps.count--
+	if ps.count == 0 {
+		// don't carry rounding errors over
+		ps.sum = 0
+	}
+}
+
+// recordResponse records that the peer answered a request with the given number of blocks in the given time
+func (ps *peerScores) recordResponse(p peer.ID, latency time.Duration, blocks int) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	ps.update(p, func(s *peerScore) {
+		if s.responses == 0 {
+			s.latency = latency
+			s.blocks = float64(blocks)
+		} else {
+			s.latency = time.Duration((1-scoreSmoothing)*float64(s.latency) + scoreSmoothing*float64(latency))
+			s.blocks = (1-scoreSmoothing)*s.blocks + scoreSmoothing*float64(blocks)
+		}
+
+		s.invalid *= 1 - scoreSmoothing
+		s.timeouts *= 1 - scoreSmoothing
+		s.responses++
+	})
+}
+
+// recordInvalid records that the peer answered a request with an invalid response
+func (ps *peerScores) recordInvalid(p peer.ID) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	ps.update(p, func(s *peerScore) {
+		s.invalid++
+	})
+}
+
+// recordTimeout records that the peer didn't answer a request in time
+func (ps *peerScores) recordTimeout(p peer.ID) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	ps.update(p, func(s *peerScore) {
+		s.timeouts++
+	})
+}
+
+// remove forgets about the score of the peer, once it's disconnected
+func (ps *peerScores) remove(p peer.ID) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	s, has := ps.scores[p]
+	if !has {
+		return
+	}
+
+	ps.removeFromSum(s)
+	delete(ps.scores, p)
+}
+
+// score returns the score of the peer. Peers that haven't answered any request yet are given
+// the average score, so they are tried without being preferred to peers known to be fast.
+func (ps *peerScores) score(p peer.ID) float64 {
+	ps.RLock()
+	defer ps.RUnlock()
+	return ps.scoreLocked(p)
+}
+
+func (ps *peerScores) scoreLocked(p peer.ID) float64 {
+	s, has := ps.scores[p]
+	if !has || s.responses == 0 {
+		avg := ps.averageLocked()
+		if s != nil {
+			return avg / (1 + invalidResponseWeight*s.invalid + timeoutWeight*s.timeouts)
+		}
+		return avg
+	}
+
+	return s.value()
+}
+
+// average returns the average score of the peers that answered at least one request, or 1 if there are none
+func (ps *peerScores) average() float64 {
+	ps.RLock()
+	defer ps.RUnlock()
+	return ps.averageLocked()
+}
+
+func (ps *peerScores) averageLocked() float64 {
+	if ps.count == 0 {
+		return 1
+	}
+	return ps.sum / float64(ps.count)
+}
+
+// selectPeer returns the peer to send a request to. The preferred peer, usually the one that answered
+// the previous request of a worker, is kept if its score is close to the best one. Otherwise a peer is
+// picked at random with a probability proportional to its score, so requests are spread over our peers
+// while the fastest ones get most of them.
+func (ps *peerScores) selectPeer(peers []peer.ID, preferred peer.ID) peer.ID {
+	if len(peers) == 0 {
+		return ""
+	}
+
+	ps.RLock()
+	defer ps.RUnlock()
+
+	var (
+		scores       = make([]float64, len(peers))
+		best, sum    float64
+		hasPreferred bool
+	)
+	for i, p := range peers {
+		scores[i] = ps.scoreLocked(p)
+		sum += scores[i]
+		if scores[i] > best {
+			best = scores[i]
+		}
+		if p == preferred {
+			hasPreferred = true
+		}
+	}
+
+	if hasPreferred && ps.scoreLocked(preferred) >= stickyPeerRatio*best {
+		return preferred
+	}
+
+	if sum <= 0 {
+		return peers[0]
+	}
+
+	target := randomFloat() * sum
+	for i, p := range peers {
+		target -= scores[i]
+		if target < 0 {
+			return p
+		}
+	}
+
+	return peers[len(peers)-1]
+}
+
+// randomFloat returns a random number in [0, 1)
+func randomFloat() float64 {
+	const precision = 1 << 53
+	n, err := rand.Int(rand.Reader, big.NewInt(precision))
+	if err != nil {
+		return 0
+	}
+	return float64(n.Int64()) / precision
+}
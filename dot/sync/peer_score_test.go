@@ -0,0 +1,112 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerScores_RecordResponse(t *testing.T) {
+	ps := newPeerScores()
+	fast, slow := peer.ID("fast"), peer.ID("slow")
+
+	// no peer answered yet, everyone gets the default score
+	require.Equal(t, float64(1), ps.score(fast))
+
+	ps.recordResponse(fast, 100*time.Millisecond, 128)
+	ps.recordResponse(slow, time.Second, 128)
+	require.InDelta(t, 1280, ps.score(fast), 0.001)
+	require.InDelta(t, 128, ps.score(slow), 0.001)
+	require.InDelta(t, 704, ps.average(), 0.001)
+
+	// peers we haven't heard from get the average score
+	require.InDelta(t, 704, ps.score(peer.ID("new")), 0.001)
+
+	// the latency is a moving average
+	ps.recordResponse(slow, 100*time.Millisecond, 128)
+	require.Greater(t, ps.score(slow), float64(128))
+	require.Less(t, ps.score(slow), ps.score(fast))
+}
+
+func TestPeerScores_Failures(t *testing.T) {
+	ps := newPeerScores()
+	p := peer.ID("noot")
+
+	ps.recordResponse(p, time.Second, 100)
+	require.InDelta(t, 100, ps.score(p), 0.001)
+
+	ps.recordInvalid(p)
+	require.InDelta(t, 100.0/(1+invalidResponseWeight), ps.score(p), 0.001)
+
+	ps.recordTimeout(p)
+	require.InDelta(t, 100.0/(1+invalidResponseWeight+timeoutWeight), ps.score(p), 0.001)
+
+	// failures are forgotten progressively
+	before := ps.score(p)
+	ps.recordResponse(p, time.Second, 100)
+	require.Greater(t, ps.score(p), before)
+
+	// failures of peers we haven't heard from lower the average score they are given
+	ps.recordTimeout(peer.ID("new"))
+	require.Less(t, ps.score(peer.ID("new")), ps.average())
+}
+
+func TestPeerScores_SelectPeer(t *testing.T) {
+	ps := newPeerScores()
+	fast, alsoFast, slow := peer.ID("fast"), peer.ID("alsoFast"), peer.ID("slow")
+
+	require.Equal(t, peer.ID(""), ps.selectPeer(nil, ""))
+	require.Equal(t, slow, ps.selectPeer([]peer.ID{slow}, fast))
+
+	ps.recordResponse(fast, time.Millisecond, 128)
+	ps.recordResponse(alsoFast, time.Millisecond, 120)
+	ps.recordResponse(slow, time.Minute, 1)
+
+	peers := []peer.ID{fast, alsoFast, slow}
+
+	// the previous peer is kept while its score is close to the best one
+	for i := 0; i < 10; i++ {
+		require.Equal(t, alsoFast, ps.selectPeer(peers, alsoFast))
+	}
+
+	// otherwise, the slow peer is very unlikely to be picked
+	for i := 0; i < 10; i++ {
+		require.NotEqual(t, slow, ps.selectPeer(peers, slow))
+	}
+}
+
+func TestPeerScores_Remove(t *testing.T) {
+	ps := newPeerScores()
+	fast, slow := peer.ID("fast"), peer.ID("slow")
+
+	ps.recordResponse(fast, 100*time.Millisecond, 128)
+	ps.recordResponse(slow, time.Second, 128)
+	ps.recordInvalid(slow)
+	require.InDelta(t, (1280+128.0/(1+invalidResponseWeight))/2, ps.average(), 0.001)
+
+	// the average only accounts for the connected peers
+	ps.remove(slow)
+	require.InDelta(t, 1280, ps.average(), 0.001)
+	require.NotContains(t, ps.scores, slow)
+
+	ps.remove(fast)
+	ps.remove(peer.ID("unknown"))
+	require.Empty(t, ps.scores)
+	require.Equal(t, float64(1), ps.average())
+}
+
+func TestChainSync_RemovePeer(t *testing.T) {
+	cs, _ := newTestChainSync(t)
+	p := peer.ID("noot")
+
+	cs.peerScores.recordResponse(p, time.Second, 1)
+	cs.commonAncestors.set(p, &commonAncestor{})
+	cs.removePeer(p)
+	require.NotContains(t, cs.peerScores.scores, p)
+	require.Nil(t, cs.commonAncestors.get(p))
+}
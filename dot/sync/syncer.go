@@ -4,6 +4,7 @@
 package sync
 
 import (
+	"errors"
 	"math/big"
 	"time"
 
@@ -19,9 +20,13 @@ var logger = log.NewFromGlobal(log.AddContext("pkg", "sync"))
 // Service deals with chain syncing by sending block request messages and watching for responses.
 type Service struct {
 	blockState     BlockState
+	storageState   StorageState
 	chainSync      ChainSync
 	chainProcessor ChainProcessor
 	network        Network
+
+	// fastSync is nil unless syncing in fast mode
+	fastSync *fastSyncer
 }
 
 // Config is the configuration for the sync Service.
@@ -30,81 +35,167 @@ type Config struct {
 	Network            Network
 	BlockState         BlockState
 	StorageState       StorageState
+	GrandpaState       GrandpaState
 	FinalityGadget     FinalityGadget
 	TransactionState   TransactionState
 	BlockImportHandler BlockImportHandler
 	BabeVerifier       BabeVerifier
+	DigestHandler      DigestHandler
 	MinPeers, MaxPeers int
 	SlotDuration       time.Duration
+	Mode               Mode
 }
 
-// NewService returns a new *sync.Service
-func NewService(cfg *Config) (*Service, error) {
-	if cfg.Network == nil {
-		return nil, errNilNetwork
-	}
-
+// checkImport checks that the config has what is needed to import blocks
+func (cfg *Config) checkImport() error {
 	if cfg.BlockState == nil {
-		return nil, errNilBlockState
+		return errNilBlockState
 	}
 
 	if cfg.StorageState == nil {
-		return nil, errNilStorageState
+		return errNilStorageState
 	}
 
 	if cfg.FinalityGadget == nil {
-		return nil, errNilFinalityGadget
+		return errNilFinalityGadget
 	}
 
 	if cfg.TransactionState == nil {
-		return nil, errNilTransactionState
+		return errNilTransactionState
 	}
 
 	if cfg.BabeVerifier == nil {
-		return nil, errNilVerifier
+		return errNilVerifier
 	}
 
 	if cfg.BlockImportHandler == nil {
-		return nil, errNilBlockImportHandler
+		return errNilBlockImportHandler
+	}
+
+	return nil
+}
+
+// NewService returns a new *sync.Service
+func NewService(cfg *Config) (*Service, error) {
+	if cfg.Network == nil {
+		return nil, errNilNetwork
+	}
+
+	if cfg.GrandpaState == nil {
+		return nil, errNilGrandpaState
+	}
+
+	if err := cfg.checkImport(); err != nil {
+		return nil, err
+	}
+
+	// the blocks imported by an unfinished fast sync don't have a state, so it is resumed
+	// even if the node is now started in full mode
+	fastSyncStart, err := cfg.BlockState.FastSyncStart()
+	if err != nil {
+		return nil, err
+	}
+
+	if fastSyncStart != nil && cfg.Mode != FastMode {
+		logger.Infof("resuming unfinished fast sync from block number %s with hash %s",
+			fastSyncStart.Number, fastSyncStart.Hash())
+		cfg.Mode = FastMode
+	}
+
+	if cfg.Mode == FastMode && cfg.DigestHandler == nil {
+		return nil, errNilDigestHandler
 	}
 
 	logger.Patch(log.SetLevel(cfg.LogLvl))
 
 	readyBlocks := newBlockQueue(maxResponseSize * 30)
 	pendingBlocks := newDisjointBlockSet(pendingBlocksLimit)
+	missingJustifications := newMissingJustifications()
 
 	csCfg := &chainSyncConfig{
-		bs:            cfg.BlockState,
-		net:           cfg.Network,
-		readyBlocks:   readyBlocks,
-		pendingBlocks: pendingBlocks,
-		minPeers:      cfg.MinPeers,
-		maxPeers:      cfg.MaxPeers,
-		slotDuration:  cfg.SlotDuration,
+		bs:                    cfg.BlockState,
+		net:                   cfg.Network,
+		gs:                    cfg.GrandpaState,
+		finalityGadget:        cfg.FinalityGadget,
+		readyBlocks:           readyBlocks,
+		pendingBlocks:         pendingBlocks,
+		missingJustifications: missingJustifications,
+		minPeers:              cfg.MinPeers,
+		maxPeers:              cfg.MaxPeers,
+		slotDuration:          cfg.SlotDuration,
 	}
 
 	chainSync := newChainSync(csCfg)
 	chainProcessor := newChainProcessor(readyBlocks, pendingBlocks,
 		cfg.BlockState, cfg.StorageState, cfg.TransactionState,
-		cfg.BabeVerifier, cfg.FinalityGadget, cfg.BlockImportHandler)
+		cfg.BabeVerifier, cfg.FinalityGadget, cfg.BlockImportHandler, missingJustifications)
+
+	var fastSync *fastSyncer
+	if cfg.Mode == FastMode {
+		fastSync = newFastSyncer(cfg)
+	}
 
 	return &Service{
 		blockState:     cfg.BlockState,
+		storageState:   cfg.StorageState,
 		chainSync:      chainSync,
 		chainProcessor: chainProcessor,
 		network:        cfg.Network,
+		fastSync:       fastSync,
 	}, nil
 }
 
-// Start begins the chainSync and chainProcessor modules. It begins syncing in bootstrap mode
+// Start begins the chainSync and chainProcessor modules. It begins syncing in bootstrap mode.
+// In fast mode, they are started once fast sync is done.
 func (s *Service) Start() error {
+	if s.fastSync != nil {
+		go s.startAfterFastSync()
+		return nil
+	}
+
 	go s.chainSync.start()
 	go s.chainProcessor.start()
 	return nil
 }
 
+// startAfterFastSync fast syncs the chain, then hands the peer heads over to the chainSync module
+// and starts it, along with the chainProcessor module. Fast sync is retried with an increasing interval
+// if it fails, the blocks imported so far don't have a state so we can't switch to full sync instead.
+func (s *Service) startAfterFastSync() {
+	retryInterval := fastSyncRetryInterval
+	for {
+		err := s.fastSync.sync()
+		if err == nil {
+			break
+		}
+
+		if errors.Is(err, ErrServiceStopped) {
+			return
+		}
+
+		logger.Errorf("failed to fast sync, retrying in %s: %s", retryInterval, err)
+		if err = s.fastSync.waitFor(retryInterval); err != nil {
+			return
+		}
+		retryInterval = nextRetryInterval(retryInterval)
+	}
+
+	s.fastSync.finish(func(ps *peerState) {
+		if err := s.chainSync.setPeerHead(ps.who, ps.hash, ps.number); err != nil {
+			logger.Debugf("failed to set head of peer %s: %s", ps.who, err)
+		}
+	})
+
+	logger.Info("fast sync done, switching to full sync")
+	go s.chainSync.start()
+	go s.chainProcessor.start()
+}
+
 // Stop stops the chainSync and chainProcessor modules
 func (s *Service) Stop() error {
+	if s.fastSync != nil {
+		s.fastSync.stop()
+	}
 	s.chainSync.stop()
 	s.chainProcessor.stop()
 	return nil
@@ -113,7 +204,12 @@ func (s *Service) Stop() error {
 // HandleBlockAnnounceHandshake notifies the `chainSync` module that
 // we have received a BlockAnnounceHandshake from the given peer.
 func (s *Service) HandleBlockAnnounceHandshake(from peer.ID, msg *network.BlockAnnounceHandshake) error {
-	return s.chainSync.setPeerHead(from, msg.BestBlockHash, big.NewInt(int64(msg.BestBlockNumber)))
+	number := big.NewInt(int64(msg.BestBlockNumber))
+	if s.fastSync != nil && s.fastSync.setPeerHead(from, msg.BestBlockHash, number) {
+		return nil
+	}
+
+	return s.chainSync.setPeerHead(from, msg.BestBlockHash, number)
 }
 
 // HandleBlockAnnounce notifies the `chainSync` module that we have received a block announcement from the given peer.
@@ -126,9 +222,19 @@ func (s *Service) HandleBlockAnnounce(from peer.ID, msg *network.BlockAnnounceMe
 		return err
 	}
 
+	// while fast syncing, announced blocks are only used to know how far our peers are
+	if s.fastSync != nil && s.fastSync.setPeerHead(from, header.Hash(), header.Number) {
+		return nil
+	}
+
 	return s.chainSync.setBlockAnnounce(from, header)
 }
 
+// HandlePeerDisconnect notifies the `chainSync` module that all the connections with the given peer were closed.
+func (s *Service) HandlePeerDisconnect(who peer.ID) {
+	s.chainSync.removePeer(who)
+}
+
 // IsSynced exposes the synced state
 func (s *Service) IsSynced() bool {
 	return s.chainSync.syncState() == tip
@@ -62,6 +62,12 @@ func newMockNetwork() *mocks.Network {
 }
 
 func newTestSyncer(t *testing.T) *Service {
+	syncer, _ := newTestSyncerWithState(t)
+	return syncer
+}
+
+// newTestSyncerWithState returns a syncer along with the state service it uses
+func newTestSyncerWithState(t testing.TB) (*Service, *state.Service) {
 	wasmer.DefaultTestLogLvl = 3
 
 	cfg := &Config{}
@@ -117,14 +123,8 @@ func newTestSyncer(t *testing.T) *Service {
 	cfg.BlockImportHandler.(*mocks.BlockImportHandler).On(
 		"HandleBlockImport", mock.AnythingOfType("*types.Block"), mock.AnythingOfType("*storage.TrieState")).
 		Return(func(block *types.Block, ts *rtstorage.TrieState) error {
-			// store updates state trie nodes in database
-			if err = stateSrvc.Storage.StoreTrie(ts, &block.Header); err != nil {
-				logger.Warnf("failed to store state trie for imported block %s: %s", block.Header.Hash(), err)
-				return err
-			}
-
-			// store block in database
-			err = stateSrvc.Block.AddBlock(block)
+			// store the block along with its updated state trie nodes in database
+			err = stateSrvc.Storage.StoreBlock(ts, block)
 			require.NoError(t, err)
 
 			stateSrvc.Block.StoreRuntime(block.Header.Hash(), instance)
@@ -134,6 +134,7 @@ func newTestSyncer(t *testing.T) *Service {
 		})
 
 	cfg.TransactionState = stateSrvc.Transaction
+	cfg.GrandpaState = stateSrvc.Grandpa
 	cfg.BabeVerifier = newMockBabeVerifier()
 	cfg.LogLvl = log.Trace
 	cfg.FinalityGadget = newMockFinalityGadget()
@@ -141,10 +142,10 @@ func newTestSyncer(t *testing.T) *Service {
 
 	syncer, err := NewService(cfg)
 	require.NoError(t, err)
-	return syncer
+	return syncer, stateSrvc
 }
 
-func newTestGenesisWithTrieAndHeader(t *testing.T) (*genesis.Genesis, *trie.Trie, *types.Header) {
+func newTestGenesisWithTrieAndHeader(t testing.TB) (*genesis.Genesis, *trie.Trie, *types.Header) {
 	fp := "../../chain/gssmr/genesis.json"
 	gen, err := genesis.NewGenesisFromJSONRaw(fp)
 	require.NoError(t, err)
@@ -93,6 +93,7 @@ func (s *tipSyncer) handleWorkerResult(res *worker) (*worker, error) {
 		targetNumber: res.targetNumber,
 		direction:    res.direction,
 		requestData:  res.requestData,
+		findAncestor: res.findAncestor,
 	}, nil
 }
 
@@ -182,6 +183,7 @@ func (s *tipSyncer) handleTick() ([]*worker, error) {
 				direction:    network.Descending,
 				requestData:  bootstrapRequestData,
 				pendingBlock: block,
+				findAncestor: true,
 			})
 			continue
 		}
@@ -212,7 +214,7 @@ func (s *tipSyncer) handleTick() ([]*worker, error) {
 			continue
 		}
 
-		// request descending chain from (parent of pending block) -> (last finalised block)
+		// request descending chain from (parent of pending block) -> (common ancestor with the peer)
 		workers = append(workers, &worker{
 			startHash:    block.header.ParentHash,
 			startNumber:  big.NewInt(0).Sub(block.number, big.NewInt(1)),
@@ -220,6 +222,7 @@ func (s *tipSyncer) handleTick() ([]*worker, error) {
 			direction:    network.Descending,
 			requestData:  bootstrapRequestData,
 			pendingBlock: block,
+			findAncestor: true,
 		})
 	}
 
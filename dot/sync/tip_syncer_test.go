@@ -171,6 +171,7 @@ func TestTipSyncer_handleTick_case1(t *testing.T) {
 			direction:    network.Descending,
 			requestData:  bootstrapRequestData,
 			pendingBlock: s.pendingBlocks.getBlock(common.Hash{0xb}),
+			findAncestor: true,
 		},
 	}
 
@@ -253,6 +254,7 @@ func TestTipSyncer_handleTick_case3(t *testing.T) {
 			direction:    network.Descending,
 			requestData:  bootstrapRequestData,
 			pendingBlock: s.pendingBlocks.getBlock(header.Hash()),
+			findAncestor: true,
 		},
 	}
 
@@ -80,6 +80,10 @@ type worker struct {
 	// if this worker is tied to a specific pending block, this field is set
 	pendingBlock *pendingBlock
 
+	// if set, the worker requests a fork descending from the start block, and first searches for
+	// the highest common ancestor with the peer so that it only requests the blocks above it
+	findAncestor bool
+
 	// bitmap of fields to request
 	requestData byte
 	direction   network.SyncDirection
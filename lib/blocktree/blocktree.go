@@ -20,24 +20,27 @@ type Hash = common.Hash
 
 // BlockTree represents the current state with all possible blocks
 type BlockTree struct {
-	root   *node
-	leaves *leafMap
+	root       *node
+	leaves     *leafMap
+	forkChoice ForkChoice
 	sync.RWMutex
 	runtime *sync.Map // map[Hash]runtime.Instance
 }
 
-// NewEmptyBlockTree creates a BlockTree with a nil head
-func NewEmptyBlockTree() *BlockTree {
+// NewEmptyBlockTree creates a BlockTree with a nil head, which chooses its best block with the given fork choice
+func NewEmptyBlockTree(fc ForkChoice) *BlockTree {
 	return &BlockTree{
-		root:    nil,
-		leaves:  newEmptyLeafMap(),
-		runtime: &sync.Map{},
+		root:       nil,
+		leaves:     newEmptyLeafMap(),
+		forkChoice: fc,
+		runtime:    &sync.Map{},
 	}
 }
 
 // NewBlockTreeFromRoot initialises a blocktree with a root block. The root block is always the most recently
-// finalised block (ie the genesis block if the node is just starting.)
-func NewBlockTreeFromRoot(root *types.Header) *BlockTree {
+// finalised block (ie the genesis block if the node is just starting.) The best block is chosen with the given
+// fork choice.
+func NewBlockTreeFromRoot(root *types.Header, fc ForkChoice) *BlockTree {
 	n := &node{
 		hash:        root.Hash(),
 		parent:      nil,
@@ -47,9 +50,10 @@ func NewBlockTreeFromRoot(root *types.Header) *BlockTree {
 	}
 
 	return &BlockTree{
-		root:    n,
-		leaves:  newLeafMap(n),
-		runtime: &sync.Map{},
+		root:       n,
+		leaves:     newLeafMap(n),
+		forkChoice: fc,
+		runtime:    &sync.Map{},
 	}
 }
 
@@ -59,34 +63,103 @@ func (bt *BlockTree) AddBlock(header *types.Header, arrivalTime time.Time) error
 	bt.Lock()
 	defer bt.Unlock()
 
+	n, err := bt.newNode(header, arrivalTime)
+	if err != nil {
+		return err
+	}
+
+	n.parent.addChild(n)
+	bt.leaves.replace(n.parent, n)
+	return nil
+}
+
+// LeavesWith returns the leaves and the best block the blocktree would have once the block with the given
+// header is added, without adding it. It's used to write them along with the block, before the block is
+// added to the blocktree.
+func (bt *BlockTree) LeavesWith(header *types.Header, arrivalTime time.Time) (leaves []Hash, best Hash, err error) {
+	bt.RLock()
+	defer bt.RUnlock()
+
+	n, err := bt.newNode(header, arrivalTime)
+	if err != nil {
+		return nil, Hash{}, err
+	}
+
+	bestNode := n
+	leaves = []Hash{n.hash}
+	for _, leaf := range bt.leaves.nodes() {
+		if leaf == n.parent {
+			continue
+		}
+
+		leaves = append(leaves, leaf.hash)
+		if leaf.isBetterThan(bestNode) {
+			bestNode = leaf
+		}
+	}
+
+	// the current best leaf is kept unless the best leaf is strictly better, as in leafMap.bestLeaf
+	if current := bt.leaves.bestLeaf(); current != nil && current != n.parent && !bestNode.isBetterThan(current) {
+		bestNode = current
+	}
+
+	return leaves, bestNode.hash, nil
+}
+
+// newNode returns the node of the block with the given header, without adding it to the children of its parent
+func (bt *BlockTree) newNode(header *types.Header, arrivalTime time.Time) (*node, error) {
 	parent := bt.getNode(header.ParentHash)
 	if parent == nil {
-		return ErrParentNotFound
+		return nil, ErrParentNotFound
 	}
 
 	// Check if it already exists
 	if n := bt.getNode(header.Hash()); n != nil {
-		return ErrBlockExists
+		return nil, ErrBlockExists
 	}
 
 	number := big.NewInt(0)
 	number.Add(parent.number, big.NewInt(1))
 
 	if number.Cmp(header.Number) != 0 {
-		return errUnexpectedNumber
+		return nil, errUnexpectedNumber
 	}
 
-	n := &node{
+	return &node{
 		hash:        header.Hash(),
 		parent:      parent,
 		children:    []*node{},
 		number:      number,
 		arrivalTime: arrivalTime,
+		weight:      parent.weight + bt.forkChoice.Weight(header),
+	}, nil
+}
+
+// RemoveSubtree removes the block with the given hash from the blocktree along with its descendants, its parent
+// becoming a leaf again if it has no other children. It returns the hashes of the removed blocks. It's used to
+// forget a bad block that was already imported, so the best block can't be on its fork.
+func (bt *BlockTree) RemoveSubtree(hash Hash) ([]Hash, error) {
+	bt.Lock()
+	defer bt.Unlock()
+
+	n := bt.getNode(hash)
+	if n == nil {
+		return nil, ErrNodeNotFound
 	}
 
-	parent.addChild(n)
-	bt.leaves.replace(parent, n)
-	return nil
+	if n.parent == nil {
+		return nil, errNotRemovableRoot
+	}
+
+	n.parent.deleteChild(n)
+	bt.leaves.removeSubtree(n)
+
+	removed := n.getAllDescendants(nil)
+	for _, h := range removed {
+		bt.runtime.Delete(h)
+	}
+
+	return removed, nil
 }
 
 // GetAllBlocksAtNumber will return all blocks hashes with the number of the given hash plus one.
@@ -188,9 +261,9 @@ func (bt *BlockTree) String() string {
 	return fmt.Sprintf("%s\n%s\n", metadata, tree.Print())
 }
 
-// longestPath returns the path from the root to the deepest leaf in the blocktree
-func (bt *BlockTree) longestPath() []*node {
-	dl := bt.deepestLeaf()
+// bestPath returns the path from the root to the best leaf in the blocktree
+func (bt *BlockTree) bestPath() []*node {
+	dl := bt.bestLeaf()
 	var path []*node
 	for curr := dl; ; curr = curr.parent {
 		path = append([]*node{curr}, path...)
@@ -230,14 +303,15 @@ func (bt *BlockTree) SubBlockchain(start, end Hash) ([]Hash, error) {
 
 }
 
-// deepestLeaf returns the deepest leaf in the block tree.
-func (bt *BlockTree) deepestLeaf() *node {
-	return bt.leaves.deepestLeaf()
+// bestLeaf returns the best leaf in the block tree according to its fork choice.
+func (bt *BlockTree) bestLeaf() *node {
+	return bt.leaves.bestLeaf()
 }
 
-// DeepestBlockHash returns the hash of the deepest block in the blocktree
-// If there is multiple deepest blocks, it returns the one with the earliest arrival time.
-func (bt *BlockTree) DeepestBlockHash() Hash {
+// BestBlockHash returns the hash of the best block in the blocktree, which is the leaf whose chain has the
+// greatest weight according to the fork choice. If there are multiple such leaves, it returns the one with the
+// greatest number, then the one with the earliest arrival time.
+func (bt *BlockTree) BestBlockHash() Hash {
 	bt.RLock()
 	defer bt.RUnlock()
 
@@ -245,12 +319,12 @@ func (bt *BlockTree) DeepestBlockHash() Hash {
 		return Hash{}
 	}
 
-	deepest := bt.leaves.deepestLeaf()
-	if deepest == nil {
+	best := bt.leaves.bestLeaf()
+	if best == nil {
 		return Hash{}
 	}
 
-	return deepest.hash
+	return best.hash
 }
 
 // IsDescendantOf returns true if the child is a descendant of parent, false otherwise.
@@ -326,13 +400,13 @@ func (bt *BlockTree) GetHashByNumber(num *big.Int) (common.Hash, error) {
 	bt.RLock()
 	defer bt.RUnlock()
 
-	deepest := bt.leaves.deepestLeaf()
-	if deepest.number.Cmp(num) == -1 {
+	best := bt.leaves.bestLeaf()
+	if best.number.Cmp(num) == -1 {
 		return common.Hash{}, ErrNumGreaterThanHighest
 	}
 
-	if deepest.number.Cmp(num) == 0 {
-		return deepest.hash, nil
+	if best.number.Cmp(num) == 0 {
+		return best.hash, nil
 	}
 
 	if bt.root.number.Cmp(num) == 1 {
@@ -343,7 +417,7 @@ func (bt *BlockTree) GetHashByNumber(num *big.Int) (common.Hash, error) {
 		return bt.root.hash, nil
 	}
 
-	curr := deepest.parent
+	curr := best.parent
 	for {
 		if curr == nil {
 			return common.Hash{}, ErrNodeNotFound
@@ -375,7 +449,9 @@ func (bt *BlockTree) DeepCopy() *BlockTree {
 	bt.RLock()
 	defer bt.RUnlock()
 
-	btCopy := &BlockTree{}
+	btCopy := &BlockTree{
+		forkChoice: bt.forkChoice,
+	}
 
 	if bt.root == nil {
 		return btCopy
@@ -32,13 +32,14 @@ type testBranch struct {
 
 func newBlockTreeFromNode(root *node) *BlockTree {
 	return &BlockTree{
-		root:   root,
-		leaves: newLeafMap(root),
+		root:       root,
+		leaves:     newLeafMap(root),
+		forkChoice: LongestChain{},
 	}
 }
 
 func createTestBlockTree(t *testing.T, header *types.Header, number int) (*BlockTree, []testBranch) {
-	bt := NewBlockTreeFromRoot(header)
+	bt := NewBlockTreeFromRoot(header, LongestChain{})
 	previousHash := header.Hash()
 
 	// branch tree randomly
@@ -100,7 +101,7 @@ func createTestBlockTree(t *testing.T, header *types.Header, number int) (*Block
 }
 
 func createFlatTree(t *testing.T, number int) (*BlockTree, []common.Hash) {
-	bt := NewBlockTreeFromRoot(testHeader)
+	bt := NewBlockTreeFromRoot(testHeader, LongestChain{})
 	require.NotNil(t, bt)
 
 	previousHash := bt.root.hash
@@ -181,6 +182,83 @@ func TestBlockTree_AddBlock(t *testing.T) {
 	}
 }
 
+func TestBlockTree_LeavesWith(t *testing.T) {
+	bt, hashes := createFlatTree(t, 2)
+
+	// a fork at block 2 doesn't change the best block, which arrived earlier
+	fork := &types.Header{
+		ParentHash: hashes[1],
+		Number:     big.NewInt(2),
+		StateRoot:  common.Hash{1},
+	}
+	leaves, best, err := bt.LeavesWith(fork, time.Unix(0, 1))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Hash{hashes[2], fork.Hash()}, leaves)
+	require.Equal(t, hashes[2], best)
+
+	// the blocktree is unchanged
+	require.Nil(t, bt.getNode(fork.Hash()))
+	require.Equal(t, []Hash{hashes[2]}, bt.Leaves())
+
+	// a child of the best block replaces it
+	child := &types.Header{
+		ParentHash: hashes[2],
+		Number:     big.NewInt(3),
+	}
+	leaves, best, err = bt.LeavesWith(child, time.Unix(0, 1))
+	require.NoError(t, err)
+	require.Equal(t, []Hash{child.Hash()}, leaves)
+	require.Equal(t, child.Hash(), best)
+
+	err = bt.AddBlock(child, time.Unix(0, 1))
+	require.NoError(t, err)
+	require.Equal(t, leaves, bt.Leaves())
+	require.Equal(t, best, bt.BestBlockHash())
+
+	_, _, err = bt.LeavesWith(child, time.Unix(0, 1))
+	require.ErrorIs(t, err, ErrBlockExists)
+
+	_, _, err = bt.LeavesWith(&types.Header{ParentHash: common.Hash{0xff}}, time.Unix(0, 1))
+	require.ErrorIs(t, err, ErrParentNotFound)
+}
+
+func TestBlockTree_RemoveSubtree(t *testing.T) {
+	bt, hashes := createFlatTree(t, 4)
+
+	// a fork at block 2, the best block being on the main chain
+	fork := &types.Header{
+		ParentHash: hashes[1],
+		Number:     big.NewInt(2),
+		StateRoot:  common.Hash{1},
+	}
+	err := bt.AddBlock(fork, time.Unix(0, 1))
+	require.NoError(t, err)
+	require.Equal(t, hashes[4], bt.BestBlockHash())
+
+	// removing the main chain from block 2 leaves the fork as the best block
+	removed, err := bt.RemoveSubtree(hashes[2])
+	require.NoError(t, err)
+	require.ElementsMatch(t, hashes[2:], removed)
+	for _, hash := range hashes[2:] {
+		require.Nil(t, bt.getNode(hash))
+	}
+	require.Equal(t, []Hash{fork.Hash()}, bt.Leaves())
+	require.Equal(t, fork.Hash(), bt.BestBlockHash())
+
+	// the parent of the removed subtree becomes a leaf again
+	removed, err = bt.RemoveSubtree(fork.Hash())
+	require.NoError(t, err)
+	require.Equal(t, []Hash{fork.Hash()}, removed)
+	require.Equal(t, []Hash{hashes[1]}, bt.Leaves())
+	require.Equal(t, hashes[1], bt.BestBlockHash())
+
+	_, err = bt.RemoveSubtree(hashes[0])
+	require.ErrorIs(t, err, errNotRemovableRoot)
+
+	_, err = bt.RemoveSubtree(common.Hash{0xff})
+	require.ErrorIs(t, err, ErrNodeNotFound)
+}
+
 func TestNode_isDecendantOf(t *testing.T) {
 	// Create tree with number 4 (with 4 nodes)
 	bt, hashes := createFlatTree(t, 4)
@@ -210,9 +288,9 @@ func TestBlockTree_LongestPath(t *testing.T) {
 	err := bt.AddBlock(header, time.Unix(0, 0))
 	require.NotNil(t, err)
 
-	longestPath := bt.longestPath()
+	bestPath := bt.bestPath()
 
-	for i, n := range longestPath {
+	for i, n := range bestPath {
 		if n.hash != hashes[i] {
 			t.Errorf("expected Hash: 0x%X got: 0x%X\n", hashes[i], n.hash)
 		}
@@ -265,7 +343,7 @@ func TestBlockTree_DeepestLeaf(t *testing.T) {
 		t.Logf("leaf=%s number=%d arrivalTime=%s", leaf, node.number, node.arrivalTime)
 	}
 
-	deepestLeaf := bt.deepestLeaf()
+	deepestLeaf := bt.bestLeaf()
 	if deepestLeaf.hash != expected {
 		t.Fatalf("Fail: got %s expected %s", deepestLeaf.hash, expected)
 	}
@@ -458,7 +536,7 @@ func TestBlockTree_Prune(t *testing.T) {
 
 func TestBlockTree_GetHashByNumber(t *testing.T) {
 	bt, _ := createTestBlockTree(t, testHeader, 8)
-	best := bt.DeepestBlockHash()
+	best := bt.BestBlockHash()
 	bn := bt.getNode(best)
 
 	for i := int64(0); i < bn.number.Int64(); i++ {
@@ -477,8 +555,8 @@ func TestBlockTree_GetHashByNumber(t *testing.T) {
 	require.Error(t, err)
 }
 
-func TestBlockTree_AllLeavesHasSameNumberAndArrivalTime_DeepestBlockHash_ShouldHasConsistentOutput(t *testing.T) {
-	bt := NewBlockTreeFromRoot(testHeader)
+func TestBlockTree_AllLeavesHasSameNumberAndArrivalTime_BestBlockHash_ShouldHasConsistentOutput(t *testing.T) {
+	bt := NewBlockTreeFromRoot(testHeader, LongestChain{})
 	previousHash := testHeader.Hash()
 
 	branches := []testBranch{}
@@ -558,14 +636,14 @@ func TestBlockTree_AllLeavesHasSameNumberAndArrivalTime_DeepestBlockHash_ShouldH
 
 	require.Len(t, leaves, deep)
 
-	// expects currentDeepestLeaf nil till call deepestLeaf() function
-	require.Nil(t, bt.leaves.currentDeepestLeaf)
-	deepestLeaf := bt.deepestLeaf()
+	// expects currentBestLeaf nil till call bestLeaf() function
+	require.Nil(t, bt.leaves.currentBestLeaf)
+	deepestLeaf := bt.bestLeaf()
 
-	require.Equal(t, deepestLeaf, bt.leaves.currentDeepestLeaf)
+	require.Equal(t, deepestLeaf, bt.leaves.currentBestLeaf)
 	require.Contains(t, leaves, deepestLeaf)
 
-	// adding a new node with a greater number, should update the currentDeepestLeaf
+	// adding a new node with a greater number, should update the currentBestLeaf
 	header := &types.Header{
 		ParentHash: previousHash,
 		Number:     big.NewInt(int64(deepestLeaf.number.Uint64() + 1)),
@@ -577,9 +655,9 @@ func TestBlockTree_AllLeavesHasSameNumberAndArrivalTime_DeepestBlockHash_ShouldH
 	err := bt.AddBlock(header, time.Unix(0, fixedArrivalTime))
 	require.NoError(t, err)
 
-	deepestLeaf = bt.deepestLeaf()
+	deepestLeaf = bt.bestLeaf()
 	require.Equal(t, hash, deepestLeaf.hash)
-	require.Equal(t, hash, bt.leaves.currentDeepestLeaf.hash)
+	require.Equal(t, hash, bt.leaves.currentBestLeaf.hash)
 }
 
 func TestBlockTree_DeepCopy(t *testing.T) {
@@ -603,6 +681,9 @@ func equalNodeValue(nd *node, ndCopy *node) bool {
 	if nd.arrivalTime != ndCopy.arrivalTime {
 		return false
 	}
+	if nd.weight != ndCopy.weight {
+		return false
+	}
 	for i, child := range nd.children {
 		return equalNodeValue(child, ndCopy.children[i])
 	}
@@ -46,4 +46,5 @@ var (
 	ErrNoCommonAncestor = errors.New("no common ancestor between two nodes")
 
 	errUnexpectedNumber = errors.New("block number is not parent number + 1")
+	errNotRemovableRoot = errors.New("cannot remove the root of the blocktree")
 )
@@ -0,0 +1,48 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package blocktree
+
+import (
+	"github.com/ChainSafe/gossamer/dot/types"
+)
+
+// ForkChoice weighs the blocks added to the blocktree. The best block of the blocktree is the leaf whose chain
+// has the greatest weight, then the greatest number, then the earliest arrival time. Since the root of the
+// blocktree is the last finalised block, the best block always descends from it, whatever the fork choice.
+type ForkChoice interface {
+	// Weight returns the weight that the block with the given header adds to the weight of its chain
+	Weight(header *types.Header) uint64
+}
+
+// LongestChain is the fork choice that chooses the chain with the most blocks
+type LongestChain struct{}
+
+// Weight returns 1 for every block, so the weight of a chain is its number of blocks
+func (LongestChain) Weight(*types.Header) uint64 {
+	return 1
+}
+
+// BABEWeight is the fork choice of BABE, which chooses the chain with the most blocks authored in primary
+// slots. Among chains of the same weight, the one with the most blocks is chosen.
+type BABEWeight struct{}
+
+// Weight returns 1 if the block was authored in a primary slot, according to the type of its BABE
+// pre-runtime digest, and 0 otherwise
+func (BABEWeight) Weight(header *types.Header) uint64 {
+	primary := byte(types.BabePrimaryPreDigest{}.Index())
+	for _, item := range header.Digest.Types {
+		digest, ok := item.Value().(types.PreRuntimeDigest)
+		if !ok || digest.ConsensusEngineID != types.BabeEngineID {
+			continue
+		}
+
+		if len(digest.Data) > 0 && digest.Data[0] == primary {
+			return 1
+		}
+
+		return 0
+	}
+
+	return 0
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package blocktree
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newBABEHeader(t *testing.T, parent common.Hash, number int64, primary bool) *types.Header {
+	var (
+		prd *types.PreRuntimeDigest
+		err error
+	)
+
+	if primary {
+		prd, err = types.NewBabePrimaryPreDigest(0, uint64(number),
+			[sr25519.VRFOutputLength]byte{}, [sr25519.VRFProofLength]byte{}).ToPreRuntimeDigest()
+	} else {
+		prd, err = types.NewBabeSecondaryPlainPreDigest(0, uint64(number)).ToPreRuntimeDigest()
+	}
+	require.NoError(t, err)
+
+	digest := types.NewDigest()
+	err = digest.Add(*prd)
+	require.NoError(t, err)
+
+	return &types.Header{
+		ParentHash: parent,
+		Number:     big.NewInt(number),
+		Digest:     digest,
+	}
+}
+
+func TestBABEWeight(t *testing.T) {
+	fc := BABEWeight{}
+
+	require.Equal(t, uint64(1), fc.Weight(newBABEHeader(t, zeroHash, 1, true)))
+	require.Equal(t, uint64(0), fc.Weight(newBABEHeader(t, zeroHash, 1, false)))
+	require.Equal(t, uint64(0), fc.Weight(testHeader))
+
+	digest := types.NewDigest()
+	err := digest.Add(*types.NewBABEPreRuntimeDigest([]byte{1}))
+	require.NoError(t, err)
+	header := &types.Header{
+		Number: big.NewInt(1),
+		Digest: digest,
+	}
+	require.Equal(t, uint64(1), fc.Weight(header))
+	require.Equal(t, uint64(1), LongestChain{}.Weight(header))
+}
+
+// addForks adds two forks to the given blocktree, a chain of a primary block followed by a secondary block and
+// a longer chain of secondary blocks which arrived earlier, and returns both chains
+func addForks(t *testing.T, bt *BlockTree) (primaryChain, secondaryChain []common.Hash) {
+	previous := testHeader.Hash()
+	for i, primary := range []bool{true, false} {
+		header := newBABEHeader(t, previous, int64(i+1), primary)
+		err := bt.AddBlock(header, time.Unix(int64(i+1), 0))
+		require.NoError(t, err)
+		previous = header.Hash()
+		primaryChain = append(primaryChain, previous)
+	}
+
+	previous = testHeader.Hash()
+	for i := 0; i < 3; i++ {
+		header := newBABEHeader(t, previous, int64(i+1), false)
+		header.StateRoot = common.Hash{0x1}
+		err := bt.AddBlock(header, time.Unix(int64(i), 0))
+		require.NoError(t, err)
+		previous = header.Hash()
+		secondaryChain = append(secondaryChain, previous)
+	}
+
+	return primaryChain, secondaryChain
+}
+
+func TestBlockTree_BestBlockHash_BABEWeight(t *testing.T) {
+	bt := NewBlockTreeFromRoot(testHeader, BABEWeight{})
+	primaryChain, secondaryChain := addForks(t, bt)
+
+	// the shorter chain has more blocks authored in primary slots
+	require.Equal(t, primaryChain[1], bt.BestBlockHash())
+
+	hash, err := bt.GetHashByNumber(big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, primaryChain[0], hash)
+
+	// the longer chain is chosen once it has as many blocks authored in primary slots
+	header := newBABEHeader(t, secondaryChain[2], 4, true)
+	err = bt.AddBlock(header, time.Unix(4, 0))
+	require.NoError(t, err)
+	require.Equal(t, header.Hash(), bt.BestBlockHash())
+}
+
+func TestBlockTree_BestBlockHash_LongestChain(t *testing.T) {
+	bt := NewBlockTreeFromRoot(testHeader, LongestChain{})
+	_, secondaryChain := addForks(t, bt)
+
+	require.Equal(t, secondaryChain[2], bt.BestBlockHash())
+}
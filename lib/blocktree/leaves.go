@@ -5,7 +5,6 @@ package blocktree
 
 import (
 	"errors"
-	"math/big"
 	"sync"
 
 	"github.com/ChainSafe/gossamer/lib/common"
@@ -13,7 +12,7 @@ import (
 
 // leafMap provides quick lookup for existing leaves
 type leafMap struct {
-	currentDeepestLeaf *node
+	currentBestLeaf *node
 	sync.RWMutex
 	smap *sync.Map // map[common.Hash]*node
 }
@@ -56,51 +55,60 @@ func (lm *leafMap) replace(oldNode, newNode *node) {
 	lm.store(newNode.hash, newNode)
 }
 
-// DeepestLeaf searches the stored leaves to the find the one with the greatest number.
-// If there are two leaves with the same number, choose the one with the earliest arrival time.
-func (lm *leafMap) deepestLeaf() *node {
+// removeSubtree deletes the leaves descending from the given node, which was deleted from the children of its
+// parent, and inserts its parent if it has no other children
+func (lm *leafMap) removeSubtree(n *node) {
+	lm.Lock()
+	defer lm.Unlock()
+
+	for _, leaf := range n.getLeaves(nil) {
+		lm.smap.Delete(leaf.hash)
+		if lm.currentBestLeaf == leaf {
+			lm.currentBestLeaf = nil
+		}
+	}
+
+	if len(n.parent.children) == 0 {
+		lm.store(n.parent.hash, n.parent)
+	}
+}
+
+// bestLeaf returns the best of the stored leaves according to node.isBetterThan. While it is still a leaf,
+// the current best leaf is kept unless another leaf is strictly better, so that the result is consistent
+// between leaves that are equally good.
+func (lm *leafMap) bestLeaf() *node {
 	lm.RLock()
 	defer lm.RUnlock()
 
-	max := big.NewInt(-1)
-
-	var deepest *node
+	var best *node
 	lm.smap.Range(func(h, n interface{}) bool {
 		if n == nil {
 			return true
 		}
 
 		node := n.(*node)
-
-		if max.Cmp(node.number) < 0 {
-			max = node.number
-			deepest = node
-		} else if max.Cmp(node.number) == 0 && node.arrivalTime.Before(deepest.arrivalTime) {
-			deepest = node
+		if best == nil || node.isBetterThan(best) {
+			best = node
 		}
 
 		return true
 	})
 
-	if lm.currentDeepestLeaf != nil {
-		if lm.currentDeepestLeaf.hash == deepest.hash {
-			return lm.currentDeepestLeaf
-		}
+	if best == nil {
+		return nil
+	}
 
-		// update the current deepest leaf if the found deepest has a greater number or
-		// if the current and the found deepest has the same number however the current
-		// arrived later then the found deepest
-		if deepest.number.Cmp(lm.currentDeepestLeaf.number) == 1 {
-			lm.currentDeepestLeaf = deepest
-		} else if deepest.number.Cmp(lm.currentDeepestLeaf.number) == 0 &&
-			deepest.arrivalTime.Before(lm.currentDeepestLeaf.arrivalTime) {
-			lm.currentDeepestLeaf = deepest
-		}
-	} else {
-		lm.currentDeepestLeaf = deepest
+	if lm.currentBestLeaf == nil || !lm.isLeaf(lm.currentBestLeaf) || best.isBetterThan(lm.currentBestLeaf) {
+		lm.currentBestLeaf = best
 	}
 
-	return lm.currentDeepestLeaf
+	return lm.currentBestLeaf
+}
+
+// isLeaf returns true if the given node is one of the stored leaves
+func (lm *leafMap) isLeaf(n *node) bool {
+	stored, has := lm.smap.Load(n.hash)
+	return has && stored == n
 }
 
 func (lm *leafMap) toMap() map[common.Hash]*node {
@@ -19,6 +19,7 @@ type node struct {
 	children    []*node     // Nodes of children blocks
 	number      *big.Int    // block number
 	arrivalTime time.Time   // Arrival time of the block
+	weight      uint64      // Weight of the chain from the root of the blocktree, given by the fork choice
 }
 
 // addChild appends Node to n's list of children
@@ -31,6 +32,21 @@ func (n *node) string() string {
 	return fmt.Sprintf("{hash: %s, number: %s, arrivalTime: %s}", n.hash.String(), n.number, n.arrivalTime)
 }
 
+// isBetterThan returns true if n is a better best block than other, that is if the weight of its chain is
+// greater, or if the weights are equal and n has a greater number, or if the numbers are also equal and n
+// arrived earlier
+func (n *node) isBetterThan(other *node) bool {
+	if n.weight != other.weight {
+		return n.weight > other.weight
+	}
+
+	if c := n.number.Cmp(other.number); c != 0 {
+		return c > 0
+	}
+
+	return n.arrivalTime.Before(other.arrivalTime)
+}
+
 // createTree adds all the nodes children to the existing printable tree.
 // Note: this is strictly for BlockTree.String()
 func (n *node) createTree(tree gotree.Tree) {
@@ -180,6 +196,7 @@ func (n *node) deepCopy(parent *node) *node {
 	nCopy := new(node)
 	nCopy.hash = n.hash
 	nCopy.arrivalTime = n.arrivalTime
+	nCopy.weight = n.weight
 
 	if n.number != nil {
 		nCopy.number = new(big.Int).Set(n.number)
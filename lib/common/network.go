@@ -3,7 +3,11 @@
 
 package common
 
-import ma "github.com/multiformats/go-multiaddr"
+import (
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
 
 // Health is network information about host needed for the rpc server
 type Health struct {
@@ -25,3 +29,25 @@ type PeerInfo struct {
 	BestHash   Hash
 	BestNumber uint64
 }
+
+// BannedPeer is network information about banned peers needed for the rpc server
+type BannedPeer struct {
+	PeerID  string
+	Reason  string
+	Expires time.Time
+}
+
+// Bandwidth is network information about the traffic of the host, in total and per protocol,
+// needed for the rpc server
+type Bandwidth struct {
+	Total     BandwidthStats
+	Protocols map[string]BandwidthStats
+}
+
+// BandwidthStats is the number of bytes sent and received, and the current rates in bytes per second
+type BandwidthStats struct {
+	TotalIn  int64
+	TotalOut int64
+	RateIn   float64
+	RateOut  float64
+}
@@ -4,6 +4,8 @@
 package genesis
 
 import (
+	"fmt"
+
 	"github.com/ChainSafe/gossamer/lib/common"
 )
 
@@ -15,6 +17,7 @@ type Genesis struct {
 	Bootnodes          []string               `json:"bootNodes"`
 	TelemetryEndpoints []interface{}          `json:"telemetryEndpoints"`
 	ProtocolID         string                 `json:"protocolId"`
+	ForkID             string                 `json:"forkId,omitempty"`
 	Genesis            Fields                 `json:"genesis"`
 	Properties         map[string]interface{} `json:"properties"`
 	ForkBlocks         []string               `json:"forkBlocks"`
@@ -31,6 +34,7 @@ type Data struct {
 	Bootnodes          [][]byte
 	TelemetryEndpoints []*TelemetryEndpoint
 	ProtocolID         string
+	ForkID             string `json:",omitempty"`
 	Properties         map[string]interface{}
 	ForkBlocks         []string
 	BadBlocks          []string
@@ -59,6 +63,7 @@ func (g *Genesis) GenesisData() *Data {
 		Bootnodes:          common.StringArrayToBytes(g.Bootnodes),
 		TelemetryEndpoints: interfaceToTelemetryEndpoint(g.TelemetryEndpoints),
 		ProtocolID:         g.ProtocolID,
+		ForkID:             g.ForkID,
 		Properties:         g.Properties,
 		ForkBlocks:         g.ForkBlocks,
 		BadBlocks:          g.BadBlocks,
@@ -67,6 +72,22 @@ func (g *Genesis) GenesisData() *Data {
 	}
 }
 
+// BadBlockHashes returns the hashes of the bad blocks listed in the genesis, which must be 0x prefixed
+// hex encoded block hashes
+func (d *Data) BadBlockHashes() ([]common.Hash, error) {
+	hashes := make([]common.Hash, len(d.BadBlocks))
+	for i, badBlock := range d.BadBlocks {
+		hash, err := common.HexToBytes(badBlock)
+		if err != nil || len(hash) != len(common.Hash{}) {
+			return nil, fmt.Errorf("invalid bad block hash %q", badBlock)
+		}
+
+		hashes[i] = common.NewHash(hash)
+	}
+
+	return hashes, nil
+}
+
 // GenesisFields returns the genesis fields including genesis raw data
 func (g *Genesis) GenesisFields() Fields {
 	return g.Genesis
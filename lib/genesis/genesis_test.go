@@ -6,6 +6,7 @@ package genesis
 import (
 	"testing"
 
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/stretchr/testify/require"
 )
 
@@ -50,3 +51,20 @@ func TestInterfaceToTelemetryEndpoint(t *testing.T) {
 		require.Equal(t, test.expected, res)
 	}
 }
+
+func TestData_BadBlockHashes(t *testing.T) {
+	hash := "0x5f6e3e3ffcf76e0d4fca3c2ff5d1fe86d1ea16f4ccfc2bdabbcd1cbbd41bbc8a"
+	data := &Data{
+		BadBlocks: []string{hash},
+	}
+
+	hashes, err := data.BadBlockHashes()
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{common.MustHexToHash(hash)}, hashes)
+
+	for _, badBlock := range []string{"badBlock1", "0x1234", hash[2:]} {
+		data.BadBlocks = []string{badBlock}
+		_, err = data.BadBlockHashes()
+		require.Error(t, err)
+	}
+}
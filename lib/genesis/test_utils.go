@@ -41,6 +41,7 @@ var TestGenesis = &Genesis{
 	Bootnodes:          testBootnodes,
 	TelemetryEndpoints: append(testEndpoints, testEndpoint1),
 	ProtocolID:         testProtocolID,
+	ForkID:             "test-fork",
 	Properties:         testProperties,
 	ForkBlocks:         testForkBlocks,
 	BadBlocks:          testBadBlocks,
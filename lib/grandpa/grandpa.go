@@ -48,6 +48,7 @@ type Service struct {
 	mapLock        sync.Mutex
 	chanLock       sync.Mutex
 	roundLock      sync.Mutex
+	localViewLock  sync.Mutex
 	authority      bool          // run the service as an authority (ie participate in voting)
 	paused         atomic.Value  // the service will be paused if it is waiting for catch up responses
 	resumed        chan struct{} // this channel will be closed when the service resumes
@@ -64,6 +65,8 @@ type Service struct {
 	pvEquivocations map[ed25519.PublicKeyBytes][]*SignedVote // equivocatory votes for current pre-vote stage
 	pcEquivocations map[ed25519.PublicKeyBytes][]*SignedVote // equivocatory votes for current pre-commit stage
 	tracker         *tracker                                 // tracker of vote messages we may need in the future
+	peerViews       *peerViews                               // views of our peers, from their neighbour messages
+	local           *view                                    // our own view, cached as it's checked for every message
 	head            *types.Header                            // most recently finalised block
 
 	// historical information
@@ -166,6 +169,7 @@ func NewService(cfg *Config) (*Service, error) {
 		network:            cfg.Network,
 		finalisedCh:        finalisedCh,
 		interval:           cfg.Interval,
+		peerViews:          newPeerViews(),
 	}
 
 	if err := s.registerProtocol(); err != nil {
@@ -349,6 +353,7 @@ func (s *Service) initiateRound() error {
 	s.pvEquivocations = make(map[ed25519.PublicKeyBytes][]*SignedVote)
 	s.pcEquivocations = make(map[ed25519.PublicKeyBytes][]*SignedVote)
 	s.roundLock.Unlock()
+	s.updateLocalView()
 
 	best, err := s.blockState.BestBlockHeader()
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"reflect"
 
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/telemetry"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/blocktree"
@@ -51,7 +52,7 @@ func (h *MessageHandler) handleMessage(from peer.ID, m GrandpaMessage) (network.
 
 		return nil, nil
 	case *CommitMessage:
-		return nil, h.handleCommitMessage(msg)
+		return nil, h.handleCommitMessage(from, msg)
 	case *NeighbourMessage:
 		return nil, h.handleNeighbourMessage(msg)
 	case *CatchUpRequest:
@@ -87,11 +88,11 @@ func (h *MessageHandler) handleNeighbourMessage(msg *NeighbourMessage) error {
 	}
 
 	logger.Debugf("got neighbour message with number %d, set id %d and round %d", msg.Number, msg.SetID, msg.Round)
-	// TODO: should we send a justification request here? potentially re-connect this to sync package? (#1815)
+	// the justifications we miss near the head are requested by the sync service
 	return nil
 }
 
-func (h *MessageHandler) handleCommitMessage(msg *CommitMessage) error {
+func (h *MessageHandler) handleCommitMessage(from peer.ID, msg *CommitMessage) error {
 	logger.Debugf("received commit message, msg: %+v", msg)
 
 	containsPrecommitsSignedBy := make([]string, len(msg.AuthData))
@@ -119,6 +120,11 @@ func (h *MessageHandler) handleCommitMessage(msg *CommitMessage) error {
 		if errors.Is(err, blocktree.ErrStartNodeNotFound) {
 			// we haven't synced the committed block yet, add this to the tracker for later processing
 			h.grandpa.tracker.addCommit(msg)
+		} else {
+			h.grandpa.network.ReportPeer(peerset.ReputationChange{
+				Value:  peerset.BadJustificationValue,
+				Reason: peerset.BadJustificationReason,
+			}, from)
 		}
 		return err
 	}
@@ -127,6 +133,7 @@ func (h *MessageHandler) handleCommitMessage(msg *CommitMessage) error {
 	if err := h.blockState.SetFinalisedHash(msg.Vote.Hash, msg.Round, h.grandpa.state.setID); err != nil {
 		return err
 	}
+	h.grandpa.updateLocalView()
 
 	pcs, err := compactToJustification(msg.Precommits, msg.AuthData)
 	if err != nil {
@@ -559,6 +566,7 @@ func (s *Service) VerifyBlockJustification(hash common.Hash, justification []byt
 	if err != nil {
 		return err
 	}
+	s.updateLocalView()
 
 	logger.Debugf(
 		"set finalised block with hash %s, round %d and set id %d",
@@ -8,6 +8,8 @@ import (
 
 	peer "github.com/libp2p/go-libp2p-core/peer"
 
+	peerset "github.com/ChainSafe/gossamer/dot/peerset"
+
 	protocol "github.com/libp2p/go-libp2p-core/protocol"
 )
 
@@ -16,18 +18,51 @@ type Network struct {
 	mock.Mock
 }
 
+// ChainProtocolID provides a mock function with given fields: sub
+func (_m *Network) ChainProtocolID(sub protocol.ID) protocol.ID {
+	ret := _m.Called(sub)
+
+	var r0 protocol.ID
+	if rf, ok := ret.Get(0).(func(protocol.ID) protocol.ID); ok {
+		r0 = rf(sub)
+	} else {
+		r0 = ret.Get(0).(protocol.ID)
+	}
+
+	return r0
+}
+
 // GossipMessage provides a mock function with given fields: msg
 func (_m *Network) GossipMessage(msg network.NotificationsMessage) {
 	_m.Called(msg)
 }
 
-// RegisterNotificationsProtocol provides a mock function with given fields: sub, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler
-func (_m *Network) RegisterNotificationsProtocol(sub protocol.ID, messageID byte, handshakeGetter func() (network.Handshake, error), handshakeDecoder func([]byte) (network.Handshake, error), handshakeValidator func(peer.ID, network.Handshake) error, messageDecoder func([]byte) (network.NotificationsMessage, error), messageHandler func(peer.ID, network.NotificationsMessage) (bool, error), batchHandler func(peer.ID, network.NotificationsMessage)) error {
-	ret := _m.Called(sub, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler)
+// RegisterNotificationsProtocol provides a mock function with given fields: protocolIDs, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler
+func (_m *Network) RegisterNotificationsProtocol(protocolIDs []protocol.ID, messageID byte, handshakeGetter func() (network.Handshake, error), handshakeDecoder func([]byte) (network.Handshake, error), handshakeValidator func(peer.ID, network.Handshake) error, messageDecoder func([]byte) (network.NotificationsMessage, error), messageHandler func(peer.ID, network.NotificationsMessage) (bool, error), batchHandler func(peer.ID, network.NotificationsMessage)) error {
+	ret := _m.Called(protocolIDs, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]protocol.ID, byte, func() (network.Handshake, error), func([]byte) (network.Handshake, error), func(peer.ID, network.Handshake) error, func([]byte) (network.NotificationsMessage, error), func(peer.ID, network.NotificationsMessage) (bool, error), func(peer.ID, network.NotificationsMessage)) error); ok {
+		r0 = rf(protocolIDs, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReportPeer provides a mock function with given fields: change, p
+func (_m *Network) ReportPeer(change peerset.ReputationChange, p peer.ID) {
+	_m.Called(change, p)
+}
+
+// SetGossipFilter provides a mock function with given fields: messageID, filter
+func (_m *Network) SetGossipFilter(messageID byte, filter func(network.NotificationsMessage) func(peer.ID) bool) error {
+	ret := _m.Called(messageID, filter)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(protocol.ID, byte, func() (network.Handshake, error), func([]byte) (network.Handshake, error), func(peer.ID, network.Handshake) error, func([]byte) (network.NotificationsMessage, error), func(peer.ID, network.NotificationsMessage) (bool, error), func(peer.ID, network.NotificationsMessage)) error); ok {
-		r0 = rf(sub, messageID, handshakeGetter, handshakeDecoder, handshakeValidator, messageDecoder, messageHandler, batchHandler)
+	if rf, ok := ret.Get(0).(func(byte, func(network.NotificationsMessage) func(peer.ID) bool) error); ok {
+		r0 = rf(messageID, filter)
 	} else {
 		r0 = ret.Error(0)
 	}
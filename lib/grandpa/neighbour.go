@@ -0,0 +1,269 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package grandpa
+
+import (
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
+)
+
+const (
+	// maxPeerViews is the maximum number of peer views kept track of, the least recently updated are dropped first
+	maxPeerViews = 1024
+
+	// maxOutOfViewMessages is the number of out-of-view messages a peer can send before its reputation is lowered.
+	// a few are expected, as a peer only learns about our view when we send it a neighbour message.
+	maxOutOfViewMessages = 5
+)
+
+// view is the state of the GRANDPA protocol of a node, as announced in its neighbour messages
+type view struct {
+	round      uint64
+	setID      uint64
+	lastCommit uint32 // number of the last finalised block
+}
+
+// allowsVote returns true if a vote for the given round and set ID is useful to a node with this view,
+// ie. it is for the same set ID and at most one round away from the view's round
+func (v *view) allowsVote(round, setID uint64) bool {
+	return setID == v.setID && round+1 >= v.round && round <= v.round+1
+}
+
+// allowsCommit returns true if a commit for the given set ID and block number is useful to a node
+// with this view, ie. it is for the same set ID and finalises a block past the view's last commit
+func (v *view) allowsCommit(setID uint64, number uint32) bool {
+	return setID == v.setID && number > v.lastCommit
+}
+
+// isStaleVote returns true if a vote for the given round and set ID is too old for a node with this view
+func (v *view) isStaleVote(round, setID uint64) bool {
+	return setID < v.setID || (setID == v.setID && round+1 < v.round)
+}
+
+// isStaleCommit returns true if a commit for the given set ID is too old for a node with this view
+func (v *view) isStaleCommit(setID uint64) bool {
+	return setID < v.setID
+}
+
+type peerView struct {
+	view      *view // nil until the peer sends us a neighbour message
+	outOfView int   // out-of-view messages received since the peer's reputation was last lowered
+}
+
+// peerViews keeps track of the views of our peers
+type peerViews struct {
+	sync.Mutex
+	peers *lru.Cache // map[peer.ID]*peerView
+}
+
+func newPeerViews() *peerViews {
+	peers, err := lru.New(maxPeerViews)
+	if err != nil {
+		// only happens if the size is not positive
+		panic(err)
+	}
+
+	return &peerViews{
+		peers: peers,
+	}
+}
+
+func (pv *peerViews) getOrCreate(p peer.ID) *peerView {
+	if v, has := pv.peers.Get(p); has {
+		return v.(*peerView)
+	}
+
+	v := new(peerView)
+	pv.peers.Add(p, v)
+	return v
+}
+
+// update sets the view of the peer from its neighbour message
+func (pv *peerViews) update(p peer.ID, msg *NeighbourMessage) {
+	pv.Lock()
+	defer pv.Unlock()
+
+	pv.getOrCreate(p).view = &view{
+		round:      msg.Round,
+		setID:      msg.SetID,
+		lastCommit: msg.Number,
+	}
+}
+
+// get returns the view of the peer, or nil if it is unknown
+func (pv *peerViews) get(p peer.ID) *view {
+	pv.Lock()
+	defer pv.Unlock()
+
+	v, has := pv.peers.Peek(p)
+	if !has || v.(*peerView).view == nil {
+		return nil
+	}
+
+	view := *v.(*peerView).view
+	return &view
+}
+
+// outOfView records that the peer sent us an out-of-view message,
+// it returns true once the peer has sent too many of them
+func (pv *peerViews) outOfView(p peer.ID) bool {
+	pv.Lock()
+	defer pv.Unlock()
+
+	v := pv.getOrCreate(p)
+	v.outOfView++
+	if v.outOfView < maxOutOfViewMessages {
+		return false
+	}
+
+	v.outOfView = 0
+	return true
+}
+
+// localView returns our own view of the GRANDPA protocol, it's computed on first use and then kept up to date
+// by updateLocalView on round and set changes
+func (s *Service) localView() (*view, error) {
+	s.localViewLock.Lock()
+	defer s.localViewLock.Unlock()
+
+	if s.local == nil {
+		local, err := s.computeLocalView()
+		if err != nil {
+			return nil, err
+		}
+		s.local = local
+	}
+
+	local := *s.local
+	return &local, nil
+}
+
+// updateLocalView refreshes our own view, it's called whenever the round or set ID changes.
+// The view is kept if it can't be computed, which is safe as a view lagging behind only lets
+// more messages through.
+func (s *Service) updateLocalView() {
+	local, err := s.computeLocalView()
+	if err != nil {
+		logger.Warnf("failed to update local view: %s", err)
+		return
+	}
+
+	s.localViewLock.Lock()
+	defer s.localViewLock.Unlock()
+	s.local = local
+}
+
+// computeLocalView returns our own view of the GRANDPA protocol. Authorities take it from the voter state,
+// other nodes don't run the voter so it's taken from the latest finalisation instead.
+func (s *Service) computeLocalView() (*view, error) {
+	if s.authority {
+		s.roundLock.Lock()
+		defer s.roundLock.Unlock()
+
+		return &view{
+			round: s.state.round,
+			setID: s.state.setID,
+		}, nil
+	}
+
+	round, setID, err := s.blockState.GetHighestRoundAndSetID()
+	if err != nil {
+		return nil, err
+	}
+
+	currSetID, err := s.grandpaState.GetCurrentSetID()
+	if err != nil {
+		return nil, err
+	}
+
+	if currSetID > setID {
+		// nothing was finalised in the current set yet, so its voters are in the first round
+		return &view{
+			setID: currSetID,
+		}, nil
+	}
+
+	return &view{
+		round: round + 1,
+		setID: setID,
+	}, nil
+}
+
+// isStale returns true if the message is too old to be of any use to us
+func (s *Service) isStale(m GrandpaMessage) (bool, error) {
+	var isStale func(*view) bool
+	switch msg := m.(type) {
+	case *VoteMessage:
+		isStale = func(v *view) bool {
+			return v.isStaleVote(msg.Round, msg.SetID)
+		}
+	case *CommitMessage:
+		isStale = func(v *view) bool {
+			return v.isStaleCommit(msg.SetID)
+		}
+	default:
+		return false, nil
+	}
+
+	local, err := s.localView()
+	if err != nil {
+		return false, err
+	}
+
+	return isStale(local), nil
+}
+
+// handleOutOfViewMessage lowers the reputation of peers that repeatedly send us out-of-view messages
+func (s *Service) handleOutOfViewMessage(from peer.ID, m GrandpaMessage) {
+	logger.Debugf("dropping out of view message from peer %s: %v", from, m)
+
+	if !s.peerViews.outOfView(from) {
+		return
+	}
+
+	s.network.ReportPeer(peerset.ReputationChange{
+		Value:  peerset.OutOfViewMessageValue,
+		Reason: peerset.OutOfViewMessageReason,
+	}, from)
+}
+
+// shouldGossip returns the function deciding which peers the message is useful to according to their views.
+// The message is decoded once for all the peers, and always sent to peers whose view is still unknown.
+func (s *Service) shouldGossip(msg network.NotificationsMessage) func(peer.ID) bool {
+	all := func(peer.ID) bool { return true }
+
+	cm, ok := msg.(*ConsensusMessage)
+	if !ok {
+		return all
+	}
+
+	m, err := decodeMessage(cm)
+	if err != nil {
+		return func(peer.ID) bool { return false }
+	}
+
+	var allows func(*view) bool
+	switch m := m.(type) {
+	case *VoteMessage:
+		allows = func(v *view) bool {
+			return v.allowsVote(m.Round, m.SetID)
+		}
+	case *CommitMessage:
+		allows = func(v *view) bool {
+			return v.allowsCommit(m.SetID, m.Vote.Number)
+		}
+	default:
+		return all
+	}
+
+	return func(to peer.ID) bool {
+		v := s.peerViews.get(to)
+		return v == nil || allows(v)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package grandpa
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/lib/grandpa/mocks"
+)
+
+func TestView_AllowsVote(t *testing.T) {
+	v := &view{round: 5, setID: 1}
+
+	require.True(t, v.allowsVote(4, 1))
+	require.True(t, v.allowsVote(5, 1))
+	require.True(t, v.allowsVote(6, 1))
+	require.False(t, v.allowsVote(3, 1))
+	require.False(t, v.allowsVote(7, 1))
+	require.False(t, v.allowsVote(5, 0))
+	require.False(t, v.allowsVote(5, 2))
+
+	require.True(t, v.isStaleVote(3, 1))
+	require.True(t, v.isStaleVote(5, 0))
+	require.False(t, v.isStaleVote(4, 1))
+	require.False(t, v.isStaleVote(7, 1))
+	require.False(t, v.isStaleVote(1, 2))
+}
+
+func TestView_AllowsCommit(t *testing.T) {
+	v := &view{round: 5, setID: 1, lastCommit: 100}
+
+	require.True(t, v.allowsCommit(1, 101))
+	require.False(t, v.allowsCommit(1, 100))
+	require.False(t, v.allowsCommit(0, 101))
+	require.False(t, v.allowsCommit(2, 101))
+
+	require.True(t, v.isStaleCommit(0))
+	require.False(t, v.isStaleCommit(1))
+	require.False(t, v.isStaleCommit(2))
+}
+
+func TestPeerViews(t *testing.T) {
+	pv := newPeerViews()
+	p := peer.ID("noot")
+
+	require.Nil(t, pv.get(p))
+
+	pv.update(p, &NeighbourMessage{
+		Version: 1,
+		Round:   2,
+		SetID:   3,
+		Number:  4,
+	})
+	require.Equal(t, &view{round: 2, setID: 3, lastCommit: 4}, pv.get(p))
+
+	for i := 0; i < maxOutOfViewMessages-1; i++ {
+		require.False(t, pv.outOfView(p))
+	}
+	require.True(t, pv.outOfView(p))
+	require.False(t, pv.outOfView(p))
+
+	// the view is kept when counting out of view messages
+	require.Equal(t, &view{round: 2, setID: 3, lastCommit: 4}, pv.get(p))
+}
+
+func TestShouldGossip(t *testing.T) {
+	gs := &Service{
+		peerViews: newPeerViews(),
+	}
+	p := peer.ID("noot")
+
+	vote := &VoteMessage{
+		Round: 5,
+		SetID: 1,
+	}
+	voteMsg, err := vote.ToConsensusMessage()
+	require.NoError(t, err)
+
+	commit := &CommitMessage{
+		Round: 5,
+		SetID: 1,
+		Vote:  Vote{Number: 10},
+	}
+	commitMsg, err := commit.ToConsensusMessage()
+	require.NoError(t, err)
+
+	neighbour := &NeighbourMessage{
+		Version: 1,
+		Round:   9,
+		SetID:   1,
+		Number:  10,
+	}
+	neighbourMsg, err := neighbour.ToConsensusMessage()
+	require.NoError(t, err)
+
+	// the peer's view is unknown
+	require.True(t, gs.shouldGossip(voteMsg)(p))
+	require.True(t, gs.shouldGossip(commitMsg)(p))
+
+	gs.peerViews.update(p, neighbour)
+	require.False(t, gs.shouldGossip(voteMsg)(p))
+	require.False(t, gs.shouldGossip(commitMsg)(p))
+	require.True(t, gs.shouldGossip(neighbourMsg)(p))
+
+	// the message is decoded once, the peer views are checked when the filter is called
+	shouldGossipVote := gs.shouldGossip(voteMsg)
+	require.False(t, shouldGossipVote(p))
+
+	gs.peerViews.update(p, &NeighbourMessage{
+		Version: 1,
+		Round:   5,
+		SetID:   1,
+		Number:  9,
+	})
+	require.True(t, shouldGossipVote(p))
+	require.True(t, gs.shouldGossip(voteMsg)(p))
+	require.True(t, gs.shouldGossip(commitMsg)(p))
+
+	require.False(t, gs.shouldGossip(&ConsensusMessage{Data: []byte{0xff}})(p))
+}
+
+func TestLocalView(t *testing.T) {
+	gs := &Service{
+		authority: true,
+		state:     NewState(nil, 1, 5),
+	}
+
+	local, err := gs.localView()
+	require.NoError(t, err)
+	require.Equal(t, &view{round: 5, setID: 1}, local)
+
+	// the view is cached until it's updated
+	gs.state.round = 6
+	local, err = gs.localView()
+	require.NoError(t, err)
+	require.Equal(t, &view{round: 5, setID: 1}, local)
+
+	gs.updateLocalView()
+	local, err = gs.localView()
+	require.NoError(t, err)
+	require.Equal(t, &view{round: 6, setID: 1}, local)
+}
+
+func TestHandleNetworkMessage_StaleVote(t *testing.T) {
+	gs := &Service{
+		authority: true,
+		state:     NewState(nil, 1, 5),
+		peerViews: newPeerViews(),
+		in:        make(chan *networkVoteMessage, 1),
+	}
+
+	vote := &VoteMessage{
+		Round: 5,
+		SetID: 0,
+	}
+	cm, err := vote.ToConsensusMessage()
+	require.NoError(t, err)
+
+	p := peer.ID("noot")
+	net := new(mocks.Network)
+	net.On("ReportPeer", peerset.ReputationChange{
+		Value:  peerset.OutOfViewMessageValue,
+		Reason: peerset.OutOfViewMessageReason,
+	}, p).Once()
+	gs.network = net
+
+	for i := 0; i < maxOutOfViewMessages; i++ {
+		propagate, err := gs.handleNetworkMessage(p, cm)
+		require.NoError(t, err)
+		require.False(t, propagate)
+	}
+
+	// stale votes are dropped before reaching the voter
+	require.Len(t, gs.in, 0)
+	net.AssertExpectations(t)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 
@@ -16,6 +17,9 @@ import (
 )
 
 var (
+	// grandpaSubProtocolID is appended to the genesis hash based protocol ID of the chain,
+	// grandpaID is the legacy protocol ID used as a fallback
+	grandpaSubProtocolID     protocol.ID = "/grandpa/1"
 	grandpaID                protocol.ID = "/paritytech/grandpa/1"
 	messageID                            = network.ConsensusMsgType
 	neighbourMessageInterval             = time.Minute * 5
@@ -79,8 +83,8 @@ func (*GrandpaHandshake) IsHandshake() bool {
 }
 
 func (s *Service) registerProtocol() error {
-	return s.network.RegisterNotificationsProtocol(
-		grandpaID,
+	err := s.network.RegisterNotificationsProtocol(
+		[]protocol.ID{s.network.ChainProtocolID(grandpaSubProtocolID), grandpaID},
 		messageID,
 		s.getHandshake,
 		s.decodeHandshake,
@@ -89,6 +93,11 @@ func (s *Service) registerProtocol() error {
 		s.handleNetworkMessage,
 		nil,
 	)
+	if err != nil {
+		return err
+	}
+
+	return s.network.SetGossipFilter(messageID, s.shouldGossip)
 }
 
 func (s *Service) getHandshake() (Handshake, error) {
@@ -137,9 +146,27 @@ func (s *Service) handleNetworkMessage(from peer.ID, msg NotificationsMessage) (
 
 	m, err := decodeMessage(cm)
 	if err != nil {
+		s.network.ReportPeer(peerset.ReputationChange{
+			Value:  peerset.BadMessageValue,
+			Reason: peerset.BadMessageReason,
+		}, from)
 		return false, err
 	}
 
+	if nm, ok := m.(*NeighbourMessage); ok {
+		s.peerViews.update(from, nm)
+	}
+
+	stale, err := s.isStale(m)
+	if err != nil {
+		return false, err
+	}
+
+	if stale {
+		s.handleOutOfViewMessage(from, m)
+		return false, nil
+	}
+
 	resp, err := s.messageHandler.handleMessage(from, m)
 	if err != nil {
 		return false, err
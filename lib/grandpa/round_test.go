@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/state"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
@@ -71,7 +72,7 @@ func (n *testNetwork) SendJustificationRequest(to peer.ID, num uint32) {
 }
 
 func (*testNetwork) RegisterNotificationsProtocol(
-	_ protocol.ID,
+	_ []protocol.ID,
 	_ byte,
 	_ network.HandshakeGetter,
 	_ network.HandshakeDecoder,
@@ -83,8 +84,18 @@ func (*testNetwork) RegisterNotificationsProtocol(
 	return nil
 }
 
+func (*testNetwork) SetGossipFilter(_ byte, _ network.GossipFilter) error {
+	return nil
+}
+
 func (n *testNetwork) SendBlockReqestByHash(_ common.Hash) {}
 
+func (*testNetwork) ReportPeer(_ peerset.ReputationChange, _ peer.ID) {}
+
+func (*testNetwork) ChainProtocolID(sub protocol.ID) protocol.ID {
+	return "/gossamer/test" + sub
+}
+
 func setupGrandpa(t *testing.T, kp *ed25519.Keypair) (
 	*Service, chan *networkVoteMessage, chan GrandpaMessage, chan GrandpaMessage) {
 	st := newTestState(t)
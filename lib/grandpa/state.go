@@ -10,6 +10,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/protocol"
 
 	"github.com/ChainSafe/gossamer/dot/network"
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
 )
@@ -67,7 +68,7 @@ type DigestHandler interface { // TODO: use GrandpaState instead (#1871)
 type Network interface {
 	GossipMessage(msg network.NotificationsMessage)
 	SendMessage(to peer.ID, msg NotificationsMessage) error
-	RegisterNotificationsProtocol(sub protocol.ID,
+	RegisterNotificationsProtocol(protocolIDs []protocol.ID,
 		messageID byte,
 		handshakeGetter network.HandshakeGetter,
 		handshakeDecoder network.HandshakeDecoder,
@@ -76,4 +77,7 @@ type Network interface {
 		messageHandler network.NotificationsMessageHandler,
 		batchHandler network.NotificationsMessageBatchHandler,
 	) error
+	SetGossipFilter(messageID byte, filter network.GossipFilter) error
+	ReportPeer(change peerset.ReputationChange, p peer.ID)
+	ChainProtocolID(sub protocol.ID) protocol.ID
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/telemetry"
 	"github.com/ChainSafe/gossamer/lib/blocktree"
 	"github.com/ChainSafe/gossamer/lib/crypto/ed25519"
@@ -68,6 +69,12 @@ func (s *Service) receiveMessages(ctx context.Context) {
 			v, err := s.validateMessage(msg.from, vm)
 			if err != nil {
 				logger.Debugf("failed to validate vote message %v: %s", vm, err)
+				if errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrVoterNotFound) {
+					s.network.ReportPeer(peerset.ReputationChange{
+						Value:  peerset.BadMessageValue,
+						Reason: peerset.BadMessageReason,
+					}, msg.from)
+				}
 				continue
 			}
 
@@ -67,6 +67,8 @@ var (
 	DecodeSessionKeys = "SessionKeys_decode_session_keys"
 	// TransactionPaymentAPIQueryInfo returns information of a given extrinsic
 	TransactionPaymentAPIQueryInfo = "TransactionPaymentApi_query_info"
+	// AuthorityDiscoveryAPIAuthorities is the runtime API call AuthorityDiscoveryApi_authorities
+	AuthorityDiscoveryAPIAuthorities = "AuthorityDiscoveryApi_authorities"
 )
 
 // GrandpaAuthoritiesKey is the location of GRANDPA authority data
@@ -6,6 +6,7 @@ package runtime
 import (
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/lib/trie"
@@ -30,6 +31,7 @@ type Instance interface {
 	Metadata() ([]byte, error)
 	BabeConfiguration() (*types.BabeConfiguration, error)
 	GrandpaAuthorities() ([]types.Authority, error)
+	AuthorityDiscoveryAuthorities() ([]*sr25519.PublicKey, error)
 	ValidateTransaction(e types.Extrinsic) (*transaction.Validity, error)
 	InitializeBlock(header *types.Header) error
 	InherentExtrinsics(data []byte) ([]byte, error)
@@ -49,26 +51,29 @@ type Instance interface {
 
 // Storage interface
 type Storage interface {
-	Set(key []byte, value []byte)
-	Get(key []byte) []byte
+	Set(key []byte, value []byte) error
+	Get(key []byte) ([]byte, error)
 	Root() (common.Hash, error)
+	RootWithVersion(version trie.Version) (common.Hash, error)
+	SetVersion(version trie.Version)
 	SetChild(keyToChild []byte, child *trie.Trie) error
 	SetChildStorage(keyToChild, key, value []byte) error
 	GetChildStorage(keyToChild, key []byte) ([]byte, error)
-	Delete(key []byte)
-	DeleteChild(keyToChild []byte)
+	Delete(key []byte) error
+	DeleteChild(keyToChild []byte) error
 	DeleteChildLimit(keyToChild []byte, limit *[]byte) (uint32, bool, error)
 	ClearChildStorage(keyToChild, key []byte) error
-	NextKey([]byte) []byte
+	NextKey([]byte) ([]byte, error)
 	ClearPrefixInChild(keyToChild, prefix []byte) error
 	GetChildNextKey(keyToChild, key []byte) ([]byte, error)
 	GetChild(keyToChild []byte) (*trie.Trie, error)
+	GetChildRootWithVersion(keyToChild []byte, version trie.Version) (common.Hash, error)
 	ClearPrefix(prefix []byte) error
-	ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool)
+	ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool, error)
 	BeginStorageTransaction()
 	CommitStorageTransaction()
 	RollbackStorageTransaction()
-	LoadCode() []byte
+	LoadCode() ([]byte, error)
 }
 
 // BasicNetwork interface for functions used by runtime network state function
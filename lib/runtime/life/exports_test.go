@@ -48,6 +48,7 @@ func TestInstance_Version_NodeRuntime(t *testing.T) {
 		0,
 		nil,
 		2,
+		0,
 	)
 
 	instance := newInstanceFromGenesis(t)
@@ -56,7 +56,11 @@ func NewRuntimeFromGenesis(cfg *Config) (runtime.Instance, error) {
 		return nil, errors.New("storage is nil")
 	}
 
-	code := cfg.Storage.LoadCode()
+	code, err := cfg.Storage.LoadCode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load :code from state: %w", err)
+	}
+
 	if len(code) == 0 {
 		return nil, fmt.Errorf("cannot find :code in state")
 	}
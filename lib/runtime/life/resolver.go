@@ -279,7 +279,12 @@ func ext_storage_get_version_1(vm *exec.VirtualMachine) int64 {
 	key := asMemorySlice(vm.Memory, keySpan)
 	logger.Debugf("[ext_storage_get_version_1] key: 0x%x", key)
 
-	value := storage.Get(key)
+	value, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_get_version_1]: %s", err)
+		ptr, _ := toWasmMemoryOptional(vm.Memory, nil)
+		return ptr
+	}
 	logger.Debugf("[ext_storage_get_version_1] value: 0x%x", value)
 
 	valueSpan, err := toWasmMemoryOptional(vm.Memory, value)
@@ -305,7 +310,10 @@ func ext_storage_set_version_1(vm *exec.VirtualMachine) int64 {
 
 	cp := make([]byte, len(value))
 	copy(cp, value)
-	storage.Set(key, cp)
+	err := storage.Set(key, cp)
+	if err != nil {
+		logger.Errorf("[ext_storage_set_version_1]: %s", err)
+	}
 	return 0
 }
 
@@ -316,7 +324,11 @@ func ext_storage_next_key_version_1(vm *exec.VirtualMachine) int64 {
 
 	key := asMemorySlice(vm.Memory, keySpan)
 
-	next := storage.NextKey(key)
+	next, err := storage.NextKey(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_next_key_version_1]: %s", err)
+		return 0
+	}
 	logger.Debugf("[ext_storage_next_key_version_1] key is 0x%x and next is 0x%x", key, next)
 
 	nextSpan, err := toWasmMemoryOptional(vm.Memory, next)
@@ -336,7 +348,10 @@ func ext_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
 	key := asMemorySlice(vm.Memory, keySpan)
 
 	logger.Debugf("[ext_storage_clear_version_1] key: 0x%x", key)
-	storage.Delete(key)
+	err := storage.Delete(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_clear_version_1]: %s", err)
+	}
 	return 0
 }
 
@@ -351,10 +366,15 @@ func ext_storage_clear_prefix_version_1(vm *exec.VirtualMachine) int64 {
 	err := storage.ClearPrefix(prefix)
 	if err != nil {
 		logger.Errorf("[ext_storage_clear_prefix_version_1]: %s", err)
+		return 0
 	}
 
 	// sanity check
-	next := storage.NextKey(prefix)
+	next, err := storage.NextKey(prefix)
+	if err != nil {
+		logger.Errorf("[ext_storage_clear_prefix_version_1]: %s", err)
+		return 0
+	}
 	if len(next) >= len(prefix) && bytes.Equal(prefix, next[:len(prefix)]) {
 		panic("did not clear prefix")
 	}
@@ -369,7 +389,12 @@ func ext_storage_exists_version_1(vm *exec.VirtualMachine) int64 {
 
 	key := asMemorySlice(vm.Memory, keySpan)
 
-	val := storage.Get(key)
+	val, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_exists_version_1]: %s", err)
+		return 0
+	}
+
 	if len(val) == 0 {
 		return 0
 	}
@@ -386,7 +411,12 @@ func ext_storage_read_version_1(vm *exec.VirtualMachine) int64 {
 	memory := vm.Memory
 
 	key := asMemorySlice(memory, keySpan)
-	value := storage.Get(key)
+	value, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_read_version_1]: %s", err)
+		ret, _ := toWasmMemoryOptional(memory, nil)
+		return ret
+	}
 	logger.Debugf("[ext_storage_read_version_1] key 0x%x and value 0x%x", key, value)
 
 	if value == nil {
@@ -419,7 +449,10 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 
 	// this function assumes the item in storage is a SCALE encoded array of items
 	// the valueToAppend is a new item, so it appends the item and increases the length prefix by 1
-	valueCurr := storage.Get(key)
+	valueCurr, err := storage.Get(key)
+	if err != nil {
+		return err
+	}
 
 	if len(valueCurr) == 0 {
 		valueRes = valueToAppend
@@ -428,8 +461,7 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 		err := scale.Unmarshal(valueCurr, &currLength)
 		if err != nil {
 			logger.Tracef("[ext_storage_append_version_1] item in storage is not SCALE encoded, overwriting at key 0x%x", key)
-			storage.Set(key, append([]byte{4}, valueToAppend...))
-			return nil
+			return storage.Set(key, append([]byte{4}, valueToAppend...))
 		}
 
 		lengthBytes, err := scale.Marshal(currLength)
@@ -453,8 +485,7 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 	// append new length prefix to start of items array
 	lengthEnc = append(lengthEnc, valueRes...)
 	logger.Debugf("[ext_storage_append_version_1] resulting value: 0x%x", lengthEnc)
-	storage.Set(key, lengthEnc)
-	return nil
+	return storage.Set(key, lengthEnc)
 }
 
 func ext_storage_append_version_1(vm *exec.VirtualMachine) int64 {
@@ -497,7 +528,10 @@ func ext_trie_blake2_256_ordered_root_version_1(vm *exec.VirtualMachine) int64 {
 		}
 		logger.Tracef("[ext_trie_blake2_256_ordered_root_version_1] key 0x%x and value 0x%x", key, val)
 
-		t.Put(key, val)
+		if err = t.Put(key, val); err != nil {
+			logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+			return 0
+		}
 	}
 
 	// allocate memory for value and copy value to memory
@@ -672,7 +706,10 @@ func ext_default_child_storage_storage_kill_version_1(vm *exec.VirtualMachine) i
 	storage := ctx.Storage
 
 	childStorageKey := asMemorySlice(memory, childStorageKeySpan)
-	storage.DeleteChild(childStorageKey)
+	err := storage.DeleteChild(childStorageKey)
+	if err != nil {
+		logger.Errorf("[ext_default_child_storage_storage_kill_version_1]: %s", err)
+	}
 	return 0
 }
 
@@ -1307,7 +1344,10 @@ func ext_trie_blake2_256_root_version_1(vm *exec.VirtualMachine) int64 {
 	}
 
 	for _, kv := range kvs {
-		t.Put(kv.Key, kv.Value)
+		if err := t.Put(kv.Key, kv.Value); err != nil {
+			logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+			return 0
+		}
 	}
 
 	// allocate memory for value and copy value to memory
@@ -116,7 +116,8 @@ func Test_ext_storage_set_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_set_version_1", append(encKey, encValue...))
 	require.NoError(t, err)
 
-	val := ctx.Storage.Get(testkey)
+	val, err := ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Equal(t, testvalue, val)
 }
 
@@ -173,7 +174,8 @@ func Test_ext_storage_clear_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_clear_version_1", enc)
 	require.NoError(t, err)
 
-	val := ctx.Storage.Get(testkey)
+	val, err := ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 }
 
@@ -192,10 +194,12 @@ func Test_ext_storage_clear_prefix_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_clear_prefix_version_1", enc)
 	require.NoError(t, err)
 
-	val := ctx.Storage.Get(testkey)
+	val, err := ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 
-	val = ctx.Storage.Get(testkey2)
+	val, err = ctx.Storage.Get(testkey2)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 }
 
@@ -222,7 +226,8 @@ func Test_ext_storage_append_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey1, doubleEncVal1...))
 	require.NoError(t, err)
 
-	val := ctx.Storage.Get(testkey)
+	val, err := ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Equal(t, encArr1, val)
 
 	encValueAppend1, err := scale.Marshal(testvalueAppend)
@@ -234,7 +239,8 @@ func Test_ext_storage_append_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey1, doubleEncValueAppend1...))
 	require.NoError(t, err)
 
-	ret := ctx.Storage.Get(testkey)
+	ret, err := ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.NotNil(t, ret)
 
 	var dec1 [][]byte
@@ -6,6 +6,8 @@ import (
 	common "github.com/ChainSafe/gossamer/lib/common"
 	keystore "github.com/ChainSafe/gossamer/lib/keystore"
 
+	sr25519 "github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+
 	mock "github.com/stretchr/testify/mock"
 
 	runtime "github.com/ChainSafe/gossamer/lib/runtime"
@@ -43,6 +45,29 @@ func (_m *Instance) ApplyExtrinsic(data types.Extrinsic) ([]byte, error) {
 	return r0, r1
 }
 
+// AuthorityDiscoveryAuthorities provides a mock function with given fields:
+func (_m *Instance) AuthorityDiscoveryAuthorities() ([]*sr25519.PublicKey, error) {
+	ret := _m.Called()
+
+	var r0 []*sr25519.PublicKey
+	if rf, ok := ret.Get(0).(func() []*sr25519.PublicKey); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*sr25519.PublicKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BabeConfiguration provides a mock function with given fields:
 func (_m *Instance) BabeConfiguration() (*types.BabeConfiguration, error) {
 	ret := _m.Called()
@@ -125,6 +125,20 @@ func (_m *Version) SpecVersion() uint32 {
 	return r0
 }
 
+// StateVersion provides a mock function with given fields:
+func (_m *Version) StateVersion() uint8 {
+	ret := _m.Called()
+
+	var r0 uint8
+	if rf, ok := ret.Get(0).(func() uint8); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint8)
+	}
+
+	return r0
+}
+
 // TransactionVersion provides a mock function with given fields:
 func (_m *Version) TransactionVersion() uint32 {
 	ret := _m.Called()
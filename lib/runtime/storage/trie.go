@@ -4,6 +4,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/binary"
 	"sort"
 	"sync"
@@ -12,12 +13,25 @@ import (
 	"github.com/ChainSafe/gossamer/lib/trie"
 )
 
+// Cache is a cache of the values of the state a TrieState is created from, which is shared with the other users
+// of that state. A nil value is cached for the keys which aren't in the state.
+type Cache interface {
+	Get(key []byte) (value []byte, has bool)
+	Add(key, value []byte)
+}
+
 // TrieState is a wrapper around a transient trie that is used during the course of executing some runtime call.
 // If the execution of the call is successful, the trie will be saved in the StorageState.
 type TrieState struct {
 	t       *trie.Trie
 	oldTrie *trie.Trie // this is the trie before BeginStorageTransaction is called. set to nil if it isn't called
 	lock    sync.RWMutex
+
+	// the keys changed and the prefixes cleared since the TrieState was created, including the changes
+	// rolled back, whose values aren't read from or added to the cache
+	cache           Cache
+	changedKeys     map[string]struct{}
+	clearedPrefixes [][]byte
 }
 
 // NewTrieState returns a new TrieState with the given trie
@@ -27,12 +41,67 @@ func NewTrieState(t *trie.Trie) (*TrieState, error) {
 	}
 
 	ts := &TrieState{
-		t: t,
+		t:           t,
+		changedKeys: make(map[string]struct{}),
 	}
 
 	return ts, nil
 }
 
+// SetCache sets the cache of the values of the state the TrieState is created from.
+// It must be set before the TrieState is written to.
+func (s *TrieState) SetCache(cache Cache) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cache = cache
+}
+
+// ChangedKeys returns the keys changed and the prefixes cleared since the TrieState was created. The changes
+// to a child trie are returned as a change of its key in the trie. The changes which were rolled back are also
+// returned, so the other keys have the same value as in the state the TrieState was created from.
+func (s *TrieState) ChangedKeys() (keys, clearedPrefixes [][]byte) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys = make([][]byte, 0, len(s.changedKeys))
+	for key := range s.changedKeys {
+		keys = append(keys, []byte(key))
+	}
+
+	return keys, s.clearedPrefixes
+}
+
+// recordChange records the change of the given key, the lock must be held
+func (s *TrieState) recordChange(key []byte) {
+	s.changedKeys[string(key)] = struct{}{}
+}
+
+// recordChildChange records the change of the child trie at the given key, the lock must be held
+func (s *TrieState) recordChildChange(keyToChild []byte) {
+	s.recordChange(append(append([]byte{}, trie.ChildStorageKeyPrefix...), keyToChild...))
+}
+
+// recordClearedPrefix records the clearing of the keys with the given prefix, the lock must be held
+func (s *TrieState) recordClearedPrefix(prefix []byte) {
+	s.clearedPrefixes = append(s.clearedPrefixes, append([]byte{}, prefix...))
+}
+
+// isChanged returns true if the given key may have changed since the TrieState was created,
+// the lock must be held
+func (s *TrieState) isChanged(key []byte) bool {
+	if _, has := s.changedKeys[string(key)]; has {
+		return true
+	}
+
+	for _, prefix := range s.clearedPrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Trie returns the TrieState's underlying trie
 func (s *TrieState) Trie() *trie.Trie {
 	return s.t
@@ -70,17 +139,33 @@ func (s *TrieState) RollbackStorageTransaction() {
 }
 
 // Set sets a key-value pair in the trie
-func (s *TrieState) Set(key, value []byte) {
+func (s *TrieState) Set(key, value []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.t.Put(key, value)
+	s.recordChange(key)
+	return s.t.Put(key, value)
 }
 
-// Get gets a value from the trie
-func (s *TrieState) Get(key []byte) []byte {
+// Get gets a value from the trie, or from the cache if the key wasn't changed
+func (s *TrieState) Get(key []byte) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	return s.t.Get(key)
+
+	if s.cache == nil || s.isChanged(key) {
+		return s.t.Get(key)
+	}
+
+	if value, has := s.cache.Get(key); has {
+		return value, nil
+	}
+
+	value, err := s.t.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Add(key, value)
+	return value, nil
 }
 
 // MustRoot returns the trie's root hash. It panics if it fails to compute the root.
@@ -93,25 +178,50 @@ func (s *TrieState) Root() (common.Hash, error) {
 	return s.t.Hash()
 }
 
+// RootWithVersion returns the trie's root hash, where the values written since the root was last computed
+// with a state version are encoded with the given version
+func (s *TrieState) RootWithVersion(version trie.Version) (common.Hash, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.t.HashWithVersion(version)
+}
+
+// SetVersion sets the state version used for the values written to the trie
+func (s *TrieState) SetVersion(version trie.Version) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.t.SetVersion(version)
+}
+
 // Has returns whether or not a key exists
-func (s *TrieState) Has(key []byte) bool {
-	return s.Get(key) != nil
+func (s *TrieState) Has(key []byte) (bool, error) {
+	value, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	return value != nil, nil
 }
 
 // Delete deletes a key from the trie
-func (s *TrieState) Delete(key []byte) {
-	val := s.t.Get(key)
+func (s *TrieState) Delete(key []byte) error {
+	val, err := s.t.Get(key)
+	if err != nil {
+		return err
+	}
+
 	if val == nil {
-		return
+		return nil
 	}
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.t.Delete(key)
+	s.recordChange(key)
+	return s.t.Delete(key)
 }
 
 // NextKey returns the next key in the trie in lexicographical order. If it does not exist, it returns nil.
-func (s *TrieState) NextKey(key []byte) []byte {
+func (s *TrieState) NextKey(key []byte) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	return s.t.NextKey(key)
@@ -121,21 +231,21 @@ func (s *TrieState) NextKey(key []byte) []byte {
 func (s *TrieState) ClearPrefix(prefix []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.t.ClearPrefix(prefix)
-	return nil
+	s.recordClearedPrefix(prefix)
+	return s.t.ClearPrefix(prefix)
 }
 
 // ClearPrefixLimit deletes key-value pairs from the trie where the key starts with the given prefix till limit reached
-func (s *TrieState) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool) {
+func (s *TrieState) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	num, del := s.t.ClearPrefixLimit(prefix, limit)
-	return num, del
+	s.recordClearedPrefix(prefix)
+	return s.t.ClearPrefixLimit(prefix, limit)
 }
 
 // TrieEntries returns every key-value pair in the trie
-func (s *TrieState) TrieEntries() map[string][]byte {
+func (s *TrieState) TrieEntries() (map[string][]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	return s.t.Entries()
@@ -145,6 +255,7 @@ func (s *TrieState) TrieEntries() map[string][]byte {
 func (s *TrieState) SetChild(keyToChild []byte, child *trie.Trie) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.recordChildChange(keyToChild)
 	return s.t.PutChild(keyToChild, child)
 }
 
@@ -152,6 +263,7 @@ func (s *TrieState) SetChild(keyToChild []byte, child *trie.Trie) error {
 func (s *TrieState) SetChildStorage(keyToChild, key, value []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.recordChildChange(keyToChild)
 	return s.t.PutIntoChild(keyToChild, key, value)
 }
 
@@ -162,6 +274,14 @@ func (s *TrieState) GetChild(keyToChild []byte) (*trie.Trie, error) {
 	return s.t.GetChild(keyToChild)
 }
 
+// GetChildRootWithVersion returns the root hash of the child trie at the given key, where the values written
+// since the root was last computed with a state version are encoded with the given version
+func (s *TrieState) GetChildRootWithVersion(keyToChild []byte, version trie.Version) (common.Hash, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.t.GetChildHashWithVersion(keyToChild, version)
+}
+
 // GetChildStorage returns a value from a child trie
 func (s *TrieState) GetChildStorage(keyToChild, key []byte) ([]byte, error) {
 	s.lock.RLock()
@@ -170,10 +290,11 @@ func (s *TrieState) GetChildStorage(keyToChild, key []byte) ([]byte, error) {
 }
 
 // DeleteChild deletes a child trie from the main trie
-func (s *TrieState) DeleteChild(key []byte) {
+func (s *TrieState) DeleteChild(key []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.t.DeleteChild(key)
+	s.recordChildChange(key)
+	return s.t.DeleteChild(key)
 }
 
 // DeleteChildLimit deletes up to limit of database entries by lexicographic order, return number
@@ -181,25 +302,35 @@ func (s *TrieState) DeleteChild(key []byte) {
 func (s *TrieState) DeleteChildLimit(key []byte, limit *[]byte) (uint32, bool, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.recordChildChange(key)
 	tr, err := s.t.GetChild(key)
 	if err != nil {
 		return 0, false, err
 	}
-	qtyEntries := uint32(len(tr.Entries()))
+	entries, err := tr.Entries()
+	if err != nil {
+		return 0, false, err
+	}
+
+	qtyEntries := uint32(len(entries))
 	if limit == nil {
-		s.t.DeleteChild(key)
+		if err = s.t.DeleteChild(key); err != nil {
+			return 0, false, err
+		}
 		return qtyEntries, true, nil
 	}
 	limitUint := binary.LittleEndian.Uint32(*limit)
 
-	keys := make([]string, 0, len(tr.Entries()))
-	for k := range tr.Entries() {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 	deleted := uint32(0)
 	for _, k := range keys {
-		tr.Delete([]byte(k))
+		if err = tr.Delete([]byte(k)); err != nil {
+			return 0, false, err
+		}
 		deleted++
 		if deleted == limitUint {
 			break
@@ -217,6 +348,7 @@ func (s *TrieState) DeleteChildLimit(key []byte, limit *[]byte) (uint32, bool, e
 func (s *TrieState) ClearChildStorage(keyToChild, key []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.recordChildChange(keyToChild)
 	return s.t.ClearFromChild(keyToChild, key)
 }
 
@@ -225,6 +357,7 @@ func (s *TrieState) ClearPrefixInChild(keyToChild, prefix []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.recordChildChange(keyToChild)
 	child, err := s.t.GetChild(keyToChild)
 	if err != nil {
 		return err
@@ -233,8 +366,7 @@ func (s *TrieState) ClearPrefixInChild(keyToChild, prefix []byte) error {
 		return nil
 	}
 
-	child.ClearPrefix(prefix)
-	return nil
+	return child.ClearPrefix(prefix)
 }
 
 // GetChildNextKey returns the next lexicographical larger key from child storage. If it does not exist, it returns nil.
@@ -248,7 +380,7 @@ func (s *TrieState) GetChildNextKey(keyToChild, key []byte) ([]byte, error) {
 	if child == nil {
 		return nil, nil
 	}
-	return child.NextKey(key), nil
+	return child.NextKey(key)
 }
 
 // GetKeysWithPrefixFromChild ...
@@ -260,17 +392,21 @@ func (s *TrieState) GetKeysWithPrefixFromChild(keyToChild, prefix []byte) ([][]b
 	if child == nil {
 		return nil, nil
 	}
-	return child.GetKeysWithPrefix(prefix), nil
+	return child.GetKeysWithPrefix(prefix)
 }
 
 // LoadCode returns the runtime code (located at :code)
-func (s *TrieState) LoadCode() []byte {
+func (s *TrieState) LoadCode() ([]byte, error) {
 	return s.Get(common.CodeKey)
 }
 
 // LoadCodeHash returns the hash of the runtime code (located at :code)
 func (s *TrieState) LoadCodeHash() (common.Hash, error) {
-	code := s.LoadCode()
+	code, err := s.LoadCode()
+	if err != nil {
+		return common.Hash{}, err
+	}
+
 	return common.Blake2bHash(code)
 }
 
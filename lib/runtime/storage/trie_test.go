@@ -37,7 +37,8 @@ func TestTrieState_SetGet(t *testing.T) {
 		}
 
 		for _, tc := range testCases {
-			res := ts.Get([]byte(tc))
+			res, err := ts.Get([]byte(tc))
+			require.NoError(t, err)
 			require.Equal(t, []byte(tc), res)
 		}
 	}
@@ -53,7 +54,8 @@ func TestTrieState_Delete(t *testing.T) {
 		}
 
 		ts.Delete([]byte(testCases[0]))
-		has := ts.Has([]byte(testCases[0]))
+		has, err := ts.Has([]byte(testCases[0]))
+		require.NoError(t, err)
 		require.False(t, has)
 	}
 
@@ -75,6 +77,37 @@ func TestTrieState_Root(t *testing.T) {
 	testFunc(ts)
 }
 
+func TestTrieState_RootWithVersion(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, trie.MaxInlineValueSize+1)
+
+	ts := newTestTrieState(t)
+	ts.Set([]byte("a"), long)
+	err := ts.SetChild([]byte("child"), trie.NewEmptyTrie())
+	require.NoError(t, err)
+	err = ts.SetChildStorage([]byte("child"), []byte("b"), long)
+	require.NoError(t, err)
+
+	expectedChild := trie.NewEmptyTrie()
+	expectedChild.SetVersion(trie.V1)
+	expectedChild.Put([]byte("b"), long)
+
+	expected := trie.NewEmptyTrie()
+	expected.SetVersion(trie.V1)
+	expected.Put([]byte("a"), long)
+	err = expected.PutChild([]byte("child"), expectedChild)
+	require.NoError(t, err)
+
+	// the values written by the runtime are encoded with the version the root is computed with
+	root, err := ts.RootWithVersion(trie.V1)
+	require.NoError(t, err)
+	require.Equal(t, expected.MustHash(), root)
+	require.Equal(t, root, ts.MustRoot())
+
+	childRoot, err := ts.GetChildRootWithVersion([]byte("child"), trie.V1)
+	require.NoError(t, err)
+	require.Equal(t, expectedChild.MustHash(), childRoot)
+}
+
 func TestTrieState_ClearPrefix(t *testing.T) {
 	ts := newTestTrieState(t)
 
@@ -91,7 +124,8 @@ func TestTrieState_ClearPrefix(t *testing.T) {
 	ts.ClearPrefix([]byte("noo"))
 
 	for i, key := range keys {
-		val := ts.Get([]byte(key))
+		val, err := ts.Get([]byte(key))
+		require.NoError(t, err)
 		if i < 2 {
 			require.Nil(t, val)
 		} else {
@@ -145,7 +179,8 @@ func TestTrieState_NextKey(t *testing.T) {
 	})
 
 	for i, tc := range testCases {
-		next := ts.NextKey([]byte(tc))
+		next, err := ts.NextKey([]byte(tc))
+		require.NoError(t, err)
 		if i == len(testCases)-1 {
 			require.Nil(t, next)
 		} else {
@@ -166,7 +201,8 @@ func TestTrieState_CommitStorageTransaction(t *testing.T) {
 	ts.Set([]byte(testCases[0]), testValue)
 	ts.CommitStorageTransaction()
 
-	val := ts.Get([]byte(testCases[0]))
+	val, err := ts.Get([]byte(testCases[0]))
+	require.NoError(t, err)
 	require.Equal(t, testValue, val)
 }
 
@@ -182,7 +218,8 @@ func TestTrieState_RollbackStorageTransaction(t *testing.T) {
 	ts.Set([]byte(testCases[0]), testValue)
 	ts.RollbackStorageTransaction()
 
-	val := ts.Get([]byte(testCases[0]))
+	val, err := ts.Get([]byte(testCases[0]))
+	require.NoError(t, err)
 	require.Equal(t, []byte(testCases[0]), val)
 }
 
@@ -238,3 +275,66 @@ func TestTrieState_DeleteChildLimit(t *testing.T) {
 		require.Equal(t, test.expectedDelAll, all)
 	}
 }
+
+type testCache map[string][]byte
+
+func (c testCache) Get(key []byte) (value []byte, has bool) {
+	value, has = c[string(key)]
+	return value, has
+}
+
+func (c testCache) Add(key, value []byte) {
+	c[string(key)] = value
+}
+
+func TestTrieState_Cache(t *testing.T) {
+	tr := trie.NewEmptyTrie()
+	tr.Put([]byte("cached"), []byte("value"))
+	tr.Put([]byte("prefix:key"), []byte("value"))
+
+	ts, err := NewTrieState(tr)
+	require.NoError(t, err)
+
+	cache := testCache{}
+	ts.SetCache(cache)
+
+	get := func(key string) []byte {
+		value, err := ts.Get([]byte(key))
+		require.NoError(t, err)
+		return value
+	}
+
+	// the values read from the trie are cached, including the missing ones
+	require.Equal(t, []byte("value"), get("cached"))
+	require.Nil(t, get("missing"))
+	require.Equal(t, testCache{"cached": []byte("value"), "missing": nil}, cache)
+
+	// the cached values are returned until the keys are changed
+	cache["cached"] = []byte("cached value")
+	require.Equal(t, []byte("cached value"), get("cached"))
+
+	cache["prefix:key"] = []byte("cached value")
+	ts.Set([]byte("cached"), []byte("new value"))
+	err = ts.ClearPrefix([]byte("prefix:"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("new value"), get("cached"))
+	require.Nil(t, get("prefix:key"))
+
+	// the rolled back changes are still returned as changed
+	ts.BeginStorageTransaction()
+	ts.Set([]byte("missing"), []byte("value"))
+	ts.RollbackStorageTransaction()
+	require.Nil(t, get("missing"))
+
+	err = ts.SetChild([]byte("child"), trie.NewEmptyTrie())
+	require.NoError(t, err)
+
+	keys, prefixes := ts.ChangedKeys()
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	require.Equal(t, [][]byte{
+		append(trie.ChildStorageKeyPrefix, []byte("child")...),
+		[]byte("cached"),
+		[]byte("missing"),
+	}, keys)
+	require.Equal(t, [][]byte{[]byte("prefix:")}, prefixes)
+}
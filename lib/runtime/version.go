@@ -4,6 +4,10 @@
 package runtime
 
 import (
+	"bytes"
+	"errors"
+	"io"
+
 	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
@@ -18,6 +22,7 @@ type Version interface {
 	ImplVersion() uint32
 	APIItems() []APIItem
 	TransactionVersion() uint32
+	StateVersion() uint8
 	Encode() ([]byte, error)
 }
 
@@ -86,6 +91,11 @@ func (lvd *LegacyVersionData) TransactionVersion() uint32 {
 	return 0
 }
 
+// StateVersion returns the state version, which is always 0 for legacy runtimes
+func (lvd *LegacyVersionData) StateVersion() uint8 {
+	return 0
+}
+
 type legacyVersionData struct {
 	SpecName         []byte
 	ImplName         []byte
@@ -131,6 +141,13 @@ func (lvd *LegacyVersionData) Decode(in []byte) error {
 	return nil
 }
 
+// coreAPIName is the name of the Core runtime API, as the blake2b 64 bits hash of "Core"
+var coreAPIName = [8]byte{0xdf, 0x6a, 0xcb, 0x68, 0x99, 0x07, 0x60, 0x9b}
+
+// stateVersionCoreAPIVersion is the version of the Core runtime API from which the version info
+// ends with the state version
+const stateVersionCoreAPIVersion = 4
+
 // VersionData is the runtime version info returned by v0.8 runtimes
 type VersionData struct {
 	specName           []byte
@@ -140,12 +157,13 @@ type VersionData struct {
 	implVersion        uint32
 	apiItems           []APIItem
 	transactionVersion uint32
+	stateVersion       uint8
 }
 
 // NewVersionData returns a new VersionData
 func NewVersionData(specName, implName []byte,
 	authoringVersion, specVersion, implVersion uint32,
-	apiItems []APIItem, transactionVersion uint32) *VersionData {
+	apiItems []APIItem, transactionVersion uint32, stateVersion uint8) *VersionData {
 	return &VersionData{
 		specName:           specName,
 		implName:           implName,
@@ -154,6 +172,7 @@ func NewVersionData(specName, implName []byte,
 		implVersion:        implVersion,
 		apiItems:           apiItems,
 		transactionVersion: transactionVersion,
+		stateVersion:       stateVersion,
 	}
 }
 
@@ -192,6 +211,24 @@ func (vd *VersionData) TransactionVersion() uint32 {
 	return vd.transactionVersion
 }
 
+// StateVersion returns the state version of the runtime, which determines how the values are
+// stored in the state trie
+func (vd *VersionData) StateVersion() uint8 {
+	return vd.stateVersion
+}
+
+// hasStateVersion returns true if the version info includes the state version, which is the case
+// from the version 4 of the Core runtime API
+func (vd *VersionData) hasStateVersion() bool {
+	for _, item := range vd.apiItems {
+		if item.Name == coreAPIName {
+			return item.Ver >= stateVersionCoreAPIVersion
+		}
+	}
+
+	return false
+}
+
 type versionData struct {
 	SpecName           []byte
 	ImplName           []byte
@@ -218,13 +255,18 @@ func (vd *VersionData) Encode() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if vd.hasStateVersion() {
+		enc = append(enc, vd.stateVersion)
+	}
 	return enc, nil
 }
 
 // Decode to scale decode []byte to VersionAPI struct
 func (vd *VersionData) Decode(in []byte) error {
 	var info versionData
-	err := scale.Unmarshal(in, &info)
+	r := bytes.NewReader(in)
+	err := scale.NewDecoder(r).Decode(&info)
 	if err != nil {
 		return err
 	}
@@ -236,6 +278,16 @@ func (vd *VersionData) Decode(in []byte) error {
 	vd.implVersion = info.ImplVersion
 	vd.apiItems = info.APIItems
 	vd.transactionVersion = info.TransactionVersion
+	vd.stateVersion = 0
 
-	return nil
+	if !vd.hasStateVersion() {
+		return nil
+	}
+
+	// the state version ends the version info of runtimes with the version 4 of the Core runtime API
+	vd.stateVersion, err = r.ReadByte()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
 }
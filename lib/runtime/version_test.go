@@ -23,6 +23,7 @@ func TestVersionData(t *testing.T) {
 		0,
 		[]APIItem{testAPIItem},
 		5,
+		0,
 	)
 
 	b, err := version.Encode()
@@ -34,6 +35,43 @@ func TestVersionData(t *testing.T) {
 	require.Equal(t, version, dec)
 }
 
+func TestVersionData_StateVersion(t *testing.T) {
+	coreAPIItem := APIItem{
+		Name: coreAPIName,
+		Ver:  stateVersionCoreAPIVersion,
+	}
+
+	version := NewVersionData(
+		[]byte("polkadot"),
+		[]byte("parity-polkadot"),
+		0,
+		9160,
+		0,
+		[]APIItem{coreAPIItem},
+		11,
+		1,
+	)
+
+	b, err := version.Encode()
+	require.NoError(t, err)
+	require.Equal(t, byte(1), b[len(b)-1])
+
+	dec := new(VersionData)
+	err = dec.Decode(b)
+	require.NoError(t, err)
+	require.Equal(t, version, dec)
+	require.Equal(t, uint8(1), dec.StateVersion())
+
+	// the state version is only part of the version info from the version 4 of the Core runtime API
+	coreAPIItem.Ver = stateVersionCoreAPIVersion - 1
+	b, err = NewVersionData(nil, nil, 0, 0, 0, []APIItem{coreAPIItem}, 0, 0).Encode()
+	require.NoError(t, err)
+
+	err = dec.Decode(append(b, 1))
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), dec.StateVersion())
+}
+
 func TestLegacyVersionData(t *testing.T) {
 	testAPIItem := APIItem{
 		Name: [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/pkg/scale"
@@ -96,6 +97,30 @@ func (in *Instance) GrandpaAuthorities() ([]types.Authority, error) {
 	return types.GrandpaAuthoritiesRawToAuthorities(gar)
 }
 
+// AuthorityDiscoveryAuthorities returns the authority discovery keys of the current authority set
+func (in *Instance) AuthorityDiscoveryAuthorities() ([]*sr25519.PublicKey, error) {
+	ret, err := in.exec(runtime.AuthorityDiscoveryAPIAuthorities, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][sr25519.PublicKeyLength]byte
+	err = scale.Unmarshal(ret, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	authorities := make([]*sr25519.PublicKey, len(keys))
+	for i, key := range keys {
+		authorities[i], err = sr25519.NewPublicKey(key[:])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return authorities, nil
+}
+
 // InitializeBlock calls runtime API function Core_initialise_block
 func (in *Instance) InitializeBlock(header *types.Header) error {
 	encodedHeader, err := scale.Marshal(*header)
@@ -16,7 +16,9 @@ import (
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/crypto/ed25519"
+	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/genesis"
+	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/lib/runtime/wasmer/testdata"
@@ -77,6 +79,7 @@ func TestInstance_Version_NodeRuntime_v098(t *testing.T) {
 		0,
 		nil,
 		2,
+		0,
 	)
 
 	instance := NewTestInstance(t, runtime.NODE_RUNTIME_v098)
@@ -109,6 +112,7 @@ func TestInstance_Version_PolkadotRuntime_v0910(t *testing.T) {
 		0,
 		nil,
 		8,
+		0,
 	)
 
 	instance := NewTestInstance(t, runtime.POLKADOT_RUNTIME_v0910)
@@ -140,6 +144,7 @@ func TestInstance_Version_PolkadotRuntime(t *testing.T) {
 		0,
 		nil,
 		5,
+		0,
 	)
 
 	instance := NewTestInstance(t, runtime.POLKADOT_RUNTIME)
@@ -192,6 +197,7 @@ func TestInstance_Version_KusamaRuntime(t *testing.T) {
 		0,
 		nil,
 		0,
+		0,
 	)
 
 	version, err := instance.(*Instance).Version()
@@ -222,6 +228,7 @@ func TestInstance_Version_NodeRuntime(t *testing.T) {
 		0,
 		nil,
 		2,
+		0,
 	)
 
 	instance := NewTestInstance(t, runtime.NODE_RUNTIME)
@@ -254,6 +261,7 @@ func TestInstance_Version_DevRuntime(t *testing.T) {
 		0,
 		nil,
 		1,
+		0,
 	)
 
 	instance := NewTestInstance(t, runtime.DEV_RUNTIME)
@@ -373,6 +381,39 @@ func TestInstance_GrandpaAuthorities_NodeRuntime(t *testing.T) {
 	require.Equal(t, expected, auths)
 }
 
+func TestInstance_AuthorityDiscoveryAuthorities_NodeRuntime(t *testing.T) {
+	tt := trie.NewEmptyTrie()
+
+	kr, err := keystore.NewSr25519Keyring()
+	require.NoError(t, err)
+
+	keys := [][sr25519.PublicKeyLength]byte{}
+	for _, kp := range []*sr25519.Keypair{kr.Alice().(*sr25519.Keypair), kr.Bob().(*sr25519.Keypair)} {
+		var key [sr25519.PublicKeyLength]byte
+		copy(key[:], kp.Public().Encode())
+		keys = append(keys, key)
+	}
+
+	value, err := scale.Marshal(keys)
+	require.NoError(t, err)
+
+	palletKey, err := common.Twox128Hash([]byte("AuthorityDiscovery"))
+	require.NoError(t, err)
+	storageKey, err := common.Twox128Hash([]byte("Keys"))
+	require.NoError(t, err)
+
+	tt.Put(append(palletKey, storageKey...), value)
+
+	rt := NewTestInstanceWithTrie(t, runtime.NODE_RUNTIME, tt, log.Info)
+
+	auths, err := rt.AuthorityDiscoveryAuthorities()
+	require.NoError(t, err)
+
+	require.Len(t, auths, 2)
+	require.Equal(t, kr.Alice().Public().Encode(), auths[0].Encode())
+	require.Equal(t, kr.Bob().Public().Encode(), auths[1].Encode())
+}
+
 func TestInstance_GrandpaAuthorities_PolkadotRuntime(t *testing.T) {
 	tt := trie.NewEmptyTrie()
 
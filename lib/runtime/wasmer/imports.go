@@ -36,6 +36,9 @@ package wasmer
 // extern int32_t ext_trie_blake2_256_root_version_1(void *context, int64_t a);
 // extern int32_t ext_trie_blake2_256_ordered_root_version_1(void *context, int64_t a);
 // extern int32_t ext_trie_blake2_256_verify_proof_version_1(void *context, int32_t a, int64_t b, int64_t c, int64_t d);
+// extern int32_t ext_trie_blake2_256_root_version_2(void *context, int64_t a, int32_t b);
+// extern int32_t ext_trie_blake2_256_ordered_root_version_2(void *context, int64_t a, int32_t b);
+// extern int32_t ext_trie_blake2_256_verify_proof_version_2(void *context, int32_t a, int64_t b, int64_t c, int64_t d, int32_t e);
 //
 // extern int64_t ext_misc_runtime_version_version_1(void *context, int64_t a);
 // extern void ext_misc_print_hex_version_1(void *context, int64_t a);
@@ -47,6 +50,7 @@ package wasmer
 // extern int64_t ext_default_child_storage_next_key_version_1(void *context, int64_t a, int64_t b);
 // extern int64_t ext_default_child_storage_read_version_1(void *context, int64_t a, int64_t b, int64_t c, int32_t d);
 // extern int64_t ext_default_child_storage_root_version_1(void *context, int64_t a);
+// extern int64_t ext_default_child_storage_root_version_2(void *context, int64_t a, int32_t b);
 // extern void ext_default_child_storage_set_version_1(void *context, int64_t a, int64_t b, int64_t c);
 // extern void ext_default_child_storage_storage_kill_version_1(void *context, int64_t a);
 // extern int32_t ext_default_child_storage_storage_kill_version_2(void *context, int64_t a, int64_t b);
@@ -91,6 +95,7 @@ package wasmer
 // extern int64_t ext_storage_read_version_1(void *context, int64_t a, int64_t b, int32_t c);
 // extern void ext_storage_rollback_transaction_version_1(void *context);
 // extern int64_t ext_storage_root_version_1(void *context);
+// extern int64_t ext_storage_root_version_2(void *context, int32_t a);
 // extern void ext_storage_set_version_1(void *context, int64_t a, int64_t b);
 // extern void ext_storage_start_transaction_version_1(void *context);
 //
@@ -782,13 +787,32 @@ func ext_crypto_finish_batch_verify_version_1(context unsafe.Pointer) C.int32_t
 //export ext_trie_blake2_256_root_version_1
 func ext_trie_blake2_256_root_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
 	logger.Debug("[ext_trie_blake2_256_root_version_1] executing...")
+	return trieRoot(context, dataSpan, trie.V0, "ext_trie_blake2_256_root_version_1")
+}
+
+//export ext_trie_blake2_256_root_version_2
+func ext_trie_blake2_256_root_version_2(context unsafe.Pointer, dataSpan C.int64_t, version C.int32_t) C.int32_t {
+	logger.Debug("[ext_trie_blake2_256_root_version_2] executing...")
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("[ext_trie_blake2_256_root_version_2]: %s", err)
+		return 0
+	}
+
+	return trieRoot(context, dataSpan, stateVersion, "ext_trie_blake2_256_root_version_2")
+}
 
+// trieRoot computes the root of the trie made of the SCALE encoded (key, value) tuples at dataSpan,
+// with the given state version, and returns a pointer to it.
+func trieRoot(context unsafe.Pointer, dataSpan C.int64_t, version trie.Version, name string) C.int32_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
 	data := asMemorySlice(instanceContext, dataSpan)
 
 	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
 
 	type kv struct {
 		Key, Value []byte
@@ -797,28 +821,31 @@ func ext_trie_blake2_256_root_version_1(context unsafe.Pointer, dataSpan C.int64
 	// this function is expecting an array of (key, value) tuples
 	var kvs []kv
 	if err := scale.Unmarshal(data, &kvs); err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
 	for _, kv := range kvs {
-		t.Put(kv.Key, kv.Value)
+		if err := t.Put(kv.Key, kv.Value); err != nil {
+			logger.Errorf("[%s]: %s", name, err)
+			return 0
+		}
 	}
 
 	// allocate memory for value and copy value to memory
 	ptr, err := runtimeCtx.Allocator.Allocate(32)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
 	hash, err := t.Hash()
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
-	logger.Debugf("[ext_trie_blake2_256_root_version_1]: root hash is %s", hash)
+	logger.Debugf("[%s]: root hash is %s", name, hash)
 	copy(memory[ptr:ptr+32], hash[:])
 	return C.int32_t(ptr)
 }
@@ -826,47 +853,67 @@ func ext_trie_blake2_256_root_version_1(context unsafe.Pointer, dataSpan C.int64
 //export ext_trie_blake2_256_ordered_root_version_1
 func ext_trie_blake2_256_ordered_root_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
 	logger.Debug("[ext_trie_blake2_256_ordered_root_version_1] executing...")
+	return trieOrderedRoot(context, dataSpan, trie.V0, "ext_trie_blake2_256_ordered_root_version_1")
+}
+
+//export ext_trie_blake2_256_ordered_root_version_2
+func ext_trie_blake2_256_ordered_root_version_2(context unsafe.Pointer, dataSpan C.int64_t, version C.int32_t) C.int32_t {
+	logger.Debug("[ext_trie_blake2_256_ordered_root_version_2] executing...")
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_2]: %s", err)
+		return 0
+	}
+
+	return trieOrderedRoot(context, dataSpan, stateVersion, "ext_trie_blake2_256_ordered_root_version_2")
+}
 
+// trieOrderedRoot computes the root of the trie made of the SCALE encoded values at dataSpan, keyed by
+// their SCALE encoded index, with the given state version, and returns a pointer to it.
+func trieOrderedRoot(context unsafe.Pointer, dataSpan C.int64_t, version trie.Version, name string) C.int32_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
 	data := asMemorySlice(instanceContext, dataSpan)
 
 	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
 	var values [][]byte
 	err := scale.Unmarshal(data, &values)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
 	for i, val := range values {
 		key, err := scale.Marshal(big.NewInt(int64(i)))
 		if err != nil {
-			logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+			logger.Errorf("[%s]: %s", name, err)
 			return 0
 		}
-		logger.Tracef(
-			"[ext_trie_blake2_256_ordered_root_version_1] put key=0x%x and value=0x%x",
-			key, val)
+		logger.Tracef("[%s] put key=0x%x and value=0x%x", name, key, val)
 
-		t.Put(key, val)
+		if err = t.Put(key, val); err != nil {
+			logger.Errorf("[%s]: %s", name, err)
+			return 0
+		}
 	}
 
 	// allocate memory for value and copy value to memory
 	ptr, err := runtimeCtx.Allocator.Allocate(32)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
 	hash, err := t.Hash()
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("[%s]: %s", name, err)
 		return 0
 	}
 
-	logger.Debugf("[ext_trie_blake2_256_ordered_root_version_1]: root hash is %s", hash)
+	logger.Debugf("[%s]: root hash is %s", name, hash)
 	copy(memory[ptr:ptr+32], hash[:])
 	return C.int32_t(ptr)
 }
@@ -905,6 +952,20 @@ func ext_trie_blake2_256_verify_proof_version_1(context unsafe.Pointer, rootSpan
 	return result
 }
 
+//export ext_trie_blake2_256_verify_proof_version_2
+func ext_trie_blake2_256_verify_proof_version_2(context unsafe.Pointer, rootSpan C.int32_t, proofSpan, keySpan, valueSpan C.int64_t, version C.int32_t) C.int32_t {
+	logger.Debug("[ext_trie_blake2_256_verify_proof_version_2] executing...")
+
+	// the encoding of the proof nodes tells how they store their values, whatever the state version
+	_, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("[ext_trie_blake2_256_verify_proof_version_2]: %s", err)
+		return C.int32_t(0)
+	}
+
+	return ext_trie_blake2_256_verify_proof_version_1(context, rootSpan, proofSpan, keySpan, valueSpan)
+}
+
 //export ext_misc_print_hex_version_1
 func ext_misc_print_hex_version_1(context unsafe.Pointer, dataSpan C.int64_t) {
 	logger.Trace("[ext_misc_print_hex_version_1] executing...")
@@ -1127,6 +1188,34 @@ func ext_default_child_storage_root_version_1(context unsafe.Pointer, childStora
 	return C.int64_t(root)
 }
 
+//export ext_default_child_storage_root_version_2
+func ext_default_child_storage_root_version_2(context unsafe.Pointer, childStorageKey C.int64_t, version C.int32_t) C.int64_t {
+	logger.Debug("[ext_default_child_storage_root_version_2] executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	storage := instanceContext.Data().(*runtime.Context).Storage
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("[ext_default_child_storage_root_version_2]: %s", err)
+		return 0
+	}
+
+	childRoot, err := storage.GetChildRootWithVersion(asMemorySlice(instanceContext, childStorageKey), stateVersion)
+	if err != nil {
+		logger.Errorf("[ext_default_child_storage_root_version_2] failed to get child root: %s", err)
+		return 0
+	}
+
+	root, err := toWasmMemoryOptional(instanceContext, childRoot[:])
+	if err != nil {
+		logger.Errorf("[ext_default_child_storage_root_version_2] failed to allocate: %s", err)
+		return 0
+	}
+
+	return C.int64_t(root)
+}
+
 //export ext_default_child_storage_set_version_1
 func ext_default_child_storage_set_version_1(context unsafe.Pointer, childStorageKeySpan, keySpan, valueSpan C.int64_t) {
 	logger.Debug("[ext_default_child_storage_set_version_1] executing...")
@@ -1158,7 +1247,10 @@ func ext_default_child_storage_storage_kill_version_1(context unsafe.Pointer, ch
 	storage := ctx.Storage
 
 	childStorageKey := asMemorySlice(instanceContext, childStorageKeySpan)
-	storage.DeleteChild(childStorageKey)
+	err := storage.DeleteChild(childStorageKey)
+	if err != nil {
+		logger.Errorf("[ext_default_child_storage_storage_kill_version_1]: %s", err)
+	}
 }
 
 //export ext_default_child_storage_storage_kill_version_2
@@ -1779,7 +1871,10 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 
 	// this function assumes the item in storage is a SCALE encoded array of items
 	// the valueToAppend is a new item, so it appends the item and increases the length prefix by 1
-	valueCurr := storage.Get(key)
+	valueCurr, err := storage.Get(key)
+	if err != nil {
+		return err
+	}
 
 	if len(valueCurr) == 0 {
 		valueRes = valueToAppend
@@ -1789,8 +1884,7 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 		if err != nil {
 			logger.Tracef(
 				"[ext_storage_append_version_1] item in storage is not SCALE encoded, overwriting at key 0x%x", key)
-			storage.Set(key, append([]byte{4}, valueToAppend...))
-			return nil
+			return storage.Set(key, append([]byte{4}, valueToAppend...))
 		}
 
 		lengthBytes, err := scale.Marshal(currLength)
@@ -1814,8 +1908,7 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 	// append new length prefix to start of items array
 	lengthEnc = append(lengthEnc, valueRes...)
 	logger.Debugf("[ext_storage_append_version_1] resulting value: 0x%x", lengthEnc)
-	storage.Set(key, lengthEnc)
-	return nil
+	return storage.Set(key, lengthEnc)
 }
 
 //export ext_storage_append_version_1
@@ -1866,7 +1959,10 @@ func ext_storage_clear_version_1(context unsafe.Pointer, keySpan C.int64_t) {
 	key := asMemorySlice(instanceContext, keySpan)
 
 	logger.Debugf("[ext_storage_clear_version_1] key: 0x%x", key)
-	storage.Delete(key)
+	err := storage.Delete(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_clear_version_1]: %s", err)
+	}
 }
 
 //export ext_storage_clear_prefix_version_1
@@ -1912,7 +2008,13 @@ func ext_storage_clear_prefix_version_2(context unsafe.Pointer, prefixSpan, lim
 	}
 
 	limitUint := binary.LittleEndian.Uint32(limit)
-	numRemoved, all := storage.ClearPrefixLimit(prefix, limitUint)
+	numRemoved, all, err := storage.ClearPrefixLimit(prefix, limitUint)
+	if err != nil {
+		logger.Errorf("[ext_storage_clear_prefix_version_2]: %s", err)
+		ret, _ := toWasmMemory(instanceContext, nil)
+		return C.int64_t(ret)
+	}
+
 	encBytes, err := toKillStorageResultEnum(all, numRemoved)
 	if err != nil {
 		logger.Errorf("[ext_storage_clear_prefix_version_2] failed to allocate memory: %s", err)
@@ -1939,7 +2041,12 @@ func ext_storage_exists_version_1(context unsafe.Pointer, keySpan C.int64_t) C.i
 	key := asMemorySlice(instanceContext, keySpan)
 	logger.Debugf("[ext_storage_exists_version_1] key: 0x%x", key)
 
-	val := storage.Get(key)
+	val, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_exists_version_1]: %s", err)
+		return 0
+	}
+
 	if len(val) > 0 {
 		return 1
 	}
@@ -1957,7 +2064,12 @@ func ext_storage_get_version_1(context unsafe.Pointer, keySpan C.int64_t) C.int6
 	key := asMemorySlice(instanceContext, keySpan)
 	logger.Debugf("[ext_storage_get_version_1] key: 0x%x", key)
 
-	value := storage.Get(key)
+	value, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_get_version_1]: %s", err)
+		ptr, _ := toWasmMemoryOptional(instanceContext, nil)
+		return C.int64_t(ptr)
+	}
 	logger.Debugf("[ext_storage_get_version_1] value: 0x%x", value)
 
 	valueSpan, err := toWasmMemoryOptional(instanceContext, value)
@@ -1979,7 +2091,11 @@ func ext_storage_next_key_version_1(context unsafe.Pointer, keySpan C.int64_t) C
 
 	key := asMemorySlice(instanceContext, keySpan)
 
-	next := storage.NextKey(key)
+	next, err := storage.NextKey(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_next_key_version_1]: %s", err)
+		return 0
+	}
 	logger.Debugf(
 		"[ext_storage_next_key_version_1] key: 0x%x; next key 0x%x",
 		key, next)
@@ -2002,7 +2118,12 @@ func ext_storage_read_version_1(context unsafe.Pointer, keySpan, valueOut C.int6
 	memory := instanceContext.Memory().Data()
 
 	key := asMemorySlice(instanceContext, keySpan)
-	value := storage.Get(key)
+	value, err := storage.Get(key)
+	if err != nil {
+		logger.Errorf("[ext_storage_read_version_1]: %s", err)
+		ret, _ := toWasmMemoryOptional(instanceContext, nil)
+		return C.int64_t(ret)
+	}
 	logger.Debugf(
 		"[ext_storage_read_version_1] key 0x%x has value 0x%x",
 		key, value)
@@ -2055,6 +2176,36 @@ func ext_storage_root_version_1(context unsafe.Pointer) C.int64_t {
 	return C.int64_t(rootSpan)
 }
 
+//export ext_storage_root_version_2
+func ext_storage_root_version_2(context unsafe.Pointer, version C.int32_t) C.int64_t {
+	logger.Trace("[ext_storage_root_version_2] executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	storage := instanceContext.Data().(*runtime.Context).Storage
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("[ext_storage_root_version_2]: %s", err)
+		return 0
+	}
+
+	root, err := storage.RootWithVersion(stateVersion)
+	if err != nil {
+		logger.Errorf("[ext_storage_root_version_2] failed to get storage root: %s", err)
+		return 0
+	}
+
+	logger.Debugf("[ext_storage_root_version_2] root hash is: %s", root)
+
+	rootSpan, err := toWasmMemory(instanceContext, root[:])
+	if err != nil {
+		logger.Errorf("[ext_storage_root_version_2] failed to allocate: %s", err)
+		return 0
+	}
+
+	return C.int64_t(rootSpan)
+}
+
 //export ext_storage_set_version_1
 func ext_storage_set_version_1(context unsafe.Pointer, keySpan, valueSpan C.int64_t) {
 	logger.Trace("[ext_storage_set_version_1] executing...")
@@ -2072,7 +2223,10 @@ func ext_storage_set_version_1(context unsafe.Pointer, keySpan, valueSpan C.int6
 	logger.Debugf(
 		"[ext_storage_set_version_1] key 0x%x has value 0x%x",
 		key, value)
-	storage.Set(key, cp)
+	err := storage.Set(key, cp)
+	if err != nil {
+		logger.Errorf("[ext_storage_set_version_1]: %s", err)
+	}
 }
 
 //export ext_storage_start_transaction_version_1
@@ -2329,6 +2483,10 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_default_child_storage_root_version_2", ext_default_child_storage_root_version_2, C.ext_default_child_storage_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_default_child_storage_set_version_1", ext_default_child_storage_set_version_1, C.ext_default_child_storage_set_version_1)
 	if err != nil {
 		return nil, err
@@ -2530,6 +2688,10 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_storage_root_version_2", ext_storage_root_version_2, C.ext_storage_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_storage_set_version_1", ext_storage_set_version_1, C.ext_storage_set_version_1)
 	if err != nil {
 		return nil, err
@@ -2543,14 +2705,26 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_trie_blake2_256_ordered_root_version_2", ext_trie_blake2_256_ordered_root_version_2, C.ext_trie_blake2_256_ordered_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_trie_blake2_256_root_version_1", ext_trie_blake2_256_root_version_1, C.ext_trie_blake2_256_root_version_1)
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_trie_blake2_256_root_version_2", ext_trie_blake2_256_root_version_2, C.ext_trie_blake2_256_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_trie_blake2_256_verify_proof_version_1", ext_trie_blake2_256_verify_proof_version_1, C.ext_trie_blake2_256_verify_proof_version_1)
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_trie_blake2_256_verify_proof_version_2", ext_trie_blake2_256_verify_proof_version_2, C.ext_trie_blake2_256_verify_proof_version_2)
+	if err != nil {
+		return nil, err
+	}
 
 	_, err = imports.Append("ext_transaction_index_index_version_1", ext_transaction_index_index_version_1, C.ext_transaction_index_index_version_1)
 	if err != nil {
@@ -209,7 +209,8 @@ func Test_ext_storage_clear_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_clear_version_1", enc)
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 }
 
@@ -394,10 +395,12 @@ func Test_ext_storage_clear_prefix_version_1_hostAPI(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_clear_prefix_version_1", enc)
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 
-	val = inst.ctx.Storage.Get(testkey2)
+	val, err = inst.ctx.Storage.Get(testkey2)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 }
 
@@ -417,10 +420,12 @@ func Test_ext_storage_clear_prefix_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_clear_prefix_version_1", enc)
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 
-	val = inst.ctx.Storage.Get(testkey2)
+	val, err = inst.ctx.Storage.Get(testkey2)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 }
 
@@ -471,10 +476,12 @@ func Test_ext_storage_clear_prefix_version_2(t *testing.T) {
 	expectedAllDeleted = 1
 	require.Equal(t, expectedAllDeleted, decVal[0])
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 
-	val = inst.ctx.Storage.Get(testkey5)
+	val, err = inst.ctx.Storage.Get(testkey5)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 	require.Equal(t, testValue5, val)
 
@@ -489,10 +496,12 @@ func Test_ext_storage_clear_prefix_version_2(t *testing.T) {
 	expectedAllDeleted = 0
 	require.Equal(t, expectedAllDeleted, decVal[0])
 
-	val = inst.ctx.Storage.Get(testkey)
+	val, err = inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Nil(t, val)
 
-	val = inst.ctx.Storage.Get(testkey5)
+	val, err = inst.ctx.Storage.Get(testkey5)
+	require.NoError(t, err)
 	require.NotNil(t, val)
 	require.Equal(t, testValue5, val)
 }
@@ -683,7 +692,8 @@ func Test_ext_storage_set_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_set_version_1", append(encKey, encValue...))
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Equal(t, testvalue, val)
 }
 
@@ -1501,7 +1511,9 @@ func Test_ext_default_child_storage_storage_kill_version_2_limit_all(t *testing.
 
 	child, err = inst.ctx.Storage.GetChild(testChildKey)
 	require.NoError(t, err)
-	require.Equal(t, 0, len(child.Entries()))
+	entries, err := child.Entries()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(entries))
 }
 
 func Test_ext_default_child_storage_storage_kill_version_2_limit_1(t *testing.T) {
@@ -1535,7 +1547,9 @@ func Test_ext_default_child_storage_storage_kill_version_2_limit_1(t *testing.T)
 
 	child, err = inst.ctx.Storage.GetChild(testChildKey)
 	require.NoError(t, err)
-	require.Equal(t, 1, len(child.Entries()))
+	entries, err := child.Entries()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
 }
 
 func Test_ext_default_child_storage_storage_kill_version_2_limit_none(t *testing.T) {
@@ -1643,7 +1657,8 @@ func Test_ext_storage_append_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey, doubleEncVal...))
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Equal(t, encArr, val)
 
 	encValueAppend, err := scale.Marshal(testvalueAppend)
@@ -1654,7 +1669,8 @@ func Test_ext_storage_append_version_1(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey, doubleEncValueAppend...))
 	require.NoError(t, err)
 
-	ret := inst.ctx.Storage.Get(testkey)
+	ret, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.NotNil(t, ret)
 
 	var res [][]byte
@@ -1693,7 +1709,8 @@ func Test_ext_storage_append_version_1_again(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey, doubleEncVal...))
 	require.NoError(t, err)
 
-	val := inst.ctx.Storage.Get(testkey)
+	val, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.Equal(t, encArr, val)
 
 	encValueAppend, err := scale.Marshal(testvalueAppend)
@@ -1704,7 +1721,8 @@ func Test_ext_storage_append_version_1_again(t *testing.T) {
 	_, err = inst.Exec("rtm_ext_storage_append_version_1", append(encKey, doubleEncValueAppend...))
 	require.NoError(t, err)
 
-	ret := inst.ctx.Storage.Get(testkey)
+	ret, err := inst.ctx.Storage.Get(testkey)
+	require.NoError(t, err)
 	require.NotNil(t, ret)
 
 	var res [][]byte
@@ -55,7 +55,11 @@ func NewRuntimeFromGenesis(cfg *Config) (runtime.Instance, error) {
 		return nil, errors.New("storage is nil")
 	}
 
-	code := cfg.Storage.LoadCode()
+	code, err := cfg.Storage.LoadCode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load :code from state: %w", err)
+	}
+
 	if len(code) == 0 {
 		return nil, fmt.Errorf("cannot find :code in state")
 	}
@@ -66,7 +70,11 @@ func NewRuntimeFromGenesis(cfg *Config) (runtime.Instance, error) {
 
 // NewInstanceFromTrie returns a new runtime instance with the code provided in the given trie
 func NewInstanceFromTrie(t *trie.Trie, cfg *Config) (*Instance, error) {
-	code := t.Get(common.CodeKey)
+	code, err := t.Get(common.CodeKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load :code from trie: %w", err)
+	}
+
 	if len(code) == 0 {
 		return nil, fmt.Errorf("cannot find :code in trie")
 	}
@@ -152,6 +160,7 @@ func NewInstance(code []byte, cfg *Config) (*Instance, error) {
 	}
 
 	inst.version, _ = inst.Version()
+	inst.setStorageVersion()
 	return inst, nil
 }
 
@@ -175,6 +184,7 @@ func (in *Instance) UpdateRuntimeCode(code []byte) error {
 		return err
 	}
 
+	in.setStorageVersion()
 	return nil
 }
 
@@ -239,6 +249,23 @@ func (in *Instance) SetContextStorage(s runtime.Storage) {
 	in.Lock()
 	defer in.Unlock()
 	in.ctx.Storage = s
+	in.setStorageVersion()
+}
+
+// setStorageVersion sets the state version of the runtime's storage to the state version of the runtime,
+// so that the values written by the runtime are stored as the runtime expects them to be.
+func (in *Instance) setStorageVersion() {
+	if in.ctx.Storage == nil || in.version == nil {
+		return
+	}
+
+	version, err := trie.ParseVersion(uint32(in.version.StateVersion()))
+	if err != nil {
+		logger.Errorf("cannot set storage state version: %s", err)
+		return
+	}
+
+	in.ctx.Storage.SetVersion(version)
 }
 
 // Stop func
@@ -53,6 +53,7 @@ func TestInstance_CheckRuntimeVersion(t *testing.T) {
 		0,
 		nil,
 		5,
+		0,
 	)
 
 	require.Equal(t, 12, len(version.APIItems()))
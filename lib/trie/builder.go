@@ -0,0 +1,139 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/ChainSafe/chaindb"
+)
+
+// ErrUnsortedKey is returned by Builder.Put for a key which isn't greater than the key of the previous entry
+var ErrUnsortedKey = errors.New("key is not greater than the previous key")
+
+// Builder builds a trie from entries put in lexicographic order of their keys. The nodes are written to
+// a database batch as soon as the later entries can't change them, and are then replaced by stubs holding
+// their hash, so only the nodes along the path of the last entry are kept in memory.
+type Builder struct {
+	trie    *Trie
+	batch   chaindb.Batch
+	lastKey []byte
+	entries int
+}
+
+// NewBuilder returns a builder writing the nodes of the trie to the given batch
+func NewBuilder(batch chaindb.Batch) *Builder {
+	return &Builder{
+		trie:  NewEmptyTrie(),
+		batch: batch,
+	}
+}
+
+// Put inserts the entry with its value encoded with the given state version. The key must be greater than the
+// key of the previous entry.
+func (b *Builder) Put(key, value []byte, version Version) error {
+	if b.entries > 0 && bytes.Compare(key, b.lastKey) <= 0 {
+		return fmt.Errorf("%w: 0x%x after 0x%x", ErrUnsortedKey, key, b.lastKey)
+	}
+
+	if err := b.trie.writeCompleted(b.batch, b.trie.root, keyToNibbles(key)); err != nil {
+		return err
+	}
+
+	if err := b.trie.put(key, value, version); err != nil {
+		return err
+	}
+
+	b.lastKey = key
+	b.entries++
+	return nil
+}
+
+// Root writes the remaining nodes of the trie to the batch and returns the root hash of the trie
+func (b *Builder) Root() (common.Hash, error) {
+	root, err := b.trie.Hash()
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if err = b.trie.writeDirty(b.batch, b.trie.root); err != nil {
+		return common.Hash{}, err
+	}
+
+	return root, nil
+}
+
+// writeCompleted writes the subtries of curr which can't change anymore once the given key is inserted, since
+// the keys are inserted in lexicographic order, and replaces them by stubs. The key is given as nibbles.
+func (t *Trie) writeCompleted(batch chaindb.Batch, curr node, key []byte) error {
+	// a leaf becomes a branch if the key is below it, or is split along with the partial key of its parent
+	b, ok := curr.(*branch)
+	if !ok {
+		return nil
+	}
+
+	length := lenCommonPrefix(b.key, key)
+	if length == len(b.key) {
+		if length == len(key) {
+			return nil
+		}
+
+		// the children before the one of the key only hold lower keys
+		idx := key[length]
+		for i := byte(0); i < idx; i++ {
+			stub, err := t.writeStub(batch, b.children[i])
+			if err != nil {
+				return err
+			}
+			b.children[i] = stub
+		}
+
+		return t.writeCompleted(batch, b.children[idx], key[length+1:])
+	}
+
+	// the key is lower than the keys of the branch, which can only happen for unsorted keys
+	if length == len(key) || key[length] < b.key[length] {
+		return nil
+	}
+
+	// the branch only holds lower keys, but its partial key is split when the key is inserted
+	for i, child := range b.children {
+		stub, err := t.writeStub(batch, child)
+		if err != nil {
+			return err
+		}
+		b.children[i] = stub
+	}
+
+	return nil
+}
+
+// writeStub writes the node and its dirty descendants to the batch, and returns a stub holding its hash
+func (t *Trie) writeStub(batch chaindb.Batch, n node) (node, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	if l, ok := n.(*leaf); ok && l.stub {
+		return n, nil
+	}
+
+	if err := t.writeDirty(batch, n); err != nil {
+		return nil, err
+	}
+
+	_, hash, err := n.encodeAndHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &leaf{
+		hash: hash,
+		stub: true,
+	}, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countLoadedNodes returns the number of nodes of the trie which aren't stubs
+func countLoadedNodes(n node) int {
+	switch n := n.(type) {
+	case *leaf:
+		if n.stub {
+			return 0
+		}
+		return 1
+	case *branch:
+		count := 1
+		for _, child := range n.children {
+			if child != nil {
+				count += countLoadedNodes(child)
+			}
+		}
+		return count
+	default:
+		return 0
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	expected, tests := newStoredTestTrie(t, 1000)
+	expected.SetVersion(V1)
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+	err := expected.Put([]byte{}, long)
+	require.NoError(t, err)
+
+	entries, err := expected.Entries()
+	require.NoError(t, err)
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	db := newTestDB(t)
+	batch := db.NewBatch()
+	builder := NewBuilder(batch)
+	for _, k := range keys {
+		version := V0
+		if k == "" {
+			version = V1
+		}
+
+		err = builder.Put([]byte(k), entries[k], version)
+		require.NoError(t, err)
+	}
+
+	// only the nodes along the path of the last key are kept in memory
+	require.Less(t, countLoadedNodes(builder.trie.root), 20)
+
+	root, err := builder.Root()
+	require.NoError(t, err)
+	require.Equal(t, expected.MustHash(), root)
+
+	err = batch.Flush()
+	require.NoError(t, err)
+
+	loaded := NewEmptyTrie()
+	err = loaded.Load(db, root)
+	require.NoError(t, err)
+	requireSameEntries(t, expected, loaded)
+
+	value, err := loaded.Get(tests[0].key)
+	require.NoError(t, err)
+	require.Equal(t, tests[0].value, value)
+}
+
+func TestBuilder_UnsortedKey(t *testing.T) {
+	builder := NewBuilder(newTestDB(t).NewBatch())
+
+	err := builder.Put([]byte("b"), []byte("value"), V0)
+	require.NoError(t, err)
+
+	err = builder.Put([]byte("a"), []byte("value"), V0)
+	require.ErrorIs(t, err, ErrUnsortedKey)
+
+	err = builder.Put([]byte("b"), []byte("value"), V0)
+	require.ErrorIs(t, err, ErrUnsortedKey)
+}
+
+func TestBuilder_Empty(t *testing.T) {
+	root, err := NewBuilder(newTestDB(t).NewBatch()).Root()
+	require.NoError(t, err)
+	require.Equal(t, EmptyHash, root)
+}
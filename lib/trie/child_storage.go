@@ -22,7 +22,13 @@ func (t *Trie) PutChild(keyToChild []byte, child *Trie) error {
 	key := append(ChildStorageKeyPrefix, keyToChild...)
 	value := [32]byte(childHash)
 
-	t.Put(key, value[:])
+	if err = t.Put(key, value[:]); err != nil {
+		return err
+	}
+
+	t.childLock.Lock()
+	defer t.childLock.Unlock()
+
 	t.childTries[childHash] = child
 	return nil
 }
@@ -30,14 +36,42 @@ func (t *Trie) PutChild(keyToChild []byte, child *Trie) error {
 // GetChild returns the child trie at key :child_storage:[keyToChild]
 func (t *Trie) GetChild(keyToChild []byte) (*Trie, error) {
 	key := append(ChildStorageKeyPrefix, keyToChild...)
-	childHash := t.Get(key)
+	childHash, err := t.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
 	if childHash == nil {
 		return nil, fmt.Errorf("child trie does not exist at key %s%s", ChildStorageKeyPrefix, keyToChild)
 	}
 
 	hash := [32]byte{}
 	copy(hash[:], childHash)
-	return t.childTries[common.Hash(hash)], nil
+
+	t.childLock.RLock()
+	child, has := t.childTries[common.Hash(hash)]
+	t.childLock.RUnlock()
+	if has || !t.IsLazy() {
+		return child, nil
+	}
+
+	// the child trie is loaded from the database of the lazy trie, and kept so that it can be written to
+	child, err = NewLazyTrie(t.db, common.Hash(hash), t.cache)
+	if err != nil {
+		return nil, err
+	}
+
+	child.version = t.version
+
+	t.childLock.Lock()
+	defer t.childLock.Unlock()
+
+	if loaded, has := t.childTries[common.Hash(hash)]; has {
+		return loaded, nil
+	}
+
+	t.childTries[common.Hash(hash)] = child
+	return child, nil
 }
 
 // PutIntoChild puts a key-value pair into the child trie located in the main trie at key :child_storage:[keyToChild]
@@ -52,18 +86,56 @@ func (t *Trie) PutIntoChild(keyToChild, key, value []byte) error {
 		return err
 	}
 
-	child.Put(key, value)
+	if err = child.Put(key, value); err != nil {
+		return err
+	}
+
 	childHash, err := child.Hash()
 	if err != nil {
 		return err
 	}
 
+	t.childLock.Lock()
 	t.childTries[origChildHash] = nil
 	t.childTries[childHash] = child
+	t.childLock.Unlock()
 
 	return t.PutChild(keyToChild, child)
 }
 
+// GetChildHashWithVersion returns the root of the child trie at key :child_storage:[keyToChild], computed
+// with HashWithVersion, and updates the root of the child trie stored in the main trie if it changed
+func (t *Trie) GetChildHashWithVersion(keyToChild []byte, version Version) (common.Hash, error) {
+	child, err := t.GetChild(keyToChild)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	origChildHash, err := child.Hash()
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if len(child.longValueKeys) == 0 {
+		return origChildHash, nil
+	}
+
+	childHash, err := child.HashWithVersion(version)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if childHash == origChildHash {
+		return childHash, nil
+	}
+
+	t.childLock.Lock()
+	t.childTries[origChildHash] = nil
+	t.childLock.Unlock()
+
+	return childHash, t.PutChild(keyToChild, child)
+}
+
 // GetFromChild retrieves a key-value pair from the child trie located
 // in the main trie at key :child_storage:[keyToChild]
 func (t *Trie) GetFromChild(keyToChild, key []byte) ([]byte, error) {
@@ -76,14 +148,13 @@ func (t *Trie) GetFromChild(keyToChild, key []byte) ([]byte, error) {
 		return nil, fmt.Errorf("child trie does not exist at key %s%s", ChildStorageKeyPrefix, keyToChild)
 	}
 
-	val := child.Get(key)
-	return val, nil
+	return child.Get(key)
 }
 
 // DeleteChild deletes the child storage trie
-func (t *Trie) DeleteChild(keyToChild []byte) {
+func (t *Trie) DeleteChild(keyToChild []byte) error {
 	key := append(ChildStorageKeyPrefix, keyToChild...)
-	t.Delete(key)
+	return t.Delete(key)
 }
 
 // ClearFromChild removes the child storage entry
@@ -95,6 +166,5 @@ func (t *Trie) ClearFromChild(keyToChild, key []byte) error {
 	if child == nil {
 		return fmt.Errorf("child trie does not exist at key %s%s", ChildStorageKeyPrefix, keyToChild)
 	}
-	child.Delete(key)
-	return nil
+	return child.Delete(key)
 }
@@ -36,6 +36,11 @@ func (t *Trie) store(db chaindb.Batch, curr node) error {
 		return nil
 	}
 
+	curr, err := t.resolve(curr)
+	if err != nil {
+		return err
+	}
+
 	enc, hash, err := curr.encodeAndHash()
 	if err != nil {
 		return err
@@ -46,6 +51,11 @@ func (t *Trie) store(db chaindb.Batch, curr node) error {
 		return err
 	}
 
+	err = storeHashedValue(db, curr)
+	if err != nil {
+		return err
+	}
+
 	if c, ok := curr.(*branch); ok {
 		for _, child := range c.children {
 			if child == nil {
@@ -67,46 +77,66 @@ func (t *Trie) store(db chaindb.Batch, curr node) error {
 }
 
 // LoadFromProof create a partial trie based on the proof slice, as it only contains nodes that are in the proof afaik.
+// The proof slice contains the encoded nodes and, for the nodes storing their value by hash, the values.
 func (t *Trie) LoadFromProof(proof [][]byte, root []byte) error {
 	if len(proof) == 0 {
 		return ErrEmptyProof
 	}
 
-	mappedNodes := make(map[string]node, len(proof))
-
-	// map all the proofs hash -> decoded node
-	// and takes the loop to indentify the root node
-	for _, rawNode := range proof {
-		decNode, err := decodeBytes(rawNode)
+	// map all the proof items by their hash, since the values stored by hash
+	// can't be decoded as nodes, they are only decoded when reached from the root
+	items := make(map[string][]byte, len(proof))
+	for _, item := range proof {
+		hash, err := common.Blake2bHash(item)
 		if err != nil {
 			return err
 		}
 
-		decNode.setDirty(false)
-		decNode.setEncodingAndHash(rawNode, nil)
+		items[common.BytesToHex(hash[:])] = item
+	}
 
-		_, computedRoot, err := decNode.encodeAndHash()
-		if err != nil {
-			return err
-		}
+	rootEnc, ok := items[common.BytesToHex(root)]
+	if !ok {
+		return nil
+	}
 
-		mappedNodes[common.BytesToHex(computedRoot)] = decNode
+	rootNode, err := loadProofNode(items, rootEnc, root)
+	if err != nil {
+		return err
+	}
 
-		if bytes.Equal(computedRoot, root) {
-			t.root = decNode
-		}
+	t.root = rootNode
+	return t.loadProof(items, t.root)
+}
+
+// loadProofNode decodes the encoded node with the given hash, and loads its value from the
+// proof items if it stores it by hash
+func loadProofNode(items map[string][]byte, enc, hash []byte) (node, error) {
+	n, err := decodeBytes(enc)
+	if err != nil {
+		return nil, err
 	}
 
-	t.loadProof(mappedNodes, t.root)
-	return nil
+	n.setDirty(false)
+	n.setEncodingAndHash(enc, hash)
+
+	err = loadHashedValue(n, func(valueHash []byte) ([]byte, error) {
+		// the value may not be part of the proof, if the node isn't proven
+		return items[common.BytesToHex(valueHash)], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
 }
 
 // loadProof is a recursive function that will create all the trie paths based
 // on the mapped proofs slice starting by the root
-func (t *Trie) loadProof(proof map[string]node, curr node) {
+func (t *Trie) loadProof(items map[string][]byte, curr node) error {
 	c, ok := curr.(*branch)
 	if !ok {
-		return
+		return nil
 	}
 
 	for i, child := range c.children {
@@ -114,14 +144,29 @@ func (t *Trie) loadProof(proof map[string]node, curr node) {
 			continue
 		}
 
-		proofNode, ok := proof[common.BytesToHex(child.getHash())]
-		if !ok {
-			continue
+		// nodes whose encoding is shorter than 32 bytes are inlined in their parent
+		hash := child.getHash()
+		enc := hash
+		if len(hash) == common.HashLength {
+			enc, ok = items[common.BytesToHex(hash)]
+			if !ok {
+				continue
+			}
+		}
+
+		proofNode, err := loadProofNode(items, enc, hash)
+		if err != nil {
+			return err
 		}
 
 		c.children[i] = proofNode
-		t.loadProof(proof, proofNode)
+		err = t.loadProof(items, proofNode)
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 // Load reconstructs the trie from the database from the given root hash.
@@ -145,6 +190,11 @@ func (t *Trie) Load(db chaindb.Database, root common.Hash) error {
 	t.root.setDirty(false)
 	t.root.setEncodingAndHash(enc, root[:])
 
+	err = loadHashedValue(t.root, db.Get)
+	if err != nil {
+		return err
+	}
+
 	return t.load(db, t.root)
 }
 
@@ -169,6 +219,11 @@ func (t *Trie) load(db chaindb.Database, curr node) error {
 			child.setDirty(false)
 			child.setEncodingAndHash(enc, hash)
 
+			err = loadHashedValue(child, db.Get)
+			if err != nil {
+				return err
+			}
+
 			c.children[i] = child
 			err = t.load(db, child)
 			if err != nil {
@@ -180,8 +235,73 @@ func (t *Trie) load(db chaindb.Database, curr node) error {
 	return nil
 }
 
+// storeHashedValue writes the value of the given node under its hash, if the node stores its value by hash
+func storeHashedValue(db chaindb.Batch, n node) error {
+	value := hashedValueOf(n)
+	if value == nil {
+		return nil
+	}
+
+	hash, err := common.Blake2bHash(value)
+	if err != nil {
+		return err
+	}
+
+	return db.Put(hash[:], value)
+}
+
+// hashedValueOf returns the value of the given node if the node stores its value by hash, and nil otherwise
+func hashedValueOf(n node) []byte {
+	switch n := n.(type) {
+	case *branch:
+		if n.hashedValue {
+			return n.value
+		}
+	case *leaf:
+		if n.hashedValue {
+			return n.value
+		}
+	}
+
+	return nil
+}
+
+// loadHashedValue replaces the value of the given node, if it was decoded from a node storing its value
+// by hash, with the value returned by get for that hash.
+func loadHashedValue(n node, get func(hash []byte) ([]byte, error)) error {
+	switch n := n.(type) {
+	case *branch:
+		if !n.hashedValue {
+			return nil
+		}
+
+		value, err := get(n.value)
+		if err != nil {
+			return fmt.Errorf("failed to find value with hash 0x%x: %w", n.value, err)
+		}
+		n.value = value
+	case *leaf:
+		if !n.hashedValue {
+			return nil
+		}
+
+		value, err := get(n.value)
+		if err != nil {
+			return fmt.Errorf("failed to find value with hash 0x%x: %w", n.value, err)
+		}
+		n.value = value
+	}
+
+	return nil
+}
+
 // GetNodeHashes return hash of each key of the trie.
 func (t *Trie) GetNodeHashes(curr node, keys map[common.Hash]struct{}) error {
+	curr, err := t.resolve(curr)
+	if err != nil {
+		return err
+	}
+
 	if c, ok := curr.(*branch); ok {
 		for _, child := range c.children {
 			if child == nil {
@@ -204,7 +324,10 @@ func (t *Trie) GetNodeHashes(curr node, keys map[common.Hash]struct{}) error {
 // Since it needs to write all the nodes from the changed node up to the root,
 // it writes these in a batch operation.
 func (t *Trie) PutInDB(db chaindb.Database, key, value []byte) error {
-	t.Put(key, value)
+	if err := t.Put(key, value); err != nil {
+		return err
+	}
+
 	return t.WriteDirty(db)
 }
 
@@ -212,7 +335,10 @@ func (t *Trie) PutInDB(db chaindb.Database, key, value []byte) error {
 // Since it needs to write all the nodes from the changed node up to the root,
 // it writes these in a batch operation.
 func (t *Trie) DeleteFromDB(db chaindb.Database, key []byte) error {
-	t.Delete(key)
+	if err := t.Delete(key); err != nil {
+		return err
+	}
+
 	return t.WriteDirty(db)
 }
 
@@ -221,7 +347,10 @@ func (t *Trie) DeleteFromDB(db chaindb.Database, key []byte) error {
 //  the nodes from the changed node up to the root, it writes these
 // in a batch operation.
 func (t *Trie) ClearPrefixFromDB(db chaindb.Database, prefix []byte) error {
-	t.ClearPrefix(prefix)
+	if err := t.ClearPrefix(prefix); err != nil {
+		return err
+	}
+
 	return t.WriteDirty(db)
 }
 
@@ -246,6 +375,11 @@ func GetFromDB(db chaindb.Database, root common.Hash, key []byte) ([]byte, error
 		return nil, err
 	}
 
+	err = loadHashedValue(rootNode, db.Get)
+	if err != nil {
+		return nil, err
+	}
+
 	return getFromDB(db, rootNode, k)
 }
 
@@ -281,6 +415,11 @@ func getFromDB(db chaindb.Database, parent node, key []byte) ([]byte, error) {
 			return nil, err
 		}
 
+		err = loadHashedValue(child, db.Get)
+		if err != nil {
+			return nil, err
+		}
+
 		value, err = getFromDB(db, child, key[length+1:])
 		if err != nil {
 			return nil, err
@@ -299,13 +438,80 @@ func getFromDB(db chaindb.Database, parent node, key []byte) ([]byte, error) {
 // WriteDirty writes all dirty nodes to the database and sets them to clean
 func (t *Trie) WriteDirty(db chaindb.Database) error {
 	batch := db.NewBatch()
-	err := t.writeDirty(batch, t.root)
+	err := t.writeDirtyTries(batch)
 	if err != nil {
 		batch.Reset()
 		return err
 	}
 
-	return batch.Flush()
+	if err = batch.Flush(); err != nil {
+		return err
+	}
+
+	t.SetClean()
+	return nil
+}
+
+// WriteDirtyToBatch writes all dirty nodes to the given batch. The batch isn't flushed, so the nodes can be
+// written to the database along with other data, and the nodes are still dirty until SetClean is called
+// once the batch is flushed.
+func (t *Trie) WriteDirtyToBatch(batch chaindb.Batch) error {
+	return t.writeDirtyTries(batch)
+}
+
+// SetClean sets all dirty nodes of the trie and of its child tries to clean, once they're written to the database
+func (t *Trie) SetClean() {
+	setClean(t.root)
+
+	t.childLock.RLock()
+	defer t.childLock.RUnlock()
+
+	for _, child := range t.childTries {
+		if child == nil {
+			continue
+		}
+
+		child.SetClean()
+	}
+}
+
+func setClean(curr node) {
+	if curr == nil || !curr.isDirty() {
+		return
+	}
+
+	if c, ok := curr.(*branch); ok {
+		for _, child := range c.children {
+			setClean(child)
+		}
+	}
+
+	curr.setDirty(false)
+}
+
+// writeDirtyTries writes the dirty nodes of the trie and of its child tries, so that the child tries
+// can be loaded from the database along with the trie
+func (t *Trie) writeDirtyTries(batch chaindb.Batch) error {
+	err := t.writeDirty(batch, t.root)
+	if err != nil {
+		return err
+	}
+
+	t.childLock.RLock()
+	defer t.childLock.RUnlock()
+
+	for _, child := range t.childTries {
+		if child == nil {
+			continue
+		}
+
+		err = child.writeDirtyTries(batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (t *Trie) writeDirty(db chaindb.Batch, curr node) error {
@@ -333,6 +539,11 @@ func (t *Trie) writeDirty(db chaindb.Batch, curr node) error {
 		return err
 	}
 
+	err = storeHashedValue(db, curr)
+	if err != nil {
+		return err
+	}
+
 	if c, ok := curr.(*branch); ok {
 		for _, child := range c.children {
 			if child == nil {
@@ -346,7 +557,6 @@ func (t *Trie) writeDirty(db chaindb.Batch, curr node) error {
 		}
 	}
 
-	curr.setDirty(false)
 	return nil
 }
 
@@ -379,6 +589,15 @@ func (t *Trie) getInsertedNodeHashes(curr node) ([]common.Hash, error) {
 	nodeHash := common.BytesToHash(hash)
 	nodeHashes = append(nodeHashes, nodeHash)
 
+	// the values stored by hash are written to the database along with their node
+	if value := hashedValueOf(curr); value != nil {
+		valueHash, err := common.Blake2bHash(value)
+		if err != nil {
+			return nil, err
+		}
+		nodeHashes = append(nodeHashes, valueHash)
+	}
+
 	if c, ok := curr.(*branch); ok {
 		for _, child := range c.children {
 			if child == nil {
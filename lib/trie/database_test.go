@@ -13,7 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func newTestDB(t *testing.T) chaindb.Database {
+func newTestDB(t testing.TB) chaindb.Database {
 	testDatadirPath := t.TempDir()
 	db, err := utils.SetupDatabase(testDatadirPath, true)
 	require.NoError(t, err)
@@ -336,6 +336,44 @@ func TestTrie_WriteDirty_ClearPrefix(t *testing.T) {
 	}
 }
 
+func TestTrie_WriteDirtyToBatch(t *testing.T) {
+	trie := NewEmptyTrie()
+	trie.Put([]byte("asdf"), []byte("asdf"))
+	trie.Put([]byte("ghjk"), []byte("ghjk"))
+
+	db := newTestDB(t)
+	batch := db.NewBatch()
+	err := trie.WriteDirtyToBatch(batch)
+	require.NoError(t, err)
+
+	// the nodes are still dirty, in case the batch isn't written
+	require.True(t, trie.root.isDirty())
+	batch.Reset()
+
+	inserted, err := trie.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	require.NotEmpty(t, inserted)
+
+	batch = db.NewBatch()
+	err = trie.WriteDirtyToBatch(batch)
+	require.NoError(t, err)
+	err = batch.Flush()
+	require.NoError(t, err)
+
+	trie.SetClean()
+	require.False(t, trie.root.isDirty())
+	for _, child := range trie.root.(*branch).children {
+		if child != nil {
+			require.False(t, child.isDirty())
+		}
+	}
+
+	res := NewEmptyTrie()
+	err = res.Load(db, trie.MustHash())
+	require.NoError(t, err)
+	require.Equal(t, trie.MustHash(), res.MustHash())
+}
+
 func TestTrie_GetFromDB(t *testing.T) {
 	cases := [][]Test{
 		{
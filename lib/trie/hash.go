@@ -160,7 +160,12 @@ func encodeBranch(b *branch, buffer io.Writer, parallel bool) (err error) {
 		return fmt.Errorf("cannot write children bitmap to buffer: %w", err)
 	}
 
-	if b.value != nil {
+	if b.value != nil && b.hashedValue {
+		err = encodeValueHash(b.value, buffer)
+		if err != nil {
+			return err
+		}
+	} else if b.value != nil {
 		bytes, err := scale.Marshal(b.value)
 		if err != nil {
 			return fmt.Errorf("cannot scale encode value: %w", err)
@@ -275,6 +280,21 @@ func encodeChild(child node, buffer io.Writer) (err error) {
 		return nil
 	}
 
+	// the child isn't loaded, its hash is known from the encoding of its parent
+	if l, ok := child.(*leaf); ok && l.stub {
+		scaleEncodedHash, err := scale.Marshal(l.hash)
+		if err != nil {
+			return fmt.Errorf("cannot scale encode hash of child: %w", err)
+		}
+
+		_, err = buffer.Write(scaleEncodedHash)
+		if err != nil {
+			return fmt.Errorf("failed to write child to buffer: %w", err)
+		}
+
+		return nil
+	}
+
 	scaleEncodedChild, err := encodeAndHash(child)
 	if err != nil {
 		return fmt.Errorf("failed to hash and scale encode child: %w", err)
@@ -334,6 +354,10 @@ func encodeLeaf(l *leaf, buffer io.Writer) (err error) {
 		return fmt.Errorf("cannot write LE key to buffer: %w", err)
 	}
 
+	if l.hashedValue {
+		return encodeValueHash(l.value, buffer)
+	}
+
 	encodedValue, err := scale.Marshal(l.value) // TODO scale encoder to write to buffer
 	if err != nil {
 		return fmt.Errorf("cannot scale marshal value: %w", err)
@@ -346,3 +370,18 @@ func encodeLeaf(l *leaf, buffer io.Writer) (err error) {
 
 	return nil
 }
+
+// encodeValueHash writes the hash of the value of a node storing its value by hash to the buffer given.
+func encodeValueHash(value []byte, buffer io.Writer) (err error) {
+	hash, err := common.Blake2bHash(value)
+	if err != nil {
+		return fmt.Errorf("cannot hash value: %w", err)
+	}
+
+	_, err = buffer.Write(hash[:])
+	if err != nil {
+		return fmt.Errorf("cannot write value hash to buffer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/ChainSafe/chaindb"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// NodeCache is a size bounded cache of the nodes loaded by lazy tries, keyed by node hash.
+// The cached nodes are never modified, since the tries copy the nodes they write to,
+// so the cache can be shared by all the lazy tries loaded from the same database.
+type NodeCache struct {
+	nodes *lru.Cache
+}
+
+// NewNodeCache returns a cache holding up to size nodes, evicting the least recently used nodes
+func NewNodeCache(size int) (*NodeCache, error) {
+	nodes, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeCache{
+		nodes: nodes,
+	}, nil
+}
+
+// Len returns the number of nodes in the cache
+func (c *NodeCache) Len() int {
+	return c.nodes.Len()
+}
+
+func (c *NodeCache) get(hash []byte) (node, bool) {
+	n, has := c.nodes.Get(string(hash))
+	if !has {
+		return nil, false
+	}
+
+	return n.(node), true
+}
+
+func (c *NodeCache) add(hash []byte, n node) {
+	c.nodes.Add(string(hash), n)
+}
+
+// NewLazyTrie returns the trie with the given root stored in the database, without loading the rest of it.
+// The other nodes are loaded from the database when they are accessed and kept in the given cache, which
+// may be nil, rather than in the trie, so the memory used by the trie doesn't depend on the size of the state.
+// Only the nodes written to the trie are kept in it, until it's loaded again once they are stored.
+// The trie methods return an error if a node can't be loaded from the database.
+func NewLazyTrie(db chaindb.Database, root common.Hash, cache *NodeCache) (*Trie, error) {
+	t := NewEmptyTrie()
+	t.db = db
+	t.cache = cache
+	// the loaded nodes are of generation 0, they are copied before being modified
+	t.generation = 1
+
+	if root == EmptyHash {
+		return t, nil
+	}
+
+	var err error
+	t.root, err = t.resolve(&leaf{hash: root[:], stub: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find root key=%s: %w", root, err)
+	}
+
+	return t, nil
+}
+
+// IsLazy returns true if the nodes of the trie are loaded from the database when they are accessed
+func (t *Trie) IsLazy() bool {
+	return t.db != nil
+}
+
+// resolve returns the node stubbed by the given node, which is loaded from the node cache or the database
+// if the trie is lazy. The other nodes are returned as they are.
+func (t *Trie) resolve(n node) (node, error) {
+	l, ok := n.(*leaf)
+	if !ok || !l.stub || t.db == nil {
+		return n, nil
+	}
+
+	if t.cache != nil {
+		if cached, has := t.cache.get(l.hash); has {
+			return cached, nil
+		}
+	}
+
+	// nodes whose encoding is shorter than 32 bytes are inlined in their parent
+	enc := l.hash
+	if len(l.hash) == common.HashLength {
+		var err error
+		enc, err = t.db.Get(l.hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find node key=0x%x: %w", l.hash, err)
+		}
+	}
+
+	resolved, err := decodeBytes(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.setDirty(false)
+	resolved.setEncodingAndHash(enc, l.hash)
+
+	err = loadHashedValue(resolved, t.db.Get)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.cache != nil {
+		t.cache.add(l.hash, resolved)
+	}
+
+	return resolved, nil
+}
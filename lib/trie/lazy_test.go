@@ -0,0 +1,307 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newStoredTestTrie(t testing.TB, size int) (*Trie, []Test) {
+	tests := GenerateRandomTests(t, size)
+
+	trie := NewEmptyTrie()
+	for _, test := range tests {
+		trie.Put(test.key, test.value)
+	}
+
+	return trie, tests
+}
+
+// requireSameEntries requires the tries to hold the same entries
+func requireSameEntries(t *testing.T, expected, actual *Trie) {
+	expectedEntries, err := expected.Entries()
+	require.NoError(t, err)
+	actualEntries, err := actual.Entries()
+	require.NoError(t, err)
+	require.Equal(t, expectedEntries, actualEntries)
+}
+
+func TestLazyTrie_Reads(t *testing.T) {
+	expected, tests := newStoredTestTrie(t, 1000)
+	db := newTestDB(t)
+	err := expected.Store(db)
+	require.NoError(t, err)
+
+	cache, err := NewNodeCache(16)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyTrie(db, expected.MustHash(), cache)
+	require.NoError(t, err)
+	require.True(t, lazy.IsLazy())
+	require.False(t, expected.IsLazy())
+
+	require.Equal(t, expected.MustHash(), lazy.MustHash())
+	requireSameEntries(t, expected, lazy)
+
+	for _, test := range tests {
+		value, err := lazy.Get(test.key)
+		require.NoError(t, err)
+		require.Equal(t, test.value, value)
+
+		expectedNext, err := expected.NextKey(test.key)
+		require.NoError(t, err)
+		next, err := lazy.NextKey(test.key)
+		require.NoError(t, err)
+		require.Equal(t, expectedNext, next)
+
+		expectedKeys, err := expected.GetKeysWithPrefix(test.key[:1])
+		require.NoError(t, err)
+		keys, err := lazy.GetKeysWithPrefix(test.key[:1])
+		require.NoError(t, err)
+		require.Equal(t, expectedKeys, keys)
+	}
+
+	value, err := lazy.Get([]byte("not in the trie"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+	require.Equal(t, 16, cache.Len())
+}
+
+func TestLazyTrie_Writes(t *testing.T) {
+	expected, tests := newStoredTestTrie(t, 1000)
+	db := newTestDB(t)
+	err := expected.Store(db)
+	require.NoError(t, err)
+
+	root := expected.MustHash()
+	entries, err := expected.Entries()
+	require.NoError(t, err)
+
+	cache, err := NewNodeCache(64)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyTrie(db, root, cache)
+	require.NoError(t, err)
+
+	for _, tr := range []*Trie{expected, lazy} {
+		for i, test := range tests[:100] {
+			switch i % 3 {
+			case 0:
+				tr.Put(test.key, []byte("new value"))
+			case 1:
+				tr.Delete(test.key)
+			default:
+				tr.Put(append(test.key, 0xff), test.value)
+			}
+		}
+
+		tr.ClearPrefix(tests[100].key[:2])
+		tr.ClearPrefixLimit(tests[101].key[:1], 10)
+	}
+
+	require.Equal(t, expected.MustHash(), lazy.MustHash())
+	requireSameEntries(t, expected, lazy)
+
+	// the cached nodes aren't modified by the writes
+	unmodified, err := NewLazyTrie(db, root, cache)
+	require.NoError(t, err)
+	require.Equal(t, root, unmodified.MustHash())
+	unmodifiedEntries, err := unmodified.Entries()
+	require.NoError(t, err)
+	require.Equal(t, entries, unmodifiedEntries)
+
+	// the written nodes are loaded from the database once stored
+	inserted, err := lazy.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	require.NotEmpty(t, inserted)
+	require.NotEmpty(t, lazy.GetDeletedNodeHash())
+
+	err = lazy.WriteDirty(db)
+	require.NoError(t, err)
+
+	reloaded, err := NewLazyTrie(db, lazy.MustHash(), cache)
+	require.NoError(t, err)
+	requireSameEntries(t, expected, reloaded)
+}
+
+func TestLazyTrie_Snapshot(t *testing.T) {
+	expected, tests := newStoredTestTrie(t, 100)
+	db := newTestDB(t)
+	err := expected.Store(db)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyTrie(db, expected.MustHash(), nil)
+	require.NoError(t, err)
+
+	snapshot := lazy.Snapshot()
+	require.True(t, snapshot.IsLazy())
+
+	err = snapshot.Put(tests[0].key, []byte("new value"))
+	require.NoError(t, err)
+
+	value, err := snapshot.Get(tests[0].key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("new value"), value)
+
+	value, err = lazy.Get(tests[0].key)
+	require.NoError(t, err)
+	require.Equal(t, tests[0].value, value)
+	require.Equal(t, expected.MustHash(), lazy.MustHash())
+}
+
+func TestLazyTrie_ChildTrie(t *testing.T) {
+	db := newTestDB(t)
+
+	child := NewEmptyTrie()
+	child.Put([]byte("child key"), []byte("child value"))
+
+	parent := NewEmptyTrie()
+	parent.Put([]byte("key"), []byte("value"))
+	err := parent.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	// the child tries are written along with the trie
+	err = parent.WriteDirty(db)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyTrie(db, parent.MustHash(), nil)
+	require.NoError(t, err)
+
+	value, err := lazy.GetFromChild([]byte("child"), []byte("child key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("child value"), value)
+
+	err = lazy.PutIntoChild([]byte("child"), []byte("other key"), []byte("other value"))
+	require.NoError(t, err)
+
+	child.Put([]byte("other key"), []byte("other value"))
+	err = parent.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+	require.Equal(t, parent.MustHash(), lazy.MustHash())
+
+	value, err = lazy.GetFromChild([]byte("child"), []byte("other key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("other value"), value)
+}
+
+func TestLazyTrie_V1(t *testing.T) {
+	expected := NewEmptyTrie()
+	expected.SetVersion(V1)
+	expected.Put([]byte("a"), bytes.Repeat([]byte{1}, 100))
+	expected.Put([]byte("ab"), bytes.Repeat([]byte{2}, 100))
+
+	db := newTestDB(t)
+	err := expected.Store(db)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyTrie(db, expected.MustHash(), nil)
+	require.NoError(t, err)
+	requireSameEntries(t, expected, lazy)
+}
+
+func TestLazyTrie_MissingNode(t *testing.T) {
+	expected, tests := newStoredTestTrie(t, 100)
+	hashes, err := expected.GetInsertedNodeHashes()
+	require.NoError(t, err)
+
+	db := newTestDB(t)
+	err = expected.Store(db)
+	require.NoError(t, err)
+
+	// only the root node is left in the database
+	root := expected.MustHash()
+	for _, hash := range hashes {
+		if hash != root {
+			err = db.Del(hash[:])
+			require.NoError(t, err)
+		}
+	}
+
+	lazy, err := NewLazyTrie(db, root, nil)
+	require.NoError(t, err)
+
+	_, err = lazy.Get(tests[0].key)
+	require.Error(t, err)
+	_, err = lazy.NextKey(tests[0].key)
+	require.Error(t, err)
+	_, err = lazy.Entries()
+	require.Error(t, err)
+	_, err = lazy.GetKeysWithPrefix(nil)
+	require.Error(t, err)
+	err = lazy.Put(tests[0].key, []byte("new value"))
+	require.Error(t, err)
+	err = lazy.Delete(tests[1].key)
+	require.Error(t, err)
+
+	// the failed writes leave the trie unchanged
+	require.Equal(t, root, lazy.MustHash())
+}
+
+func TestNewLazyTrie(t *testing.T) {
+	db := newTestDB(t)
+
+	lazy, err := NewLazyTrie(db, EmptyHash, nil)
+	require.NoError(t, err)
+	require.Equal(t, EmptyHash, lazy.MustHash())
+
+	_, err = NewLazyTrie(db, common.Hash{0x1}, nil)
+	require.Error(t, err)
+}
+
+func BenchmarkTrie_LargeState(b *testing.B) {
+	trie, tests := newStoredTestTrie(b, 100000)
+	db := newTestDB(b)
+	err := trie.Store(db)
+	require.NoError(b, err)
+
+	root := trie.MustHash()
+
+	b.Run("load", func(b *testing.B) {
+		benchmarkGet(b, tests, func() *Trie {
+			t := NewEmptyTrie()
+			err := t.Load(db, root)
+			require.NoError(b, err)
+			return t
+		})
+	})
+
+	b.Run("lazy", func(b *testing.B) {
+		cache, err := NewNodeCache(4096)
+		require.NoError(b, err)
+
+		benchmarkGet(b, tests, func() *Trie {
+			t, err := NewLazyTrie(db, root, cache)
+			require.NoError(b, err)
+			return t
+		})
+	})
+}
+
+// benchmarkGet gets the values of the trie returned by load, and reports the heap used by the trie
+func benchmarkGet(b *testing.B, tests []Test, load func() *Trie) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	trie := load()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := trie.Get(tests[i%len(tests)].key)
+		require.NoError(b, err)
+	}
+	b.StopTimer()
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(trie)
+
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)), "heap-bytes")
+}
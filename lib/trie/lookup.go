@@ -5,14 +5,21 @@ package trie
 
 import (
 	"bytes"
+
+	"github.com/ChainSafe/gossamer/lib/common"
 )
 
 // findAndRecord search for a desired key recording all the nodes in the path including the desired node
 func findAndRecord(t *Trie, key []byte, recorder *recorder) error {
-	return find(t.root, key, recorder)
+	return t.find(t.root, key, recorder)
 }
 
-func find(parent node, key []byte, recorder *recorder) error {
+func (t *Trie) find(parent node, key []byte, recorder *recorder) error {
+	parent, err := t.resolve(parent)
+	if err != nil {
+		return err
+	}
+
 	enc, hash, err := parent.encodeAndHash()
 	if err != nil {
 		return err
@@ -22,6 +29,10 @@ func find(parent node, key []byte, recorder *recorder) error {
 
 	b, ok := parent.(*branch)
 	if !ok {
+		l := parent.(*leaf)
+		if bytes.Equal(l.key, key) && l.hashedValue {
+			return recordValue(l.value, recorder)
+		}
 		return nil
 	}
 
@@ -29,6 +40,9 @@ func find(parent node, key []byte, recorder *recorder) error {
 
 	// found the value at this node
 	if bytes.Equal(b.key, key) || len(key) == 0 {
+		if b.hashedValue {
+			return recordValue(b.value, recorder)
+		}
 		return nil
 	}
 
@@ -37,5 +51,16 @@ func find(parent node, key []byte, recorder *recorder) error {
 		return nil
 	}
 
-	return find(b.children[key[length]], key[length+1:], recorder)
+	return t.find(b.children[key[length]], key[length+1:], recorder)
+}
+
+// recordValue records a value stored by hash, which is part of the proof along with the node storing it
+func recordValue(value []byte, recorder *recorder) error {
+	hash, err := common.Blake2bHash(value)
+	if err != nil {
+		return err
+	}
+
+	recorder.record(hash[:], value)
+	return nil
 }
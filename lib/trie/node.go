@@ -24,6 +24,13 @@
 // `Extra partial key length` is included if len(key) > 63 and consists of the remaining key length
 // `Partial Key` is the leaf's key
 // `Value` is the leaf's SCALE encoded value
+//
+// State version 1 adds node headers for the nodes storing their value by hash, when the value is longer than
+// 32 bytes. `Value` is then the 32 bytes hash of the value instead of the SCALE encoded value:
+// most significant three bits of `NodeHeader`: 001 for a leaf w/ hashed value, followed by five bits of key length
+// most significant four bits of `NodeHeader`: 0001 for a branch w/ hashed value, followed by four bits of key length
+// In both cases, the key length bits are all set if the key is too long for them, and the extra partial key length
+// is included.
 
 package trie
 
@@ -55,23 +62,26 @@ type node interface {
 
 type (
 	branch struct {
-		key        []byte // partial key
-		children   [16]node
-		value      []byte
-		dirty      bool
-		hash       []byte
-		encoding   []byte
-		generation uint64
+		key         []byte // partial key
+		children    [16]node
+		value       []byte
+		hashedValue bool // value is stored by its hash, with the state version V1
+		dirty       bool
+		hash        []byte
+		encoding    []byte
+		generation  uint64
 		sync.RWMutex
 	}
 	leaf struct {
-		key        []byte // partial key
-		value      []byte
-		dirty      bool
-		hash       []byte
-		encoding   []byte
-		encodingMu sync.RWMutex
-		generation uint64
+		key         []byte // partial key
+		value       []byte
+		hashedValue bool // value is stored by its hash, with the state version V1
+		dirty       bool
+		hash        []byte
+		encoding    []byte
+		encodingMu  sync.RWMutex
+		generation  uint64
+		stub        bool // only the hash of the node is known, from the encoding of its parent
 		sync.RWMutex
 	}
 )
@@ -89,13 +99,14 @@ func (b *branch) copy() node {
 	defer b.RUnlock()
 
 	cpy := &branch{
-		key:        make([]byte, len(b.key)),
-		children:   b.children, // copy interface pointers
-		value:      nil,
-		dirty:      b.dirty,
-		hash:       make([]byte, len(b.hash)),
-		encoding:   make([]byte, len(b.encoding)),
-		generation: b.generation,
+		key:         make([]byte, len(b.key)),
+		children:    b.children, // copy interface pointers
+		value:       nil,
+		hashedValue: b.hashedValue,
+		dirty:       b.dirty,
+		hash:        make([]byte, len(b.hash)),
+		encoding:    make([]byte, len(b.encoding)),
+		generation:  b.generation,
 	}
 	copy(cpy.key, b.key)
 
@@ -118,12 +129,13 @@ func (l *leaf) copy() node {
 	defer l.encodingMu.RUnlock()
 
 	cpy := &leaf{
-		key:        make([]byte, len(l.key)),
-		value:      make([]byte, len(l.value)),
-		dirty:      l.dirty,
-		hash:       make([]byte, len(l.hash)),
-		encoding:   make([]byte, len(l.encoding)),
-		generation: l.generation,
+		key:         make([]byte, len(l.key)),
+		value:       make([]byte, len(l.value)),
+		hashedValue: l.hashedValue,
+		dirty:       l.dirty,
+		hash:        make([]byte, len(l.hash)),
+		encoding:    make([]byte, len(l.encoding)),
+		generation:  l.generation,
 	}
 	copy(cpy.key, l.key)
 	copy(cpy.value, l.value)
@@ -318,12 +330,12 @@ func decode(r io.Reader) (node, error) {
 		return nil, err
 	}
 
-	nodeType := header >> 6
-	if nodeType == 1 {
+	switch {
+	case header>>6 == 1, header>>5 == 1:
 		l := new(leaf)
 		err := l.decode(r, header)
 		return l, err
-	} else if nodeType == 2 || nodeType == 3 {
+	case header>>6 == 2, header>>6 == 3, header>>4 == 1:
 		b := new(branch)
 		err := b.decode(r, header)
 		return b, err
@@ -334,7 +346,10 @@ func decode(r io.Reader) (node, error) {
 
 // Decode decodes a byte array with the encoding specified at the top of this package into a branch node
 // Note that since the encoded branch stores the hash of the children nodes, we aren't able to reconstruct the child
-// nodes from the encoding. This function instead stubs where the children are known to be with an empty leaf.
+// nodes from the encoding. This function instead stubs where the children are known to be with a leaf holding
+// only their hash.
+// Similarly, if the branch stores its value by hash, the value is set to the hash of the value, which has to be
+// loaded by the caller.
 func (b *branch) decode(r io.Reader, header byte) (err error) {
 	if header == 0 {
 		header, err = readByte(r)
@@ -343,13 +358,25 @@ func (b *branch) decode(r io.Reader, header byte) (err error) {
 		}
 	}
 
-	nodeType := header >> 6
-	if nodeType != 2 && nodeType != 3 {
+	var (
+		keyLenMask byte
+		hasValue   bool
+	)
+	switch {
+	case header>>6 == 2:
+		keyLenMask = 0x3f
+	case header>>6 == 3:
+		keyLenMask = 0x3f
+		hasValue = true
+	case header>>4 == 1:
+		keyLenMask = 0x0f
+		hasValue = true
+		b.hashedValue = true
+	default:
 		return fmt.Errorf("cannot decode node to branch")
 	}
 
-	keyLen := header & 0x3f
-	b.key, err = decodeKey(r, keyLen)
+	b.key, err = decodeKey(r, header&keyLenMask, keyLenMask)
 	if err != nil {
 		return err
 	}
@@ -362,7 +389,12 @@ func (b *branch) decode(r io.Reader, header byte) (err error) {
 
 	sd := scale.NewDecoder(r)
 
-	if nodeType == 3 {
+	if b.hashedValue {
+		b.value, err = decodeValueHash(r)
+		if err != nil {
+			return err
+		}
+	} else if hasValue {
 		var value []byte
 		// branch w/ value
 		err := sd.Decode(&value)
@@ -382,6 +414,7 @@ func (b *branch) decode(r io.Reader, header byte) (err error) {
 
 			b.children[i] = &leaf{
 				hash: hash,
+				stub: true,
 			}
 		}
 	}
@@ -392,6 +425,8 @@ func (b *branch) decode(r io.Reader, header byte) (err error) {
 }
 
 // Decode decodes a byte array with the encoding specified at the top of this package into a leaf node
+// If the leaf stores its value by hash, the value is set to the hash of the value, which has to be loaded
+// by the caller.
 func (l *leaf) decode(r io.Reader, header byte) (err error) {
 	if header == 0 {
 		header, err = readByte(r)
@@ -400,17 +435,32 @@ func (l *leaf) decode(r io.Reader, header byte) (err error) {
 		}
 	}
 
-	nodeType := header >> 6
-	if nodeType != 1 {
+	var keyLenMask byte
+	switch {
+	case header>>6 == 1:
+		keyLenMask = 0x3f
+	case header>>5 == 1:
+		keyLenMask = 0x1f
+		l.hashedValue = true
+	default:
 		return fmt.Errorf("cannot decode node to leaf")
 	}
 
-	keyLen := header & 0x3f
-	l.key, err = decodeKey(r, keyLen)
+	l.key, err = decodeKey(r, header&keyLenMask, keyLenMask)
 	if err != nil {
 		return err
 	}
 
+	if l.hashedValue {
+		l.value, err = decodeValueHash(r)
+		if err != nil {
+			return err
+		}
+
+		l.dirty = true
+		return nil
+	}
+
 	sd := scale.NewDecoder(r)
 	var value []byte
 	err = sd.Decode(&value)
@@ -427,53 +477,54 @@ func (l *leaf) decode(r io.Reader, header byte) (err error) {
 	return nil
 }
 
+// node header variants, the key length uses the remaining bits of the header byte
+const (
+	leafHeader                  byte = 1 << 6
+	branchHeader                byte = 2 << 6
+	branchWithValueHeader       byte = 3 << 6
+	leafWithHashedValueHeader   byte = 1 << 5
+	branchWithHashedValueHeader byte = 1 << 4
+)
+
 func (b *branch) header() ([]byte, error) {
-	var header byte
-	if b.value == nil {
-		header = 2 << 6
-	} else {
-		header = 3 << 6
-	}
-	var encodePkLen []byte
-	var err error
-
-	if len(b.key) >= 63 {
-		header = header | 0x3f
-		encodePkLen, err = encodeExtraPartialKeyLength(len(b.key))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		header = header | byte(len(b.key))
+	switch {
+	case b.value == nil:
+		return encodeHeader(branchHeader, 0x3f, len(b.key))
+	case b.hashedValue:
+		return encodeHeader(branchWithHashedValueHeader, 0x0f, len(b.key))
+	default:
+		return encodeHeader(branchWithValueHeader, 0x3f, len(b.key))
 	}
-
-	fullHeader := append([]byte{header}, encodePkLen...)
-	return fullHeader, nil
 }
 
 func (l *leaf) header() ([]byte, error) {
-	var header byte = 1 << 6
-	var encodePkLen []byte
-	var err error
+	if l.hashedValue {
+		return encodeHeader(leafWithHashedValueHeader, 0x1f, len(l.key))
+	}
 
-	if len(l.key) >= 63 {
-		header = header | 0x3f
-		encodePkLen, err = encodeExtraPartialKeyLength(len(l.key))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		header = header | byte(len(l.key))
+	return encodeHeader(leafHeader, 0x3f, len(l.key))
+}
+
+// encodeHeader returns the header of a node of the given variant with a partial key of the given length,
+// where keyLenMask is the mask of the header bits holding the key length
+func encodeHeader(variant, keyLenMask byte, keyLen int) ([]byte, error) {
+	if keyLen < int(keyLenMask) {
+		return []byte{variant | byte(keyLen)}, nil
 	}
 
-	fullHeader := append([]byte{header}, encodePkLen...)
+	encodePkLen, err := encodeExtraPartialKeyLength(keyLen - int(keyLenMask))
+	if err != nil {
+		return nil, err
+	}
+
+	fullHeader := append([]byte{variant | keyLenMask}, encodePkLen...)
 	return fullHeader, nil
 }
 
 var ErrPartialKeyTooBig = errors.New("partial key length greater than or equal to 2^16")
 
+// encodeExtraPartialKeyLength encodes the part of the partial key length which doesn't fit in the header
 func encodeExtraPartialKeyLength(pkLen int) ([]byte, error) {
-	pkLen -= 63
 	fullHeader := []byte{}
 
 	if pkLen >= 1<<16 {
@@ -493,11 +544,11 @@ func encodeExtraPartialKeyLength(pkLen int) ([]byte, error) {
 	return fullHeader, nil
 }
 
-func decodeKey(r io.Reader, keyLen byte) ([]byte, error) {
+func decodeKey(r io.Reader, keyLen, keyLenMask byte) ([]byte, error) {
 	var totalKeyLen = int(keyLen)
 
-	if keyLen == 0x3f {
-		// partial key longer than 63, read next bytes for rest of pk len
+	if keyLen == keyLenMask {
+		// partial key too long for the header, read next bytes for rest of pk len
 		for {
 			nextKeyLen, err := readByte(r)
 			if err != nil {
@@ -528,6 +579,17 @@ func decodeKey(r io.Reader, keyLen byte) ([]byte, error) {
 	return []byte{}, nil
 }
 
+// decodeValueHash reads the hash of a value stored by hash
+func decodeValueHash(r io.Reader) ([]byte, error) {
+	hash := make([]byte, common.HashLength)
+	_, err := io.ReadFull(r, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash, nil
+}
+
 func readByte(r io.Reader) (byte, error) {
 	buf := make([]byte, 1)
 	_, err := r.Read(buf)
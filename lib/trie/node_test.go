@@ -84,6 +84,12 @@ func TestBranchHeader(t *testing.T) {
 		{&branch{key: byteArray(317), children: [16]node{}, value: []byte{0x01}}, []byte{255, 254}},
 		{&branch{key: byteArray(318), children: [16]node{}, value: []byte{0x01}}, []byte{255, 255, 0}},
 		{&branch{key: byteArray(573), children: [16]node{}, value: []byte{0x01}}, []byte{255, 255, 255, 0}},
+
+		{&branch{key: nil, children: [16]node{}, value: []byte{0x01}, hashedValue: true}, []byte{0x10}},
+		{&branch{key: byteArray(14), children: [16]node{}, value: []byte{0x01}, hashedValue: true}, []byte{0x1e}},
+		{&branch{key: byteArray(15), children: [16]node{}, value: []byte{0x01}, hashedValue: true}, []byte{0x1f, 0}},
+		{&branch{key: byteArray(16), children: [16]node{}, value: []byte{0x01}, hashedValue: true}, []byte{0x1f, 1}},
+		{&branch{key: byteArray(270), children: [16]node{}, value: []byte{0x01}, hashedValue: true}, []byte{0x1f, 255, 0}},
 	}
 
 	for _, test := range tests {
@@ -127,6 +133,11 @@ func TestLeafHeader(t *testing.T) {
 
 		{&leaf{key: byteArray(318), value: []byte{0x01}}, []byte{0x7f, 0xff, 0}},
 		{&leaf{key: byteArray(573), value: []byte{0x01}}, []byte{0x7f, 0xff, 0xff, 0}},
+
+		{&leaf{key: nil, value: []byte{0x01}, hashedValue: true}, []byte{0x20}},
+		{&leaf{key: byteArray(30), value: []byte{0x01}, hashedValue: true}, []byte{0x3e}},
+		{&leaf{key: byteArray(31), value: []byte{0x01}, hashedValue: true}, []byte{0x3f, 0}},
+		{&leaf{key: byteArray(32), value: []byte{0x01}, hashedValue: true}, []byte{0x3f, 1}},
 	}
 
 	for i, test := range tests {
@@ -220,14 +231,15 @@ func TestEncodeRoot(t *testing.T) {
 		for _, test := range rt {
 			trie.Put(test.key, test.value)
 
-			val := trie.Get(test.key)
+			val, err := trie.Get(test.key)
+			require.NoError(t, err)
 			if !bytes.Equal(val, test.value) {
 				t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 			}
 
 			buffer := bytes.NewBuffer(nil)
 			const parallel = false
-			err := encodeNode(trie.root, buffer, parallel)
+			err = encodeNode(trie.root, buffer, parallel)
 			require.NoError(t, err)
 		}
 	}
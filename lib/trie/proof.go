@@ -34,8 +34,9 @@ var (
 func GenerateProof(root []byte, keys [][]byte, db chaindb.Database) ([][]byte, error) {
 	trackedProofs := make(map[string][]byte)
 
-	proofTrie := NewEmptyTrie()
-	if err := proofTrie.Load(db, common.BytesToHash(root)); err != nil {
+	// only the nodes on the paths of the keys are loaded from the database
+	proofTrie, err := NewLazyTrie(db, common.BytesToHash(root), nil)
+	if err != nil {
 		return nil, err
 	}
 
@@ -88,7 +89,11 @@ func VerifyProof(proof [][]byte, root []byte, items []Pair) (bool, error) {
 	}
 
 	for _, item := range items {
-		recValue := proofTrie.Get(item.Key)
+		recValue, err := proofTrie.Get(item.Key)
+		if err != nil {
+			return false, err
+		}
+
 		if recValue == nil {
 			return false, ErrKeyNotFound
 		}
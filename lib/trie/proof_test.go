@@ -4,6 +4,7 @@
 package trie
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ChainSafe/chaindb"
@@ -75,7 +76,8 @@ func testGenerateProof(t *testing.T, entries []Pair, keys [][]byte) ([]byte, [][
 
 	items := make([]Pair, len(keys))
 	for idx, key := range keys {
-		value := trie.Get(key)
+		value, err := trie.Get(key)
+		require.NoError(t, err)
 		require.NotNil(t, value)
 
 		itemFromDB := Pair{
@@ -158,3 +160,46 @@ func TestVerifyProof_ShouldReturnTrueWithouCompareValues(t *testing.T) {
 	require.True(t, v)
 	require.NoError(t, err)
 }
+
+func TestVerifyProof_V1(t *testing.T) {
+	t.Parallel()
+
+	memdb, err := chaindb.NewBadgerDB(&chaindb.Config{
+		InMemory: true,
+		DataDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	longValue := bytes.Repeat([]byte{1}, 64)
+
+	trie := NewEmptyTrie()
+	trie.SetVersion(V1)
+	trie.Put([]byte("do"), []byte("verb"))
+	trie.Put([]byte("dog"), longValue)
+	trie.Put([]byte("doge"), bytes.Repeat([]byte{2}, 40))
+	trie.Put([]byte("horse"), bytes.Repeat([]byte{3}, 100))
+
+	err = trie.Store(memdb)
+	require.NoError(t, err)
+
+	root := trie.MustHash()
+
+	proof, err := GenerateProof(root.ToBytes(), [][]byte{[]byte("dog")}, memdb)
+	require.NoError(t, err)
+
+	// the value stored by hash is part of the proof
+	require.Contains(t, proof, longValue)
+
+	v, err := VerifyProof(proof, root.ToBytes(), []Pair{{Key: []byte("dog"), Value: longValue}})
+	require.NoError(t, err)
+	require.True(t, v)
+
+	v, err = VerifyProof(proof, root.ToBytes(), []Pair{{Key: []byte("dog"), Value: []byte("puppy")}})
+	require.ErrorIs(t, err, ErrValueNotFound)
+	require.False(t, v)
+
+	// the value of a node which isn't proven isn't part of the proof
+	v, err = VerifyProof(proof, root.ToBytes(), []Pair{{Key: []byte("horse")}})
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.False(t, v)
+}
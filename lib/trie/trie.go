@@ -6,8 +6,11 @@ package trie
 import (
 	"bytes"
 	"fmt"
+	"sync"
 
 	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/ChainSafe/chaindb"
 )
 
 // EmptyHash is the empty trie hash.
@@ -20,8 +23,18 @@ type Trie struct {
 	generation  uint64
 	root        node
 	childTries  map[common.Hash]*Trie // Used to store the child tries.
+	childLock   sync.RWMutex          // protects childTries, which GetChild writes to for lazy tries
 	deletedKeys []common.Hash
 	parallel    bool
+	version     Version // state version used for the values written to the trie
+
+	// longValueKeys are the keys of the values longer than MaxInlineValueSize written since the root was
+	// last computed with HashWithVersion, which encodes them with the state version it's given
+	longValueKeys map[string]struct{}
+
+	// the nodes of a lazy trie are loaded from its database when they are accessed
+	db    chaindb.Database
+	cache *NodeCache
 }
 
 // NewEmptyTrie creates a trie with a nil root
@@ -29,43 +42,69 @@ func NewEmptyTrie() *Trie {
 	return NewTrie(nil)
 }
 
-// NewTrie creates a trie with an existing root node
+// NewTrie creates a trie with an existing root node. The values written to the trie use the state version V0,
+// until SetVersion is called.
 func NewTrie(root node) *Trie {
 	return &Trie{
-		root:        root,
-		childTries:  make(map[common.Hash]*Trie),
-		generation:  0, // Initially zero but increases after every snapshot.
-		deletedKeys: make([]common.Hash, 0),
-		parallel:    true,
+		root:          root,
+		childTries:    make(map[common.Hash]*Trie),
+		generation:    0, // Initially zero but increases after every snapshot.
+		deletedKeys:   make([]common.Hash, 0),
+		parallel:      true,
+		longValueKeys: make(map[string]struct{}),
 	}
 }
 
 // Snapshot created a copy of the trie.
 func (t *Trie) Snapshot() *Trie {
+	t.childLock.RLock()
+	defer t.childLock.RUnlock()
+
 	children := make(map[common.Hash]*Trie)
 	for h, c := range t.childTries {
 		children[h] = &Trie{
-			generation:  c.generation + 1,
-			root:        c.root,
-			deletedKeys: make([]common.Hash, 0),
-			parallel:    c.parallel,
+			generation:    c.generation + 1,
+			root:          c.root,
+			deletedKeys:   make([]common.Hash, 0),
+			parallel:      c.parallel,
+			version:       c.version,
+			longValueKeys: copyKeys(c.longValueKeys),
+			db:            c.db,
+			cache:         c.cache,
 		}
 	}
 
 	newTrie := &Trie{
-		generation:  t.generation + 1,
-		root:        t.root,
-		childTries:  children,
-		deletedKeys: make([]common.Hash, 0),
-		parallel:    t.parallel,
+		generation:    t.generation + 1,
+		root:          t.root,
+		childTries:    children,
+		deletedKeys:   make([]common.Hash, 0),
+		parallel:      t.parallel,
+		version:       t.version,
+		longValueKeys: copyKeys(t.longValueKeys),
+		db:            t.db,
+		cache:         t.cache,
 	}
 
 	return newTrie
 }
 
-func (t *Trie) maybeUpdateGeneration(n node) node {
+func copyKeys(keys map[string]struct{}) map[string]struct{} {
+	cpy := make(map[string]struct{}, len(keys))
+	for k := range keys {
+		cpy[k] = struct{}{}
+	}
+	return cpy
+}
+
+func (t *Trie) maybeUpdateGeneration(n node) (node, error) {
 	if n == nil {
-		return nil
+		return nil, nil
+	}
+
+	n, err := t.resolve(n)
+	if err != nil {
+		return nil, err
 	}
 
 	// Make a copy if the generation is updated.
@@ -79,28 +118,61 @@ func (t *Trie) maybeUpdateGeneration(n node) node {
 		if len(oldNodeHash) > 0 {
 			hash := common.BytesToHash(oldNodeHash)
 			t.deletedKeys = append(t.deletedKeys, hash)
+
+			// the value stored by hash along with the old node is deleted with it
+			if value := hashedValueOf(n); value != nil {
+				t.deletedKeys = append(t.deletedKeys, common.MustBlake2bHash(value))
+			}
 		}
-		return newNode
+		return newNode, nil
 	}
 
-	return n
+	return n, nil
 }
 
 // DeepCopy makes a new trie and copies over the existing trie into the new trie
 func (t *Trie) DeepCopy() (*Trie, error) {
+	entries, err := t.Entries()
+	if err != nil {
+		return nil, err
+	}
+
 	cp := NewEmptyTrie()
-	for k, v := range t.Entries() {
+	cp.version = t.version
+	for k, v := range entries {
 		keyCp := make([]byte, len(k))
 		copy(keyCp, k)
 		valCp := make([]byte, len(v))
 		copy(valCp, v)
 
-		cp.Put(keyCp, valCp)
+		if err = cp.Put(keyCp, valCp); err != nil {
+			return nil, err
+		}
 	}
 
 	return cp, nil
 }
 
+// Version returns the state version used for the values written to the trie
+func (t *Trie) Version() Version {
+	return t.version
+}
+
+// SetVersion sets the state version used for the values written to the trie and to its child tries.
+// The values already in the trie keep the version they were written with.
+func (t *Trie) SetVersion(version Version) {
+	t.version = version
+
+	t.childLock.RLock()
+	defer t.childLock.RUnlock()
+
+	for _, child := range t.childTries {
+		if child != nil {
+			child.SetVersion(version)
+		}
+	}
+}
+
 // RootNode returns the root of the trie
 func (t *Trie) RootNode() node {
 	return t.root
@@ -135,48 +207,147 @@ func (t *Trie) Hash() (common.Hash, error) {
 	return common.Blake2bHash(buffer.Bytes())
 }
 
+// HashWithVersion returns the hashed root of the trie, where the values longer than MaxInlineValueSize written
+// since the root was last computed with a state version are encoded with the given version, whichever version
+// they were written with. The values written before keep their encoding. The child tries are hashed the same way.
+func (t *Trie) HashWithVersion(version Version) (common.Hash, error) {
+	// the roots of the child tries are stored in the trie, so they're computed first
+	childKeys, err := t.GetKeysWithPrefix(ChildStorageKeyPrefix)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	for _, key := range childKeys {
+		_, err = t.GetChildHashWithVersion(key[len(ChildStorageKeyPrefix):], version)
+		if err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	for key := range t.longValueKeys {
+		k := []byte(key)
+		value, err := t.Get(k)
+		if err != nil {
+			return common.Hash{}, err
+		}
+
+		if value == nil {
+			continue
+		}
+
+		if err = t.put(k, value, version); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	t.longValueKeys = make(map[string]struct{})
+
+	return t.Hash()
+}
+
 // Entries returns all the key-value pairs in the trie as a map of keys to values
-func (t *Trie) Entries() map[string][]byte {
-	return t.entries(t.root, nil, make(map[string][]byte))
+func (t *Trie) Entries() (map[string][]byte, error) {
+	kv := make(map[string][]byte)
+	if err := t.entries(t.root, nil, kv); err != nil {
+		return nil, err
+	}
+
+	return kv, nil
 }
 
-func (t *Trie) entries(current node, prefix []byte, kv map[string][]byte) map[string][]byte {
+func (t *Trie) entries(current node, prefix []byte, kv map[string][]byte) error {
+	current, err := t.resolve(current)
+	if err != nil {
+		return err
+	}
+
 	switch c := current.(type) {
 	case *branch:
 		if c.value != nil {
 			kv[string(nibblesToKeyLE(append(prefix, c.key...)))] = c.value
 		}
 		for i, child := range c.children {
-			t.entries(child, append(prefix, append(c.key, byte(i))...), kv)
+			if err = t.entries(child, append(prefix, append(c.key, byte(i))...), kv); err != nil {
+				return err
+			}
 		}
 	case *leaf:
 		kv[string(nibblesToKeyLE(append(prefix, c.key...)))] = c.value
-		return kv
 	}
 
-	return kv
+	return nil
+}
+
+// WalkEntries calls fn with each entry of the trie in lexicographic order of the keys, along with whether
+// the value is stored by its hash in its node. It stops at the first error returned by fn, and returns it.
+// The value passed to fn must not be modified.
+func (t *Trie) WalkEntries(fn func(key, value []byte, hashedValue bool) error) error {
+	return t.walkEntries(t.root, nil, fn)
+}
+
+func (t *Trie) walkEntries(current node, prefix []byte, fn func(key, value []byte, hashedValue bool) error) error {
+	current, err := t.resolve(current)
+	if err != nil {
+		return err
+	}
+
+	switch c := current.(type) {
+	case *branch:
+		fullKey := make([]byte, 0, len(prefix)+len(c.key)+1)
+		fullKey = append(append(fullKey, prefix...), c.key...)
+
+		if c.value != nil {
+			if err := fn(nibblesToKeyLE(fullKey), c.value, c.hashedValue); err != nil {
+				return err
+			}
+		}
+
+		for i, child := range c.children {
+			if child == nil {
+				continue
+			}
+
+			// the children reuse the same prefix, which they copy
+			if err := t.walkEntries(child, append(fullKey, byte(i)), fn); err != nil {
+				return err
+			}
+		}
+	case *leaf:
+		fullKey := append(append(make([]byte, 0, len(prefix)+len(c.key)), prefix...), c.key...)
+		return fn(nibblesToKeyLE(fullKey), c.value, c.hashedValue)
+	}
+
+	return nil
 }
 
 // NextKey returns the next key in the trie in lexicographic order. It returns nil if there is no next key
-func (t *Trie) NextKey(key []byte) []byte {
+func (t *Trie) NextKey(key []byte) ([]byte, error) {
 	k := keyToNibbles(key)
 
-	next := t.nextKey(t.root, nil, k)
+	next, err := t.nextKey(t.root, nil, k)
+	if err != nil {
+		return nil, err
+	}
+
 	if next == nil {
-		return nil
+		return nil, nil
 	}
 
-	return nibblesToKeyLE(next)
+	return nibblesToKeyLE(next), nil
 }
 
-func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
+func (t *Trie) nextKey(curr node, prefix, key []byte) ([]byte, error) {
+	curr, err := t.resolve(curr)
+	if err != nil {
+		return nil, err
+	}
+
 	switch c := curr.(type) {
 	case *branch:
 		fullKey := append(prefix, c.key...)
 		var cmp int
 		if len(key) < len(fullKey) {
 			if bytes.Compare(key, fullKey[:len(key)]) == 1 { // arg key is greater than full, return nil
-				return nil
+				return nil, nil
 			}
 
 			// the key is lexicographically less than the current node key. return first key available
@@ -191,7 +362,7 @@ func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
 		// if it's a branch with value.
 		if (cmp == 0 && len(key) == len(fullKey)) || cmp == 1 {
 			if c.value != nil && bytes.Compare(fullKey, key) > 0 {
-				return fullKey
+				return fullKey, nil
 			}
 
 			for i, child := range c.children {
@@ -199,9 +370,13 @@ func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
 					continue
 				}
 
-				next := t.nextKey(child, append(fullKey, byte(i)), key)
+				next, err := t.nextKey(child, append(fullKey, byte(i)), key)
+				if err != nil {
+					return nil, err
+				}
+
 				if len(next) != 0 {
-					return next
+					return next, nil
 				}
 			}
 		}
@@ -214,9 +389,13 @@ func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
 					continue
 				}
 
-				next := t.nextKey(child, append(fullKey, byte(i)+idx), key)
+				next, err := t.nextKey(child, append(fullKey, byte(i)+idx), key)
+				if err != nil {
+					return nil, err
+				}
+
 				if len(next) != 0 {
-					return next
+					return next, nil
 				}
 			}
 		}
@@ -225,7 +404,7 @@ func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
 		var cmp int
 		if len(key) < len(fullKey) {
 			if bytes.Compare(key, fullKey[:len(key)]) == 1 { // arg key is greater than full, return nil
-				return nil
+				return nil, nil
 			}
 
 			// the key is lexicographically less than the current node key. return first key available
@@ -236,47 +415,82 @@ func (t *Trie) nextKey(curr node, prefix, key []byte) []byte {
 		}
 
 		if cmp == 1 {
-			return append(prefix, c.key...)
+			return append(prefix, c.key...), nil
 		}
 	case nil:
-		return nil
+		return nil, nil
 	}
-	return nil
+	return nil, nil
 }
 
 // Put inserts a key with value into the trie
-func (t *Trie) Put(key, value []byte) {
-	t.tryPut(key, value)
+func (t *Trie) Put(key, value []byte) error {
+	return t.tryPut(key, value)
 }
 
-func (t *Trie) tryPut(key, value []byte) {
+func (t *Trie) tryPut(key, value []byte) error {
+	if len(value) > MaxInlineValueSize {
+		if t.longValueKeys == nil {
+			t.longValueKeys = make(map[string]struct{})
+		}
+		t.longValueKeys[string(key)] = struct{}{}
+	}
+
+	return t.put(key, value, t.version)
+}
+
+// put inserts a key with value into the trie, encoding the value with the given state version
+func (t *Trie) put(key, value []byte, version Version) error {
 	k := keyToNibbles(key)
 
-	t.root = t.insert(t.root, k, &leaf{key: nil, value: value, dirty: true, generation: t.generation})
+	l := &leaf{
+		key:         nil,
+		value:       value,
+		hashedValue: version.hashesValue(value),
+		dirty:       true,
+		generation:  t.generation,
+	}
+
+	root, err := t.insert(t.root, k, l)
+	if err != nil {
+		return err
+	}
+
+	t.root = root
+	return nil
 }
 
 // insert attempts to insert a key with value into the trie
-func (t *Trie) insert(parent node, key []byte, value node) node {
-	switch p := t.maybeUpdateGeneration(parent).(type) {
+func (t *Trie) insert(parent node, key []byte, value node) (node, error) {
+	parent, err := t.maybeUpdateGeneration(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
 	case *branch:
-		n := t.updateBranch(p, key, value)
+		n, err := t.updateBranch(p, key, value)
+		if err != nil {
+			return nil, err
+		}
 
 		if p != nil && n != nil && n.isDirty() {
 			p.setDirty(true)
 		}
-		return n
+		return n, nil
 	case nil:
 		value.setKey(key)
-		return value
+		return value, nil
 	case *leaf:
 		// if a value already exists in the trie at this key, overwrite it with the new value
-		// if the values are the same, don't mark node dirty
+		// if the values and their encodings are the same, don't mark node dirty
 		if p.value != nil && bytes.Equal(p.key, key) {
-			if !bytes.Equal(value.(*leaf).value, p.value) {
+			if !bytes.Equal(value.(*leaf).value, p.value) || value.(*leaf).hashedValue != p.hashedValue {
 				p.value = value.(*leaf).value
+				p.hashedValue = value.(*leaf).hashedValue
 				p.dirty = true
 			}
-			return p
+			return p, nil
 		}
 
 		length := lenCommonPrefix(key, p.key)
@@ -288,6 +502,7 @@ func (t *Trie) insert(parent node, key []byte, value node) node {
 		// value goes at this branch
 		if len(key) == length {
 			br.value = value.(*leaf).value
+			br.hashedValue = value.(*leaf).hashedValue
 			br.setDirty(true)
 
 			// if we are not replacing previous leaf, then add it as a child to the new branch
@@ -297,7 +512,7 @@ func (t *Trie) insert(parent node, key []byte, value node) node {
 				p.setDirty(true)
 			}
 
-			return br
+			return br, nil
 		}
 
 		value.setKey(key[length+1:])
@@ -306,6 +521,7 @@ func (t *Trie) insert(parent node, key []byte, value node) node {
 			// if leaf's key is covered by this branch, then make the leaf's
 			// value the value at this branch
 			br.value = p.value
+			br.hashedValue = p.hashedValue
 			br.children[key[length]] = value
 		} else {
 			// otherwise, make the leaf a child of the branch and update its partial key
@@ -315,16 +531,16 @@ func (t *Trie) insert(parent node, key []byte, value node) node {
 			br.children[key[length]] = value
 		}
 
-		return br
+		return br, nil
 	}
 	// This will never happen.
-	return nil
+	return nil, nil
 }
 
 // updateBranch attempts to add the value node to a branch
 // inserts the value node as the branch's child at the index that's
 // the first nibble of the key
-func (t *Trie) updateBranch(p *branch, key []byte, value node) (n node) {
+func (t *Trie) updateBranch(p *branch, key []byte, value node) (n node, err error) {
 	length := lenCommonPrefix(key, p.key)
 
 	// whole parent key matches
@@ -335,28 +551,34 @@ func (t *Trie) updateBranch(p *branch, key []byte, value node) (n node) {
 			switch v := value.(type) {
 			case *branch:
 				p.value = v.value
+				p.hashedValue = v.hashedValue
 			case *leaf:
 				p.value = v.value
+				p.hashedValue = v.hashedValue
 			}
-			return p
+			return p, nil
 		}
 
 		switch c := p.children[key[length]].(type) {
 		case *branch, *leaf:
-			n = t.insert(c, key[length+1:], value)
+			n, err = t.insert(c, key[length+1:], value)
+			if err != nil {
+				return nil, err
+			}
+
 			p.children[key[length]] = n
 			n.setDirty(true)
 			p.setDirty(true)
-			return p
+			return p, nil
 		case nil:
 			// otherwise, add node as child of this branch
 			value.(*leaf).key = key[length+1:]
 			p.children[key[length]] = value
 			p.setDirty(true)
-			return p
+			return p, nil
 		}
 
-		return n
+		return n, nil
 	}
 
 	// we need to branch out at the point where the keys diverge
@@ -364,16 +586,23 @@ func (t *Trie) updateBranch(p *branch, key []byte, value node) (n node) {
 	br := &branch{key: key[:length], dirty: true, generation: t.generation}
 
 	parentIndex := p.key[length]
-	br.children[parentIndex] = t.insert(nil, p.key[length+1:], p)
+	br.children[parentIndex], err = t.insert(nil, p.key[length+1:], p)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(key) <= length {
 		br.value = value.(*leaf).value
+		br.hashedValue = value.(*leaf).hashedValue
 	} else {
-		br.children[key[length]] = t.insert(nil, key[length+1:], value)
+		br.children[key[length]], err = t.insert(nil, key[length+1:], value)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	br.setDirty(true)
-	return br
+	return br, nil
 }
 
 // LoadFromMap loads the given data into trie
@@ -387,14 +616,17 @@ func (t *Trie) LoadFromMap(data map[string]string) error {
 		if err != nil {
 			return err
 		}
-		t.Put(keyBytes, valueBytes)
+
+		if err = t.Put(keyBytes, valueBytes); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 // GetKeysWithPrefix returns all keys in the trie that have the given prefix
-func (t *Trie) GetKeysWithPrefix(prefix []byte) [][]byte {
+func (t *Trie) GetKeysWithPrefix(prefix []byte) ([][]byte, error) {
 	var p []byte
 	if len(prefix) != 0 {
 		p = keyToNibbles(prefix)
@@ -406,38 +638,47 @@ func (t *Trie) GetKeysWithPrefix(prefix []byte) [][]byte {
 	return t.getKeysWithPrefix(t.root, []byte{}, p, [][]byte{})
 }
 
-func (t *Trie) getKeysWithPrefix(parent node, prefix, key []byte, keys [][]byte) [][]byte {
+func (t *Trie) getKeysWithPrefix(parent node, prefix, key []byte, keys [][]byte) ([][]byte, error) {
+	parent, err := t.resolve(parent)
+	if err != nil {
+		return nil, err
+	}
+
 	switch p := parent.(type) {
 	case *branch:
 		length := lenCommonPrefix(p.key, key)
 
 		if bytes.Equal(p.key[:length], key) || len(key) == 0 {
 			// node has prefix, add to list and add all descendant nodes to list
-			keys = t.addAllKeys(p, prefix, keys)
-			return keys
+			return t.addAllKeys(p, prefix, keys)
 		}
 
 		if len(key) <= len(p.key) || length < len(p.key) {
 			// no prefixed keys to be found here, return
-			return keys
+			return keys, nil
 		}
 
 		key = key[len(p.key):]
-		keys = t.getKeysWithPrefix(p.children[key[0]], append(append(prefix, p.key...), key[0]), key[1:], keys)
+		return t.getKeysWithPrefix(p.children[key[0]], append(append(prefix, p.key...), key[0]), key[1:], keys)
 	case *leaf:
 		length := lenCommonPrefix(p.key, key)
 		if bytes.Equal(p.key[:length], key) || len(key) == 0 {
 			keys = append(keys, nibblesToKeyLE(append(prefix, p.key...)))
 		}
 	case nil:
-		return keys
+		return keys, nil
 	}
-	return keys
+	return keys, nil
 }
 
 // addAllKeys appends all keys that are descendants of the parent node to a slice of keys
 // it uses the prefix to determine the entire key
-func (t *Trie) addAllKeys(parent node, prefix []byte, keys [][]byte) [][]byte {
+func (t *Trie) addAllKeys(parent node, prefix []byte, keys [][]byte) ([][]byte, error) {
+	parent, err := t.resolve(parent)
+	if err != nil {
+		return nil, err
+	}
+
 	switch p := parent.(type) {
 	case *branch:
 		if p.value != nil {
@@ -445,36 +686,44 @@ func (t *Trie) addAllKeys(parent node, prefix []byte, keys [][]byte) [][]byte {
 		}
 
 		for i, child := range p.children {
-			keys = t.addAllKeys(child, append(append(prefix, p.key...), byte(i)), keys)
+			keys, err = t.addAllKeys(child, append(append(prefix, p.key...), byte(i)), keys)
+			if err != nil {
+				return nil, err
+			}
 		}
 	case *leaf:
 		keys = append(keys, nibblesToKeyLE(append(prefix, p.key...)))
 	case nil:
-		return keys
+		return keys, nil
 	}
 
-	return keys
+	return keys, nil
 }
 
 // Get returns the value for key stored in the trie at the corresponding key
-func (t *Trie) Get(key []byte) []byte {
-	l := t.tryGet(key)
+func (t *Trie) Get(key []byte) ([]byte, error) {
+	l, err := t.tryGet(key)
+	if err != nil {
+		return nil, err
+	}
+
 	if l == nil {
-		return nil
+		return nil, nil
 	}
 
-	return l.value
+	return l.value, nil
 }
 
-func (t *Trie) tryGet(key []byte) *leaf {
+func (t *Trie) tryGet(key []byte) (*leaf, error) {
 	k := keyToNibbles(key)
 	return t.retrieve(t.root, k)
 }
 
-func (t *Trie) retrieve(parent node, key []byte) *leaf {
-	var (
-		value *leaf
-	)
+func (t *Trie) retrieve(parent node, key []byte) (*leaf, error) {
+	parent, err := t.resolve(parent)
+	if err != nil {
+		return nil, err
+	}
 
 	switch p := parent.(type) {
 	case *branch:
@@ -482,29 +731,28 @@ func (t *Trie) retrieve(parent node, key []byte) *leaf {
 
 		// found the value at this node
 		if bytes.Equal(p.key, key) || len(key) == 0 {
-			return &leaf{key: p.key, value: p.value, dirty: false}
+			return &leaf{key: p.key, value: p.value, dirty: false}, nil
 		}
 
 		// did not find value
 		if bytes.Equal(p.key[:length], key) && len(key) < len(p.key) {
-			return nil
+			return nil, nil
 		}
 
-		value = t.retrieve(p.children[key[length]], key[length+1:])
+		return t.retrieve(p.children[key[length]], key[length+1:])
 	case *leaf:
 		if bytes.Equal(p.key, key) {
-			value = p
+			return p, nil
 		}
-	case nil:
-		return nil
 	}
-	return value
+
+	return nil, nil
 }
 
 // ClearPrefixLimit deletes the keys having the prefix till limit reached
-func (t *Trie) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool) {
+func (t *Trie) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool, error) {
 	if limit == 0 {
-		return 0, false
+		return 0, false, nil
 	}
 
 	p := keyToNibbles(prefix)
@@ -513,81 +761,108 @@ func (t *Trie) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool) {
 	}
 
 	l := limit
-	var allDeleted bool
-	t.root, _, allDeleted = t.clearPrefixLimit(t.root, p, &limit)
-	return l - limit, allDeleted
+	root, _, allDeleted, err := t.clearPrefixLimit(t.root, p, &limit)
+	if err != nil {
+		return 0, false, err
+	}
+
+	t.root = root
+	return l - limit, allDeleted, nil
 }
 
 // clearPrefixLimit deletes the keys having the prefix till limit reached and returns updated trie root node,
 // true if any node in the trie got updated, and next bool returns true if there is no keys left with prefix.
-func (t *Trie) clearPrefixLimit(cn node, prefix []byte, limit *uint32) (node, bool, bool) {
-	curr := t.maybeUpdateGeneration(cn)
+func (t *Trie) clearPrefixLimit(cn node, prefix []byte, limit *uint32) (node, bool, bool, error) {
+	curr, err := t.maybeUpdateGeneration(cn)
+	if err != nil {
+		return nil, false, false, err
+	}
 
 	switch c := curr.(type) {
 	case *branch:
 		length := lenCommonPrefix(c.key, prefix)
 		if length == len(prefix) {
-			n, _ := t.deleteNodes(c, []byte{}, limit)
+			n, _, err := t.deleteNodes(c, []byte{}, limit)
+			if err != nil {
+				return nil, false, false, err
+			}
+
 			if n == nil {
-				return nil, true, true
+				return nil, true, true, nil
 			}
-			return n, true, false
+			return n, true, false, nil
 		}
 
 		if len(prefix) == len(c.key)+1 && length == len(prefix)-1 {
 			i := prefix[len(c.key)]
-			c.children[i], _ = t.deleteNodes(c.children[i], []byte{}, limit)
+			c.children[i], _, err = t.deleteNodes(c.children[i], []byte{}, limit)
+			if err != nil {
+				return nil, false, false, err
+			}
 
 			c.setDirty(true)
-			curr = handleDeletion(c, prefix)
+			curr, err = t.handleDeletion(c, prefix)
+			if err != nil {
+				return nil, false, false, err
+			}
 
 			if c.children[i] == nil {
-				return curr, true, true
+				return curr, true, true, nil
 			}
-			return c, true, false
+			return c, true, false, nil
 		}
 
 		if len(prefix) <= len(c.key) || length < len(c.key) {
 			// this node doesn't have the prefix, return
-			return c, false, true
+			return c, false, true, nil
 		}
 
 		i := prefix[len(c.key)]
 
 		var wasUpdated, allDeleted bool
-		c.children[i], wasUpdated, allDeleted = t.clearPrefixLimit(c.children[i], prefix[len(c.key)+1:], limit)
+		c.children[i], wasUpdated, allDeleted, err = t.clearPrefixLimit(c.children[i], prefix[len(c.key)+1:], limit)
+		if err != nil {
+			return nil, false, false, err
+		}
+
 		if wasUpdated {
 			c.setDirty(true)
-			curr = handleDeletion(c, prefix)
+			curr, err = t.handleDeletion(c, prefix)
+			if err != nil {
+				return nil, false, false, err
+			}
 		}
 
-		return curr, curr.isDirty(), allDeleted
+		return curr, curr.isDirty(), allDeleted, nil
 	case *leaf:
 		length := lenCommonPrefix(c.key, prefix)
 		if length == len(prefix) {
 			*limit--
-			return nil, true, true
+			return nil, true, true, nil
 		}
 		// Prefix not found might be all deleted
-		return curr, false, true
+		return curr, false, true, nil
 
 	case nil:
-		return nil, false, true
+		return nil, false, true, nil
 	}
 
-	return nil, false, true
+	return nil, false, true, nil
 }
 
-func (t *Trie) deleteNodes(cn node, prefix []byte, limit *uint32) (node, bool) {
-	curr := t.maybeUpdateGeneration(cn)
+func (t *Trie) deleteNodes(cn node, prefix []byte, limit *uint32) (node, bool, error) {
+	curr, err := t.maybeUpdateGeneration(cn)
+	if err != nil {
+		return nil, false, err
+	}
 
 	switch c := curr.(type) {
 	case *leaf:
 		if *limit == 0 {
-			return c, false
+			return c, false, nil
 		}
 		*limit--
-		return nil, true
+		return nil, true, nil
 	case *branch:
 		if len(c.key) != 0 {
 			prefix = append(prefix, c.key...)
@@ -599,41 +874,50 @@ func (t *Trie) deleteNodes(cn node, prefix []byte, limit *uint32) (node, bool) {
 			}
 
 			var isDel bool
-			if c.children[i], isDel = t.deleteNodes(child, prefix, limit); !isDel {
+			c.children[i], isDel, err = t.deleteNodes(child, prefix, limit)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if !isDel {
 				continue
 			}
 
 			c.setDirty(true)
-			curr = handleDeletion(c, prefix)
+			curr, err = t.handleDeletion(c, prefix)
+			if err != nil {
+				return nil, false, err
+			}
+
 			isAllNil := c.numChildren() == 0
 			if isAllNil && c.value == nil {
 				curr = nil
 			}
 
 			if *limit == 0 {
-				return curr, true
+				return curr, true, nil
 			}
 		}
 
 		if *limit == 0 {
-			return c, true
+			return c, true, nil
 		}
 
 		// Delete the current node as well
 		if c.value != nil {
 			*limit--
 		}
-		return nil, true
+		return nil, true, nil
 	}
 
-	return curr, true
+	return curr, true, nil
 }
 
 // ClearPrefix deletes all key-value pairs from the trie where the key starts with the given prefix
-func (t *Trie) ClearPrefix(prefix []byte) {
+func (t *Trie) ClearPrefix(prefix []byte) error {
 	if len(prefix) == 0 {
 		t.root = nil
-		return
+		return nil
 	}
 
 	p := keyToNibbles(prefix)
@@ -641,18 +925,28 @@ func (t *Trie) ClearPrefix(prefix []byte) {
 		p = p[:len(p)-1]
 	}
 
-	t.root, _ = t.clearPrefix(t.root, p)
+	root, _, err := t.clearPrefix(t.root, p)
+	if err != nil {
+		return err
+	}
+
+	t.root = root
+	return nil
 }
 
-func (t *Trie) clearPrefix(cn node, prefix []byte) (node, bool) {
-	curr := t.maybeUpdateGeneration(cn)
+func (t *Trie) clearPrefix(cn node, prefix []byte) (node, bool, error) {
+	curr, err := t.maybeUpdateGeneration(cn)
+	if err != nil {
+		return nil, false, err
+	}
+
 	switch c := curr.(type) {
 	case *branch:
 		length := lenCommonPrefix(c.key, prefix)
 
 		if length == len(prefix) {
 			// found prefix at this branch, delete it
-			return nil, true
+			return nil, true, nil
 		}
 
 		// Store the current node and return it, if the trie is not updated.
@@ -662,76 +956,111 @@ func (t *Trie) clearPrefix(cn node, prefix []byte) (node, bool) {
 			i := prefix[len(c.key)]
 			c.children[i] = nil
 			c.setDirty(true)
-			curr = handleDeletion(c, prefix)
-			return curr, true
+			curr, err = t.handleDeletion(c, prefix)
+			if err != nil {
+				return nil, false, err
+			}
+			return curr, true, nil
 		}
 
 		if len(prefix) <= len(c.key) || length < len(c.key) {
 			// this node doesn't have the prefix, return
-			return c, false
+			return c, false, nil
 		}
 
 		var wasUpdated bool
 		i := prefix[len(c.key)]
 
-		c.children[i], wasUpdated = t.clearPrefix(c.children[i], prefix[len(c.key)+1:])
+		c.children[i], wasUpdated, err = t.clearPrefix(c.children[i], prefix[len(c.key)+1:])
+		if err != nil {
+			return nil, false, err
+		}
+
 		if wasUpdated {
 			c.setDirty(true)
-			curr = handleDeletion(c, prefix)
+			curr, err = t.handleDeletion(c, prefix)
+			if err != nil {
+				return nil, false, err
+			}
 		}
 
-		return curr, curr.isDirty()
+		return curr, curr.isDirty(), nil
 	case *leaf:
 		length := lenCommonPrefix(c.key, prefix)
 		if length == len(prefix) {
-			return nil, true
+			return nil, true, nil
 		}
-		return c, false
+		return c, false, nil
 	case nil:
-		return nil, false
+		return nil, false, nil
 	}
 	// This should never happen.
-	return nil, false
+	return nil, false, nil
 }
 
 // Delete removes any existing value for key from the trie.
-func (t *Trie) Delete(key []byte) {
+func (t *Trie) Delete(key []byte) error {
 	k := keyToNibbles(key)
-	t.root, _ = t.delete(t.root, k)
+
+	root, _, err := t.delete(t.root, k)
+	if err != nil {
+		return err
+	}
+
+	t.root = root
+	return nil
 }
 
-func (t *Trie) delete(parent node, key []byte) (node, bool) {
+func (t *Trie) delete(parent node, key []byte) (node, bool, error) {
 	// Store the current node and return it, if the trie is not updated.
-	switch p := t.maybeUpdateGeneration(parent).(type) {
+	parent, err := t.maybeUpdateGeneration(parent)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch p := parent.(type) {
 	case *branch:
 
 		length := lenCommonPrefix(p.key, key)
 		if bytes.Equal(p.key, key) || len(key) == 0 {
 			// found the value at this node
 			p.value = nil
+			p.hashedValue = false
 			p.setDirty(true)
-			return handleDeletion(p, key), true
+
+			n, err := t.handleDeletion(p, key)
+			if err != nil {
+				return nil, false, err
+			}
+			return n, true, nil
+		}
+
+		n, del, err := t.delete(p.children[key[length]], key[length+1:])
+		if err != nil {
+			return nil, false, err
 		}
 
-		n, del := t.delete(p.children[key[length]], key[length+1:])
 		if !del {
 			// If nothing was deleted then don't copy the path.
-			return p, false
+			return p, false, nil
 		}
 
 		p.children[key[length]] = n
 		p.setDirty(true)
-		n = handleDeletion(p, key)
-		return n, true
+		n, err = t.handleDeletion(p, key)
+		if err != nil {
+			return nil, false, err
+		}
+		return n, true, nil
 	case *leaf:
 		if bytes.Equal(key, p.key) || len(key) == 0 {
 			// Key exists. Delete it.
-			return nil, true
+			return nil, true, nil
 		}
 		// Key doesn't exist.
-		return p, false
+		return p, false, nil
 	case nil:
-		return nil, false
+		return nil, false, nil
 	default:
 		panic(fmt.Sprintf("%T: invalid node: %v (%v)", p, p, key))
 	}
@@ -740,14 +1069,14 @@ func (t *Trie) delete(parent node, key []byte) (node, bool) {
 // handleDeletion is called when a value is deleted from a branch
 // if the updated branch only has 1 child, it should be combined with that child
 // if the updated branch only has a value, it should be turned into a leaf
-func handleDeletion(p *branch, key []byte) node {
+func (t *Trie) handleDeletion(p *branch, key []byte) (node, error) {
 	var n node = p
 	length := lenCommonPrefix(p.key, key)
 	bitmap := p.childrenBitmap()
 
 	// if branch has no children, just a value, turn it into a leaf
 	if bitmap == 0 && p.value != nil {
-		n = &leaf{key: key[:length], value: p.value, dirty: true}
+		n = &leaf{key: key[:length], value: p.value, hashedValue: p.hashedValue, dirty: true}
 	} else if p.numChildren() == 1 && p.value == nil {
 		// there is only 1 child and no value, combine the child branch with this branch
 		// find index of child
@@ -759,10 +1088,14 @@ func handleDeletion(p *branch, key []byte) node {
 			}
 		}
 
-		child := p.children[i]
+		child, err := t.resolve(p.children[i])
+		if err != nil {
+			return nil, err
+		}
+
 		switch c := child.(type) {
 		case *leaf:
-			n = &leaf{key: append(append(p.key, []byte{byte(i)}...), c.key...), value: c.value}
+			n = &leaf{key: append(append(p.key, []byte{byte(i)}...), c.key...), value: c.value, hashedValue: c.hashedValue}
 		case *branch:
 			br := new(branch)
 			br.key = append(p.key, append([]byte{byte(i)}, c.key...)...)
@@ -775,6 +1108,7 @@ func handleDeletion(p *branch, key []byte) node {
 			}
 
 			br.value = c.value
+			br.hashedValue = c.hashedValue
 			n = br
 		default:
 			// do nothing
@@ -782,7 +1116,7 @@ func handleDeletion(p *branch, key []byte) node {
 		n.setDirty(true)
 
 	}
-	return n
+	return n, nil
 }
 
 // lenCommonPrefix returns the length of the common prefix between two keys
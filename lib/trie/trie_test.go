@@ -88,7 +88,8 @@ func TestEntries(t *testing.T) {
 		trie.Put(test.key, test.value)
 	}
 
-	entries := trie.Entries()
+	entries, err := trie.Entries()
+	require.NoError(t, err)
 	if len(entries) != len(tests) {
 		t.Fatal("length of trie.Entries does not equal length of values put into trie")
 	}
@@ -100,6 +101,41 @@ func TestEntries(t *testing.T) {
 	}
 }
 
+func TestTrie_WalkEntries(t *testing.T) {
+	trie, _ := newStoredTestTrie(t, 1000)
+	trie.Put([]byte{}, []byte("empty key"))
+	trie.SetVersion(V1)
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+	trie.Put([]byte("long value"), long)
+
+	entries, err := trie.Entries()
+	require.NoError(t, err)
+	var prev []byte
+	err = trie.WalkEntries(func(key, value []byte, hashedValue bool) error {
+		if prev != nil {
+			require.Less(t, string(prev), string(key))
+		}
+		prev = key
+
+		require.Equal(t, entries[string(key)], value)
+		require.Equal(t, string(key) == "long value", hashedValue)
+		delete(entries, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// the walk stops at the first error
+	walked := 0
+	errTest := fmt.Errorf("test error")
+	err = trie.WalkEntries(func(key, value []byte, hashedValue bool) error {
+		walked++
+		return errTest
+	})
+	require.ErrorIs(t, err, errTest)
+	require.Equal(t, 1, walked)
+}
+
 func hexDecode(in string) []byte {
 	out, _ := hex.DecodeString(in)
 	return out
@@ -150,14 +186,16 @@ func runTests(t *testing.T, trie *Trie, tests []Test) {
 			if test.op == PUT {
 				trie.Put(test.key, test.value)
 			} else if test.op == GET {
-				val := trie.Get(test.key)
+				val, err := trie.Get(test.key)
+				require.NoError(t, err)
 				if !bytes.Equal(val, test.value) {
 					t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 				}
 			} else if test.op == DEL {
 				trie.Delete(test.key)
 			} else if test.op == GETLEAF {
-				leaf := trie.tryGet(test.key)
+				leaf, err := trie.tryGet(test.key)
+				require.NoError(t, err)
 				if leaf == nil {
 					t.Errorf("Fail to get key %x: nil leaf", test.key)
 				} else if !bytes.Equal(leaf.value, test.value) {
@@ -252,14 +290,16 @@ func TestPutAndGet(t *testing.T) {
 		for _, test := range rt {
 			trie.Put(test.key, test.value)
 
-			val := trie.Get(test.key)
+			val, err := trie.Get(test.key)
+			require.NoError(t, err)
 			if !bytes.Equal(val, test.value) {
 				t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 			}
 		}
 
 		for _, test := range rt {
-			val := trie.Get(test.key)
+			val, err := trie.Get(test.key)
+			require.NoError(t, err)
 			if !bytes.Equal(val, test.value) {
 				writeToTestFile(rt)
 				t.Fatalf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
@@ -299,7 +339,8 @@ func TestFailingTests(t *testing.T) {
 		if len(test.key) != 0 {
 			trie.Put(test.key, test.value)
 
-			val := trie.Get(test.key)
+			val, err := trie.Get(test.key)
+			require.NoError(t, err)
 			if !bytes.Equal(val, test.value) {
 				t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 			}
@@ -311,7 +352,8 @@ func TestFailingTests(t *testing.T) {
 				passedFailingTest = true
 			}
 
-			val = trie.Get(failingKey)
+			val, err = trie.Get(failingKey)
+			require.NoError(t, err)
 			if !bytes.Equal(val, failingVal) && !hasFailed && passedFailingTest {
 				t.Errorf("Fail to get key %x with value %x: got %x", failingKey, failingVal, val)
 				t.Logf("test failed at insertion of key %x index %d", test.key, i)
@@ -322,7 +364,8 @@ func TestFailingTests(t *testing.T) {
 
 	for _, test := range rt {
 		if len(test.key) != 0 {
-			val := trie.Get(test.key)
+			val, err := trie.Get(test.key)
+			require.NoError(t, err)
 			if !bytes.Equal(val, test.value) {
 				t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 			}
@@ -556,12 +599,14 @@ func TestDelete(t *testing.T) {
 			switch r {
 			case 0:
 				ssTrie.Delete(test.key)
-				val = ssTrie.Get(test.key)
+				val, err = ssTrie.Get(test.key)
+				require.NoError(t, err)
 				if val != nil {
 					t.Errorf("Fail to delete key %x with value %x: got %x", test.key, test.value, val)
 				}
 			case 1:
-				val = ssTrie.Get(test.key)
+				val, err = ssTrie.Get(test.key)
+				require.NoError(t, err)
 				if !bytes.Equal(test.value, val) {
 					t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
 				}
@@ -605,23 +650,28 @@ func TestGetKeysWithPrefix(t *testing.T) {
 	}
 
 	expected := [][]byte{{0x01, 0x35}, {0x01, 0x35, 0x79}}
-	keys := trie.GetKeysWithPrefix([]byte{0x01})
+	keys, err := trie.GetKeysWithPrefix([]byte{0x01})
+	require.NoError(t, err)
 	require.Equal(t, expected, keys)
 
 	expected = [][]byte{{0x01, 0x35}, {0x01, 0x35, 0x79}, {0x07, 0x3a}, {0x07, 0x3b}}
-	keys = trie.GetKeysWithPrefix([]byte{0x0})
+	keys, err = trie.GetKeysWithPrefix([]byte{0x0})
+	require.NoError(t, err)
 	require.Equal(t, expected, keys)
 
 	expected = [][]byte{{0x07, 0x3a}, {0x07, 0x3b}}
-	keys = trie.GetKeysWithPrefix([]byte{0x07, 0x30})
+	keys, err = trie.GetKeysWithPrefix([]byte{0x07, 0x30})
+	require.NoError(t, err)
 	require.Equal(t, expected, keys)
 
 	expected = [][]byte{[]byte(":key1")}
-	keys = trie.GetKeysWithPrefix([]byte(":key1"))
+	keys, err = trie.GetKeysWithPrefix([]byte(":key1"))
+	require.NoError(t, err)
 	require.Equal(t, expected, keys)
 
 	expected = [][]byte{}
-	keys = trie.GetKeysWithPrefix([]byte{0xff, 0xee, 0xbb, 0xcc, 0xbb, 0x11})
+	keys, err = trie.GetKeysWithPrefix([]byte{0xff, 0xee, 0xbb, 0xcc, 0xbb, 0x11})
+	require.NoError(t, err)
 	require.Equal(t, expected, keys)
 }
 
@@ -672,7 +722,8 @@ func TestNextKey(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		next := trie.NextKey(tc.input)
+		next, err := trie.NextKey(tc.input)
+		require.NoError(t, err)
 		require.Equal(t, tc.expected, next)
 	}
 }
@@ -758,7 +809,8 @@ func TestNextKey_MoreAncestors(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		next := trie.NextKey(tc.input)
+		next, err := trie.NextKey(tc.input)
+		require.NoError(t, err)
 		require.Equal(t, tc.expected, next, common.BytesToHex(tc.input))
 	}
 }
@@ -780,7 +832,8 @@ func TestNextKey_Again(t *testing.T) {
 	}
 
 	for i, tc := range testCases {
-		next := trie.NextKey([]byte(tc))
+		next, err := trie.NextKey([]byte(tc))
+		require.NoError(t, err)
 		if i == len(testCases)-1 {
 			require.Nil(t, next)
 		} else {
@@ -804,7 +857,8 @@ func TestNextKey_HostAPI(t *testing.T) {
 	nextCases := []string{"Opti", "Option"}
 
 	for _, tc := range nextCases {
-		next := trie.NextKey([]byte(tc))
+		next, err := trie.NextKey([]byte(tc))
+		require.NoError(t, err)
 		require.Nil(t, next)
 	}
 }
@@ -879,7 +933,8 @@ func TestClearPrefix(t *testing.T) {
 		}
 
 		for _, test := range tests {
-			res := ssTrie.Get(test.key)
+			res, err := ssTrie.Get(test.key)
+			require.NoError(t, err)
 
 			keyNibbles := keyToNibbles(test.key)
 			length := lenCommonPrefix(keyNibbles, prefixNibbles)
@@ -1018,7 +1073,8 @@ func TestTrie_ClearPrefixVsDelete(t *testing.T) {
 				trieClearPrefix.Put(test.key, test.value)
 			}
 
-			prefixedKeys := trieDelete.GetKeysWithPrefix(prefix)
+			prefixedKeys, err := trieDelete.GetKeysWithPrefix(prefix)
+			require.NoError(t, err)
 			for _, key := range prefixedKeys {
 				trieDelete.Delete(key)
 			}
@@ -1107,7 +1163,8 @@ func TestNextKey_Random(t *testing.T) {
 		}
 
 		for idx, tc := range testCases {
-			next := trie.NextKey(tc)
+			next, err := trie.NextKey(tc)
+			require.NoError(t, err)
 			if idx == len(testCases)-1 {
 				require.Nil(t, next)
 			} else {
@@ -1322,12 +1379,14 @@ func TestTrie_ClearPrefixLimit(t *testing.T) {
 				trieClearPrefix.Put(test.key, test.value)
 			}
 
-			num, allDeleted := trieClearPrefix.ClearPrefixLimit(prefix, uint32(lim))
+			num, allDeleted, err := trieClearPrefix.ClearPrefixLimit(prefix, uint32(lim))
+			require.NoError(t, err)
 			deleteCount := uint32(0)
 			isAllDeleted := true
 
 			for _, test := range testCase {
-				val := trieClearPrefix.Get(test.key)
+				val, err := trieClearPrefix.Get(test.key)
+				require.NoError(t, err)
 
 				keyNibbles := keyToNibbles(test.key)
 				length := lenCommonPrefix(keyNibbles, prefixNibbles)
@@ -1451,12 +1510,14 @@ func TestTrie_ClearPrefixLimitSnapshot(t *testing.T) {
 				require.Equal(t, tHash, dcTrieHash)
 				require.Equal(t, dcTrieHash, ssTrieHash)
 
-				num, allDeleted := ssTrie.ClearPrefixLimit(prefix, uint32(lim))
+				num, allDeleted, err := ssTrie.ClearPrefixLimit(prefix, uint32(lim))
+				require.NoError(t, err)
 				deleteCount := uint32(0)
 				isAllDeleted := true
 
 				for _, test := range testCase {
-					val := ssTrie.Get(test.key)
+					val, err := ssTrie.Get(test.key)
+					require.NoError(t, err)
 
 					keyNibbles := keyToNibbles(test.key)
 					length := lenCommonPrefix(keyNibbles, prefixNibbles)
@@ -0,0 +1,56 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxInlineValueSize is the maximum size of a value stored in its node with the state version V1.
+// Longer values are stored by their hash.
+const MaxInlineValueSize = 32
+
+// ErrVersionUnknown is returned when parsing a state version which isn't known
+var ErrVersionUnknown = errors.New("state version unknown")
+
+// Version is the state version of a trie, which determines how the values written to it are encoded
+// in its nodes. A trie may contain nodes of both versions, since the nodes which aren't written to
+// keep the encoding they were created with.
+type Version uint8
+
+const (
+	// V0 is the original state version, where values are always stored in their node
+	V0 Version = iota
+	// V1 is the state version where values longer than MaxInlineValueSize bytes are stored by their
+	// hash in their node, and in the database under that hash
+	V1
+)
+
+// ParseVersion returns the state version with the given number, as passed by the runtime
+func ParseVersion(v uint32) (Version, error) {
+	switch Version(v) {
+	case V0, V1:
+		return Version(v), nil
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrVersionUnknown, v)
+	}
+}
+
+// String returns the name of the state version
+func (v Version) String() string {
+	switch v {
+	case V0:
+		return "V0"
+	case V1:
+		return "V1"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(v))
+	}
+}
+
+// hashesValue returns true if the given value is stored by its hash with the state version
+func (v Version) hashesValue(value []byte) bool {
+	return v == V1 && len(value) > MaxInlineValueSize
+}
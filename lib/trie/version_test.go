@@ -0,0 +1,302 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion(0)
+	require.NoError(t, err)
+	require.Equal(t, V0, v)
+
+	v, err = ParseVersion(1)
+	require.NoError(t, err)
+	require.Equal(t, V1, v)
+
+	_, err = ParseVersion(2)
+	require.ErrorIs(t, err, ErrVersionUnknown)
+}
+
+func TestDecode_HashedValue(t *testing.T) {
+	value := bytes.Repeat([]byte{7}, MaxInlineValueSize+1)
+	valueHash := common.MustBlake2bHash(value)
+
+	tests := []node{
+		&leaf{key: []byte{}, value: value, hashedValue: true},
+		&leaf{key: byteArray(30), value: value, hashedValue: true},
+		&leaf{key: byteArray(31), value: value, hashedValue: true},
+		&leaf{key: byteArray(318), value: value, hashedValue: true},
+		&branch{key: []byte{}, children: [16]node{&leaf{}}, value: value, hashedValue: true},
+		&branch{key: byteArray(14), children: [16]node{&leaf{}}, value: value, hashedValue: true},
+		&branch{key: byteArray(15), children: [16]node{nil, &leaf{}}, value: value, hashedValue: true},
+		&branch{key: byteArray(318), children: [16]node{&leaf{}}, value: value, hashedValue: true},
+	}
+
+	for _, test := range tests {
+		buffer := bytes.NewBuffer(nil)
+		err := encodeNode(test, buffer, false)
+		require.NoError(t, err)
+
+		res, err := decode(buffer)
+		require.NoError(t, err)
+		require.Zero(t, buffer.Len())
+
+		switch n := test.(type) {
+		case *branch:
+			require.Equal(t, n.key, res.(*branch).key)
+			require.Equal(t, n.childrenBitmap(), res.(*branch).childrenBitmap())
+			require.True(t, res.(*branch).hashedValue)
+			require.Equal(t, valueHash[:], res.(*branch).value)
+		case *leaf:
+			require.Equal(t, n.key, res.(*leaf).key)
+			require.True(t, res.(*leaf).hashedValue)
+			require.Equal(t, valueHash[:], res.(*leaf).value)
+		}
+	}
+}
+
+func TestTrie_Version(t *testing.T) {
+	short := []byte("short value")
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+
+	v0 := NewEmptyTrie()
+	v1 := NewEmptyTrie()
+	v1.SetVersion(V1)
+	require.Equal(t, V1, v1.Version())
+
+	// the versions only differ for long values
+	v0.Put([]byte("a"), short)
+	v1.Put([]byte("a"), short)
+	require.Equal(t, v0.MustHash(), v1.MustHash())
+
+	v0.Put([]byte("b"), long)
+	v1.Put([]byte("b"), long)
+	require.NotEqual(t, v0.MustHash(), v1.MustHash())
+	value, err := v1.Get([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, long, value)
+
+	// a single leaf of the version V1: header | partial key | hash of the value
+	single := NewEmptyTrie()
+	single.SetVersion(V1)
+	single.Put([]byte{0xab}, long)
+
+	valueHash := common.MustBlake2bHash(long)
+	enc := append([]byte{0x22, 0xab}, valueHash[:]...)
+	require.Equal(t, common.MustBlake2bHash(enc), single.MustHash())
+
+	// values only have to be longer than the maximum inline size to be hashed
+	inline := NewEmptyTrie()
+	inline.SetVersion(V1)
+	inline.Put([]byte{0xab}, long[1:])
+
+	encValue, err := scale.Marshal(long[1:])
+	require.NoError(t, err)
+	enc = append([]byte{0x42, 0xab}, encValue...)
+	require.Equal(t, common.MustBlake2bHash(enc), inline.MustHash())
+}
+
+func TestTrie_SetVersion_KeepsWrittenValues(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+
+	trie := NewEmptyTrie()
+	trie.Put([]byte("a"), long)
+	trie.Put([]byte("ab"), long)
+	v0Root := trie.MustHash()
+
+	trie.SetVersion(V1)
+	require.Equal(t, v0Root, trie.MustHash())
+
+	// the value moved to a branch keeps its version
+	trie.Put([]byte("abc"), long)
+	trie.Delete([]byte("abc"))
+	require.Equal(t, v0Root, trie.MustHash())
+
+	// rewriting the value uses the new version
+	trie.Put([]byte("a"), bytes.Repeat([]byte{2}, MaxInlineValueSize+1))
+	trie.Put([]byte("a"), long)
+	require.NotEqual(t, v0Root, trie.MustHash())
+
+	expected := NewEmptyTrie()
+	expected.Put([]byte("ab"), long)
+	expected.SetVersion(V1)
+	expected.Put([]byte("a"), long)
+	require.Equal(t, expected.MustHash(), trie.MustHash())
+}
+
+func TestTrie_DatabaseStoreAndLoad_V1(t *testing.T) {
+	db := newTestDB(t)
+
+	entries := map[string][]byte{
+		"a":     bytes.Repeat([]byte{1}, MaxInlineValueSize+1),
+		"ab":    bytes.Repeat([]byte{2}, 100),
+		"abc":   []byte("short"),
+		"b":     bytes.Repeat([]byte{3}, MaxInlineValueSize),
+		"bcdef": bytes.Repeat([]byte{4}, 1000),
+	}
+
+	trie := NewEmptyTrie()
+	trie.SetVersion(V1)
+	for k, v := range entries {
+		trie.Put([]byte(k), v)
+	}
+
+	err := trie.Store(db)
+	require.NoError(t, err)
+
+	root := trie.MustHash()
+
+	// the long values are stored under their hash
+	valueHash := common.MustBlake2bHash(entries["ab"])
+	stored, err := db.Get(valueHash[:])
+	require.NoError(t, err)
+	require.Equal(t, entries["ab"], stored)
+
+	loaded := NewEmptyTrie()
+	err = loaded.Load(db, root)
+	require.NoError(t, err)
+	require.Equal(t, root, loaded.MustHash())
+
+	for k, v := range entries {
+		value, err := loaded.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, v, value)
+
+		value, err = GetFromDB(db, root, []byte(k))
+		require.NoError(t, err)
+		require.Equal(t, v, value)
+	}
+
+	// the dirty nodes written afterwards store their values too
+	newValue := bytes.Repeat([]byte{5}, 64)
+	err = loaded.PutInDB(db, []byte("c"), newValue)
+	require.NoError(t, err)
+
+	value, err := GetFromDB(db, loaded.MustHash(), []byte("c"))
+	require.NoError(t, err)
+	require.Equal(t, newValue, value)
+}
+
+func TestTrie_HashWithVersion(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+
+	// the values are written with the version V0, but the root is computed with the version V1
+	trie := NewEmptyTrie()
+	trie.Put([]byte("a"), long)
+	trie.Put([]byte("b"), []byte("short"))
+	v0Root := trie.MustHash()
+
+	root, err := trie.HashWithVersion(V1)
+	require.NoError(t, err)
+	require.NotEqual(t, v0Root, root)
+	require.Equal(t, root, trie.MustHash())
+
+	expected := NewEmptyTrie()
+	expected.SetVersion(V1)
+	expected.Put([]byte("a"), long)
+	expected.Put([]byte("b"), []byte("short"))
+	require.Equal(t, expected.MustHash(), root)
+
+	// the values already encoded keep their version
+	trie.Put([]byte("c"), long)
+	root, err = trie.HashWithVersion(V0)
+	require.NoError(t, err)
+
+	expected.SetVersion(V0)
+	expected.Put([]byte("c"), long)
+	require.Equal(t, expected.MustHash(), root)
+}
+
+func TestTrie_HashWithVersion_Vector(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+	longer := bytes.Repeat([]byte{2}, 64)
+
+	trie := NewEmptyTrie()
+	trie.Put([]byte("a"), long)
+	trie.Put([]byte("b"), longer)
+	trie.Put([]byte("c"), []byte("v"))
+
+	root, err := trie.HashWithVersion(V1)
+	require.NoError(t, err)
+
+	// leaves with an empty partial key storing their value by hash: 0b001 header | hash of the value,
+	// they're referenced by the hash of their encoding
+	leafRef := func(value []byte) []byte {
+		valueHash := common.MustBlake2bHash(value)
+		enc := append([]byte{0x20}, valueHash[:]...)
+		hash := common.MustBlake2bHash(enc)
+		return append([]byte{0x80}, hash[:]...)
+	}
+
+	// the short value is stored inline: 0b01 header | value, and the leaf is inlined in its parent
+	// since its encoding is shorter than 32 bytes
+	inline := []byte{0x0c, 0x40, 0x04, 'v'}
+
+	// the root branch has the partial key 0x6, no value, and children at the indexes 1, 2 and 3
+	enc := []byte{0x81, 0x06, 0x0e, 0x00}
+	enc = append(enc, leafRef(long)...)
+	enc = append(enc, leafRef(longer)...)
+	enc = append(enc, inline...)
+	require.Equal(t, common.MustBlake2bHash(enc), root)
+}
+
+func TestTrie_GetChildHashWithVersion(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+
+	child := NewEmptyTrie()
+	child.Put([]byte("a"), long)
+
+	trie := NewEmptyTrie()
+	err := trie.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	expected := NewEmptyTrie()
+	expected.SetVersion(V1)
+	expected.Put([]byte("a"), long)
+
+	root, err := trie.GetChildHashWithVersion([]byte("child"), V1)
+	require.NoError(t, err)
+	require.Equal(t, expected.MustHash(), root)
+
+	// the root of the child trie stored in the main trie is updated
+	stored, err := trie.Get(append(ChildStorageKeyPrefix, []byte("child")...))
+	require.NoError(t, err)
+	require.Equal(t, root[:], stored)
+
+	updated, err := trie.GetChild([]byte("child"))
+	require.NoError(t, err)
+	require.Equal(t, root, updated.MustHash())
+}
+
+func TestTrie_GetInsertedNodeHashes_HashedValues(t *testing.T) {
+	long := bytes.Repeat([]byte{1}, MaxInlineValueSize+1)
+	valueHash := common.MustBlake2bHash(long)
+
+	trie := NewEmptyTrie()
+	trie.SetVersion(V1)
+	trie.Put([]byte("a"), long)
+	trie.Put([]byte("b"), []byte("short"))
+
+	// the hashed value is written to the database along with its node
+	inserted, err := trie.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	require.Contains(t, inserted, valueHash)
+
+	db := newTestDB(t)
+	err = trie.WriteDirty(db)
+	require.NoError(t, err)
+
+	// so it's deleted along with it
+	next := trie.Snapshot()
+	next.Put([]byte("a"), []byte("short"))
+	require.Contains(t, next.GetDeletedNodeHash(), valueHash)
+}
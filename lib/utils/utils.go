@@ -149,6 +149,7 @@ func KeystoreFilepaths(basepath string) ([]string, error) {
 func GetGssmrGenesisRawPath() string {
 	path1 := "../chain/gssmr/genesis.json"
 	path2 := "../../chain/gssmr/genesis.json"
+	path3 := "../../../chain/gssmr/genesis.json"
 
 	var fp string
 
@@ -156,6 +157,8 @@ func GetGssmrGenesisRawPath() string {
 		fp, _ = filepath.Abs(path1)
 	} else if PathExists(path2) {
 		fp, _ = filepath.Abs(path2)
+	} else if PathExists(path3) {
+		fp, _ = filepath.Abs(path3)
 	}
 
 	return fp